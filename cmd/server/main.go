@@ -12,6 +12,7 @@ import (
 	"github.com/automax/backend/internal/config"
 	"github.com/automax/backend/internal/database"
 	"github.com/automax/backend/internal/handlers"
+	"github.com/automax/backend/internal/mail"
 	"github.com/automax/backend/internal/middleware"
 	"github.com/automax/backend/internal/repository"
 	"github.com/automax/backend/internal/services"
@@ -70,22 +71,58 @@ func main() {
 	reportTemplateRepo := repository.NewReportTemplateRepository(db)
 	lookupRepo := repository.NewLookupRepository(db)
 	callLogRepo := repository.NewCallLogRepository(db)
+	emailDeliveryRepo := repository.NewEmailDeliveryRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	webhookDeliveryRepo := repository.NewWebhookDeliveryRepository(db)
+	bulkOperationRepo := repository.NewBulkOperationRepository(db)
+	workflowAnalyticsRepo := repository.NewWorkflowAnalyticsRepository(db)
+	incidentSearchRepo := repository.NewIncidentSearchRepository(db)
+	incidentViewRepo := repository.NewIncidentViewRepository(db)
 
 	// Initialize services
 	userService := services.NewUserService(userRepo, jwtManager, sessionStore, minioStorage, cfg)
 	actionLogService := services.NewActionLogService(actionLogRepo)
 	callLogService := services.NewCallLogService(callLogRepo)
-	workflowService := services.NewWorkflowService(workflowRepo, roleRepo, departmentRepo, classificationRepo, db)
-	incidentService := services.NewIncidentService(incidentRepo, workflowRepo, userRepo, minioStorage)
+	workflowService := services.NewWorkflowService(workflowRepo, roleRepo, departmentRepo, classificationRepo, lookupRepo, db)
+	workflowAnalyticsService := services.NewWorkflowAnalyticsService(workflowAnalyticsRepo, workflowRepo)
+
+	// Initialize event bus for real-time pushes (Redis pub/sub fan-out across replicas)
+	eventBus := services.NewEventBus(redisClient)
+
+	// Initialize background job queue for async transition actions and email delivery
+	jobQueue := services.NewJobQueue(redisClient, 4)
+
+	mailService := services.NewMailService(mail.NewSender(&cfg.Mail), emailDeliveryRepo, jobQueue, cfg.Mail.MaxRetries)
+	notificationService := services.NewNotificationService(notificationRepo, eventBus)
+	webhookService := services.NewWebhookService(webhookDeliveryRepo)
+	templateRenderer := services.NewActionTemplateRenderer(classificationRepo, cfg.Server.FrontendURL)
+
+	actionExecutor := services.NewActionExecutor(incidentRepo, userRepo, workflowRepo, lookupRepo, jobQueue, mailService, notificationService, webhookService, templateRenderer)
+
+	incidentService := services.NewIncidentService(incidentRepo, workflowRepo, userRepo, departmentRepo, minioStorage, actionExecutor, eventBus, db)
+	bulkOperationService := services.NewBulkOperationService(bulkOperationRepo, incidentRepo, incidentService, jobQueue)
+	incidentSearchService := services.NewIncidentSearchService(incidentSearchRepo, userRepo)
+	incidentViewService := services.NewIncidentViewService(incidentViewRepo, userRepo)
 	reportService := services.NewReportService(reportRepo)
 	reportTemplateService := services.NewReportTemplateService(reportTemplateRepo, reportRepo)
 
 	// Initialize and start SLA Monitor (checks every 5 minutes)
-	slaMonitor := services.NewSLAMonitor(incidentRepo, 5*time.Minute)
+	slaMonitor := services.NewSLAMonitor(incidentRepo, notificationService, 5*time.Minute)
 	ctx := context.Background()
 	slaMonitor.Start(ctx)
 	defer slaMonitor.Stop()
 
+	// Automatic transitions (checks every minute, one replica at a time)
+	transitionScheduler := services.NewTransitionScheduler(incidentRepo, userRepo, incidentService, redisClient, time.Minute)
+	transitionScheduler.Start(ctx)
+	defer transitionScheduler.Stop()
+
+	jobQueue.Start(ctx)
+	defer jobQueue.Stop()
+
+	eventBus.Start(ctx)
+	defer eventBus.Stop()
+
 	// Initialize validator
 	validate := validator.New()
 
@@ -99,10 +136,18 @@ func main() {
 	actionLogHandler := handlers.NewActionLogHandler(actionLogService, validate)
 	callLogHandler := handlers.NewCallLogHandler(callLogService, validate, userService)
 	workflowHandler := handlers.NewWorkflowHandler(workflowService)
-	incidentHandler := handlers.NewIncidentHandler(incidentService, userRepo, incidentRepo, minioStorage)
+	workflowAnalyticsHandler := handlers.NewWorkflowAnalyticsHandler(workflowAnalyticsService)
+	incidentHandler := handlers.NewIncidentHandler(incidentService, incidentViewService, userRepo, incidentRepo, minioStorage)
+	bulkOperationHandler := handlers.NewBulkOperationHandler(bulkOperationService, userRepo)
+	searchHandler := handlers.NewSearchHandler(incidentSearchService)
+	incidentViewHandler := handlers.NewIncidentViewHandler(incidentViewService)
 	reportHandler := handlers.NewReportHandler(reportService)
 	reportTemplateHandler := handlers.NewReportTemplateHandler(reportTemplateService)
 	lookupHandler := handlers.NewLookupHandler(lookupRepo)
+	emailDeliveryHandler := handlers.NewEmailDeliveryHandler(mailService)
+	webhookDeliveryHandler := handlers.NewWebhookDeliveryHandler(webhookService)
+	notificationHandler := handlers.NewNotificationHandler(notificationService)
+	eventHandler := handlers.NewEventHandler(eventBus, userRepo)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(jwtManager, sessionStore, userRepo)
@@ -146,6 +191,9 @@ func main() {
 	users.Delete("/me", authMiddleware.Authenticate(), userHandler.DeleteAccount)
 	users.Put("/:userExtID/status", userHandler.UpdateUserCallStatus)
 
+	// Full-text search across the record types the user may view
+	v1.Get("/search", authMiddleware.Authenticate(), searchHandler.Search)
+
 	// Incident routes (authenticated users)
 	incidents := v1.Group("/incidents", authMiddleware.Authenticate())
 	incidents.Post("/", authMiddleware.RequirePermission("incidents:create"), incidentHandler.CreateIncident)
@@ -154,15 +202,29 @@ func main() {
 	incidents.Get("/my-assigned", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetMyAssigned)
 	incidents.Get("/my-reported", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetMyReported)
 	incidents.Get("/sla-breached", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetSLABreached)
+	incidents.Get("/pending-approvals", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetPendingApprovals)
+	incidents.Post("/bulk/transition", authMiddleware.RequirePermission("incidents:transition"), bulkOperationHandler.BulkTransition)
+	incidents.Post("/bulk/assign", authMiddleware.RequirePermission("incidents:assign"), bulkOperationHandler.BulkAssign)
+	incidents.Post("/bulk/update", authMiddleware.RequirePermission("incidents:update"), bulkOperationHandler.BulkUpdate)
+	incidents.Get("/bulk/:id", authMiddleware.RequirePermission("incidents:view"), bulkOperationHandler.GetOperation)
+	incidents.Get("/views", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.ListViews)
+	incidents.Post("/views", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.CreateView)
+	incidents.Get("/views/:view_id", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.GetView)
+	incidents.Put("/views/:view_id", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.UpdateView)
+	incidents.Delete("/views/:view_id", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.DeleteView)
+	incidents.Post("/views/:view_id/pin", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.PinView)
+	incidents.Delete("/views/:view_id/pin", authMiddleware.RequirePermission("incidents:view"), incidentViewHandler.UnpinView)
 	incidents.Get("/:id", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetIncident)
 	incidents.Get("/:id/report", authMiddleware.RequirePermission("reports:view"), incidentHandler.GenerateReport)
 	incidents.Put("/:id", authMiddleware.RequirePermission("incidents:update"), incidentHandler.UpdateIncident)
 	incidents.Delete("/:id", authMiddleware.RequirePermission("incidents:delete"), incidentHandler.DeleteIncident)
 	incidents.Post("/:id/transition", authMiddleware.RequirePermission("incidents:transition"), incidentHandler.ExecuteTransition)
+	incidents.Post("/:id/transition/preview", authMiddleware.RequirePermission("incidents:transition"), incidentHandler.PreviewTransition)
 	incidents.Post("/:id/convert-to-request", authMiddleware.RequirePermission("incidents:update"), incidentHandler.ConvertToRequest)
 	incidents.Get("/:id/can-convert", authMiddleware.RequirePermission("incidents:view"), incidentHandler.CanConvertToRequest)
 	incidents.Get("/:id/available-transitions", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetAvailableTransitions)
 	incidents.Get("/:id/history", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetTransitionHistory)
+	incidents.Get("/:id/approvals", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetApprovals)
 	incidents.Post("/:id/comments", authMiddleware.RequirePermission("incidents:comment"), incidentHandler.AddComment)
 	incidents.Get("/:id/comments", authMiddleware.RequirePermission("incidents:view"), incidentHandler.ListComments)
 	incidents.Put("/:id/comments/:comment_id", authMiddleware.RequirePermission("incidents:comment"), incidentHandler.UpdateComment)
@@ -172,6 +234,25 @@ func main() {
 	incidents.Delete("/:id/attachments/:attachment_id", authMiddleware.RequirePermission("incidents:update"), incidentHandler.DeleteAttachment)
 	incidents.Put("/:id/assign", authMiddleware.RequirePermission("incidents:assign"), incidentHandler.AssignIncident)
 	incidents.Get("/:id/revisions", authMiddleware.RequirePermission("incidents:view"), incidentHandler.ListRevisions)
+	incidents.Get("/:id/relations", authMiddleware.RequirePermission("incidents:view"), incidentHandler.ListRelations)
+	incidents.Post("/:id/relations", authMiddleware.RequirePermission("incidents:update"), incidentHandler.CreateRelation)
+	incidents.Put("/:id/relations/:relation_id", authMiddleware.RequirePermission("incidents:update"), incidentHandler.UpdateRelation)
+	incidents.Delete("/:id/relations/:relation_id", authMiddleware.RequirePermission("incidents:update"), incidentHandler.DeleteRelation)
+	incidents.Get("/:id/duplicates", authMiddleware.RequirePermission("incidents:view"), incidentHandler.GetDuplicates)
+	incidents.Post("/:id/merge-duplicate", authMiddleware.RequirePermission("incidents:update"), incidentHandler.MergeDuplicate)
+	incidents.Post("/:id/merge", authMiddleware.RequirePermission("incidents:update"), incidentHandler.MergeIncident)
+	incidents.Post("/:id/split", authMiddleware.RequirePermission("incidents:create"), incidentHandler.SplitIncident)
+
+	// Notification routes (current user's notifications)
+	notifications := v1.Group("/notifications", authMiddleware.Authenticate())
+	notifications.Get("/", notificationHandler.ListNotifications)
+	notifications.Get("/unread-count", notificationHandler.GetUnreadCount)
+	notifications.Put("/read-all", notificationHandler.MarkAllRead)
+	notifications.Put("/:id/read", notificationHandler.MarkRead)
+	notifications.Delete("/:id", notificationHandler.DeleteNotification)
+
+	// Real-time event stream (Server-Sent Events)
+	v1.Get("/events", authMiddleware.Authenticate(), eventHandler.Stream)
 
 	// Attachment download route
 	attachments := v1.Group("/attachments", authMiddleware.Authenticate())
@@ -185,8 +266,10 @@ func main() {
 	complaints.Get("/:id/report", authMiddleware.RequirePermission("reports:view"), incidentHandler.GenerateReport)
 	complaints.Put("/:id", authMiddleware.RequirePermission("complaints:update"), incidentHandler.UpdateIncident)
 	complaints.Post("/:id/transition", authMiddleware.RequirePermission("complaints:transition"), incidentHandler.ExecuteTransition)
+	complaints.Post("/:id/transition/preview", authMiddleware.RequirePermission("complaints:transition"), incidentHandler.PreviewTransition)
 	complaints.Get("/:id/available-transitions", authMiddleware.RequirePermission("complaints:view"), incidentHandler.GetAvailableTransitions)
 	complaints.Get("/:id/history", authMiddleware.RequirePermission("complaints:view"), incidentHandler.GetTransitionHistory)
+	complaints.Get("/:id/approvals", authMiddleware.RequirePermission("complaints:view"), incidentHandler.GetApprovals)
 	complaints.Post("/:id/comments", authMiddleware.RequirePermission("complaints:comment"), incidentHandler.AddComment)
 	complaints.Get("/:id/comments", authMiddleware.RequirePermission("complaints:view"), incidentHandler.ListComments)
 	complaints.Put("/:id/comments/:comment_id", authMiddleware.RequirePermission("complaints:comment"), incidentHandler.UpdateComment)
@@ -196,6 +279,14 @@ func main() {
 	complaints.Delete("/:id/attachments/:attachment_id", authMiddleware.RequirePermission("complaints:update"), incidentHandler.DeleteAttachment)
 	complaints.Post("/:id/evaluate", authMiddleware.RequirePermission("complaints:update"), incidentHandler.IncrementEvaluation)
 	complaints.Get("/:id/revisions", authMiddleware.RequirePermission("complaints:view"), incidentHandler.ListRevisions)
+	complaints.Get("/:id/relations", authMiddleware.RequirePermission("complaints:view"), incidentHandler.ListRelations)
+	complaints.Post("/:id/relations", authMiddleware.RequirePermission("complaints:update"), incidentHandler.CreateRelation)
+	complaints.Put("/:id/relations/:relation_id", authMiddleware.RequirePermission("complaints:update"), incidentHandler.UpdateRelation)
+	complaints.Delete("/:id/relations/:relation_id", authMiddleware.RequirePermission("complaints:update"), incidentHandler.DeleteRelation)
+	complaints.Get("/:id/duplicates", authMiddleware.RequirePermission("complaints:view"), incidentHandler.GetDuplicates)
+	complaints.Post("/:id/merge-duplicate", authMiddleware.RequirePermission("complaints:update"), incidentHandler.MergeDuplicate)
+	complaints.Post("/:id/merge", authMiddleware.RequirePermission("complaints:update"), incidentHandler.MergeIncident)
+	complaints.Post("/:id/split", authMiddleware.RequirePermission("complaints:create"), incidentHandler.SplitIncident)
 
 	// Query routes (authenticated users)
 	queries := v1.Group("/queries", authMiddleware.Authenticate())
@@ -205,8 +296,10 @@ func main() {
 	queries.Get("/:id/report", authMiddleware.RequirePermission("reports:view"), incidentHandler.GenerateReport)
 	queries.Put("/:id", authMiddleware.RequirePermission("queries:update"), incidentHandler.UpdateIncident)
 	queries.Post("/:id/transition", authMiddleware.RequirePermission("queries:transition"), incidentHandler.ExecuteTransition)
+	queries.Post("/:id/transition/preview", authMiddleware.RequirePermission("queries:transition"), incidentHandler.PreviewTransition)
 	queries.Get("/:id/available-transitions", authMiddleware.RequirePermission("queries:view"), incidentHandler.GetAvailableTransitions)
 	queries.Get("/:id/history", authMiddleware.RequirePermission("queries:view"), incidentHandler.GetTransitionHistory)
+	queries.Get("/:id/approvals", authMiddleware.RequirePermission("queries:view"), incidentHandler.GetApprovals)
 	queries.Post("/:id/comments", authMiddleware.RequirePermission("queries:comment"), incidentHandler.AddComment)
 	queries.Get("/:id/comments", authMiddleware.RequirePermission("queries:view"), incidentHandler.ListComments)
 	queries.Put("/:id/comments/:comment_id", authMiddleware.RequirePermission("queries:comment"), incidentHandler.UpdateComment)
@@ -215,6 +308,10 @@ func main() {
 	queries.Get("/:id/attachments", authMiddleware.RequirePermission("queries:view"), incidentHandler.ListAttachments)
 	queries.Delete("/:id/attachments/:attachment_id", authMiddleware.RequirePermission("queries:update"), incidentHandler.DeleteAttachment)
 	queries.Get("/:id/revisions", authMiddleware.RequirePermission("queries:view"), incidentHandler.ListRevisions)
+	queries.Get("/:id/relations", authMiddleware.RequirePermission("queries:view"), incidentHandler.ListRelations)
+	queries.Post("/:id/relations", authMiddleware.RequirePermission("queries:update"), incidentHandler.CreateRelation)
+	queries.Put("/:id/relations/:relation_id", authMiddleware.RequirePermission("queries:update"), incidentHandler.UpdateRelation)
+	queries.Delete("/:id/relations/:relation_id", authMiddleware.RequirePermission("queries:update"), incidentHandler.DeleteRelation)
 
 	// Admin routes
 	admin := v1.Group("/admin", authMiddleware.Authenticate())
@@ -295,6 +392,18 @@ func main() {
 	actionLogs.Get("/:id", authMiddleware.RequirePermission("action-logs:view"), actionLogHandler.GetActionLog)
 	actionLogs.Delete("/cleanup", authMiddleware.RequirePermission("action-logs:delete"), actionLogHandler.CleanupOldLogs)
 
+	// Email delivery routes
+	emailDeliveries := admin.Group("/email-deliveries")
+	emailDeliveries.Get("/", authMiddleware.RequirePermission("emails:view"), emailDeliveryHandler.ListDeliveries)
+	emailDeliveries.Get("/:id", authMiddleware.RequirePermission("emails:view"), emailDeliveryHandler.GetDelivery)
+	emailDeliveries.Post("/:id/resend", authMiddleware.RequirePermission("emails:resend"), emailDeliveryHandler.ResendDelivery)
+
+	// Webhook Delivery routes
+	webhookDeliveries := admin.Group("/webhook-deliveries")
+	webhookDeliveries.Get("/", authMiddleware.RequirePermission("webhooks:view"), webhookDeliveryHandler.ListDeliveries)
+	webhookDeliveries.Get("/:id", authMiddleware.RequirePermission("webhooks:view"), webhookDeliveryHandler.GetDelivery)
+	webhookDeliveries.Post("/:id/redeliver", authMiddleware.RequirePermission("webhooks:redeliver"), webhookDeliveryHandler.RedeliverDelivery)
+
 	// Call Log routes
 	callLogs := admin.Group("/call-logs")
 	callLogs.Post("/", authMiddleware.RequirePermission("call-logs:create"), callLogHandler.CreateCallLog)
@@ -320,6 +429,12 @@ func main() {
 	workflows.Post("/:id/classifications", authMiddleware.RequirePermission("workflows:update"), workflowHandler.AssignClassifications)
 	workflows.Get("/:id/initial-state", authMiddleware.RequirePermission("workflows:view"), workflowHandler.GetInitialState)
 	workflows.Get("/:id/export", authMiddleware.RequirePermission("workflows:view"), workflowHandler.ExportWorkflow)
+	workflows.Get("/:id/validate", authMiddleware.RequirePermission("workflows:view"), workflowHandler.ValidateWorkflow)
+	workflows.Post("/:id/publish", authMiddleware.RequirePermission("workflows:update"), workflowHandler.PublishWorkflow)
+	workflows.Get("/:id/versions", authMiddleware.RequirePermission("workflows:view"), workflowHandler.ListVersions)
+	workflows.Get("/:id/versions/diff", authMiddleware.RequirePermission("workflows:view"), workflowHandler.DiffVersions)
+	workflows.Get("/:id/versions/:version", authMiddleware.RequirePermission("workflows:view"), workflowHandler.GetVersion)
+	workflows.Post("/:id/migrate-incidents", authMiddleware.RequirePermission("workflows:update"), workflowHandler.MigrateIncidents)
 	workflows.Post("/import", authMiddleware.RequirePermission("workflows:create"), workflowHandler.ImportWorkflow)
 
 	// Workflow state routes
@@ -335,11 +450,18 @@ func main() {
 	workflows.Put("/:id/transitions/:transition_id", authMiddleware.RequirePermission("workflows:update"), workflowHandler.UpdateTransition)
 	workflows.Delete("/:id/transitions/:transition_id", authMiddleware.RequirePermission("workflows:update"), workflowHandler.DeleteTransition)
 
+	// Workflow analytics routes
+	workflows.Get("/:id/analytics/dwell-times", authMiddleware.RequirePermission("workflows:view"), workflowAnalyticsHandler.GetDwellTimes)
+	workflows.Get("/:id/analytics/transitions", authMiddleware.RequirePermission("workflows:view"), workflowAnalyticsHandler.GetTransitionFrequencies)
+	workflows.Get("/:id/analytics/rework", authMiddleware.RequirePermission("workflows:view"), workflowAnalyticsHandler.GetRework)
+	workflows.Get("/:id/analytics/throughput", authMiddleware.RequirePermission("workflows:view"), workflowAnalyticsHandler.GetUserThroughput)
+
 	// Transition configuration routes
 	transitions := admin.Group("/transitions")
 	transitions.Put("/:id/roles", authMiddleware.RequirePermission("workflows:update"), workflowHandler.SetTransitionRoles)
 	transitions.Put("/:id/requirements", authMiddleware.RequirePermission("workflows:update"), workflowHandler.SetTransitionRequirements)
 	transitions.Put("/:id/actions", authMiddleware.RequirePermission("workflows:update"), workflowHandler.SetTransitionActions)
+	transitions.Put("/:id/approval", authMiddleware.RequirePermission("workflows:update"), workflowHandler.SetTransitionApproval)
 
 	// Report routes
 	reports := admin.Group("/reports")
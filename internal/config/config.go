@@ -11,11 +11,13 @@ type Config struct {
 	Redis    RedisConfig
 	MinIO    MinIOConfig
 	JWT      JWTConfig
+	Mail     MailConfig
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
+	Port        string
+	Host        string
+	FrontendURL string // Base URL used for links in notifications and emails
 }
 
 type DatabaseConfig struct {
@@ -47,11 +49,25 @@ type JWTConfig struct {
 	ExpireHour int
 }
 
+type MailConfig struct {
+	Host               string
+	Port               int
+	Username           string
+	Password           string
+	FromAddress        string
+	FromName           string
+	Encryption         string // none, starttls, tls
+	InsecureSkipVerify bool
+	MaxRetries         int
+	TimeoutSeconds     int
+}
+
 func Load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port: getEnv("SERVER_PORT", "8080"),
-			Host: getEnv("SERVER_HOST", "0.0.0.0"),
+			Port:        getEnv("SERVER_PORT", "8080"),
+			Host:        getEnv("SERVER_HOST", "0.0.0.0"),
+			FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
 		},
 		Database: DatabaseConfig{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -78,6 +94,18 @@ func Load() *Config {
 			Secret:     getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
 			ExpireHour: getEnvAsInt("JWT_EXPIRE_HOUR", 24),
 		},
+		Mail: MailConfig{
+			Host:               getEnv("SMTP_HOST", ""),
+			Port:               getEnvAsInt("SMTP_PORT", 587),
+			Username:           getEnv("SMTP_USERNAME", ""),
+			Password:           getEnv("SMTP_PASSWORD", ""),
+			FromAddress:        getEnv("SMTP_FROM_ADDRESS", "no-reply@automax.com"),
+			FromName:           getEnv("SMTP_FROM_NAME", "Automax"),
+			Encryption:         getEnv("SMTP_ENCRYPTION", "starttls"),
+			InsecureSkipVerify: getEnvAsBool("SMTP_INSECURE_SKIP_VERIFY", false),
+			MaxRetries:         getEnvAsInt("SMTP_MAX_RETRIES", 3),
+			TimeoutSeconds:     getEnvAsInt("SMTP_TIMEOUT_SECONDS", 30),
+		},
 	}
 }
 
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// incidentFilterStatements define the helpers filter expressions use to read custom fields.
+// Custom fields are free-form text, so values that are not a JSON object read as NULL.
+var incidentFilterStatements = []string{
+	`CREATE OR REPLACE FUNCTION incident_custom_field(fields text, key text) RETURNS text AS $$
+	BEGIN
+		RETURN fields::jsonb ->> key;
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql IMMUTABLE`,
+
+	`CREATE OR REPLACE FUNCTION incident_custom_number(fields text, key text) RETURNS numeric AS $$
+	BEGIN
+		RETURN (fields::jsonb ->> key)::numeric;
+	EXCEPTION WHEN others THEN
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql IMMUTABLE`,
+}
+
+// migrateIncidentFilters installs the custom field helpers
+func migrateIncidentFilters(db *gorm.DB) error {
+	for _, stmt := range incidentFilterStatements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to set up incident filters: %w", err)
+		}
+	}
+	return nil
+}
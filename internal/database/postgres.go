@@ -7,6 +7,7 @@ import (
 	"github.com/automax/backend/internal/config"
 	"github.com/automax/backend/internal/models"
 	"github.com/automax/backend/pkg/utils"
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -65,6 +66,9 @@ func Migrate(db *gorm.DB) error {
 		&models.WorkflowTransition{},
 		&models.TransitionRequirement{},
 		&models.TransitionAction{},
+		&models.WorkflowVersion{},
+		&models.TransitionApproval{},
+		&models.BulkOperation{},
 		// Incident models
 		&models.Incident{},
 		&models.IncidentComment{},
@@ -72,6 +76,16 @@ func Migrate(db *gorm.DB) error {
 		&models.IncidentFeedback{},
 		&models.IncidentTransitionHistory{},
 		&models.IncidentRevision{},
+		&models.IncidentSearchDocument{},
+		&models.IncidentView{},
+		&models.IncidentViewPin{},
+		&models.IncidentRelation{},
+		// Email models
+		&models.EmailDelivery{},
+		// Webhook models
+		&models.WebhookDelivery{},
+		// Notification models
+		&models.Notification{},
 		// Report models
 		&models.Report{},
 		&models.ReportExecution{},
@@ -80,6 +94,12 @@ func Migrate(db *gorm.DB) error {
 	if err != nil {
 		return fmt.Errorf("failed to run migrations: %w", err)
 	}
+	if err := migrateIncidentSearch(db); err != nil {
+		return err
+	}
+	if err := migrateIncidentFilters(db); err != nil {
+		return err
+	}
 	log.Println("Database migrations completed")
 	return nil
 }
@@ -197,6 +217,14 @@ func Seed(db *gorm.DB) error {
 		{Name: "Update Lookups", Code: "lookups:update", Module: "lookups", Action: "update", Description: "Update lookup categories and values"},
 		{Name: "Delete Lookups", Code: "lookups:delete", Module: "lookups", Action: "delete", Description: "Delete lookup categories and values"},
 
+		// Email delivery permissions
+		{Name: "View Email Deliveries", Code: "emails:view", Module: "emails", Action: "view", Description: "View outgoing email delivery records"},
+		{Name: "Resend Emails", Code: "emails:resend", Module: "emails", Action: "resend", Description: "Resend failed emails"},
+
+		// Webhook delivery permissions
+		{Name: "View Webhook Deliveries", Code: "webhooks:view", Module: "webhooks", Action: "view", Description: "View outbound webhook delivery records"},
+		{Name: "Redeliver Webhooks", Code: "webhooks:redeliver", Module: "webhooks", Action: "redeliver", Description: "Redeliver webhook calls"},
+
 		// Dashboard permissions
 		{Name: "Admin Dashboard", Code: "dashboard:admin", Module: "dashboard", Action: "admin", Description: "Access admin section cards on dashboard"},
 		{Name: "Incidents Dashboard", Code: "dashboard:incidents", Module: "dashboard", Action: "incidents", Description: "Access incident cards on dashboard"},
@@ -287,6 +315,23 @@ func Seed(db *gorm.DB) error {
 		db.Model(&adminUser).Association("Roles").Append(&adminRole)
 	}
 
+	// Create the system user that automatic transitions and actions are attributed to. It has a
+	// random password, so nobody can log in as it, but stays active so the jobs acting as it pass
+	// the usual user checks.
+	var systemUser models.User
+	result = db.Where("email = ?", models.SystemUserEmail).First(&systemUser)
+	if result.Error == gorm.ErrRecordNotFound {
+		hashedPassword, _ := utils.HashPassword(uuid.New().String())
+		systemUser = models.User{
+			Email:     models.SystemUserEmail,
+			Username:  "system",
+			Password:  hashedPassword,
+			FirstName: "System",
+			IsActive:  true,
+		}
+		db.Create(&systemUser)
+	}
+
 	// Seed default lookup categories
 	seedLookupCategories(db)
 
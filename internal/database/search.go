@@ -0,0 +1,104 @@
+package database
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// incidentSearchStatements keep incident_search_documents in step with incidents and their
+// comments. Every text is indexed with the english and arabic stemmers plus the simple
+// configuration, so exact tokens such as incident numbers and names match too.
+var incidentSearchStatements = []string{
+	`CREATE OR REPLACE FUNCTION incident_search_vector(body text, weight "char") RETURNS tsvector AS $$
+		SELECT setweight(
+			to_tsvector('english', coalesce(body, '')) ||
+			to_tsvector('arabic', coalesce(body, '')) ||
+			to_tsvector('simple', coalesce(body, '')),
+			weight)
+	$$ LANGUAGE sql IMMUTABLE`,
+
+	// Title and number weigh most, then description and reporter, then comments and custom fields
+	`CREATE OR REPLACE FUNCTION refresh_incident_search_document(target uuid) RETURNS void AS $$
+	BEGIN
+		DELETE FROM incident_search_documents WHERE incident_id = target;
+		INSERT INTO incident_search_documents (incident_id, content, document, updated_at)
+		SELECT i.id,
+			concat_ws(E'\n', i.description, c.body, f.body),
+			incident_search_vector(concat_ws(' ', i.incident_number, i.title), 'A') ||
+			incident_search_vector(concat_ws(' ', i.description, i.reporter_name, i.reporter_email, i.created_by_name, u.first_name, u.last_name), 'B') ||
+			incident_search_vector(concat_ws(' ', c.body, f.body), 'C'),
+			NOW()
+		FROM incidents i
+		LEFT JOIN users u ON u.id = i.reporter_id
+		LEFT JOIN LATERAL (
+			SELECT string_agg(ic.content, E'\n' ORDER BY ic.created_at) AS body
+			FROM incident_comments ic
+			WHERE ic.incident_id = i.id AND ic.deleted_at IS NULL
+		) c ON true
+		CROSS JOIN LATERAL (
+			SELECT regexp_replace(coalesce(i.custom_fields, ''), '[{}\[\]":,]+', ' ', 'g') AS body
+		) f
+		WHERE i.id = target;
+	END;
+	$$ LANGUAGE plpgsql`,
+
+	`CREATE OR REPLACE FUNCTION incident_search_incident_trigger() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP = 'DELETE' THEN
+			PERFORM refresh_incident_search_document(OLD.id);
+		ELSE
+			PERFORM refresh_incident_search_document(NEW.id);
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`,
+
+	`CREATE OR REPLACE FUNCTION incident_search_comment_trigger() RETURNS trigger AS $$
+	BEGIN
+		IF TG_OP <> 'INSERT' THEN
+			PERFORM refresh_incident_search_document(OLD.incident_id);
+		END IF;
+		IF TG_OP = 'INSERT' OR (TG_OP = 'UPDATE' AND NEW.incident_id <> OLD.incident_id) THEN
+			PERFORM refresh_incident_search_document(NEW.incident_id);
+		END IF;
+		RETURN NULL;
+	END;
+	$$ LANGUAGE plpgsql`,
+
+	`DROP TRIGGER IF EXISTS incident_search_write ON incidents`,
+	`CREATE TRIGGER incident_search_write AFTER INSERT OR DELETE ON incidents
+		FOR EACH ROW EXECUTE FUNCTION incident_search_incident_trigger()`,
+
+	// Saves rewrite every column, so only refresh when an indexed one changed
+	`DROP TRIGGER IF EXISTS incident_search_update ON incidents`,
+	`CREATE TRIGGER incident_search_update AFTER UPDATE ON incidents
+		FOR EACH ROW WHEN (
+			OLD.incident_number IS DISTINCT FROM NEW.incident_number OR
+			OLD.title IS DISTINCT FROM NEW.title OR
+			OLD.description IS DISTINCT FROM NEW.description OR
+			OLD.reporter_id IS DISTINCT FROM NEW.reporter_id OR
+			OLD.reporter_name IS DISTINCT FROM NEW.reporter_name OR
+			OLD.reporter_email IS DISTINCT FROM NEW.reporter_email OR
+			OLD.created_by_name IS DISTINCT FROM NEW.created_by_name OR
+			OLD.custom_fields IS DISTINCT FROM NEW.custom_fields
+		) EXECUTE FUNCTION incident_search_incident_trigger()`,
+
+	`DROP TRIGGER IF EXISTS incident_search_comment ON incident_comments`,
+	`CREATE TRIGGER incident_search_comment AFTER INSERT OR UPDATE OR DELETE ON incident_comments
+		FOR EACH ROW EXECUTE FUNCTION incident_search_comment_trigger()`,
+
+	// Index records created before the triggers existed
+	`SELECT refresh_incident_search_document(i.id) FROM incidents i
+		WHERE NOT EXISTS (SELECT 1 FROM incident_search_documents d WHERE d.incident_id = i.id)`,
+}
+
+// migrateIncidentSearch installs the search index triggers and backfills missing documents
+func migrateIncidentSearch(db *gorm.DB) error {
+	for _, stmt := range incidentSearchStatements {
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to set up incident search: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type BulkOperationHandler struct {
+	service   services.BulkOperationService
+	userRepo  repository.UserRepository
+	validator *validator.Validate
+}
+
+func NewBulkOperationHandler(service services.BulkOperationService, userRepo repository.UserRepository) *BulkOperationHandler {
+	return &BulkOperationHandler{
+		service:   service,
+		userRepo:  userRepo,
+		validator: validator.New(),
+	}
+}
+
+func (h *BulkOperationHandler) getUserRoleIDs(c *fiber.Ctx) []uuid.UUID {
+	userID := c.Locals("user_id").(uuid.UUID)
+	roles, err := h.userRepo.GetUserRoles(c.Context(), userID)
+	if err != nil {
+		return []uuid.UUID{}
+	}
+
+	roleIDs := make([]uuid.UUID, len(roles))
+	for i, role := range roles {
+		roleIDs[i] = role.ID
+	}
+	return roleIDs
+}
+
+// respond returns 202 while a queued operation is still waiting to run
+func (h *BulkOperationHandler) respond(c *fiber.Ctx, operation *models.BulkOperationResponse) error {
+	if operation.Status == models.BulkStatusPending {
+		return utils.SuccessResponse(c, fiber.StatusAccepted, "Bulk operation queued", operation)
+	}
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bulk operation completed", operation)
+}
+
+// BulkTransition handles POST /incidents/bulk/transition
+func (h *BulkOperationHandler) BulkTransition(c *fiber.Ctx) error {
+	var req models.BulkTransitionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req.Transition); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	operation, err := h.service.BulkTransition(c.Context(), &req, userID, h.getUserRoleIDs(c))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.respond(c, operation)
+}
+
+// BulkAssign handles POST /incidents/bulk/assign
+func (h *BulkOperationHandler) BulkAssign(c *fiber.Ctx) error {
+	var req models.BulkAssignRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	operation, err := h.service.BulkAssign(c.Context(), &req, userID, h.getUserRoleIDs(c))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.respond(c, operation)
+}
+
+// BulkUpdate handles POST /incidents/bulk/update
+func (h *BulkOperationHandler) BulkUpdate(c *fiber.Ctx) error {
+	var req models.BulkUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req.Update); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	operation, err := h.service.BulkUpdate(c.Context(), &req, userID, h.getUserRoleIDs(c))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return h.respond(c, operation)
+}
+
+// GetOperation handles GET /incidents/bulk/:id for progress polling
+func (h *BulkOperationHandler) GetOperation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	operation, err := h.service.GetOperation(c.Context(), id, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Bulk operation retrieved", operation)
+}
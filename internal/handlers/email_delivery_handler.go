@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type EmailDeliveryHandler struct {
+	service services.MailService
+}
+
+func NewEmailDeliveryHandler(service services.MailService) *EmailDeliveryHandler {
+	return &EmailDeliveryHandler{service: service}
+}
+
+// ListDeliveries handles GET /admin/email-deliveries
+func (h *EmailDeliveryHandler) ListDeliveries(c *fiber.Ctx) error {
+	filter := &models.EmailDeliveryFilter{
+		Page:  1,
+		Limit: 20,
+	}
+
+	// Parse query parameters
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = l
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filter.Status = status
+	}
+	if to := c.Query("to"); to != "" {
+		filter.To = to
+	}
+	if incidentID := c.Query("incident_id"); incidentID != "" {
+		if id, err := uuid.Parse(incidentID); err == nil {
+			filter.IncidentID = &id
+		}
+	}
+	if search := c.Query("search"); search != "" {
+		filter.Search = search
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			filter.StartDate = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			// Set to end of day
+			t = t.Add(24*time.Hour - time.Second)
+			filter.EndDate = &t
+		}
+	}
+
+	deliveries, total, err := h.service.ListDeliveries(c.Context(), filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	totalPages := (int(total) + filter.Limit - 1) / filter.Limit
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"data":        deliveries,
+		"total_items": total,
+		"total_pages": totalPages,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+	})
+}
+
+// GetDelivery handles GET /admin/email-deliveries/:id
+func (h *EmailDeliveryHandler) GetDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid email delivery ID")
+	}
+
+	delivery, err := h.service.GetDelivery(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Email delivery not found")
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Email delivery retrieved successfully", delivery)
+}
+
+// ResendDelivery handles POST /admin/email-deliveries/:id/resend
+func (h *EmailDeliveryHandler) ResendDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid email delivery ID")
+	}
+
+	delivery, err := h.service.Resend(c.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailDeliveryNotFound):
+			return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+		case errors.Is(err, services.ErrEmailDeliveryPending):
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Email resend attempted", delivery)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+const eventStreamHeartbeat = 25 * time.Second
+
+type EventHandler struct {
+	eventBus services.EventBus
+	userRepo repository.UserRepository
+}
+
+func NewEventHandler(eventBus services.EventBus, userRepo repository.UserRepository) *EventHandler {
+	return &EventHandler{
+		eventBus: eventBus,
+		userRepo: userRepo,
+	}
+}
+
+// Stream handles GET /events as a Server-Sent Events stream. The token may be passed
+// as a query parameter since EventSource cannot set the Authorization header.
+func (h *EventHandler) Stream(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	user, err := h.userRepo.FindByIDWithPermissions(c.Context(), userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "User not found")
+	}
+
+	subscriber := &models.EventSubscriber{
+		UserID:       user.ID,
+		IsSuperAdmin: user.IsSuperAdmin,
+		RoleIDs:      make(map[uuid.UUID]bool),
+		Permissions:  make(map[string]bool),
+	}
+	for _, role := range user.Roles {
+		if !role.IsActive {
+			continue
+		}
+		subscriber.RoleIDs[role.ID] = true
+	}
+	for _, code := range user.GetPermissions() {
+		subscriber.Permissions[code] = true
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	sub, unsubscribe := h.eventBus.Subscribe(subscriber)
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		heartbeat := time.NewTicker(eventStreamHeartbeat)
+		defer heartbeat.Stop()
+
+		// Tell the client the stream is open
+		fmt.Fprintf(w, "retry: 5000\nevent: connected\ndata: {\"user_id\":%q}\n\n", subscriber.UserID.String())
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case event := <-sub.Events:
+				payload, err := json.Marshal(event)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+
+			// A flush error means the client disconnected
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
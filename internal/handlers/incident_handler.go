@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -17,15 +19,17 @@ import (
 
 type IncidentHandler struct {
 	service      services.IncidentService
+	viewService  services.IncidentViewService
 	userRepo     repository.UserRepository
 	incidentRepo repository.IncidentRepository
 	storage      *storage.MinIOStorage
 	validator    *validator.Validate
 }
 
-func NewIncidentHandler(service services.IncidentService, userRepo repository.UserRepository, incidentRepo repository.IncidentRepository, storage *storage.MinIOStorage) *IncidentHandler {
+func NewIncidentHandler(service services.IncidentService, viewService services.IncidentViewService, userRepo repository.UserRepository, incidentRepo repository.IncidentRepository, storage *storage.MinIOStorage) *IncidentHandler {
 	return &IncidentHandler{
 		service:      service,
+		viewService:  viewService,
 		userRepo:     userRepo,
 		incidentRepo: incidentRepo,
 		storage:      storage,
@@ -48,6 +52,32 @@ func (h *IncidentHandler) getUserRoleIDs(c *fiber.Ctx) []uuid.UUID {
 	return roleIDs
 }
 
+// applyFilterExpression adds the ?filter= expression (JSON) and the saved ?view_id= to a list filter
+func (h *IncidentHandler) applyFilterExpression(c *fiber.Ctx, filter *models.IncidentFilter) error {
+	if raw := c.Query("filter"); raw != "" {
+		var node models.IncidentFilterNode
+		if err := json.Unmarshal([]byte(raw), &node); err != nil {
+			return errors.New("invalid filter expression")
+		}
+		if err := node.Validate(); err != nil {
+			return err
+		}
+		filter.Expression = &node
+	}
+
+	if viewID := c.Query("view_id"); viewID != "" {
+		id, err := uuid.Parse(viewID)
+		if err != nil {
+			return errors.New("invalid view_id")
+		}
+		userID := c.Locals("user_id").(uuid.UUID)
+		if err := h.viewService.ApplyView(c.Context(), id, userID, filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Incident CRUD
 
 func (h *IncidentHandler) CreateIncident(c *fiber.Ctx) error {
@@ -168,6 +198,10 @@ func (h *IncidentHandler) ListIncidents(c *fiber.Ctx) error {
 		}
 	}
 
+	if err := h.applyFilterExpression(c, filter); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
 	incidents, total, err := h.service.ListIncidents(c.Context(), filter)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
@@ -293,12 +327,48 @@ func (h *IncidentHandler) ExecuteTransition(c *fiber.Ctx) error {
 
 	incident, err := h.service.ExecuteTransition(c.Context(), id, &req, userID, roleIDs)
 	if err != nil {
+		var reqErr *services.TransitionRequirementsError
+		if errors.As(err, &reqErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Transition requirements not met",
+				"details": reqErr.Failures,
+			})
+		}
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Transition executed", incident)
 }
 
+// PreviewTransition reports what a transition would do without executing it
+func (h *IncidentHandler) PreviewTransition(c *fiber.Ctx) error {
+	idStr := c.Params("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	var req models.IncidentTransitionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+	roleIDs := h.getUserRoleIDs(c)
+
+	preview, err := h.service.PreviewTransition(c.Context(), id, &req, userID, roleIDs)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Transition preview", preview)
+}
+
 func (h *IncidentHandler) GetAvailableTransitions(c *fiber.Ctx) error {
 	idStr := c.Params("id")
 	id, err := uuid.Parse(idStr)
@@ -331,6 +401,33 @@ func (h *IncidentHandler) GetTransitionHistory(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, fiber.StatusOK, "Transition history retrieved", history)
 }
 
+func (h *IncidentHandler) GetApprovals(c *fiber.Ctx) error {
+	idStr := c.Params("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	approvals, err := h.service.GetIncidentApprovals(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Approvals retrieved", approvals)
+}
+
+func (h *IncidentHandler) GetPendingApprovals(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+	recordType := c.Query("record_type", "") // Optional filter: incident, request, complaint
+
+	pending, err := h.service.GetPendingApprovals(c.Context(), userID, recordType)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Pending approvals retrieved", pending)
+}
+
 // Comments
 
 func (h *IncidentHandler) AddComment(c *fiber.Ctx) error {
@@ -696,6 +793,199 @@ func (h *IncidentHandler) ListRevisions(c *fiber.Ctx) error {
 	})
 }
 
+// Duplicates
+
+// GetDuplicates lists the open records that likely report the same problem
+func (h *IncidentHandler) GetDuplicates(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	candidates, err := h.service.FindDuplicates(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Duplicate candidates retrieved", candidates)
+}
+
+// MergeDuplicate merges the record into the master record given in the body
+func (h *IncidentHandler) MergeDuplicate(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	var req models.MergeDuplicateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result, err := h.service.MergeDuplicate(c.Context(), id, &req, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Duplicate merged", result)
+}
+
+// Merge and split
+
+// MergeIncident merges the record into the target record given in the body and closes it
+func (h *IncidentHandler) MergeIncident(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	var req models.MergeIncidentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result, err := h.service.MergeIncident(c.Context(), id, &req, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Record merged", result)
+}
+
+// SplitIncident creates new records from a subset of the record's comments and attachments
+func (h *IncidentHandler) SplitIncident(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	var req models.SplitIncidentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	result, err := h.service.SplitIncident(c.Context(), id, &req, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Record split", result)
+}
+
+// Relations
+
+// ListRelations lists the record's relations, optionally of one type (?type=blocked_by)
+func (h *IncidentHandler) ListRelations(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	relations, err := h.service.ListRelations(c.Context(), id, c.Query("type"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Relations retrieved", relations)
+}
+
+func (h *IncidentHandler) CreateRelation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+
+	var req models.IncidentRelationCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	relation, err := h.service.CreateRelation(c.Context(), id, &req, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Relation created", relation)
+}
+
+func (h *IncidentHandler) UpdateRelation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+	relationID, err := uuid.Parse(c.Params("relation_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid relation ID")
+	}
+
+	var req models.IncidentRelationUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	relation, err := h.service.UpdateRelation(c.Context(), id, relationID, &req, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrIncidentRelationNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Relation not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Relation updated", relation)
+}
+
+func (h *IncidentHandler) DeleteRelation(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid ID")
+	}
+	relationID, err := uuid.Parse(c.Params("relation_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid relation ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.DeleteRelation(c.Context(), id, relationID, userID); err != nil {
+		if errors.Is(err, services.ErrIncidentRelationNotFound) {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Relation not found")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Relation deleted", nil)
+}
+
 // Complaint handlers
 
 func (h *IncidentHandler) CreateComplaint(c *fiber.Ctx) error {
@@ -792,6 +1082,10 @@ func (h *IncidentHandler) ListComplaints(c *fiber.Ctx) error {
 		}
 	}
 
+	if err := h.applyFilterExpression(c, filter); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
 	complaints, total, err := h.service.ListIncidents(c.Context(), filter)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
@@ -945,6 +1239,10 @@ func (h *IncidentHandler) ListQueries(c *fiber.Ctx) error {
 		}
 	}
 
+	if err := h.applyFilterExpression(c, filter); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
 	queries, total, err := h.service.ListIncidents(c.Context(), filter)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type IncidentViewHandler struct {
+	service   services.IncidentViewService
+	validator *validator.Validate
+}
+
+func NewIncidentViewHandler(service services.IncidentViewService) *IncidentViewHandler {
+	return &IncidentViewHandler{
+		service:   service,
+		validator: validator.New(),
+	}
+}
+
+// ListViews handles GET /incidents/views. Counts are included unless ?counts=false.
+func (h *IncidentViewHandler) ListViews(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	views, err := h.service.ListViews(c.Context(), userID, c.Query("counts") != "false")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Views retrieved", views)
+}
+
+// CreateView handles POST /incidents/views
+func (h *IncidentViewHandler) CreateView(c *fiber.Ctx) error {
+	var req models.IncidentViewCreateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	view, err := h.service.CreateView(c.Context(), &req, userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "View created", view)
+}
+
+// GetView handles GET /incidents/views/:view_id
+func (h *IncidentViewHandler) GetView(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("view_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	view, err := h.service.GetView(c.Context(), id, userID)
+	if err != nil {
+		return incidentViewError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "View retrieved", view)
+}
+
+// UpdateView handles PUT /incidents/views/:view_id
+func (h *IncidentViewHandler) UpdateView(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("view_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	var req models.IncidentViewUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	view, err := h.service.UpdateView(c.Context(), id, &req, userID)
+	if err != nil {
+		return incidentViewError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "View updated", view)
+}
+
+// DeleteView handles DELETE /incidents/views/:view_id
+func (h *IncidentViewHandler) DeleteView(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("view_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.DeleteView(c.Context(), id, userID); err != nil {
+		return incidentViewError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "View deleted", nil)
+}
+
+// PinView handles POST /incidents/views/:view_id/pin
+func (h *IncidentViewHandler) PinView(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("view_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	var req models.IncidentViewPinRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.PinView(c.Context(), id, userID, req.SortOrder); err != nil {
+		return incidentViewError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "View pinned", nil)
+}
+
+// UnpinView handles DELETE /incidents/views/:view_id/pin
+func (h *IncidentViewHandler) UnpinView(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("view_id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid view ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.UnpinView(c.Context(), id, userID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "View unpinned", nil)
+}
+
+func incidentViewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrIncidentViewNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "View not found")
+	case errors.Is(err, services.ErrIncidentViewNotOwner):
+		return utils.ErrorResponse(c, fiber.StatusForbidden, err.Error())
+	}
+	return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+}
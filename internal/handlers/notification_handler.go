@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type NotificationHandler struct {
+	service services.NotificationService
+}
+
+func NewNotificationHandler(service services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{service: service}
+}
+
+// ListNotifications handles GET /notifications
+func (h *NotificationHandler) ListNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	filter := &models.NotificationFilter{
+		UserID:     userID,
+		UnreadOnly: c.Query("unread") == "true",
+		Type:       c.Query("type"),
+		Page:       1,
+		Limit:      20,
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = l
+		}
+	}
+
+	notifications, total, err := h.service.ListNotifications(c.Context(), filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	totalPages := (int(total) + filter.Limit - 1) / filter.Limit
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"data":        notifications,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_items": total,
+		"total_pages": totalPages,
+	})
+}
+
+// GetUnreadCount handles GET /notifications/unread-count
+func (h *NotificationHandler) GetUnreadCount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	count, err := h.service.GetUnreadCount(c.Context(), userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Unread count retrieved", fiber.Map{"count": count})
+}
+
+// MarkRead handles PUT /notifications/:id/read
+func (h *NotificationHandler) MarkRead(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid notification ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.MarkRead(c.Context(), id, userID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Notification marked as read", nil)
+}
+
+// MarkAllRead handles PUT /notifications/read-all
+func (h *NotificationHandler) MarkAllRead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	updated, err := h.service.MarkAllRead(c.Context(), userID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "All notifications marked as read", fiber.Map{"updated": updated})
+}
+
+// DeleteNotification handles DELETE /notifications/:id
+func (h *NotificationHandler) DeleteNotification(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid notification ID")
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	if err := h.service.DeleteNotification(c.Context(), id, userID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Notification deleted", nil)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type SearchHandler struct {
+	service services.IncidentSearchService
+}
+
+func NewSearchHandler(service services.IncidentSearchService) *SearchHandler {
+	return &SearchHandler{service: service}
+}
+
+// Search handles GET /search. Results cover every record type the caller may view,
+// or only ?record_type= when given.
+func (h *SearchHandler) Search(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	filter := &models.IncidentSearchFilter{
+		Query:      c.Query("q"),
+		RecordType: c.Query("record_type"),
+	}
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = l
+		}
+	}
+
+	results, total, err := h.service.Search(c.Context(), userID, filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSearchQueryRequired):
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Search query (q) is required")
+		case errors.Is(err, services.ErrSearchForbidden):
+			return utils.ErrorResponse(c, fiber.StatusForbidden, "You are not allowed to search these records")
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	totalPages := (int(total) + filter.Limit - 1) / filter.Limit
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"data":        results,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+		"total_items": total,
+		"total_pages": totalPages,
+	})
+}
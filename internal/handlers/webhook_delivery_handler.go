@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WebhookDeliveryHandler struct {
+	service services.WebhookService
+}
+
+func NewWebhookDeliveryHandler(service services.WebhookService) *WebhookDeliveryHandler {
+	return &WebhookDeliveryHandler{service: service}
+}
+
+// ListDeliveries handles GET /admin/webhook-deliveries
+func (h *WebhookDeliveryHandler) ListDeliveries(c *fiber.Ctx) error {
+	filter := &models.WebhookDeliveryFilter{
+		Page:  1,
+		Limit: 20,
+	}
+
+	// Parse query parameters
+	if page := c.Query("page"); page != "" {
+		if p, err := strconv.Atoi(page); err == nil {
+			filter.Page = p
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		if l, err := strconv.Atoi(limit); err == nil {
+			filter.Limit = l
+		}
+	}
+	if status := c.Query("status"); status != "" {
+		filter.Status = status
+	}
+	if key := c.Query("idempotency_key"); key != "" {
+		filter.IdempotencyKey = key
+	}
+	if incidentID := c.Query("incident_id"); incidentID != "" {
+		if id, err := uuid.Parse(incidentID); err == nil {
+			filter.IncidentID = &id
+		}
+	}
+	if actionID := c.Query("transition_action_id"); actionID != "" {
+		if id, err := uuid.Parse(actionID); err == nil {
+			filter.TransitionActionID = &id
+		}
+	}
+	if search := c.Query("search"); search != "" {
+		filter.Search = search
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if t, err := time.Parse("2006-01-02", startDate); err == nil {
+			filter.StartDate = &t
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if t, err := time.Parse("2006-01-02", endDate); err == nil {
+			// Set to end of day
+			t = t.Add(24*time.Hour - time.Second)
+			filter.EndDate = &t
+		}
+	}
+
+	deliveries, total, err := h.service.ListDeliveries(c.Context(), filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	totalPages := (int(total) + filter.Limit - 1) / filter.Limit
+
+	return c.JSON(fiber.Map{
+		"success":     true,
+		"data":        deliveries,
+		"total_items": total,
+		"total_pages": totalPages,
+		"page":        filter.Page,
+		"limit":       filter.Limit,
+	})
+}
+
+// GetDelivery handles GET /admin/webhook-deliveries/:id
+func (h *WebhookDeliveryHandler) GetDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid webhook delivery ID")
+	}
+
+	delivery, err := h.service.GetDelivery(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Webhook delivery not found")
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Webhook delivery retrieved successfully", delivery)
+}
+
+// RedeliverDelivery handles POST /admin/webhook-deliveries/:id/redeliver
+func (h *WebhookDeliveryHandler) RedeliverDelivery(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid webhook delivery ID")
+	}
+
+	delivery, err := h.service.Redeliver(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Webhook redelivery attempted", delivery)
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/services"
+	"github.com/automax/backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+type WorkflowAnalyticsHandler struct {
+	service services.WorkflowAnalyticsService
+}
+
+func NewWorkflowAnalyticsHandler(service services.WorkflowAnalyticsService) *WorkflowAnalyticsHandler {
+	return &WorkflowAnalyticsHandler{service: service}
+}
+
+// GetDwellTimes handles GET /admin/workflows/:id/analytics/dwell-times
+func (h *WorkflowAnalyticsHandler) GetDwellTimes(c *fiber.Ctx) error {
+	filter, err := parseWorkflowAnalyticsFilter(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rows, err := h.service.GetDwellTimes(c.Context(), filter)
+	if err != nil {
+		return workflowAnalyticsError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "State dwell times retrieved", rows)
+}
+
+// GetTransitionFrequencies handles GET /admin/workflows/:id/analytics/transitions
+func (h *WorkflowAnalyticsHandler) GetTransitionFrequencies(c *fiber.Ctx) error {
+	filter, err := parseWorkflowAnalyticsFilter(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rows, err := h.service.GetTransitionFrequencies(c.Context(), filter)
+	if err != nil {
+		return workflowAnalyticsError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Transition frequencies retrieved", rows)
+}
+
+// GetRework handles GET /admin/workflows/:id/analytics/rework
+func (h *WorkflowAnalyticsHandler) GetRework(c *fiber.Ctx) error {
+	filter, err := parseWorkflowAnalyticsFilter(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rework, err := h.service.GetRework(c.Context(), filter)
+	if err != nil {
+		return workflowAnalyticsError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Rework loops retrieved", rework)
+}
+
+// GetUserThroughput handles GET /admin/workflows/:id/analytics/throughput
+func (h *WorkflowAnalyticsHandler) GetUserThroughput(c *fiber.Ctx) error {
+	filter, err := parseWorkflowAnalyticsFilter(c)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	rows, err := h.service.GetUserThroughput(c.Context(), filter)
+	if err != nil {
+		return workflowAnalyticsError(c, err)
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "User throughput retrieved", rows)
+}
+
+// parseWorkflowAnalyticsFilter reads the workflow ID and the optional period, classification
+// and department filters. Dates are RFC3339 or YYYY-MM-DD; a plain end date covers the whole day.
+func parseWorkflowAnalyticsFilter(c *fiber.Ctx) (*models.WorkflowAnalyticsFilter, error) {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+	filter := &models.WorkflowAnalyticsFilter{WorkflowID: id}
+
+	if startDate := c.Query("start_date"); startDate != "" {
+		t, err := parseAnalyticsDate(startDate, false)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid start_date")
+		}
+		filter.StartDate = &t
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		t, err := parseAnalyticsDate(endDate, true)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid end_date")
+		}
+		filter.EndDate = &t
+	}
+	if classificationID := c.Query("classification_id"); classificationID != "" {
+		cid, err := uuid.Parse(classificationID)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid classification_id")
+		}
+		filter.ClassificationID = &cid
+	}
+	if departmentID := c.Query("department_id"); departmentID != "" {
+		did, err := uuid.Parse(departmentID)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid department_id")
+		}
+		filter.DepartmentID = &did
+	}
+
+	return filter, nil
+}
+
+func parseAnalyticsDate(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
+
+func workflowAnalyticsError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAnalyticsWorkflowNotFound):
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Workflow not found")
+	case errors.Is(err, services.ErrAnalyticsInvalidPeriod):
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to compute workflow analytics")
+}
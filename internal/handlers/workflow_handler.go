@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
+	"strconv"
 
 	"github.com/automax/backend/internal/models"
 	"github.com/automax/backend/internal/services"
@@ -95,6 +97,14 @@ func (h *WorkflowHandler) UpdateWorkflow(c *fiber.Ctx) error {
 
 	workflow, err := h.service.UpdateWorkflow(c.Context(), id, &req)
 	if err != nil {
+		var validationErr *services.WorkflowValidationError
+		if errors.As(err, &validationErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Workflow cannot be activated until validation errors are fixed",
+				"data":    validationErr.Result,
+			})
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
 	}
 
@@ -242,7 +252,7 @@ func (h *WorkflowHandler) CreateState(c *fiber.Ctx) error {
 
 	state, err := h.service.CreateState(c.Context(), workflowID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusCreated, "State created", state)
@@ -277,7 +287,7 @@ func (h *WorkflowHandler) UpdateState(c *fiber.Ctx) error {
 
 	state, err := h.service.UpdateState(c.Context(), stateID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "State updated", state)
@@ -291,12 +301,29 @@ func (h *WorkflowHandler) DeleteState(c *fiber.Ctx) error {
 	}
 
 	if err := h.service.DeleteState(c.Context(), stateID); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "State deleted", nil)
 }
 
+// workflowEditError responds to a failed state or transition edit, listing the validation
+// errors when the edit would have broken an active workflow
+func workflowEditError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, services.ErrWorkflowItemPinned) {
+		return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+	}
+	var validationErr *services.WorkflowValidationError
+	if errors.As(err, &validationErr) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"success": false,
+			"error":   "Change would leave the active workflow with validation errors",
+			"data":    validationErr.Result,
+		})
+	}
+	return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+}
+
 // Transition management
 
 func (h *WorkflowHandler) CreateTransition(c *fiber.Ctx) error {
@@ -317,7 +344,7 @@ func (h *WorkflowHandler) CreateTransition(c *fiber.Ctx) error {
 
 	transition, err := h.service.CreateTransition(c.Context(), workflowID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusCreated, "Transition created", transition)
@@ -352,7 +379,7 @@ func (h *WorkflowHandler) UpdateTransition(c *fiber.Ctx) error {
 
 	transition, err := h.service.UpdateTransition(c.Context(), transitionID, &req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Transition updated", transition)
@@ -366,7 +393,7 @@ func (h *WorkflowHandler) DeleteTransition(c *fiber.Ctx) error {
 	}
 
 	if err := h.service.DeleteTransition(c.Context(), transitionID); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+		return workflowEditError(c, err)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Transition deleted", nil)
@@ -419,6 +446,10 @@ func (h *WorkflowHandler) SetTransitionRequirements(c *fiber.Ctx) error {
 	}
 
 	if err := h.service.SetTransitionRequirements(c.Context(), transitionID, req.Requirements); err != nil {
+		var configErr *services.RequirementConfigError
+		if errors.As(err, &configErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
 	}
 
@@ -440,12 +471,44 @@ func (h *WorkflowHandler) SetTransitionActions(c *fiber.Ctx) error {
 	}
 
 	if err := h.service.SetTransitionActions(c.Context(), transitionID, req.Actions); err != nil {
+		var validationErr *services.ActionValidationError
+		if errors.As(err, &validationErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusOK, "Transition actions updated", nil)
 }
 
+func (h *WorkflowHandler) SetTransitionApproval(c *fiber.Ctx) error {
+	transitionIDStr := c.Params("id")
+	transitionID, err := uuid.Parse(transitionIDStr)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid transition ID")
+	}
+
+	var req models.TransitionApprovalRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	transition, err := h.service.SetTransitionApproval(c.Context(), transitionID, &req)
+	if err != nil {
+		var configErr *services.ApprovalConfigError
+		if errors.As(err, &configErr) {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Transition approval updated", transition)
+}
+
 // Helper endpoints
 
 func (h *WorkflowHandler) GetTransitionsFromState(c *fiber.Ctx) error {
@@ -478,6 +541,21 @@ func (h *WorkflowHandler) GetInitialState(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, fiber.StatusOK, "Initial state retrieved", state)
 }
 
+// ValidateWorkflow handles GET /admin/workflows/:id/validate
+func (h *WorkflowHandler) ValidateWorkflow(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+
+	result, err := h.service.ValidateWorkflow(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Workflow not found")
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Workflow validated", result)
+}
+
 // MatchWorkflow finds a workflow based on incident criteria and returns form configuration
 // This endpoint is designed for mobile apps and other clients to get:
 // 1. The matched workflow based on classification, location, source, etc.
@@ -498,6 +576,125 @@ func (h *WorkflowHandler) MatchWorkflow(c *fiber.Ctx) error {
 	return utils.SuccessResponse(c, fiber.StatusOK, "Workflow matched", result)
 }
 
+// PublishWorkflow handles POST /admin/workflows/:id/publish
+func (h *WorkflowHandler) PublishWorkflow(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+
+	var req models.WorkflowPublishRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("user_id").(uuid.UUID)
+
+	version, err := h.service.PublishWorkflow(c.Context(), id, &req, userID)
+	if err != nil {
+		var validationErr *services.WorkflowValidationError
+		if errors.As(err, &validationErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Workflow cannot be published until validation errors are fixed",
+				"data":    validationErr.Result,
+			})
+		}
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusCreated, "Workflow published", version)
+}
+
+// ListVersions handles GET /admin/workflows/:id/versions
+func (h *WorkflowHandler) ListVersions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+
+	versions, err := h.service.ListVersions(c.Context(), id)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Workflow versions retrieved", versions)
+}
+
+// GetVersion handles GET /admin/workflows/:id/versions/:version
+func (h *WorkflowHandler) GetVersion(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version < 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid version")
+	}
+
+	detail, err := h.service.GetVersion(c.Context(), id, version)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Workflow version not found")
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Workflow version retrieved", detail)
+}
+
+// DiffVersions handles GET /admin/workflows/:id/versions/diff?from=1&to=2.
+// Omitting to compares against the current unpublished definition.
+func (h *WorkflowHandler) DiffVersions(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from < 1 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid from version")
+	}
+	to := 0
+	if toStr := c.Query("to"); toStr != "" {
+		to, err = strconv.Atoi(toStr)
+		if err != nil || to < 0 {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid to version")
+		}
+	}
+
+	diff, err := h.service.DiffVersions(c.Context(), id, from, to)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Workflow versions compared", diff)
+}
+
+// MigrateIncidents handles POST /admin/workflows/:id/migrate-incidents
+func (h *WorkflowHandler) MigrateIncidents(c *fiber.Ctx) error {
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid workflow ID")
+	}
+
+	var req models.WorkflowMigrateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := h.service.MigrateIncidents(c.Context(), id, &req)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return utils.SuccessResponse(c, fiber.StatusOK, "Incidents migrated", result)
+}
+
 // ExportWorkflow exports a workflow as a JSON file
 func (h *WorkflowHandler) ExportWorkflow(c *fiber.Ctx) error {
 	idStr := c.Params("id")
@@ -554,16 +751,34 @@ func (h *WorkflowHandler) ImportWorkflow(c *fiber.Ctx) error {
 	// Get user ID from context
 	userID := c.Locals("user_id").(uuid.UUID)
 
+	// dry_run=true checks the bundle without saving; mode=update replaces the workflow with the same code
+	opts := models.WorkflowImportOptions{
+		DryRun:         c.Query("dry_run") == "true",
+		UpdateExisting: c.Query("mode") == "update",
+	}
+
 	// Import workflow
-	workflow, warnings, err := h.service.ImportWorkflow(c.Context(), &importData, userID)
+	response, err := h.service.ImportWorkflow(c.Context(), &importData, userID, opts)
 	if err != nil {
+		if errors.Is(err, services.ErrWorkflowItemPinned) {
+			return utils.ErrorResponse(c, fiber.StatusConflict, err.Error())
+		}
+		var validationErr *services.WorkflowValidationError
+		if errors.As(err, &validationErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"success": false,
+				"error":   "Active workflow cannot be updated with validation errors",
+				"data":    validationErr.Result,
+			})
+		}
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, err.Error())
 	}
 
-	// Build response
-	response := models.WorkflowImportResponse{
-		Workflow: *workflow,
-		Warnings: warnings,
+	if opts.DryRun {
+		return utils.SuccessResponse(c, fiber.StatusOK, "Workflow import checked, nothing was saved", response)
+	}
+	if response.Mode == models.WorkflowImportUpdated {
+		return utils.SuccessResponse(c, fiber.StatusOK, "Workflow updated successfully", response)
 	}
 
 	return utils.SuccessResponse(c, fiber.StatusCreated, "Workflow imported successfully", response)
@@ -0,0 +1,273 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/config"
+)
+
+// Message is an outgoing email
+type Message struct {
+	To       []string
+	Subject  string
+	TextBody string
+	HTMLBody string
+	Headers  map[string]string
+}
+
+// Sender delivers email messages. It returns the generated Message-ID on success.
+type Sender interface {
+	Send(ctx context.Context, msg *Message) (string, error)
+	From() string
+}
+
+// NewSender returns an SMTP sender when a host is configured, otherwise a sender
+// that only logs messages (useful for local development)
+func NewSender(cfg *config.MailConfig) Sender {
+	if cfg.Host == "" {
+		log.Println("SMTP_HOST not configured, emails will be logged instead of sent")
+		return &logSender{from: formatAddress(cfg.FromName, cfg.FromAddress)}
+	}
+	return NewSMTPSender(cfg)
+}
+
+// IsPermanent reports whether a send error should not be retried (5xx SMTP replies)
+func IsPermanent(err error) bool {
+	var tpErr *textproto.Error
+	if errors.As(err, &tpErr) {
+		return tpErr.Code >= 500
+	}
+	return false
+}
+
+// SMTPSender sends mail through an SMTP server
+type SMTPSender struct {
+	cfg     *config.MailConfig
+	from    string
+	timeout time.Duration
+}
+
+// NewSMTPSender creates a new SMTP sender
+func NewSMTPSender(cfg *config.MailConfig) *SMTPSender {
+	timeout := time.Duration(cfg.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	return &SMTPSender{
+		cfg:     cfg,
+		from:    formatAddress(cfg.FromName, cfg.FromAddress),
+		timeout: timeout,
+	}
+}
+
+// From returns the formatted sender address
+func (s *SMTPSender) From() string {
+	return s.from
+}
+
+// Send delivers a message over SMTP
+func (s *SMTPSender) Send(ctx context.Context, msg *Message) (string, error) {
+	if len(msg.To) == 0 {
+		return "", errors.New("no recipients")
+	}
+
+	messageID := generateMessageID(s.cfg.FromAddress)
+	body, err := Build(s.from, messageID, msg)
+	if err != nil {
+		return "", err
+	}
+
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: s.timeout}
+	tlsConfig := &tls.Config{
+		ServerName:         s.cfg.Host,
+		InsecureSkipVerify: s.cfg.InsecureSkipVerify,
+	}
+
+	var conn net.Conn
+	if s.cfg.Encryption == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+
+	deadline := time.Now().Add(s.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return "", fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer client.Close()
+
+	if s.cfg.Encryption == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return "", errors.New("SMTP server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return "", fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+
+	if s.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			auth := smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)
+			if err := client.Auth(auth); err != nil {
+				return "", fmt.Errorf("SMTP authentication failed: %w", err)
+			}
+		}
+	}
+
+	if err := client.Mail(s.cfg.FromAddress); err != nil {
+		return "", err
+	}
+	for _, rcpt := range msg.To {
+		if err := client.Rcpt(rcpt); err != nil {
+			return "", err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(body); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return messageID, client.Quit()
+}
+
+// logSender logs messages instead of sending them
+type logSender struct {
+	from string
+}
+
+func (s *logSender) From() string {
+	return s.from
+}
+
+func (s *logSender) Send(ctx context.Context, msg *Message) (string, error) {
+	messageID := generateMessageID("localhost")
+	log.Printf("Email (not sent, SMTP disabled): To=%v, Subject=%s", msg.To, msg.Subject)
+	return messageID, nil
+}
+
+// Build renders a message as RFC 5322 bytes. Messages with both text and HTML
+// bodies are sent as multipart/alternative.
+func Build(from, messageID string, msg *Message) ([]byte, error) {
+	var buf bytes.Buffer
+
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(msg.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+	}
+	for key, value := range msg.Headers {
+		headers = append(headers, textproto.CanonicalMIMEHeaderKey(key)+": "+value)
+	}
+	for _, h := range headers {
+		buf.WriteString(h + "\r\n")
+	}
+
+	switch {
+	case msg.TextBody != "" && msg.HTMLBody != "":
+		mw := multipart.NewWriter(&buf)
+		buf.WriteString("Content-Type: multipart/alternative; boundary=" + mw.Boundary() + "\r\n\r\n")
+		if err := writePart(mw, "text/plain", msg.TextBody); err != nil {
+			return nil, err
+		}
+		if err := writePart(mw, "text/html", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+		if err := mw.Close(); err != nil {
+			return nil, err
+		}
+	case msg.HTMLBody != "":
+		if err := writeSinglePart(&buf, "text/html", msg.HTMLBody); err != nil {
+			return nil, err
+		}
+	default:
+		if err := writeSinglePart(&buf, "text/plain", msg.TextBody); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeSinglePart(buf *bytes.Buffer, contentType, body string) error {
+	buf.WriteString("Content-Type: " + contentType + "; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(buf)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func formatAddress(name, address string) string {
+	if name == "" {
+		return address
+	}
+	return (&mail.Address{Name: name, Address: address}).String()
+}
+
+func generateMessageID(fromAddress string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(fromAddress, "@"); at >= 0 && at < len(fromAddress)-1 {
+		domain = fromAddress[at+1:]
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("<%d@%s>", time.Now().UnixNano(), domain)
+	}
+	return fmt.Sprintf("<%s.%d@%s>", hex.EncodeToString(b), time.Now().Unix(), domain)
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Approval modes for multi-signoff transitions
+const (
+	ApprovalModeAnyN       = "any_n"        // ApprovalQuorum approvals from eligible users
+	ApprovalModeAll        = "all"          // every listed approver
+	ApprovalModeOnePerRole = "one_per_role" // one approval from each allowed role
+)
+
+// Approval vote decisions
+const (
+	ApprovalDecisionApprove = "approve"
+	ApprovalDecisionReject  = "reject"
+)
+
+// TransitionApproval is a single user's vote on an approval transition. Votes are open
+// until the round ends (quorum reached, rejection, or the incident changes state).
+type TransitionApproval struct {
+	ID           uuid.UUID           `gorm:"type:uuid;primary_key" json:"id"`
+	IncidentID   uuid.UUID           `gorm:"type:uuid;index;not null" json:"incident_id"`
+	TransitionID uuid.UUID           `gorm:"type:uuid;index;not null" json:"transition_id"`
+	Transition   *WorkflowTransition `gorm:"foreignKey:TransitionID" json:"transition,omitempty"`
+	FromStateID  uuid.UUID           `gorm:"type:uuid;not null" json:"from_state_id"`
+	UserID       uuid.UUID           `gorm:"type:uuid;index;not null" json:"user_id"`
+	User         *User               `gorm:"foreignKey:UserID" json:"user,omitempty"`
+
+	Decision string `gorm:"size:20;not null" json:"decision"` // approve, reject
+	Comment  string `gorm:"type:text" json:"comment"`
+	RoleIDs  string `gorm:"type:text" json:"role_ids"` // JSON array of the voter's allowed roles, for one_per_role
+
+	HistoryID  *uuid.UUID `gorm:"type:uuid" json:"history_id"` // Transition history entry of the vote
+	ResolvedAt *time.Time `gorm:"index" json:"resolved_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (a *TransitionApproval) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// TransitionApprovalRequest configures approvals on a transition
+type TransitionApprovalRequest struct {
+	ApprovalMode   string   `json:"approval_mode" validate:"omitempty,oneof=any_n all one_per_role"` // empty disables approvals
+	ApprovalQuorum int      `json:"approval_quorum" validate:"min=0"`
+	ApproverIDs    []string `json:"approver_ids"`
+	RejectStateID  *string  `json:"reject_state_id" validate:"omitempty,uuid"`
+}
+
+// PendingApprovalKey identifies an approval transition awaiting a user's vote on an incident
+type PendingApprovalKey struct {
+	IncidentID   uuid.UUID
+	TransitionID uuid.UUID
+}
+
+type TransitionApprovalResponse struct {
+	ID           uuid.UUID     `json:"id"`
+	TransitionID uuid.UUID     `json:"transition_id"`
+	User         *UserResponse `json:"user,omitempty"`
+	Decision     string        `json:"decision"`
+	Comment      string        `json:"comment,omitempty"`
+	ResolvedAt   *time.Time    `json:"resolved_at,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// ApprovalStatusResponse summarizes the open round of an approval transition
+type ApprovalStatusResponse struct {
+	TransitionID   uuid.UUID                    `json:"transition_id"`
+	TransitionName string                       `json:"transition_name"`
+	ApprovalMode   string                       `json:"approval_mode"`
+	Approved       int                          `json:"approved"`
+	Required       int                          `json:"required"`
+	Votes          []TransitionApprovalResponse `json:"votes"`
+}
+
+// PendingApprovalResponse is an incident waiting for the current user's vote
+type PendingApprovalResponse struct {
+	Incident IncidentResponse       `json:"incident"`
+	Approval ApprovalStatusResponse `json:"approval"`
+}
+
+func ToTransitionApprovalResponse(a *TransitionApproval) TransitionApprovalResponse {
+	resp := TransitionApprovalResponse{
+		ID:           a.ID,
+		TransitionID: a.TransitionID,
+		Decision:     a.Decision,
+		Comment:      a.Comment,
+		ResolvedAt:   a.ResolvedAt,
+		CreatedAt:    a.CreatedAt,
+	}
+	if a.User != nil {
+		user := ToUserResponse(a.User)
+		resp.User = &user
+	}
+	return resp
+}
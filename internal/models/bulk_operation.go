@@ -0,0 +1,128 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bulk operation types
+const (
+	BulkOperationTransition = "transition"
+	BulkOperationAssign     = "assign"
+	BulkOperationUpdate     = "update"
+)
+
+// Bulk operation statuses
+const (
+	BulkStatusPending   = "pending"
+	BulkStatusRunning   = "running"
+	BulkStatusCompleted = "completed"
+	BulkStatusFailed    = "failed"
+)
+
+// BulkOperation tracks a transition, assignment or update applied to many incidents.
+// Targets are resolved when the operation is submitted; progress is saved after each item.
+type BulkOperation struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Operation string    `gorm:"size:20;not null" json:"operation"` // transition, assign, update
+	Status    string    `gorm:"size:20;not null;index" json:"status"`
+
+	Total     int `gorm:"default:0" json:"total"`
+	Processed int `gorm:"default:0" json:"processed"`
+	Succeeded int `gorm:"default:0" json:"succeeded"`
+	Failed    int `gorm:"default:0" json:"failed"`
+
+	Params      string `gorm:"type:text" json:"-"` // JSON of the operation request
+	IncidentIDs string `gorm:"type:text" json:"-"` // JSON array of target incident IDs
+	RoleIDs     string `gorm:"type:text" json:"-"` // JSON array of the requester's role IDs
+	Results     string `gorm:"type:text" json:"-"` // JSON array of BulkItemResult
+	Error       string `gorm:"type:text" json:"error,omitempty"`
+
+	RequestedByID uuid.UUID `gorm:"type:uuid;index;not null" json:"requested_by_id"`
+	RequestedBy   *User     `gorm:"foreignKey:RequestedByID" json:"requested_by,omitempty"`
+
+	StartedAt   *time.Time `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (b *BulkOperation) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// BulkItemResult is the outcome for one incident of a bulk operation
+type BulkItemResult struct {
+	IncidentID     uuid.UUID   `json:"incident_id"`
+	IncidentNumber string      `json:"incident_number,omitempty"`
+	Success        bool        `json:"success"`
+	Error          string      `json:"error,omitempty"`
+	Details        interface{} `json:"details,omitempty"` // e.g. unmet transition requirements
+}
+
+// BulkTarget selects the incidents of a bulk operation, either by ID or by filter
+type BulkTarget struct {
+	IncidentIDs []string        `json:"incident_ids"`
+	Filter      *IncidentFilter `json:"filter"`
+}
+
+type BulkTransitionRequest struct {
+	BulkTarget
+	Transition IncidentTransitionRequest `json:"transition"`
+}
+
+type BulkAssignRequest struct {
+	BulkTarget
+	AssigneeID string `json:"assignee_id" validate:"required,uuid"`
+}
+
+type BulkUpdateRequest struct {
+	BulkTarget
+	Update IncidentUpdateRequest `json:"update"`
+}
+
+type BulkOperationResponse struct {
+	ID          uuid.UUID        `json:"id"`
+	Operation   string           `json:"operation"`
+	Status      string           `json:"status"`
+	Total       int              `json:"total"`
+	Processed   int              `json:"processed"`
+	Succeeded   int              `json:"succeeded"`
+	Failed      int              `json:"failed"`
+	Progress    float64          `json:"progress"` // percentage of processed items
+	Results     []BulkItemResult `json:"results"`
+	Error       string           `json:"error,omitempty"`
+	StartedAt   *time.Time       `json:"started_at,omitempty"`
+	CompletedAt *time.Time       `json:"completed_at,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+}
+
+func ToBulkOperationResponse(b *BulkOperation) BulkOperationResponse {
+	resp := BulkOperationResponse{
+		ID:          b.ID,
+		Operation:   b.Operation,
+		Status:      b.Status,
+		Total:       b.Total,
+		Processed:   b.Processed,
+		Succeeded:   b.Succeeded,
+		Failed:      b.Failed,
+		Results:     []BulkItemResult{},
+		Error:       b.Error,
+		StartedAt:   b.StartedAt,
+		CompletedAt: b.CompletedAt,
+		CreatedAt:   b.CreatedAt,
+	}
+	if b.Total > 0 {
+		resp.Progress = float64(b.Processed) * 100 / float64(b.Total)
+	}
+	if b.Results != "" {
+		json.Unmarshal([]byte(b.Results), &resp.Results)
+	}
+	return resp
+}
@@ -0,0 +1,96 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Email delivery statuses
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusFailed  = "failed"
+)
+
+// EmailDelivery records every outgoing email and the outcome of its delivery attempts
+type EmailDelivery struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	MessageID string    `gorm:"size:255;index" json:"message_id"` // SMTP Message-ID header
+	FromAddr  string    `gorm:"size:255" json:"from"`
+	ToAddr    string    `gorm:"size:255;index;not null" json:"to"`
+	Subject   string    `gorm:"size:500" json:"subject"`
+	TextBody  string    `gorm:"type:text" json:"text_body,omitempty"`
+	HTMLBody  string    `gorm:"type:text" json:"html_body,omitempty"`
+
+	Status    string     `gorm:"size:20;index;default:'pending'" json:"status"` // pending, sent, failed
+	Attempts  int        `gorm:"default:0" json:"attempts"`
+	LastError string     `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt    *time.Time `json:"sent_at,omitempty"`
+
+	// Source of the email (optional)
+	IncidentID         *uuid.UUID `gorm:"type:uuid;index" json:"incident_id,omitempty"`
+	TransitionActionID *uuid.UUID `gorm:"type:uuid;index" json:"transition_action_id,omitempty"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (e *EmailDelivery) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// EmailDeliveryFilter holds filter parameters for querying email deliveries
+type EmailDeliveryFilter struct {
+	Status     string     `json:"status"`
+	To         string     `json:"to"`
+	IncidentID *uuid.UUID `json:"incident_id"`
+	StartDate  *time.Time `json:"start_date"`
+	EndDate    *time.Time `json:"end_date"`
+	Search     string     `json:"search"`
+	Page       int        `json:"page"`
+	Limit      int        `json:"limit"`
+}
+
+// EmailDeliveryResponse is the response structure for email deliveries
+type EmailDeliveryResponse struct {
+	ID                 uuid.UUID  `json:"id"`
+	MessageID          string     `json:"message_id"`
+	From               string     `json:"from"`
+	To                 string     `json:"to"`
+	Subject            string     `json:"subject"`
+	TextBody           string     `json:"text_body,omitempty"`
+	HTMLBody           string     `json:"html_body,omitempty"`
+	Status             string     `json:"status"`
+	Attempts           int        `json:"attempts"`
+	LastError          string     `json:"last_error,omitempty"`
+	SentAt             *time.Time `json:"sent_at,omitempty"`
+	IncidentID         *uuid.UUID `json:"incident_id,omitempty"`
+	TransitionActionID *uuid.UUID `json:"transition_action_id,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+}
+
+func ToEmailDeliveryResponse(e *EmailDelivery) EmailDeliveryResponse {
+	return EmailDeliveryResponse{
+		ID:                 e.ID,
+		MessageID:          e.MessageID,
+		From:               e.FromAddr,
+		To:                 e.ToAddr,
+		Subject:            e.Subject,
+		TextBody:           e.TextBody,
+		HTMLBody:           e.HTMLBody,
+		Status:             e.Status,
+		Attempts:           e.Attempts,
+		LastError:          e.LastError,
+		SentAt:             e.SentAt,
+		IncidentID:         e.IncidentID,
+		TransitionActionID: e.TransitionActionID,
+		CreatedAt:          e.CreatedAt,
+		UpdatedAt:          e.UpdatedAt,
+	}
+}
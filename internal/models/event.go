@@ -0,0 +1,80 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Real-time event types pushed to connected clients
+const (
+	EventIncidentCreated      = "incident.created"
+	EventIncidentUpdated      = "incident.updated"
+	EventIncidentTransitioned = "incident.transitioned"
+	EventIncidentCommented    = "incident.commented"
+	EventIncidentAssigned     = "incident.assigned"
+	EventIncidentApprovalVote = "incident.approval_voted"
+	EventNotificationCreated  = "notification.created"
+)
+
+// Event is the payload delivered to clients over the event stream
+type Event struct {
+	ID         uuid.UUID       `json:"id"`
+	Type       string          `json:"type"`
+	IncidentID *uuid.UUID      `json:"incident_id,omitempty"`
+	RecordType string          `json:"record_type,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// EventAudience restricts which connected users receive an event. It travels with the
+// event between replicas but is never sent to clients.
+type EventAudience struct {
+	UserIDs         []uuid.UUID `json:"user_ids,omitempty"`          // Only these users (e.g. notification recipient)
+	Permission      string      `json:"permission,omitempty"`        // Permission the user must hold
+	ViewableRoleIDs []uuid.UUID `json:"viewable_role_ids,omitempty"` // Empty means visible to all roles
+}
+
+// EventSubscriber describes a connected user for audience matching
+type EventSubscriber struct {
+	UserID       uuid.UUID
+	IsSuperAdmin bool
+	RoleIDs      map[uuid.UUID]bool
+	Permissions  map[string]bool
+}
+
+// Allows reports whether the subscriber may receive an event with this audience
+func (a *EventAudience) Allows(sub *EventSubscriber) bool {
+	if len(a.UserIDs) > 0 {
+		found := false
+		for _, id := range a.UserIDs {
+			if id == sub.UserID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if sub.IsSuperAdmin {
+		return true
+	}
+
+	if a.Permission != "" && !sub.Permissions[a.Permission] {
+		return false
+	}
+
+	if len(a.ViewableRoleIDs) > 0 {
+		for _, roleID := range a.ViewableRoleIDs {
+			if sub.RoleIDs[roleID] {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
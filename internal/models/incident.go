@@ -19,32 +19,44 @@ type Incident struct {
 	Description    string    `gorm:"type:text" json:"description"`
 
 	// Record Type: 'incident', 'request', 'complaint', or 'query'
-	RecordType       string     `gorm:"size:20;default:'incident';index" json:"record_type"`
-	SourceIncidentID *uuid.UUID `gorm:"type:uuid;index" json:"source_incident_id"`
-	SourceIncident   *Incident  `gorm:"foreignKey:SourceIncidentID" json:"source_incident,omitempty"`
+	RecordType        string     `gorm:"size:20;default:'incident';index" json:"record_type"`
+	SourceIncidentID  *uuid.UUID `gorm:"type:uuid;index" json:"source_incident_id"`
+	SourceIncident    *Incident  `gorm:"foreignKey:SourceIncidentID" json:"source_incident,omitempty"`
+	BlocksParentClose bool       `gorm:"default:false" json:"blocks_parent_close"` // Source cannot reach a terminal state while this record is open
+
+	// Set on records launched by a sub-workflow state of the source; closing the record resumes the source
+	SubWorkflowStateID *uuid.UUID `gorm:"type:uuid;index" json:"sub_workflow_state_id"`
 
 	// Reference to converted request (when incident is converted to request)
 	ConvertedRequestID *uuid.UUID `gorm:"type:uuid;index" json:"converted_request_id"`
 	ConvertedRequest   *Incident  `gorm:"foreignKey:ConvertedRequestID" json:"converted_request,omitempty"`
 
+	// Master record this one was merged into as a duplicate
+	DuplicateOfID *uuid.UUID `gorm:"type:uuid;index" json:"duplicate_of_id"`
+
+	// Record this one was merged into and closed in favour of
+	MergedIntoID *uuid.UUID `gorm:"type:uuid;index" json:"merged_into_id"`
+
 	// Classification
 	ClassificationID *uuid.UUID      `gorm:"type:uuid;index" json:"classification_id"`
 	Classification   *Classification `gorm:"foreignKey:ClassificationID" json:"classification,omitempty"`
 
 	// Workflow State
-	WorkflowID     uuid.UUID      `gorm:"type:uuid;index;not null" json:"workflow_id"`
-	Workflow       *Workflow      `gorm:"foreignKey:WorkflowID" json:"workflow,omitempty"`
-	CurrentStateID uuid.UUID      `gorm:"type:uuid;index;not null" json:"current_state_id"`
-	CurrentState   *WorkflowState `gorm:"foreignKey:CurrentStateID" json:"current_state,omitempty"`
+	WorkflowID      uuid.UUID      `gorm:"type:uuid;index;not null" json:"workflow_id"`
+	Workflow        *Workflow      `gorm:"foreignKey:WorkflowID" json:"workflow,omitempty"`
+	WorkflowVersion int            `gorm:"default:0;index" json:"workflow_version"` // Published version the incident runs on, 0 = live definition
+	CurrentStateID  uuid.UUID      `gorm:"type:uuid;index;not null" json:"current_state_id"`
+	CurrentState    *WorkflowState `gorm:"foreignKey:CurrentStateID" json:"current_state,omitempty"`
 
 	// Dynamic Attributes from Lookup
 	LookupValues []LookupValue `gorm:"many2many:incident_lookup_values;" json:"lookup_values,omitempty"`
 
 	// Assignment
-	AssigneeID   *uuid.UUID  `gorm:"type:uuid;index" json:"assignee_id"`
-	Assignee     *User       `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
-	DepartmentID *uuid.UUID  `gorm:"type:uuid;index" json:"department_id"`
-	Department   *Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
+	AssigneeID         *uuid.UUID  `gorm:"type:uuid;index" json:"assignee_id"`
+	Assignee           *User       `gorm:"foreignKey:AssigneeID" json:"assignee,omitempty"`
+	PreviousAssigneeID *uuid.UUID  `gorm:"type:uuid;index" json:"previous_assignee_id,omitempty"` // Assignee before the last reassignment
+	DepartmentID       *uuid.UUID  `gorm:"type:uuid;index" json:"department_id"`
+	Department         *Department `gorm:"foreignKey:DepartmentID" json:"department,omitempty"`
 
 	// Location
 	LocationID *uuid.UUID `gorm:"type:uuid;index" json:"location_id"`
@@ -190,7 +202,8 @@ type IncidentTransitionHistory struct {
 	IncidentID uuid.UUID `gorm:"type:uuid;index;not null" json:"incident_id"`
 	Incident   *Incident `gorm:"foreignKey:IncidentID" json:"incident,omitempty"`
 
-	TransitionID uuid.UUID           `gorm:"type:uuid;index;not null" json:"transition_id"`
+	// Empty when the state changed without a transition, e.g. when the record was merged
+	TransitionID *uuid.UUID          `gorm:"type:uuid;index" json:"transition_id"`
 	Transition   *WorkflowTransition `gorm:"foreignKey:TransitionID" json:"transition,omitempty"`
 
 	FromStateID uuid.UUID      `gorm:"type:uuid;index;not null" json:"from_state_id"`
@@ -203,6 +216,9 @@ type IncidentTransitionHistory struct {
 
 	Comment string `gorm:"type:text" json:"comment"`
 
+	// Set when the entry records an approval vote rather than a completed transition
+	ApprovalDecision string `gorm:"size:20" json:"approval_decision,omitempty"`
+
 	// Snapshot of field changes (JSON)
 	OldValues string `gorm:"type:text" json:"old_values"`
 	NewValues string `gorm:"type:text" json:"new_values"`
@@ -221,6 +237,28 @@ func (h *IncidentTransitionHistory) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Transition action result statuses
+const (
+	ActionResultSuccess  = "success"
+	ActionResultFailed   = "failed"
+	ActionResultQueued   = "queued"
+	ActionResultRetrying = "retrying"
+	ActionResultSkipped  = "skipped"
+)
+
+// TransitionActionResult is the outcome of a single transition action,
+// stored as a JSON array in IncidentTransitionHistory.ActionResults
+type TransitionActionResult struct {
+	ActionID   uuid.UUID  `json:"action_id"`
+	ActionType string     `json:"action_type"`
+	Name       string     `json:"name"`
+	IsAsync    bool       `json:"is_async"`
+	Status     string     `json:"status"` // success, failed, queued, retrying, skipped
+	Error      string     `json:"error,omitempty"`
+	Attempts   int        `json:"attempts"`
+	ExecutedAt *time.Time `json:"executed_at,omitempty"`
+}
+
 // IncidentRevisionActionType represents the type of revision action
 type IncidentRevisionActionType string
 
@@ -234,6 +272,7 @@ const (
 	RevisionActionAssigneeChanged   IncidentRevisionActionType = "assignee_changed"
 	RevisionActionStatusChanged     IncidentRevisionActionType = "status_changed"
 	RevisionActionCreated           IncidentRevisionActionType = "created"
+	RevisionActionMerged            IncidentRevisionActionType = "merged"
 )
 
 // IncidentRevision records detailed change history for an incident
@@ -341,6 +380,9 @@ type IncidentTransitionRequest struct {
 	// Feedback (collected during transition if required)
 	Feedback *IncidentFeedbackRequest `json:"feedback"`
 
+	// Vote for approval transitions, defaults to approve
+	Decision string `json:"decision" validate:"omitempty,oneof=approve reject"`
+
 	// Assignment overrides (used when auto-detect finds multiple matches)
 	DepartmentID *string `json:"department_id" validate:"omitempty,uuid"`
 	UserID       *string `json:"user_id" validate:"omitempty,uuid"`
@@ -364,6 +406,8 @@ type CreateComplaintRequest struct {
 	WorkflowID       string   `json:"workflow_id" validate:"required,uuid"`
 	SourceIncidentID *string  `json:"source_incident_id" validate:"omitempty,uuid"` // optional reference to source incident
 	Channel          string   `json:"channel"`
+	CreatedByName    string   `json:"created_by_name" validate:"max=255"`
+	CreatedByMobile  string   `json:"created_by_mobile" validate:"max=50"`
 	ReporterID       *string  `json:"reporter_id" validate:"omitempty,uuid"` // link to user who created the complaint
 	DepartmentID     *string  `json:"department_id" validate:"omitempty,uuid"`
 	AssigneeID       *string  `json:"assignee_id" validate:"omitempty,uuid"`
@@ -422,6 +466,9 @@ type IncidentFilter struct {
 	Page             int         `json:"page"`
 	Limit            int         `json:"limit"`
 	UserRoleIDs      []uuid.UUID `json:"-"` // For filtering stats by user's roles
+
+	// Composable AND/OR expression applied on top of the fields above
+	Expression *IncidentFilterNode `json:"expression,omitempty"`
 }
 
 // Response types
@@ -434,10 +481,14 @@ type IncidentResponse struct {
 	RecordType         string                  `json:"record_type"`
 	SourceIncidentID   *uuid.UUID              `json:"source_incident_id,omitempty"`
 	SourceIncident     *IncidentResponse       `json:"source_incident,omitempty"`
+	SubWorkflowStateID *uuid.UUID              `json:"sub_workflow_state_id,omitempty"`
 	ConvertedRequestID *uuid.UUID              `json:"converted_request_id,omitempty"`
 	ConvertedRequest   *IncidentResponse       `json:"converted_request,omitempty"`
+	DuplicateOfID      *uuid.UUID              `json:"duplicate_of_id,omitempty"`
+	MergedIntoID       *uuid.UUID              `json:"merged_into_id,omitempty"`
 	Classification     *ClassificationResponse `json:"classification,omitempty"`
 	Workflow         *WorkflowResponse       `json:"workflow,omitempty"`
+	WorkflowVersion  int                     `json:"workflow_version"`
 	CurrentState     *WorkflowStateResponse  `json:"current_state,omitempty"`
 	Assignee         *UserResponse           `json:"assignee,omitempty"`
 	Assignees        []UserResponse          `json:"assignees,omitempty"`
@@ -468,6 +519,9 @@ type IncidentResponse struct {
 	CreatedAt        time.Time               `json:"created_at"`
 	UpdatedAt        time.Time               `json:"updated_at"`
 	LookupValues     []LookupValueResponse   `json:"lookup_values,omitempty"`
+
+	// Likely duplicates found when the record was created
+	DuplicateCandidates []DuplicateCandidate `json:"duplicate_candidates,omitempty"`
 }
 
 type IncidentDetailResponse struct {
@@ -475,6 +529,7 @@ type IncidentDetailResponse struct {
 	Comments          []IncidentCommentResponse    `json:"comments,omitempty"`
 	Attachments       []IncidentAttachmentResponse `json:"attachments,omitempty"`
 	TransitionHistory []TransitionHistoryResponse  `json:"transition_history,omitempty"`
+	Relations         []IncidentRelationResponse   `json:"relations,omitempty"`
 }
 
 type IncidentCommentResponse struct {
@@ -517,6 +572,7 @@ type TransitionHistoryResponse struct {
 	ToState       *WorkflowStateResponse      `json:"to_state,omitempty"`
 	PerformedBy   *UserResponse               `json:"performed_by,omitempty"`
 	Comment       string                      `json:"comment,omitempty"`
+	ApprovalDecision string                   `json:"approval_decision,omitempty"`
 	OldValues     string                      `json:"old_values,omitempty"`
 	NewValues     string                      `json:"new_values,omitempty"`
 	ActionResults string                      `json:"action_results,omitempty"`
@@ -530,6 +586,41 @@ type AvailableTransitionResponse struct {
 	Reason       string                     `json:"reason,omitempty"`
 }
 
+// TransitionPreviewResponse describes what executing a transition would do, without doing it
+type TransitionPreviewResponse struct {
+	Transition        WorkflowTransitionResponse     `json:"transition"`
+	CanExecute        bool                           `json:"can_execute"`
+	Reason            string                         `json:"reason,omitempty"`
+	UnmetRequirements []TransitionRequirementFailure `json:"unmet_requirements"`
+	FromState         *WorkflowStateResponse         `json:"from_state,omitempty"`
+	ToState           *WorkflowStateResponse         `json:"to_state,omitempty"`
+	ClosesRecord      bool                           `json:"closes_record"`
+	SLADeadline       *time.Time                     `json:"sla_deadline,omitempty"`
+
+	// Department that would be set, and the auto-detect matches to choose from
+	Department           *DepartmentResponse  `json:"department,omitempty"`
+	DepartmentCandidates []DepartmentResponse `json:"department_candidates,omitempty"`
+
+	// Users that would be assigned; the first becomes the primary assignee
+	Assignees []UserResponse `json:"assignees"`
+
+	Actions []TransitionActionPreview `json:"actions"`
+
+	// For approval transitions: the open round and what the user's vote would do
+	Approval        *ApprovalStatusResponse `json:"approval,omitempty"`
+	ApprovalOutcome string                  `json:"approval_outcome,omitempty"` // vote_recorded, quorum_reached, rejected
+}
+
+// TransitionActionPreview tells whether an action would run for a transition
+type TransitionActionPreview struct {
+	ActionID   uuid.UUID `json:"action_id"`
+	ActionType string    `json:"action_type"`
+	Name       string    `json:"name"`
+	IsAsync    bool      `json:"is_async"`
+	WillRun    bool      `json:"will_run"`
+	Reason     string    `json:"reason,omitempty"` // why the action would not run
+}
+
 type StateStatDetail struct {
 	ID    uuid.UUID `json:"id"`
 	Name  string    `json:"name"`
@@ -558,8 +649,12 @@ func ToIncidentResponse(i *Incident) IncidentResponse {
 		Title:              i.Title,
 		Description:        i.Description,
 		RecordType:         i.RecordType,
+		WorkflowVersion:    i.WorkflowVersion,
 		SourceIncidentID:   i.SourceIncidentID,
+		SubWorkflowStateID: i.SubWorkflowStateID,
 		ConvertedRequestID: i.ConvertedRequestID,
+		DuplicateOfID:      i.DuplicateOfID,
+		MergedIntoID:       i.MergedIntoID,
 		Latitude:           i.Latitude,
 		Longitude:          i.Longitude,
 		Address:            i.Address,
@@ -742,6 +837,7 @@ func ToTransitionHistoryResponse(h *IncidentTransitionHistory) TransitionHistory
 		ID:             h.ID,
 		IncidentID:     h.IncidentID,
 		Comment:        h.Comment,
+		ApprovalDecision: h.ApprovalDecision,
 		OldValues:      h.OldValues,
 		NewValues:      h.NewValues,
 		ActionResults:  h.ActionResults,
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a record was flagged as a likely duplicate
+const (
+	DuplicateReasonClassification = "same_classification"
+	DuplicateReasonNearby         = "nearby"
+	DuplicateReasonSimilarTitle   = "similar_title"
+	DuplicateReasonSameMobile     = "same_mobile"
+)
+
+// DuplicateCandidateQuery selects the recent open records that share at least one signal
+// with a new record. Signals that are not set are ignored.
+type DuplicateCandidateQuery struct {
+	ExcludeID        uuid.UUID
+	RecordType       string
+	Since            time.Time
+	ClassificationID *uuid.UUID
+	Latitude         *float64
+	Longitude        *float64
+	RadiusMeters     float64
+	Mobile           string   // Digits only
+	TitleTerms       []string // Lower-case title words
+	Limit            int
+}
+
+// DuplicateCandidate is a record that likely reports the same problem
+type DuplicateCandidate struct {
+	ID              uuid.UUID              `json:"id"`
+	IncidentNumber  string                 `json:"incident_number"`
+	Title           string                 `json:"title"`
+	RecordType      string                 `json:"record_type"`
+	CurrentState    *WorkflowStateResponse `json:"current_state,omitempty"`
+	Score           float64                `json:"score"` // 0-1, higher is more likely a duplicate
+	Reasons         []string               `json:"reasons"`
+	DistanceMeters  *float64               `json:"distance_meters,omitempty"`
+	TitleSimilarity float64                `json:"title_similarity"`
+	CreatedAt       time.Time              `json:"created_at"`
+}
+
+// MergeDuplicateRequest merges the record in the path into a master record
+type MergeDuplicateRequest struct {
+	MasterID string `json:"master_id" validate:"required,uuid"`
+}
+
+// MergeDuplicateResult tells what was moved to the master record
+type MergeDuplicateResult struct {
+	Comments    int64 `json:"comments"`
+	Attachments int64 `json:"attachments"`
+	Duplicates  int64 `json:"duplicates"` // Earlier duplicates of the merged record now linked to the master
+}
+
+type MergeDuplicateResponse struct {
+	Master    IncidentResponse     `json:"master"`
+	Duplicate IncidentResponse     `json:"duplicate"`
+	Moved     MergeDuplicateResult `json:"moved"`
+}
@@ -0,0 +1,305 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IncidentFilterNode is a node of an incident filter expression: either a group that combines
+// its conditions with "and"/"or", or a single condition comparing a field with a value.
+//
+//	{"op": "and", "conditions": [
+//	  {"field": "department_id", "operator": "in", "value": ["<uuid>", "<uuid>"]},
+//	  {"field": "due_date", "operator": "between", "value": ["today", "now+7d"]},
+//	  {"op": "or", "conditions": [
+//	    {"field": "lookup.PRIORITY", "operator": "in", "value": ["HIGH", "CRITICAL"]},
+//	    {"field": "custom_fields.vip", "operator": "eq", "value": true}
+//	  ]}
+//	]}
+//
+// Lookup fields take value codes or IDs. Date values are RFC3339, YYYY-MM-DD, or relative to
+// the time of the query: "now", "today", optionally followed by +/-N and h, d or w.
+type IncidentFilterNode struct {
+	Op         string               `json:"op,omitempty"`
+	Conditions []IncidentFilterNode `json:"conditions,omitempty"`
+	Field      string               `json:"field,omitempty"`
+	Operator   string               `json:"operator,omitempty"`
+	Value      interface{}          `json:"value,omitempty"`
+}
+
+// Filter field kinds
+const (
+	FilterKindString = "string"
+	FilterKindUUID   = "uuid"
+	FilterKindTime   = "time"
+	FilterKindNumber = "number"
+	FilterKindBool   = "bool"
+	FilterKindLookup = "lookup"
+	FilterKindCustom = "custom"
+)
+
+// Filter operators
+const (
+	FilterOpEq         = "eq"
+	FilterOpNeq        = "neq"
+	FilterOpIn         = "in"
+	FilterOpNotIn      = "not_in"
+	FilterOpGt         = "gt"
+	FilterOpGte        = "gte"
+	FilterOpLt         = "lt"
+	FilterOpLte        = "lte"
+	FilterOpBetween    = "between"
+	FilterOpContains   = "contains"
+	FilterOpIsEmpty    = "is_empty"
+	FilterOpIsNotEmpty = "is_not_empty"
+)
+
+const (
+	filterMaxDepth      = 5
+	filterMaxConditions = 50
+)
+
+// IncidentFilterFields lists the built-in incident columns available to filter expressions
+var IncidentFilterFields = map[string]string{
+	"incident_number":   FilterKindString,
+	"title":             FilterKindString,
+	"description":       FilterKindString,
+	"record_type":       FilterKindString,
+	"channel":           FilterKindString,
+	"reporter_email":    FilterKindString,
+	"reporter_name":     FilterKindString,
+	"created_by_name":   FilterKindString,
+	"workflow_id":       FilterKindUUID,
+	"current_state_id":  FilterKindUUID,
+	"classification_id": FilterKindUUID,
+	"assignee_id":       FilterKindUUID,
+	"department_id":     FilterKindUUID,
+	"location_id":       FilterKindUUID,
+	"reporter_id":       FilterKindUUID,
+	"created_at":        FilterKindTime,
+	"updated_at":        FilterKindTime,
+	"due_date":          FilterKindTime,
+	"resolved_at":       FilterKindTime,
+	"closed_at":         FilterKindTime,
+	"sla_deadline":      FilterKindTime,
+	"sla_breached":      FilterKindBool,
+	"workflow_version":  FilterKindNumber,
+	"evaluation_count":  FilterKindNumber,
+}
+
+var filterKindOperators = map[string][]string{
+	FilterKindString: {FilterOpEq, FilterOpNeq, FilterOpIn, FilterOpNotIn, FilterOpContains, FilterOpIsEmpty, FilterOpIsNotEmpty},
+	FilterKindUUID:   {FilterOpEq, FilterOpNeq, FilterOpIn, FilterOpNotIn, FilterOpIsEmpty, FilterOpIsNotEmpty},
+	FilterKindTime:   {FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpBetween, FilterOpIsEmpty, FilterOpIsNotEmpty},
+	FilterKindNumber: {FilterOpEq, FilterOpNeq, FilterOpIn, FilterOpNotIn, FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpBetween},
+	FilterKindBool:   {FilterOpEq, FilterOpNeq},
+	FilterKindLookup: {FilterOpEq, FilterOpNeq, FilterOpIn, FilterOpNotIn, FilterOpIsEmpty, FilterOpIsNotEmpty},
+	FilterKindCustom: {FilterOpEq, FilterOpNeq, FilterOpIn, FilterOpNotIn, FilterOpGt, FilterOpGte, FilterOpLt, FilterOpLte, FilterOpBetween, FilterOpContains, FilterOpIsEmpty, FilterOpIsNotEmpty},
+}
+
+var (
+	filterKeyPattern      = regexp.MustCompile(`^[A-Za-z0-9_\-]{1,100}$`)
+	relativeFilterPattern = regexp.MustCompile(`^(now|today)(?:([+-])(\d+)([hdw]))?$`)
+)
+
+// IsGroup reports whether the node combines other nodes
+func (n *IncidentFilterNode) IsGroup() bool {
+	return n.Op != ""
+}
+
+// FieldKind returns the kind of the node's field and, for lookup and custom fields, the
+// category code or custom field key
+func (n *IncidentFilterNode) FieldKind() (string, string, error) {
+	if kind, ok := IncidentFilterFields[n.Field]; ok {
+		return kind, "", nil
+	}
+	if code, ok := strings.CutPrefix(n.Field, "lookup."); ok && filterKeyPattern.MatchString(code) {
+		return FilterKindLookup, code, nil
+	}
+	if key, ok := strings.CutPrefix(n.Field, "custom_fields."); ok && filterKeyPattern.MatchString(key) {
+		return FilterKindCustom, key, nil
+	}
+	return "", "", fmt.Errorf("unknown filter field %q", n.Field)
+}
+
+// Validate checks the structure, fields, operators and values of an expression
+func (n *IncidentFilterNode) Validate() error {
+	count := 0
+	return n.validate(1, &count)
+}
+
+func (n *IncidentFilterNode) validate(depth int, count *int) error {
+	if depth > filterMaxDepth {
+		return fmt.Errorf("filter groups can be nested at most %d levels deep", filterMaxDepth)
+	}
+
+	if n.IsGroup() {
+		if n.Op != "and" && n.Op != "or" {
+			return fmt.Errorf("unknown filter group op %q, expected and/or", n.Op)
+		}
+		if n.Field != "" {
+			return fmt.Errorf("filter group %q cannot also have a field", n.Op)
+		}
+		if len(n.Conditions) == 0 {
+			return fmt.Errorf("filter group %q has no conditions", n.Op)
+		}
+		for i := range n.Conditions {
+			if err := n.Conditions[i].validate(depth+1, count); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	*count++
+	if *count > filterMaxConditions {
+		return fmt.Errorf("filters are limited to %d conditions", filterMaxConditions)
+	}
+	kind, _, err := n.FieldKind()
+	if err != nil {
+		return err
+	}
+	allowed := false
+	for _, op := range filterKindOperators[kind] {
+		if op == n.Operator {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("operator %q is not supported for field %q", n.Operator, n.Field)
+	}
+	_, err = n.Operands(time.Now())
+	return err
+}
+
+// Operands converts the condition's value to typed operands for its field: none for the
+// emptiness operators, two for between, one or more for in/not_in, and one otherwise.
+// Relative dates resolve against now.
+func (n *IncidentFilterNode) Operands(now time.Time) ([]interface{}, error) {
+	kind, _, err := n.FieldKind()
+	if err != nil {
+		return nil, err
+	}
+
+	var raw []interface{}
+	switch n.Operator {
+	case FilterOpIsEmpty, FilterOpIsNotEmpty:
+		return nil, nil
+	case FilterOpIn, FilterOpNotIn, FilterOpBetween:
+		list, ok := n.Value.([]interface{})
+		if !ok || len(list) == 0 {
+			return nil, fmt.Errorf("%s on %q needs a list value", n.Operator, n.Field)
+		}
+		if n.Operator == FilterOpBetween && len(list) != 2 {
+			return nil, fmt.Errorf("between on %q needs exactly two values", n.Field)
+		}
+		raw = list
+	default:
+		if n.Value == nil {
+			return nil, fmt.Errorf("%s on %q needs a value", n.Operator, n.Field)
+		}
+		raw = []interface{}{n.Value}
+	}
+
+	operands := make([]interface{}, len(raw))
+	for i, v := range raw {
+		// The upper bound of a date-only range covers the whole day
+		endOfDay := n.Operator == FilterOpLte || (n.Operator == FilterOpBetween && i == 1)
+		operand, err := filterOperand(kind, v, now, endOfDay)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %q: %w", n.Field, err)
+		}
+		operands[i] = operand
+	}
+	return operands, nil
+}
+
+func filterOperand(kind string, v interface{}, now time.Time, endOfDay bool) (interface{}, error) {
+	switch kind {
+	case FilterKindUUID:
+		s, _ := v.(string)
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("%v is not a UUID", v)
+		}
+		return id, nil
+	case FilterKindTime:
+		s, _ := v.(string)
+		return parseFilterTime(s, now, endOfDay)
+	case FilterKindNumber:
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case string:
+			return strconv.ParseFloat(t, 64)
+		}
+		return nil, fmt.Errorf("%v is not a number", v)
+	case FilterKindBool:
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			return strconv.ParseBool(t)
+		}
+		return nil, fmt.Errorf("%v is not a boolean", v)
+	case FilterKindCustom:
+		// Custom fields keep numbers numeric so ranges compare by value
+		switch t := v.(type) {
+		case float64:
+			return t, nil
+		case bool:
+			return strconv.FormatBool(t), nil
+		case string:
+			return t, nil
+		}
+		return nil, fmt.Errorf("%v is not a scalar value", v)
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a string", v)
+		}
+		return s, nil
+	}
+}
+
+func parseFilterTime(s string, now time.Time, endOfDay bool) (time.Time, error) {
+	if m := relativeFilterPattern.FindStringSubmatch(s); m != nil {
+		t := now
+		if m[1] == "today" {
+			y, mo, d := now.Date()
+			t = time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+		}
+		if m[2] != "" {
+			amount, _ := strconv.Atoi(m[3])
+			if m[2] == "-" {
+				amount = -amount
+			}
+			switch m[4] {
+			case "h":
+				t = t.Add(time.Duration(amount) * time.Hour)
+			case "d":
+				t = t.AddDate(0, 0, amount)
+			case "w":
+				t = t.AddDate(0, 0, 7*amount)
+			}
+		}
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return t, fmt.Errorf("%q is not a date", s)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Second)
+	}
+	return t, nil
+}
@@ -0,0 +1,40 @@
+package models
+
+// MergeIncidentRequest merges the record in the path into a target record and closes it
+type MergeIncidentRequest struct {
+	TargetID     string  `json:"target_id" validate:"required,uuid"`
+	CloseStateID *string `json:"close_state_id" validate:"omitempty,uuid"` // Terminal state for the source, defaults to the first one
+	Reason       string  `json:"reason" validate:"max=500"`
+}
+
+// MergeIncidentResult tells what was moved to the target record
+type MergeIncidentResult struct {
+	Comments    int64 `json:"comments"`
+	Attachments int64 `json:"attachments"`
+	Feedback    int64 `json:"feedback"`
+	Assignees   int64 `json:"assignees"`
+}
+
+type MergeIncidentResponse struct {
+	Source IncidentResponse    `json:"source"`
+	Target IncidentResponse    `json:"target"`
+	Moved  MergeIncidentResult `json:"moved"`
+}
+
+// SplitIncidentRequest creates new records from parts of the record in the path
+type SplitIncidentRequest struct {
+	Records []SplitRecordRequest `json:"records" validate:"required,min=1,max=10,dive"`
+}
+
+// SplitRecordRequest describes one new record and the comments and attachments it takes over
+type SplitRecordRequest struct {
+	Title         string   `json:"title" validate:"required,min=5,max=200"`
+	Description   string   `json:"description"`
+	CommentIDs    []string `json:"comment_ids" validate:"omitempty,dive,uuid"`
+	AttachmentIDs []string `json:"attachment_ids" validate:"omitempty,dive,uuid"`
+}
+
+type SplitIncidentResponse struct {
+	Source  IncidentResponse   `json:"source"`
+	Records []IncidentResponse `json:"records"`
+}
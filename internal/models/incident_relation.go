@@ -0,0 +1,165 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Relation types. Each directional type is stored in one direction and read back in the
+// other from the target's side, e.g. "A blocks B" shows as "B blocked_by A" on B.
+const (
+	RelationBlocks       = "blocks"
+	RelationBlockedBy    = "blocked_by"
+	RelationDuplicates   = "duplicates"
+	RelationDuplicatedBy = "duplicated_by"
+	RelationCausedBy     = "caused_by"
+	RelationCauses       = "causes"
+	RelationParentOf     = "parent_of"
+	RelationChildOf      = "child_of"
+	RelationRelatedTo    = "related_to"
+)
+
+// relationInverses maps every relation type to how it reads from the other record
+var relationInverses = map[string]string{
+	RelationBlocks:       RelationBlockedBy,
+	RelationBlockedBy:    RelationBlocks,
+	RelationDuplicates:   RelationDuplicatedBy,
+	RelationDuplicatedBy: RelationDuplicates,
+	RelationCausedBy:     RelationCauses,
+	RelationCauses:       RelationCausedBy,
+	RelationParentOf:     RelationChildOf,
+	RelationChildOf:      RelationParentOf,
+	RelationRelatedTo:    RelationRelatedTo,
+}
+
+// storedRelationTypes are the forms relations are saved in
+var storedRelationTypes = map[string]bool{
+	RelationBlocks:     true,
+	RelationDuplicates: true,
+	RelationCausedBy:   true,
+	RelationParentOf:   true,
+	RelationRelatedTo:  true,
+}
+
+// InverseRelationType returns how a relation type reads from the other record
+func InverseRelationType(relationType string) string {
+	return relationInverses[relationType]
+}
+
+// NormalizeRelationType returns the stored form of a relation type and whether source and
+// target have to be swapped to store it. ok is false for unknown types.
+func NormalizeRelationType(relationType string) (stored string, swap bool, ok bool) {
+	if storedRelationTypes[relationType] {
+		return relationType, false, true
+	}
+	inverse, known := relationInverses[relationType]
+	if !known {
+		return "", false, false
+	}
+	return inverse, true, true
+}
+
+// IncidentRelation is a typed link between two records of any type
+type IncidentRelation struct {
+	ID           uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	SourceID     uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_incident_relation" json:"source_id"`
+	Source       *Incident `gorm:"foreignKey:SourceID" json:"source,omitempty"`
+	TargetID     uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_incident_relation" json:"target_id"`
+	Target       *Incident `gorm:"foreignKey:TargetID" json:"target,omitempty"`
+	RelationType string    `gorm:"size:20;not null;uniqueIndex:idx_incident_relation" json:"relation_type"` // Stored form
+
+	// For blocks relations: the target cannot reach a terminal state while the source is open
+	Enforced bool   `gorm:"default:false" json:"enforced"`
+	Note     string `gorm:"size:500" json:"note"`
+
+	CreatedByID uuid.UUID `gorm:"type:uuid;not null" json:"created_by_id"`
+	CreatedBy   *User     `gorm:"foreignKey:CreatedByID" json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *IncidentRelation) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Request types
+
+// IncidentRelationCreateRequest relates the record in the path to target_id, read as
+// "<record> <relation_type> <target>"
+type IncidentRelationCreateRequest struct {
+	TargetID     string `json:"target_id" validate:"required,uuid"`
+	RelationType string `json:"relation_type" validate:"required,oneof=blocks blocked_by duplicates duplicated_by caused_by causes parent_of child_of related_to"`
+	Enforced     bool   `json:"enforced"`
+	Note         string `json:"note" validate:"max=500"`
+}
+
+type IncidentRelationUpdateRequest struct {
+	Enforced *bool   `json:"enforced"`
+	Note     *string `json:"note" validate:"omitempty,max=500"`
+}
+
+// Response types
+
+// RelatedRecordResponse summarizes the record on the other side of a relation
+type RelatedRecordResponse struct {
+	ID             uuid.UUID              `json:"id"`
+	IncidentNumber string                 `json:"incident_number"`
+	Title          string                 `json:"title"`
+	RecordType     string                 `json:"record_type"`
+	CurrentState   *WorkflowStateResponse `json:"current_state,omitempty"`
+	ClosedAt       *time.Time             `json:"closed_at"`
+}
+
+// IncidentRelationResponse describes a relation from one record's side
+type IncidentRelationResponse struct {
+	ID           uuid.UUID             `json:"id"`
+	RelationType string                `json:"relation_type"`
+	Record       RelatedRecordResponse `json:"record"`
+	Enforced     bool                  `json:"enforced"`
+	Note         string                `json:"note,omitempty"`
+	CreatedBy    *UserResponse         `json:"created_by,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// ToIncidentRelationResponse describes the relation as seen from the given record. The
+// other record must be loaded.
+func ToIncidentRelationResponse(r *IncidentRelation, incidentID uuid.UUID) IncidentRelationResponse {
+	relationType := r.RelationType
+	other := r.Target
+	if r.TargetID == incidentID && r.SourceID != incidentID {
+		relationType = InverseRelationType(r.RelationType)
+		other = r.Source
+	}
+
+	resp := IncidentRelationResponse{
+		ID:           r.ID,
+		RelationType: relationType,
+		Enforced:     r.Enforced,
+		Note:         r.Note,
+		CreatedAt:    r.CreatedAt,
+	}
+	if other != nil {
+		resp.Record = RelatedRecordResponse{
+			ID:             other.ID,
+			IncidentNumber: other.IncidentNumber,
+			Title:          other.Title,
+			RecordType:     other.RecordType,
+			ClosedAt:       other.ClosedAt,
+		}
+		if other.CurrentState != nil {
+			state := ToWorkflowStateResponse(other.CurrentState)
+			resp.Record.CurrentState = &state
+		}
+	}
+	if r.CreatedBy != nil {
+		user := ToUserResponse(r.CreatedBy)
+		resp.CreatedBy = &user
+	}
+	return resp
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IncidentSearchDocument is the full-text index entry of a record. Rows are written by
+// database triggers on incidents and incident_comments, never by the application.
+type IncidentSearchDocument struct {
+	IncidentID uuid.UUID `gorm:"type:uuid;primary_key" json:"incident_id"`
+	Content    string    `gorm:"type:text" json:"-"` // Description, comments and custom fields used for snippets
+	Document   string    `gorm:"type:tsvector;index:idx_incident_search_documents_document,type:gin" json:"-"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IncidentSearchFilter describes a full-text search. Query accepts words, "quoted phrases",
+// prefix* terms and -excluded terms. RecordTypes and UserRoleIDs carry the caller's visibility.
+type IncidentSearchFilter struct {
+	Query       string      `json:"query"`
+	RecordType  string      `json:"record_type"`
+	Page        int         `json:"page"`
+	Limit       int         `json:"limit"`
+	RecordTypes []string    `json:"-"` // Record types the caller may view
+	UserRoleIDs []uuid.UUID `json:"-"` // For state viewable_roles
+	AllStates   bool        `json:"-"` // Super admins see records in every state
+}
+
+// IncidentSearchResult is a ranked search hit. TitleHighlight and Snippet are HTML-escaped,
+// with matches wrapped in <mark> tags.
+type IncidentSearchResult struct {
+	ID               uuid.UUID `json:"id"`
+	IncidentNumber   string    `json:"incident_number"`
+	RecordType       string    `json:"record_type"`
+	Title            string    `json:"title"`
+	TitleHighlight   string    `json:"title_highlight"`
+	Snippet          string    `json:"snippet"`
+	Rank             float64   `json:"rank"`
+	CurrentStateID   uuid.UUID `json:"current_state_id"`
+	CurrentStateName string    `json:"current_state_name"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -0,0 +1,136 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IncidentView is a named, saved incident filter. The owner can share it with roles;
+// any user who can see a view can pin it to their own list.
+type IncidentView struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	Name        string    `gorm:"size:100;not null" json:"name"`
+	Description string    `gorm:"size:500" json:"description"`
+	RecordType  string    `gorm:"size:20" json:"record_type"` // Empty = all record types
+	Search      string    `gorm:"size:200" json:"search"`
+	Filter      string    `gorm:"type:text" json:"filter"` // JSON serialized IncidentFilterNode
+
+	OwnerID     uuid.UUID `gorm:"type:uuid;index;not null" json:"owner_id"`
+	Owner       *User     `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
+	SharedRoles []Role    `gorm:"many2many:incident_view_roles;" json:"shared_roles,omitempty"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (v *IncidentView) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// FilterNode decodes the stored filter expression, nil when the view has none
+func (v *IncidentView) FilterNode() *IncidentFilterNode {
+	if v.Filter == "" {
+		return nil
+	}
+	var node IncidentFilterNode
+	if err := json.Unmarshal([]byte(v.Filter), &node); err != nil {
+		return nil
+	}
+	return &node
+}
+
+// ToIncidentFilter returns the incident filter the view stands for
+func (v *IncidentView) ToIncidentFilter() *IncidentFilter {
+	filter := &IncidentFilter{
+		Search:     v.Search,
+		Expression: v.FilterNode(),
+	}
+	if v.RecordType != "" {
+		recordType := v.RecordType
+		filter.RecordType = &recordType
+	}
+	return filter
+}
+
+// IncidentViewPin places a view in a user's pinned list
+type IncidentViewPin struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"user_id"`
+	ViewID    uuid.UUID `gorm:"type:uuid;primaryKey;index" json:"view_id"`
+	SortOrder int       `gorm:"default:0" json:"sort_order"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Request types
+
+type IncidentViewCreateRequest struct {
+	Name        string              `json:"name" validate:"required,max=100"`
+	Description string              `json:"description" validate:"max=500"`
+	RecordType  string              `json:"record_type" validate:"omitempty,oneof=incident request complaint query"`
+	Search      string              `json:"search" validate:"max=200"`
+	Filter      *IncidentFilterNode `json:"filter"`
+	RoleIDs     []string            `json:"role_ids" validate:"omitempty,dive,uuid"`
+}
+
+// IncidentViewUpdateRequest changes the fields that are set. An empty filter object clears
+// the filter and an empty role_ids list stops sharing.
+type IncidentViewUpdateRequest struct {
+	Name        *string             `json:"name" validate:"omitempty,max=100"`
+	Description *string             `json:"description" validate:"omitempty,max=500"`
+	RecordType  *string             `json:"record_type"`
+	Search      *string             `json:"search" validate:"omitempty,max=200"`
+	Filter      *IncidentFilterNode `json:"filter"`
+	RoleIDs     *[]string           `json:"role_ids" validate:"omitempty,dive,uuid"`
+}
+
+type IncidentViewPinRequest struct {
+	SortOrder int `json:"sort_order"`
+}
+
+// Response types
+
+type IncidentViewResponse struct {
+	ID          uuid.UUID           `json:"id"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	RecordType  string              `json:"record_type"`
+	Search      string              `json:"search"`
+	Filter      *IncidentFilterNode `json:"filter"`
+	Owner       *UserResponse       `json:"owner,omitempty"`
+	IsOwner     bool                `json:"is_owner"`
+	SharedRoles []RoleResponse      `json:"shared_roles"`
+	Pinned      bool                `json:"pinned"`
+	PinOrder    int                 `json:"pin_order"`
+	Count       *int64              `json:"count,omitempty"` // Matching records, when counts are requested
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+func ToIncidentViewResponse(v *IncidentView, userID uuid.UUID) IncidentViewResponse {
+	resp := IncidentViewResponse{
+		ID:          v.ID,
+		Name:        v.Name,
+		Description: v.Description,
+		RecordType:  v.RecordType,
+		Search:      v.Search,
+		Filter:      v.FilterNode(),
+		IsOwner:     v.OwnerID == userID,
+		SharedRoles: make([]RoleResponse, len(v.SharedRoles)),
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.UpdatedAt,
+	}
+	if v.Owner != nil {
+		owner := ToUserResponse(v.Owner)
+		resp.Owner = &owner
+	}
+	for i := range v.SharedRoles {
+		resp.SharedRoles[i] = ToRoleResponse(&v.SharedRoles[i])
+	}
+	return resp
+}
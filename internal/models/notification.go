@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification types
+const (
+	NotificationTypeTransitionAction = "transition_action"
+	NotificationTypeSLABreach        = "sla_breach"
+)
+
+// Notification is an in-app notification addressed to a single user
+type Notification struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;index;not null" json:"user_id"`
+	User       *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Type       string     `gorm:"size:50;index;not null" json:"type"` // transition_action, sla_breach
+	Title      string     `gorm:"size:255;not null" json:"title"`
+	Message    string     `gorm:"type:text" json:"message"`
+	IncidentID *uuid.UUID `gorm:"type:uuid;index" json:"incident_id"`
+	Incident   *Incident  `gorm:"foreignKey:IncidentID" json:"incident,omitempty"`
+	IsRead     bool       `gorm:"default:false;index" json:"is_read"`
+	ReadAt     *time.Time `json:"read_at"`
+
+	CreatedAt time.Time      `gorm:"index" json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
+
+// NotificationFilter holds filter parameters for listing a user's notifications
+type NotificationFilter struct {
+	UserID     uuid.UUID `json:"-"`
+	UnreadOnly bool      `json:"unread_only"`
+	Type       string    `json:"type"`
+	Page       int       `json:"page"`
+	Limit      int       `json:"limit"`
+}
+
+// NotificationResponse is the response structure for notifications
+type NotificationResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	Type           string     `json:"type"`
+	Title          string     `json:"title"`
+	Message        string     `json:"message"`
+	IncidentID     *uuid.UUID `json:"incident_id,omitempty"`
+	IncidentNumber string     `json:"incident_number,omitempty"`
+	RecordType     string     `json:"record_type,omitempty"`
+	IsRead         bool       `json:"is_read"`
+	ReadAt         *time.Time `json:"read_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func ToNotificationResponse(n *Notification) NotificationResponse {
+	resp := NotificationResponse{
+		ID:         n.ID,
+		Type:       n.Type,
+		Title:      n.Title,
+		Message:    n.Message,
+		IncidentID: n.IncidentID,
+		IsRead:     n.IsRead,
+		ReadAt:     n.ReadAt,
+		CreatedAt:  n.CreatedAt,
+	}
+
+	if n.Incident != nil {
+		resp.IncidentNumber = n.Incident.IncidentNumber
+		resp.RecordType = n.Incident.RecordType
+	}
+
+	return resp
+}
@@ -15,6 +15,9 @@ const (
 	CallStatusBusy    CallStatus = "busy"
 )
 
+// SystemUserEmail identifies the seeded system user that background jobs act as
+const SystemUserEmail = "system@automax.com"
+
 type User struct {
 	ID              uuid.UUID        `gorm:"type:uuid;primary_key" json:"id"`
 	Email           string           `gorm:"uniqueIndex;not null" json:"email"`
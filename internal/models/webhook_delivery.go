@@ -0,0 +1,117 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses
+const (
+	WebhookStatusSuccess = "success"
+	WebhookStatusFailed  = "failed"
+)
+
+// WebhookDelivery records a single outbound webhook attempt. Retries and redeliveries
+// of the same event share an IdempotencyKey and increment Attempt.
+type WebhookDelivery struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	IdempotencyKey string    `gorm:"size:100;index;not null" json:"idempotency_key"`
+	Attempt        int       `gorm:"not null" json:"attempt"`
+	IsRedelivery   bool      `gorm:"default:false" json:"is_redelivery"`
+
+	// Request
+	URL            string `gorm:"size:2000;not null" json:"url"`
+	Method         string `gorm:"size:10;not null" json:"method"`
+	RequestHeaders string `gorm:"type:text" json:"request_headers"` // JSON object
+	RequestBody    string `gorm:"type:text" json:"request_body"`
+
+	// Response
+	Status         string `gorm:"size:20;index" json:"status"` // success, failed
+	ResponseStatus int    `json:"response_status"`
+	ResponseBody   string `gorm:"type:text" json:"response_body"` // Truncated snippet
+	LatencyMs      int64  `json:"latency_ms"`
+	Error          string `gorm:"type:text" json:"error,omitempty"`
+
+	// Source of the webhook (optional)
+	IncidentID         *uuid.UUID `gorm:"type:uuid;index" json:"incident_id,omitempty"`
+	TransitionActionID *uuid.UUID `gorm:"type:uuid;index" json:"transition_action_id,omitempty"`
+
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+func (w *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// WebhookDeliveryFilter holds filter parameters for querying webhook deliveries
+type WebhookDeliveryFilter struct {
+	Status             string     `json:"status"`
+	IdempotencyKey     string     `json:"idempotency_key"`
+	IncidentID         *uuid.UUID `json:"incident_id"`
+	TransitionActionID *uuid.UUID `json:"transition_action_id"`
+	StartDate          *time.Time `json:"start_date"`
+	EndDate            *time.Time `json:"end_date"`
+	Search             string     `json:"search"`
+	Page               int        `json:"page"`
+	Limit              int        `json:"limit"`
+}
+
+// WebhookDeliveryResponse is the response structure for webhook deliveries
+type WebhookDeliveryResponse struct {
+	ID                 uuid.UUID         `json:"id"`
+	IdempotencyKey     string            `json:"idempotency_key"`
+	Attempt            int               `json:"attempt"`
+	IsRedelivery       bool              `json:"is_redelivery"`
+	URL                string            `json:"url"`
+	Method             string            `json:"method"`
+	RequestHeaders     map[string]string `json:"request_headers,omitempty"`
+	RequestBody        string            `json:"request_body"`
+	Status             string            `json:"status"`
+	ResponseStatus     int               `json:"response_status"`
+	ResponseBody       string            `json:"response_body"`
+	LatencyMs          int64             `json:"latency_ms"`
+	Error              string            `json:"error,omitempty"`
+	IncidentID         *uuid.UUID        `json:"incident_id,omitempty"`
+	TransitionActionID *uuid.UUID        `json:"transition_action_id,omitempty"`
+	CreatedAt          time.Time         `json:"created_at"`
+}
+
+func ToWebhookDeliveryResponse(w *WebhookDelivery) WebhookDeliveryResponse {
+	resp := WebhookDeliveryResponse{
+		ID:                 w.ID,
+		IdempotencyKey:     w.IdempotencyKey,
+		Attempt:            w.Attempt,
+		IsRedelivery:       w.IsRedelivery,
+		URL:                w.URL,
+		Method:             w.Method,
+		RequestBody:        w.RequestBody,
+		Status:             w.Status,
+		ResponseStatus:     w.ResponseStatus,
+		ResponseBody:       w.ResponseBody,
+		LatencyMs:          w.LatencyMs,
+		Error:              w.Error,
+		IncidentID:         w.IncidentID,
+		TransitionActionID: w.TransitionActionID,
+		CreatedAt:          w.CreatedAt,
+	}
+
+	if w.RequestHeaders != "" {
+		_ = json.Unmarshal([]byte(w.RequestHeaders), &resp.RequestHeaders)
+		// Hide credentials configured on the action
+		for key := range resp.RequestHeaders {
+			lower := strings.ToLower(key)
+			if lower == "authorization" || strings.Contains(lower, "token") || strings.Contains(lower, "secret") || strings.Contains(lower, "api-key") {
+				resp.RequestHeaders[key] = "[redacted]"
+			}
+		}
+	}
+
+	return resp
+}
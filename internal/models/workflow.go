@@ -56,9 +56,14 @@ type WorkflowState struct {
 	Name        string    `gorm:"not null;size:100" json:"name"`
 	Code        string    `gorm:"not null;size:50" json:"code"`
 	Description string    `gorm:"size:500" json:"description"`
-	StateType   string    `gorm:"size:20;default:'normal'" json:"state_type"` // initial, normal, terminal
+	StateType   string    `gorm:"size:20;default:'normal'" json:"state_type"` // initial, normal, subworkflow, terminal
 	Color       string    `gorm:"size:20;default:'#6366f1'" json:"color"`
 
+	// Sub-workflow - entering a subworkflow state launches a child record in SubWorkflowID.
+	// The parent waits until the child closes, then fires its child_completed transition.
+	SubWorkflowID *uuid.UUID `gorm:"type:uuid" json:"sub_workflow_id"`
+	SubRecordType string     `gorm:"size:20" json:"sub_record_type"` // record type of the child, empty = same as the parent
+
 	// Visual position on canvas
 	PositionX int `gorm:"default:0" json:"position_x"`
 	PositionY int `gorm:"default:0" json:"position_y"`
@@ -119,6 +124,19 @@ type WorkflowTransition struct {
 	ManualSelectUser bool `gorm:"default:false" json:"manual_select_user"`
 	// If manual_select_user=true: user performing transition manually selects the assignee from dropdown
 
+	// Approvals - when ApprovalMode is set, executing the transition records a vote and
+	// the state only changes once the quorum is reached
+	ApprovalMode   string     `gorm:"size:20" json:"approval_mode"`                              // "", any_n, all, one_per_role
+	ApprovalQuorum int        `gorm:"default:0" json:"approval_quorum"`                          // approvals needed for any_n
+	Approvers      []User     `gorm:"many2many:transition_approvers;" json:"approvers,omitempty"` // eligible voters, empty = anyone allowed by role
+	RejectStateID  *uuid.UUID `gorm:"type:uuid" json:"reject_state_id"`                          // a rejection moves the incident here
+
+	// Automatic execution - the transition scheduler runs the transition as the system user
+	// once the trigger fires, e.g. auto-close after 72h in Pending Customer
+	AutoTrigger    string `gorm:"size:30" json:"auto_trigger"`       // "", time_in_state, due_date_passed, child_completed
+	AutoAfterHours int    `gorm:"default:0" json:"auto_after_hours"` // hours in the state, or after the due date
+	ChildStateCode string `gorm:"size:50" json:"child_state_code"`   // child_completed: terminal state of the child, empty = any
+
 	// Requirements and Actions
 	Requirements []TransitionRequirement `gorm:"foreignKey:TransitionID" json:"requirements,omitempty"`
 	Actions      []TransitionAction      `gorm:"foreignKey:TransitionID" json:"actions,omitempty"`
@@ -137,15 +155,33 @@ func (t *WorkflowTransition) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// Automatic transition triggers
+const (
+	AutoTriggerTimeInState    = "time_in_state"    // AutoAfterHours after entering the from state
+	AutoTriggerDueDatePassed  = "due_date_passed"  // AutoAfterHours after the incident's due date
+	AutoTriggerChildCompleted = "child_completed"  // the sub-workflow child launched by the from state closed
+)
+
+// StateTypeSubWorkflow is the state type that launches a child record and waits for it
+const StateTypeSubWorkflow = "subworkflow"
+
+// DueAutoTransition is an incident whose automatic transition has fired
+type DueAutoTransition struct {
+	IncidentID   uuid.UUID
+	TransitionID uuid.UUID
+}
+
 // TransitionRequirement defines mandatory requirements for a transition
 type TransitionRequirement struct {
 	ID           uuid.UUID           `gorm:"type:uuid;primary_key" json:"id"`
 	TransitionID uuid.UUID           `gorm:"type:uuid;index;not null" json:"transition_id"`
 	Transition   *WorkflowTransition `gorm:"foreignKey:TransitionID" json:"transition,omitempty"`
 
-	RequirementType string `gorm:"size:50;not null" json:"requirement_type"` // comment, attachment, field_value
-	FieldName       string `gorm:"size:100" json:"field_name"`               // for field_value type
-	FieldValue      string `gorm:"size:500" json:"field_value"`              // expected value or validation rule
+	RequirementType string `gorm:"size:50;not null" json:"requirement_type"` // comment, attachment, feedback, field_value
+	FieldName       string `gorm:"size:100" json:"field_name"`               // for field_value type, e.g. title, lookup:PRIORITY, custom_field:key
+	Operator        string `gorm:"size:30" json:"operator"`                  // for field_value type, see RequirementOperator* constants
+	FieldValue      string `gorm:"size:500" json:"field_value"`              // operand for the operator, or allowed MIME types for attachment
+	MinCount        int    `gorm:"default:0" json:"min_count"`               // for attachment type, minimum number of files
 	IsMandatory     *bool  `gorm:"default:true" json:"is_mandatory"`
 	ErrorMessage    string `gorm:"size:200" json:"error_message"`
 
@@ -153,6 +189,31 @@ type TransitionRequirement struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Operators for field_value requirements
+const (
+	RequirementOperatorRequired   = "required"
+	RequirementOperatorEquals     = "equals"
+	RequirementOperatorNotEquals  = "not_equals"
+	RequirementOperatorIn         = "in"
+	RequirementOperatorRegex      = "regex"
+	RequirementOperatorMin        = "min"
+	RequirementOperatorMax        = "max"
+	RequirementOperatorMinLength  = "min_length"
+	RequirementOperatorMaxLength  = "max_length"
+	RequirementOperatorDateFuture = "date_future"
+	RequirementOperatorDatePast   = "date_past"
+	RequirementOperatorExpression = "expression" // FieldValue is an action condition expression
+)
+
+// TransitionRequirementFailure describes one unmet transition requirement
+type TransitionRequirementFailure struct {
+	RequirementID   uuid.UUID `json:"requirement_id"`
+	RequirementType string    `json:"requirement_type"`
+	Field           string    `json:"field,omitempty"`
+	Operator        string    `json:"operator,omitempty"`
+	Message         string    `json:"message"`
+}
+
 func (r *TransitionRequirement) BeforeCreate(tx *gorm.DB) error {
 	if r.ID == uuid.Nil {
 		r.ID = uuid.New()
@@ -166,13 +227,17 @@ type TransitionAction struct {
 	TransitionID uuid.UUID           `gorm:"type:uuid;index;not null" json:"transition_id"`
 	Transition   *WorkflowTransition `gorm:"foreignKey:TransitionID" json:"transition,omitempty"`
 
-	ActionType  string `gorm:"size:50;not null" json:"action_type"` // email, field_update, webhook, notification
+	ActionType  string `gorm:"size:50;not null" json:"action_type"` // email, field_update, webhook, notification, create_linked_record
 	Name        string `gorm:"size:100;not null" json:"name"`
 	Description string `gorm:"size:500" json:"description"`
 
 	// Configuration (JSON for flexibility)
 	Config string `gorm:"type:text" json:"config"`
 
+	// Optional expression evaluated against the incident, e.g. "PRIORITY == CRITICAL".
+	// The action is skipped when it evaluates to false.
+	Condition string `gorm:"type:text" json:"condition"`
+
 	ExecutionOrder int  `gorm:"default:0" json:"execution_order"`
 	IsAsync        bool `gorm:"default:false" json:"is_async"`
 	IsActive       bool `gorm:"default:true" json:"is_active"`
@@ -216,20 +281,22 @@ type WorkflowStateCreateRequest struct {
 	Name            string   `json:"name" validate:"required,min=2,max=100"`
 	Code            string   `json:"code" validate:"required,min=2,max=50"`
 	Description     string   `json:"description" validate:"max=500"`
-	StateType       string   `json:"state_type" validate:"omitempty,oneof=initial normal terminal"`
+	StateType       string   `json:"state_type" validate:"omitempty,oneof=initial normal subworkflow terminal"`
 	Color           string   `json:"color" validate:"omitempty,max=20"`
 	PositionX       int      `json:"position_x"`
 	PositionY       int      `json:"position_y"`
 	SLAHours        *int     `json:"sla_hours"`
 	SortOrder       int      `json:"sort_order"`
 	ViewableRoleIDs []string `json:"viewable_role_ids"`
+	SubWorkflowID   *string  `json:"sub_workflow_id" validate:"omitempty,uuid"`
+	SubRecordType   string   `json:"sub_record_type" validate:"omitempty,oneof=incident request complaint query"`
 }
 
 type WorkflowStateUpdateRequest struct {
 	Name            string   `json:"name" validate:"omitempty,min=2,max=100"`
 	Code            string   `json:"code" validate:"omitempty,min=2,max=50"`
 	Description     string   `json:"description" validate:"max=500"`
-	StateType       string   `json:"state_type" validate:"omitempty,oneof=initial normal terminal"`
+	StateType       string   `json:"state_type" validate:"omitempty,oneof=initial normal subworkflow terminal"`
 	Color           string   `json:"color" validate:"omitempty,max=20"`
 	PositionX       *int     `json:"position_x"`
 	PositionY       *int     `json:"position_y"`
@@ -237,6 +304,8 @@ type WorkflowStateUpdateRequest struct {
 	SortOrder       *int     `json:"sort_order"`
 	IsActive        *bool    `json:"is_active"`
 	ViewableRoleIDs []string `json:"viewable_role_ids"`
+	SubWorkflowID   *string  `json:"sub_workflow_id" validate:"omitempty,len=0|uuid"` // "" clears
+	SubRecordType   *string  `json:"sub_record_type" validate:"omitempty,oneof=incident request complaint query"`
 }
 
 type WorkflowTransitionCreateRequest struct {
@@ -257,6 +326,11 @@ type WorkflowTransitionCreateRequest struct {
 	AssignmentRoleID *string `json:"assignment_role_id" validate:"omitempty,uuid"`
 	AutoMatchUser    bool    `json:"auto_match_user"`
 	ManualSelectUser bool    `json:"manual_select_user"`
+
+	// Automatic execution
+	AutoTrigger    string `json:"auto_trigger" validate:"omitempty,oneof=time_in_state due_date_passed child_completed"`
+	AutoAfterHours int    `json:"auto_after_hours" validate:"min=0"`
+	ChildStateCode string `json:"child_state_code" validate:"max=50"`
 }
 
 type WorkflowTransitionUpdateRequest struct {
@@ -278,21 +352,29 @@ type WorkflowTransitionUpdateRequest struct {
 	AssignmentRoleID *string `json:"assignment_role_id" validate:"omitempty,uuid"`
 	AutoMatchUser    *bool   `json:"auto_match_user"`
 	ManualSelectUser *bool   `json:"manual_select_user"`
+
+	// Automatic execution
+	AutoTrigger    *string `json:"auto_trigger" validate:"omitempty,oneof=time_in_state due_date_passed child_completed"`
+	AutoAfterHours *int    `json:"auto_after_hours" validate:"omitempty,min=0"`
+	ChildStateCode *string `json:"child_state_code" validate:"omitempty,max=50"`
 }
 
 type TransitionRequirementRequest struct {
 	RequirementType string `json:"requirement_type" validate:"required,oneof=comment attachment feedback field_value"`
 	FieldName       string `json:"field_name"`
+	Operator        string `json:"operator"`
 	FieldValue      string `json:"field_value"`
+	MinCount        int    `json:"min_count" validate:"min=0"`
 	IsMandatory     *bool  `json:"is_mandatory"`
 	ErrorMessage    string `json:"error_message"`
 }
 
 type TransitionActionRequest struct {
-	ActionType     string `json:"action_type" validate:"required,oneof=email field_update webhook notification"`
+	ActionType     string `json:"action_type" validate:"required,oneof=email field_update webhook notification create_linked_record"`
 	Name           string `json:"name" validate:"required,min=2,max=100"`
 	Description    string `json:"description"`
 	Config         string `json:"config"`
+	Condition      string `json:"condition"`
 	ExecutionOrder int    `json:"execution_order"`
 	IsAsync        bool   `json:"is_async"`
 	IsActive       bool   `json:"is_active"`
@@ -364,6 +446,8 @@ type WorkflowStateResponse struct {
 	SLAHours      *int           `json:"sla_hours"`
 	SortOrder     int            `json:"sort_order"`
 	IsActive      bool           `json:"is_active"`
+	SubWorkflowID *uuid.UUID     `json:"sub_workflow_id,omitempty"`
+	SubRecordType string         `json:"sub_record_type,omitempty"`
 	ViewableRoles []RoleResponse `json:"viewable_roles,omitempty"`
 	CreatedAt     time.Time      `json:"created_at"`
 }
@@ -393,6 +477,17 @@ type WorkflowTransitionResponse struct {
 	AutoMatchUser    bool          `json:"auto_match_user"`
 	ManualSelectUser bool          `json:"manual_select_user"`
 
+	// Approvals
+	ApprovalMode   string         `json:"approval_mode,omitempty"`
+	ApprovalQuorum int            `json:"approval_quorum,omitempty"`
+	Approvers      []UserResponse `json:"approvers,omitempty"`
+	RejectStateID  *uuid.UUID     `json:"reject_state_id,omitempty"`
+
+	// Automatic execution
+	AutoTrigger    string `json:"auto_trigger,omitempty"`
+	AutoAfterHours int    `json:"auto_after_hours,omitempty"`
+	ChildStateCode string `json:"child_state_code,omitempty"`
+
 	Requirements []TransitionRequirementResponse `json:"requirements,omitempty"`
 	Actions      []TransitionActionResponse      `json:"actions,omitempty"`
 	IsActive     bool                            `json:"is_active"`
@@ -405,7 +500,9 @@ type TransitionRequirementResponse struct {
 	TransitionID    uuid.UUID `json:"transition_id"`
 	RequirementType string    `json:"requirement_type"`
 	FieldName       string    `json:"field_name,omitempty"`
+	Operator        string    `json:"operator,omitempty"`
 	FieldValue      string    `json:"field_value,omitempty"`
+	MinCount        int       `json:"min_count,omitempty"`
 	IsMandatory     *bool     `json:"is_mandatory"`
 	ErrorMessage    string    `json:"error_message,omitempty"`
 }
@@ -417,6 +514,7 @@ type TransitionActionResponse struct {
 	Name           string    `json:"name"`
 	Description    string    `json:"description,omitempty"`
 	Config         string    `json:"config,omitempty"`
+	Condition      string    `json:"condition,omitempty"`
 	ExecutionOrder int       `json:"execution_order"`
 	IsAsync        bool      `json:"is_async"`
 	IsActive       bool      `json:"is_active"`
@@ -502,6 +600,9 @@ func ToWorkflowStateResponse(s *WorkflowState) WorkflowStateResponse {
 		SortOrder:   s.SortOrder,
 		IsActive:    s.IsActive,
 		CreatedAt:   s.CreatedAt,
+
+		SubWorkflowID: s.SubWorkflowID,
+		SubRecordType: s.SubRecordType,
 	}
 
 	if len(s.ViewableRoles) > 0 {
@@ -529,6 +630,12 @@ func ToWorkflowTransitionResponse(t *WorkflowTransition) WorkflowTransitionRespo
 		AssignmentRoleID:     t.AssignmentRoleID,
 		AutoMatchUser:        t.AutoMatchUser,
 		ManualSelectUser:     t.ManualSelectUser,
+		ApprovalMode:         t.ApprovalMode,
+		ApprovalQuorum:       t.ApprovalQuorum,
+		RejectStateID:        t.RejectStateID,
+		AutoTrigger:          t.AutoTrigger,
+		AutoAfterHours:       t.AutoAfterHours,
+		ChildStateCode:       t.ChildStateCode,
 		IsActive:             t.IsActive,
 		SortOrder:            t.SortOrder,
 		CreatedAt:            t.CreatedAt,
@@ -551,6 +658,13 @@ func ToWorkflowTransitionResponse(t *WorkflowTransition) WorkflowTransitionRespo
 		}
 	}
 
+	if len(t.Approvers) > 0 {
+		resp.Approvers = make([]UserResponse, len(t.Approvers))
+		for i := range t.Approvers {
+			resp.Approvers[i] = ToUserResponse(&t.Approvers[i])
+		}
+	}
+
 	// Department Assignment
 	if t.AssignDepartment != nil {
 		deptResp := ToDepartmentResponse(t.AssignDepartment)
@@ -591,12 +705,18 @@ func ToTransitionRequirementResponse(r *TransitionRequirement) TransitionRequire
 		TransitionID:    r.TransitionID,
 		RequirementType: r.RequirementType,
 		FieldName:       r.FieldName,
+		Operator:        r.Operator,
 		FieldValue:      r.FieldValue,
+		MinCount:        r.MinCount,
 		IsMandatory:     r.IsMandatory,
 		ErrorMessage:    r.ErrorMessage,
 	}
 }
 
+// RedactedSecret stands in for webhook signing secrets in responses. Sending it back
+// unchanged keeps the stored secret.
+const RedactedSecret = "********"
+
 func ToTransitionActionResponse(a *TransitionAction) TransitionActionResponse {
 	return TransitionActionResponse{
 		ID:             a.ID,
@@ -604,13 +724,34 @@ func ToTransitionActionResponse(a *TransitionAction) TransitionActionResponse {
 		ActionType:     a.ActionType,
 		Name:           a.Name,
 		Description:    a.Description,
-		Config:         a.Config,
+		Config:         redactActionConfig(a),
+		Condition:      a.Condition,
 		ExecutionOrder: a.ExecutionOrder,
 		IsAsync:        a.IsAsync,
 		IsActive:       a.IsActive,
 	}
 }
 
+// redactActionConfig hides the signing secret of webhook actions
+func redactActionConfig(a *TransitionAction) string {
+	if a.ActionType != "webhook" || a.Config == "" {
+		return a.Config
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(a.Config), &config); err != nil {
+		return ""
+	}
+	if secret, _ := config["secret"].(string); secret == "" {
+		return a.Config
+	}
+	config["secret"] = RedactedSecret
+	data, err := json.Marshal(config)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
 // Export/Import structures
 
 // CodeNamePair represents a portable reference using code and name
@@ -623,9 +764,37 @@ type CodeNamePair struct {
 type WorkflowExportData struct {
 	ExportVersion string                 `json:"export_version"`
 	ExportedAt    string                 `json:"exported_at"`
+	Manifest      *WorkflowBundleManifest `json:"manifest,omitempty"` // 2.0 bundles and later
 	Workflow      WorkflowExportContent  `json:"workflow"`
 }
 
+// Kinds of records a workflow bundle depends on
+const (
+	BundleDependencyRole           = "role"           // by role code
+	BundleDependencyDepartment     = "department"     // by department code
+	BundleDependencyUser           = "user"           // by email
+	BundleDependencyClassification = "classification" // by name path, e.g. "IT / Network"
+	BundleDependencyLocation       = "location"       // by location code
+	BundleDependencyWorkflow       = "workflow"       // by workflow code
+)
+
+// WorkflowBundleManifest summarizes a bundle and lists the records it expects to find
+// in the target environment
+type WorkflowBundleManifest struct {
+	Workflow        CodeNamePair               `json:"workflow"`
+	StateCount      int                        `json:"state_count"`
+	TransitionCount int                        `json:"transition_count"`
+	ActionCount     int                        `json:"action_count"`
+	Dependencies    []WorkflowBundleDependency `json:"dependencies"`
+}
+
+// WorkflowBundleDependency is a record referenced by code rather than ID
+type WorkflowBundleDependency struct {
+	Kind string `json:"kind"`
+	Code string `json:"code"`
+	Name string `json:"name,omitempty"`
+}
+
 // WorkflowExportContent contains workflow data with codes instead of IDs
 type WorkflowExportContent struct {
 	Name                  string                      `json:"name"`
@@ -651,6 +820,8 @@ type WorkflowStateExport struct {
 	SLAHours      *int           `json:"sla_hours,omitempty"`
 	SortOrder     int            `json:"sort_order"`
 	ViewableRoles []CodeNamePair `json:"viewable_roles,omitempty"`
+	SubWorkflow   *CodeNamePair  `json:"sub_workflow,omitempty"`
+	SubRecordType string         `json:"sub_record_type,omitempty"`
 }
 
 // WorkflowTransitionExport represents a transition with codes and nested requirements/actions
@@ -667,6 +838,13 @@ type WorkflowTransitionExport struct {
 	AssignmentRole       *CodeNamePair                       `json:"assignment_role,omitempty"`
 	AutoMatchUser        bool                                `json:"auto_match_user"`
 	ManualSelectUser     bool                                `json:"manual_select_user"`
+	ApprovalMode         string                              `json:"approval_mode,omitempty"`
+	ApprovalQuorum       int                                 `json:"approval_quorum,omitempty"`
+	Approvers            []CodeNamePair                      `json:"approvers,omitempty"`
+	RejectStateCode      string                              `json:"reject_state_code,omitempty"`
+	AutoTrigger          string                              `json:"auto_trigger,omitempty"`
+	AutoAfterHours       int                                 `json:"auto_after_hours,omitempty"`
+	ChildStateCode       string                              `json:"child_state_code,omitempty"`
 	Requirements         []TransitionRequirementExport       `json:"requirements,omitempty"`
 	Actions              []TransitionActionExport            `json:"actions,omitempty"`
 	SortOrder            int                                 `json:"sort_order"`
@@ -676,7 +854,9 @@ type WorkflowTransitionExport struct {
 type TransitionRequirementExport struct {
 	RequirementType string `json:"requirement_type"`
 	FieldName       string `json:"field_name,omitempty"`
+	Operator        string `json:"operator,omitempty"`
 	FieldValue      string `json:"field_value,omitempty"`
+	MinCount        int    `json:"min_count,omitempty"`
 	IsMandatory     *bool  `json:"is_mandatory"`
 	ErrorMessage    string `json:"error_message,omitempty"`
 }
@@ -687,6 +867,7 @@ type TransitionActionExport struct {
 	Name           string `json:"name"`
 	Description    string `json:"description,omitempty"`
 	Config         string `json:"config,omitempty"`
+	Condition      string `json:"condition,omitempty"`
 	ExecutionOrder int    `json:"execution_order"`
 	IsAsync        bool   `json:"is_async"`
 	IsActive       bool   `json:"is_active"`
@@ -695,8 +876,47 @@ type TransitionActionExport struct {
 // WorkflowImportData is an alias for import (same structure as export)
 type WorkflowImportData = WorkflowExportData
 
+// Workflow import modes
+const (
+	WorkflowImportCreated = "created" // imported as a new workflow
+	WorkflowImportUpdated = "updated" // replaced the definition of the workflow with the same code
+)
+
+// WorkflowImportOptions controls how a bundle is applied
+type WorkflowImportOptions struct {
+	DryRun         bool // resolve and apply everything, then roll back
+	UpdateExisting bool // update the workflow with the same code in place instead of creating a copy
+}
+
 // WorkflowImportResponse contains the imported workflow and any warnings
 type WorkflowImportResponse struct {
-	Workflow WorkflowResponse `json:"workflow"`
-	Warnings []string         `json:"warnings"`
+	Workflow *WorkflowResponse          `json:"workflow,omitempty"` // omitted on dry runs
+	Mode     string                     `json:"mode"`
+	DryRun   bool                       `json:"dry_run"`
+	Missing  []WorkflowBundleDependency `json:"missing"` // dependencies not found in this environment
+	Warnings []string                   `json:"warnings"`
+}
+
+// Workflow validation issue severities
+const (
+	WorkflowIssueError   = "error"
+	WorkflowIssueWarning = "warning"
+)
+
+// WorkflowValidationIssue is a single problem found in a workflow graph
+type WorkflowValidationIssue struct {
+	Severity     string     `json:"severity"` // error, warning
+	Code         string     `json:"code"`     // e.g. unreachable_state, dead_end_state
+	Message      string     `json:"message"`
+	StateID      *uuid.UUID `json:"state_id,omitempty"`
+	TransitionID *uuid.UUID `json:"transition_id,omitempty"`
+}
+
+// WorkflowValidationResult is the outcome of validating a workflow graph.
+// Errors block activation, warnings are informational.
+type WorkflowValidationResult struct {
+	WorkflowID uuid.UUID                 `json:"workflow_id"`
+	Valid      bool                      `json:"valid"`
+	Errors     []WorkflowValidationIssue `json:"errors"`
+	Warnings   []WorkflowValidationIssue `json:"warnings"`
 }
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkflowAnalyticsFilter narrows workflow analytics to records and a period.
+// The date range applies to when the measured event happened, e.g. entering a state.
+type WorkflowAnalyticsFilter struct {
+	WorkflowID       uuid.UUID
+	StartDate        *time.Time
+	EndDate          *time.Time
+	ClassificationID *uuid.UUID
+	DepartmentID     *uuid.UUID
+}
+
+// StateDwellTime summarizes how long records stay in a state. Times are in hours and only
+// count completed stays; records still in the state are counted in InState.
+type StateDwellTime struct {
+	StateID     uuid.UUID `json:"state_id"`
+	StateName   string    `json:"state_name"`
+	StateCode   string    `json:"state_code"`
+	StateType   string    `json:"state_type"`
+	Visits      int64     `json:"visits"`
+	Completed   int64     `json:"completed"`
+	InState     int64     `json:"in_state"`
+	AvgHours    *float64  `json:"avg_hours"`
+	MedianHours *float64  `json:"median_hours"`
+	P90Hours    *float64  `json:"p90_hours"`
+}
+
+// TransitionFrequency counts how often records moved between two states
+type TransitionFrequency struct {
+	TransitionID   uuid.UUID `json:"transition_id"`
+	TransitionName string    `json:"transition_name"`
+	TransitionCode string    `json:"transition_code"`
+	FromStateID    uuid.UUID `json:"from_state_id"`
+	FromStateName  string    `json:"from_state_name"`
+	ToStateID      uuid.UUID `json:"to_state_id"`
+	ToStateName    string    `json:"to_state_name"`
+	Count          int64     `json:"count"`
+	Incidents      int64     `json:"incidents"`
+}
+
+// ReworkState counts the times records came back to a state they had already been in
+type ReworkState struct {
+	StateID   uuid.UUID `json:"state_id"`
+	StateName string    `json:"state_name"`
+	StateCode string    `json:"state_code"`
+	Reentries int64     `json:"reentries"`
+	Incidents int64     `json:"incidents"`
+}
+
+// ReworkTransition counts the times a transition sent records back to an earlier state
+type ReworkTransition struct {
+	TransitionID   uuid.UUID `json:"transition_id"`
+	TransitionName string    `json:"transition_name"`
+	TransitionCode string    `json:"transition_code"`
+	Reentries      int64     `json:"reentries"`
+	Incidents      int64     `json:"incidents"`
+}
+
+// WorkflowReworkResponse lists the rework loops of a workflow
+type WorkflowReworkResponse struct {
+	States      []ReworkState      `json:"states"`
+	Transitions []ReworkTransition `json:"transitions"`
+}
+
+// UserThroughput summarizes the transitions a user performed. AvgHandleHours is the
+// average time between a record entering a state and the user moving it on.
+type UserThroughput struct {
+	UserID         uuid.UUID `json:"user_id"`
+	Username       string    `json:"username"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Transitions    int64     `json:"transitions"`
+	Incidents      int64     `json:"incidents"`
+	Closed         int64     `json:"closed"`
+	AvgHandleHours *float64  `json:"avg_handle_hours"`
+}
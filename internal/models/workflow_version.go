@@ -0,0 +1,112 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkflowVersion is an immutable snapshot of a workflow taken when it is published.
+// Incidents are pinned to the version that was published when they were created.
+type WorkflowVersion struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key" json:"id"`
+	WorkflowID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_workflow_version" json:"workflow_id"`
+	Version    int       `gorm:"not null;uniqueIndex:idx_workflow_version" json:"version"`
+	Notes      string    `gorm:"size:500" json:"notes"`
+
+	// Snapshot is the workflow with its states, transitions, requirements and actions as JSON
+	Snapshot string `gorm:"type:text;not null" json:"-"`
+
+	PublishedByID *uuid.UUID `gorm:"type:uuid" json:"published_by_id"`
+	PublishedBy   *User      `gorm:"foreignKey:PublishedByID" json:"published_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func (v *WorkflowVersion) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// WorkflowPublishRequest is the body of a publish request
+type WorkflowPublishRequest struct {
+	Notes string `json:"notes" validate:"max=500"`
+}
+
+// WorkflowMigrateRequest moves open incidents from one workflow version to another.
+// StateMapping maps state IDs of the source version to state IDs of the target version;
+// states that exist in both versions (by ID or code) are mapped automatically.
+type WorkflowMigrateRequest struct {
+	FromVersion  int               `json:"from_version" validate:"min=0"` // 0 migrates incidents not pinned to any version
+	ToVersion    int               `json:"to_version" validate:"min=0"`   // 0 means the latest published version
+	StateMapping map[string]string `json:"state_mapping"`
+	IncidentIDs  []string          `json:"incident_ids"` // Optional, limits the migration to these incidents
+}
+
+// WorkflowMigrateResponse reports the outcome of an incident migration
+type WorkflowMigrateResponse struct {
+	FromVersion int   `json:"from_version"`
+	ToVersion   int   `json:"to_version"`
+	Migrated    int64 `json:"migrated"`
+}
+
+// WorkflowVersionResponse represents a published version without its snapshot
+type WorkflowVersionResponse struct {
+	ID          uuid.UUID     `json:"id"`
+	WorkflowID  uuid.UUID     `json:"workflow_id"`
+	Version     int           `json:"version"`
+	Notes       string        `json:"notes,omitempty"`
+	PublishedBy *UserResponse `json:"published_by,omitempty"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// WorkflowVersionDetailResponse includes the snapshot of the version
+type WorkflowVersionDetailResponse struct {
+	WorkflowVersionResponse
+	Workflow WorkflowResponse `json:"workflow"`
+}
+
+func ToWorkflowVersionResponse(v *WorkflowVersion) WorkflowVersionResponse {
+	resp := WorkflowVersionResponse{
+		ID:         v.ID,
+		WorkflowID: v.WorkflowID,
+		Version:    v.Version,
+		Notes:      v.Notes,
+		CreatedAt:  v.CreatedAt,
+	}
+	if v.PublishedBy != nil {
+		user := ToUserResponse(v.PublishedBy)
+		resp.PublishedBy = &user
+	}
+	return resp
+}
+
+// WorkflowFieldChange is a single changed attribute in a version diff
+type WorkflowFieldChange struct {
+	Field    string `json:"field"`
+	OldValue string `json:"old_value"`
+	NewValue string `json:"new_value"`
+}
+
+// WorkflowDiffEntry describes a state or transition that differs between versions
+type WorkflowDiffEntry struct {
+	ID      uuid.UUID             `json:"id"`
+	Code    string                `json:"code"`
+	Name    string                `json:"name"`
+	Changes []WorkflowFieldChange `json:"changes,omitempty"`
+}
+
+// WorkflowVersionDiff lists the differences between two workflow versions
+type WorkflowVersionDiff struct {
+	FromVersion        int                   `json:"from_version"`
+	ToVersion          int                   `json:"to_version"` // 0 is the current unpublished draft
+	WorkflowChanges    []WorkflowFieldChange `json:"workflow_changes"`
+	StatesAdded        []WorkflowDiffEntry   `json:"states_added"`
+	StatesRemoved      []WorkflowDiffEntry   `json:"states_removed"`
+	StatesChanged      []WorkflowDiffEntry   `json:"states_changed"`
+	TransitionsAdded   []WorkflowDiffEntry   `json:"transitions_added"`
+	TransitionsRemoved []WorkflowDiffEntry   `json:"transitions_removed"`
+	TransitionsChanged []WorkflowDiffEntry   `json:"transitions_changed"`
+}
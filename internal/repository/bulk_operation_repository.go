@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BulkOperationRepository interface {
+	Create(ctx context.Context, operation *models.BulkOperation) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.BulkOperation, error)
+	Update(ctx context.Context, operation *models.BulkOperation) error
+}
+
+type bulkOperationRepository struct {
+	db *gorm.DB
+}
+
+func NewBulkOperationRepository(db *gorm.DB) BulkOperationRepository {
+	return &bulkOperationRepository{db: db}
+}
+
+func (r *bulkOperationRepository) Create(ctx context.Context, operation *models.BulkOperation) error {
+	return r.db.WithContext(ctx).Create(operation).Error
+}
+
+func (r *bulkOperationRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.BulkOperation, error) {
+	var operation models.BulkOperation
+	err := r.db.WithContext(ctx).First(&operation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &operation, nil
+}
+
+func (r *bulkOperationRepository) Update(ctx context.Context, operation *models.BulkOperation) error {
+	return r.db.WithContext(ctx).Save(operation).Error
+}
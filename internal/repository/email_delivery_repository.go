@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type EmailDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.EmailDelivery) error
+	Update(ctx context.Context, delivery *models.EmailDelivery) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.EmailDelivery, error)
+	List(ctx context.Context, filter *models.EmailDeliveryFilter) ([]models.EmailDelivery, int64, error)
+}
+
+type emailDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailDeliveryRepository(db *gorm.DB) EmailDeliveryRepository {
+	return &emailDeliveryRepository{db: db}
+}
+
+func (r *emailDeliveryRepository) Create(ctx context.Context, delivery *models.EmailDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *emailDeliveryRepository) Update(ctx context.Context, delivery *models.EmailDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+func (r *emailDeliveryRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.EmailDelivery, error) {
+	var delivery models.EmailDelivery
+	err := r.db.WithContext(ctx).First(&delivery, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *emailDeliveryRepository) List(ctx context.Context, filter *models.EmailDeliveryFilter) ([]models.EmailDelivery, int64, error) {
+	var deliveries []models.EmailDelivery
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.EmailDelivery{})
+
+	// Apply filters
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.To != "" {
+		query = query.Where("to_addr = ?", filter.To)
+	}
+	if filter.IncidentID != nil {
+		query = query.Where("incident_id = ?", *filter.IncidentID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", *filter.EndDate)
+	}
+	if filter.Search != "" {
+		searchPattern := "%" + filter.Search + "%"
+		query = query.Where("subject ILIKE ? OR to_addr ILIKE ?", searchPattern, searchPattern)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
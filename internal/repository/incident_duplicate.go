@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const metersPerDegree = 111320.0
+
+// FindDuplicateCandidates returns recent open records of the same type that share a signal
+// with the query. Scoring is left to the caller; this only narrows the set.
+func (r *incidentRepository) FindDuplicateCandidates(ctx context.Context, q *models.DuplicateCandidateQuery) ([]models.Incident, error) {
+	var signals []string
+	var args []interface{}
+
+	if q.ClassificationID != nil {
+		signals = append(signals, "incidents.classification_id = ?")
+		args = append(args, *q.ClassificationID)
+	}
+	if q.Latitude != nil && q.Longitude != nil && q.RadiusMeters > 0 {
+		// Bounding box around the point; the exact distance is checked by the caller
+		dLat := q.RadiusMeters / metersPerDegree
+		dLng := q.RadiusMeters / (metersPerDegree * math.Max(math.Cos(*q.Latitude*math.Pi/180), 0.01))
+		signals = append(signals, "(incidents.latitude BETWEEN ? AND ? AND incidents.longitude BETWEEN ? AND ?)")
+		args = append(args, *q.Latitude-dLat, *q.Latitude+dLat, *q.Longitude-dLng, *q.Longitude+dLng)
+	}
+	if q.Mobile != "" {
+		signals = append(signals, `(regexp_replace(incidents.created_by_mobile, '\D', '', 'g') = ? OR incidents.reporter_id IN
+			(SELECT id FROM users WHERE regexp_replace(phone, '\D', '', 'g') = ?))`)
+		args = append(args, q.Mobile, q.Mobile)
+	}
+	for _, term := range q.TitleTerms {
+		signals = append(signals, "incidents.title ILIKE ?")
+		args = append(args, "%"+escapeLikePattern(term)+"%")
+	}
+	if len(signals) == 0 {
+		return nil, nil
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var incidents []models.Incident
+	err := r.db.WithContext(ctx).
+		Preload("CurrentState").
+		Preload("Reporter").
+		Where("incidents.id <> ? AND incidents.record_type = ? AND incidents.created_at >= ?", q.ExcludeID, q.RecordType, q.Since).
+		Where("incidents.closed_at IS NULL AND incidents.duplicate_of_id IS NULL").
+		Where("("+strings.Join(signals, " OR ")+")", args...).
+		Order("incidents.created_at DESC").
+		Limit(limit).
+		Find(&incidents).Error
+	return incidents, err
+}
+
+// MergeDuplicate moves the duplicate's comments and attachments to the master and links the
+// duplicate, and any records already merged into it, to the master
+func (r *incidentRepository) MergeDuplicate(ctx context.Context, duplicateID, masterID uuid.UUID) (*models.MergeDuplicateResult, error) {
+	result := &models.MergeDuplicateResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if result.Comments, err = moveIncidentRows(tx, &models.IncidentComment{}, duplicateID, masterID); err != nil {
+			return err
+		}
+		if result.Attachments, err = moveIncidentRows(tx, &models.IncidentAttachment{}, duplicateID, masterID); err != nil {
+			return err
+		}
+
+		moved := tx.Model(&models.Incident{}).
+			Where("duplicate_of_id = ?", duplicateID).
+			Update("duplicate_of_id", masterID)
+		if moved.Error != nil {
+			return moved.Error
+		}
+		result.Duplicates = moved.RowsAffected
+
+		return tx.Model(&models.Incident{}).
+			Where("id = ?", duplicateID).
+			Update("duplicate_of_id", masterID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
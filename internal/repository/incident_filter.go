@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+)
+
+const lookupFilterSubquery = `SELECT ilv.incident_id FROM incident_lookup_values ilv
+	JOIN lookup_values lv ON lv.id = ilv.lookup_value_id
+	JOIN lookup_categories lc ON lc.id = lv.category_id
+	WHERE lc.code = ?`
+
+// incidentFilterSQL compiles a filter expression into a WHERE fragment on the incidents table
+func incidentFilterSQL(node *models.IncidentFilterNode, now time.Time) (string, []interface{}, error) {
+	if node.IsGroup() {
+		parts := make([]string, 0, len(node.Conditions))
+		var args []interface{}
+		for i := range node.Conditions {
+			part, partArgs, err := incidentFilterSQL(&node.Conditions[i], now)
+			if err != nil {
+				return "", nil, err
+			}
+			parts = append(parts, part)
+			args = append(args, partArgs...)
+		}
+		joiner := " AND "
+		if node.Op == "or" {
+			joiner = " OR "
+		}
+		return "(" + strings.Join(parts, joiner) + ")", args, nil
+	}
+
+	kind, key, err := node.FieldKind()
+	if err != nil {
+		return "", nil, err
+	}
+	operands, err := node.Operands(now)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch {
+	case kind == models.FilterKindLookup:
+		return lookupFilterSQL(key, node.Operator, operands)
+	case kind == models.FilterKindCustom:
+		return customFieldFilterSQL(key, node.Operator, operands)
+	case node.Field == "assignee_id":
+		return assigneeFilterSQL(node.Operator, operands)
+	}
+	return comparisonSQL("incidents."+node.Field, kind, node.Operator, operands)
+}
+
+// comparisonSQL compares a column expression without placeholders with the operands.
+// Negative comparisons include NULLs, so "department not in X" also returns records
+// without a department.
+func comparisonSQL(column, kind, operator string, operands []interface{}) (string, []interface{}, error) {
+	textual := kind == models.FilterKindString || kind == models.FilterKindCustom
+
+	switch operator {
+	case models.FilterOpEq:
+		return column + " = ?", operands, nil
+	case models.FilterOpNeq:
+		return "(" + column + " <> ? OR " + column + " IS NULL)", operands, nil
+	case models.FilterOpIn:
+		return column + " IN ?", []interface{}{operands}, nil
+	case models.FilterOpNotIn:
+		return "(" + column + " NOT IN ? OR " + column + " IS NULL)", []interface{}{operands}, nil
+	case models.FilterOpGt:
+		return column + " > ?", operands, nil
+	case models.FilterOpGte:
+		return column + " >= ?", operands, nil
+	case models.FilterOpLt:
+		return column + " < ?", operands, nil
+	case models.FilterOpLte:
+		return column + " <= ?", operands, nil
+	case models.FilterOpBetween:
+		return column + " BETWEEN ? AND ?", operands, nil
+	case models.FilterOpContains:
+		pattern := "%" + escapeLikePattern(fmt.Sprint(operands[0])) + "%"
+		return column + " ILIKE ?", []interface{}{pattern}, nil
+	case models.FilterOpIsEmpty:
+		if textual {
+			return "(" + column + " IS NULL OR " + column + " = '')", nil, nil
+		}
+		return column + " IS NULL", nil, nil
+	case models.FilterOpIsNotEmpty:
+		if textual {
+			return "(" + column + " IS NOT NULL AND " + column + " <> '')", nil, nil
+		}
+		return column + " IS NOT NULL", nil, nil
+	}
+	return "", nil, fmt.Errorf("unsupported filter operator %q", operator)
+}
+
+// lookupFilterSQL matches records by the values they hold in a lookup category, given
+// as value codes or IDs
+func lookupFilterSQL(categoryCode, operator string, operands []interface{}) (string, []interface{}, error) {
+	switch operator {
+	case models.FilterOpEq, models.FilterOpIn:
+		return "incidents.id IN (" + lookupFilterSubquery + " AND (lv.code IN ? OR lv.id::text IN ?))",
+			[]interface{}{categoryCode, operands, operands}, nil
+	case models.FilterOpNeq, models.FilterOpNotIn:
+		return "incidents.id NOT IN (" + lookupFilterSubquery + " AND (lv.code IN ? OR lv.id::text IN ?))",
+			[]interface{}{categoryCode, operands, operands}, nil
+	case models.FilterOpIsEmpty:
+		return "incidents.id NOT IN (" + lookupFilterSubquery + ")", []interface{}{categoryCode}, nil
+	case models.FilterOpIsNotEmpty:
+		return "incidents.id IN (" + lookupFilterSubquery + ")", []interface{}{categoryCode}, nil
+	}
+	return "", nil, fmt.Errorf("unsupported filter operator %q", operator)
+}
+
+// customFieldFilterSQL compares a key of the custom fields JSON. Ranges over numbers compare
+// numerically; everything else compares the text value. The key is validated by the model,
+// so it is safe to inline.
+func customFieldFilterSQL(key, operator string, operands []interface{}) (string, []interface{}, error) {
+	numeric := len(operands) > 0
+	for _, o := range operands {
+		if _, ok := o.(float64); !ok {
+			numeric = false
+		}
+	}
+	switch operator {
+	case models.FilterOpGt, models.FilterOpGte, models.FilterOpLt, models.FilterOpLte, models.FilterOpBetween:
+		if numeric {
+			return comparisonSQL("incident_custom_number(incidents.custom_fields, '"+key+"')", models.FilterKindNumber, operator, operands)
+		}
+	}
+
+	texts := make([]interface{}, len(operands))
+	for i, o := range operands {
+		if f, ok := o.(float64); ok {
+			texts[i] = strconv.FormatFloat(f, 'f', -1, 64)
+		} else {
+			texts[i] = o
+		}
+	}
+	return comparisonSQL("incident_custom_field(incidents.custom_fields, '"+key+"')", models.FilterKindCustom, operator, texts)
+}
+
+// assigneeFilterSQL matches the primary assignee as well as the additional assignees
+func assigneeFilterSQL(operator string, operands []interface{}) (string, []interface{}, error) {
+	const assigned = "(COALESCE(incidents.assignee_id IN ?, false) OR incidents.id IN (SELECT incident_id FROM incident_assignees WHERE user_id IN ?))"
+	const unassigned = "(incidents.assignee_id IS NULL AND NOT EXISTS (SELECT 1 FROM incident_assignees WHERE incident_id = incidents.id))"
+
+	switch operator {
+	case models.FilterOpEq, models.FilterOpIn:
+		return assigned, []interface{}{operands, operands}, nil
+	case models.FilterOpNeq, models.FilterOpNotIn:
+		return "NOT " + assigned, []interface{}{operands, operands}, nil
+	case models.FilterOpIsEmpty:
+		return unassigned, nil, nil
+	case models.FilterOpIsNotEmpty:
+		return "NOT " + unassigned, nil, nil
+	}
+	return "", nil, fmt.Errorf("unsupported filter operator %q", operator)
+}
+
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
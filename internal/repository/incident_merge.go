@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// moveIncidentRows re-points a child table's rows from one record to another
+func moveIncidentRows(tx *gorm.DB, model interface{}, fromID, toID uuid.UUID) (int64, error) {
+	result := tx.Model(model).Where("incident_id = ?", fromID).Update("incident_id", toID)
+	return result.RowsAffected, result.Error
+}
+
+// MergeIncident moves the source's comments, attachments, feedback and assignees to the target
+// and applies the closing updates to the source, recording the state change in its history.
+// Records already merged into the source, or linked to it as duplicates, now point at the target.
+func (r *incidentRepository) MergeIncident(ctx context.Context, sourceID, targetID uuid.UUID, sourceUpdates map[string]interface{}, history *models.IncidentTransitionHistory) (*models.MergeIncidentResult, error) {
+	result := &models.MergeIncidentResult{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if result.Comments, err = moveIncidentRows(tx, &models.IncidentComment{}, sourceID, targetID); err != nil {
+			return err
+		}
+		if result.Attachments, err = moveIncidentRows(tx, &models.IncidentAttachment{}, sourceID, targetID); err != nil {
+			return err
+		}
+		if result.Feedback, err = moveIncidentRows(tx, &models.IncidentFeedback{}, sourceID, targetID); err != nil {
+			return err
+		}
+
+		// The source's primary and additional assignees join the target's assignees
+		added := tx.Exec(`INSERT INTO incident_assignees (incident_id, user_id)
+			SELECT ?, user_id FROM (
+				SELECT user_id FROM incident_assignees WHERE incident_id = ?
+				UNION SELECT assignee_id FROM incidents WHERE id = ? AND assignee_id IS NOT NULL
+			) source_assignees
+			ON CONFLICT DO NOTHING`, targetID, sourceID, sourceID)
+		if added.Error != nil {
+			return added.Error
+		}
+		result.Assignees = added.RowsAffected
+		if err := tx.Exec("DELETE FROM incident_assignees WHERE incident_id = ?", sourceID).Error; err != nil {
+			return err
+		}
+		err = tx.Exec(`UPDATE incidents SET assignee_id = (SELECT assignee_id FROM incidents WHERE id = ?)
+			WHERE id = ? AND assignee_id IS NULL`, sourceID, targetID).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Incident{}).Where("merged_into_id = ?", sourceID).Update("merged_into_id", targetID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Incident{}).Where("duplicate_of_id = ?", sourceID).Update("duplicate_of_id", targetID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Incident{}).Where("id = ?", sourceID).Updates(sourceUpdates).Error; err != nil {
+			return err
+		}
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// MoveRecordItems moves the given comments and attachments of one record to another.
+// Items that belong to a different record are left alone.
+func (r *incidentRepository) MoveRecordItems(ctx context.Context, fromID, toID uuid.UUID, commentIDs, attachmentIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(commentIDs) > 0 {
+			err := tx.Model(&models.IncidentComment{}).
+				Where("incident_id = ? AND id IN ?", fromID, commentIDs).
+				Update("incident_id", toID).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(attachmentIDs) > 0 {
+			err := tx.Model(&models.IncidentAttachment{}).
+				Where("incident_id = ? AND id IN ?", fromID, attachmentIDs).
+				Update("incident_id", toID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
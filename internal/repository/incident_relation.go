@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+func (r *incidentRepository) CreateRelation(ctx context.Context, relation *models.IncidentRelation) error {
+	return r.db.WithContext(ctx).Create(relation).Error
+}
+
+func (r *incidentRepository) FindRelationByID(ctx context.Context, id uuid.UUID) (*models.IncidentRelation, error) {
+	var relation models.IncidentRelation
+	err := r.db.WithContext(ctx).
+		Preload("Source.CurrentState").
+		Preload("Target.CurrentState").
+		Preload("CreatedBy").
+		First(&relation, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// ListRelations returns the relations a record takes part in on either side, skipping those
+// whose other record was deleted
+func (r *incidentRepository) ListRelations(ctx context.Context, incidentID uuid.UUID) ([]models.IncidentRelation, error) {
+	var relations []models.IncidentRelation
+	err := r.db.WithContext(ctx).
+		Preload("Source.CurrentState").
+		Preload("Target.CurrentState").
+		Preload("CreatedBy").
+		Where("source_id = ? OR target_id = ?", incidentID, incidentID).
+		Where(`NOT EXISTS (SELECT 1 FROM incidents i WHERE i.deleted_at IS NOT NULL
+			AND i.id IN (incident_relations.source_id, incident_relations.target_id))`).
+		Order("relation_type ASC, created_at ASC").
+		Find(&relations).Error
+	return relations, err
+}
+
+// RelationExists reports whether two records are already related with the type, in either direction
+func (r *incidentRepository) RelationExists(ctx context.Context, a, b uuid.UUID, relationType string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.IncidentRelation{}).
+		Where("relation_type = ? AND ((source_id = ? AND target_id = ?) OR (source_id = ? AND target_id = ?))",
+			relationType, a, b, b, a).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RelationReachable reports whether to can be reached from from by following relations of
+// the type, used to keep blocks and parent_of chains free of cycles
+func (r *incidentRepository) RelationReachable(ctx context.Context, fromID, toID uuid.UUID, relationType string) (bool, error) {
+	var reachable bool
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE chain(id) AS (
+			SELECT target_id FROM incident_relations WHERE source_id = ? AND relation_type = ?
+			UNION
+			SELECT rel.target_id FROM incident_relations rel
+			JOIN chain ON rel.source_id = chain.id
+			WHERE rel.relation_type = ?
+		)
+		SELECT EXISTS (SELECT 1 FROM chain WHERE id = ?)`,
+		fromID, relationType, relationType, toID).
+		Scan(&reachable).Error
+	return reachable, err
+}
+
+func (r *incidentRepository) UpdateRelation(ctx context.Context, relation *models.IncidentRelation) error {
+	return r.db.WithContext(ctx).
+		Model(&models.IncidentRelation{}).
+		Where("id = ?", relation.ID).
+		Updates(map[string]interface{}{
+			"enforced": relation.Enforced,
+			"note":     relation.Note,
+		}).Error
+}
+
+func (r *incidentRepository) DeleteRelation(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.IncidentRelation{}, "id = ?", id).Error
+}
+
+// ListOpenBlockers returns the open records holding an enforced blocks relation on the record
+func (r *incidentRepository) ListOpenBlockers(ctx context.Context, incidentID uuid.UUID) ([]models.Incident, error) {
+	var blockers []models.Incident
+	err := r.db.WithContext(ctx).
+		Where("incidents.closed_at IS NULL").
+		Where("incidents.id IN (SELECT source_id FROM incident_relations WHERE target_id = ? AND relation_type = ? AND enforced = ?)",
+			incidentID, models.RelationBlocks, true).
+		Order("incidents.incident_number ASC").
+		Find(&blockers).Error
+	return blockers, err
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/automax/backend/internal/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type IncidentRepository interface {
@@ -17,6 +19,7 @@ type IncidentRepository interface {
 	FindByIDWithRelations(ctx context.Context, id uuid.UUID) (*models.Incident, error)
 	FindByIncidentNumber(ctx context.Context, number string) (*models.Incident, error)
 	List(ctx context.Context, filter *models.IncidentFilter) ([]models.Incident, int64, error)
+	ListIDs(ctx context.Context, filter *models.IncidentFilter, max int) ([]uuid.UUID, error)
 	Update(ctx context.Context, incident *models.Incident) error
 	UpdateFields(ctx context.Context, id uuid.UUID, updates map[string]interface{}) error
 	Delete(ctx context.Context, id uuid.UUID) error
@@ -26,11 +29,14 @@ type IncidentRepository interface {
 	GenerateRequestNumber(ctx context.Context) (string, error)
 	GenerateComplaintNumber(ctx context.Context) (string, error)
 	GenerateQueryNumber(ctx context.Context) (string, error)
+	GenerateRecordNumber(ctx context.Context, recordType string) (string, error)
 
 	// State transitions
 	UpdateState(ctx context.Context, incidentID, newStateID uuid.UUID) error
 	CreateTransitionHistory(ctx context.Context, history *models.IncidentTransitionHistory) error
 	GetTransitionHistory(ctx context.Context, incidentID uuid.UUID) ([]models.IncidentTransitionHistory, error)
+	SetTransitionActionResults(ctx context.Context, historyID uuid.UUID, results []models.TransitionActionResult) error
+	UpsertTransitionActionResult(ctx context.Context, historyID uuid.UUID, result models.TransitionActionResult) error
 
 	// Comments
 	CreateComment(ctx context.Context, comment *models.IncidentComment) error
@@ -52,11 +58,44 @@ type IncidentRepository interface {
 	ClearAssignees(ctx context.Context, incidentID uuid.UUID) error
 	SetLookupValues(ctx context.Context, incidentID uuid.UUID, lookupValues []models.LookupValue) error
 
+	// Linked records
+	CountOpenBlockingChildren(ctx context.Context, parentID uuid.UUID) (int64, error)
+	CountOpenSubWorkflowChildren(ctx context.Context, parentID, stateID uuid.UUID) (int64, error)
+
+	// Duplicates
+	FindDuplicateCandidates(ctx context.Context, query *models.DuplicateCandidateQuery) ([]models.Incident, error)
+	MergeDuplicate(ctx context.Context, duplicateID, masterID uuid.UUID) (*models.MergeDuplicateResult, error)
+
+	// Merge and split
+	MergeIncident(ctx context.Context, sourceID, targetID uuid.UUID, sourceUpdates map[string]interface{}, history *models.IncidentTransitionHistory) (*models.MergeIncidentResult, error)
+	MoveRecordItems(ctx context.Context, fromID, toID uuid.UUID, commentIDs, attachmentIDs []uuid.UUID) error
+
+	// Relations
+	CreateRelation(ctx context.Context, relation *models.IncidentRelation) error
+	FindRelationByID(ctx context.Context, id uuid.UUID) (*models.IncidentRelation, error)
+	ListRelations(ctx context.Context, incidentID uuid.UUID) ([]models.IncidentRelation, error)
+	RelationExists(ctx context.Context, a, b uuid.UUID, relationType string) (bool, error)
+	RelationReachable(ctx context.Context, fromID, toID uuid.UUID, relationType string) (bool, error)
+	UpdateRelation(ctx context.Context, relation *models.IncidentRelation) error
+	DeleteRelation(ctx context.Context, id uuid.UUID) error
+	ListOpenBlockers(ctx context.Context, incidentID uuid.UUID) ([]models.Incident, error)
+
+	// Approvals
+	CastApproval(ctx context.Context, approval *models.TransitionApproval) (before, after []models.TransitionApproval, err error)
+	SetApprovalHistory(ctx context.Context, approvalID, historyID uuid.UUID) error
+	DeleteApproval(ctx context.Context, id uuid.UUID) error
+	ListOpenApprovals(ctx context.Context, incidentID uuid.UUID, transitionID *uuid.UUID) ([]models.TransitionApproval, error)
+	ResolveOpenApprovals(ctx context.Context, incidentID uuid.UUID) error
+	ListPendingApprovals(ctx context.Context, userID uuid.UUID, recordType string) ([]models.PendingApprovalKey, error)
+
+	// Automatic transitions
+	ListDueAutoTransitions(ctx context.Context, now time.Time) ([]models.DueAutoTransition, error)
+
 	// Stats
 	GetStats(ctx context.Context, filter *models.IncidentFilter) (*models.IncidentStatsResponse, error)
 	GetSLABreachedIncidents(ctx context.Context) ([]models.Incident, error)
 	UpdateSLABreached(ctx context.Context, incidentID uuid.UUID, breached bool) error
-	MarkSLABreached(ctx context.Context) (int64, error)
+	MarkSLABreached(ctx context.Context) ([]models.Incident, error)
 
 	// User-specific queries
 	GetAssignedToUser(ctx context.Context, userID uuid.UUID, recordType string, page, limit int) ([]models.Incident, int64, error)
@@ -161,6 +200,43 @@ func (r *incidentRepository) List(ctx context.Context, filter *models.IncidentFi
 	query := r.db.WithContext(ctx).Model(&models.Incident{})
 
 	// Apply filters
+	query = applyIncidentFilter(query, filter)
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	offset := (filter.Page - 1) * filter.Limit
+
+	err := query.
+		Preload("Classification").
+		Preload("Workflow").
+		Preload("CurrentState").
+		Preload("Assignee").
+		Preload("Department").
+		Preload("Location").
+		Preload("LookupValues.Category").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&incidents).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return incidents, total, nil
+}
+
+// applyIncidentFilter adds the WHERE clauses of an incident filter to a query
+func applyIncidentFilter(query *gorm.DB, filter *models.IncidentFilter) *gorm.DB {
 	if filter.WorkflowID != nil {
 		query = query.Where("workflow_id = ?", *filter.WorkflowID)
 	}
@@ -202,38 +278,23 @@ func (r *incidentRepository) List(ctx context.Context, filter *models.IncidentFi
 		searchPattern := "%" + filter.Search + "%"
 		query = query.Where("incident_number ILIKE ? OR title ILIKE ? OR description ILIKE ?", searchPattern, searchPattern, searchPattern)
 	}
-
-	// Count total
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
-	}
-
-	// Apply pagination
-	if filter.Page < 1 {
-		filter.Page = 1
-	}
-	if filter.Limit < 1 || filter.Limit > 100 {
-		filter.Limit = 20
-	}
-	offset := (filter.Page - 1) * filter.Limit
-
-	err := query.
-		Preload("Classification").
-		Preload("Workflow").
-		Preload("CurrentState").
-		Preload("Assignee").
-		Preload("Department").
-		Preload("Location").
-		Preload("LookupValues.Category").
-		Order("created_at DESC").
-		Offset(offset).
-		Limit(filter.Limit).
-		Find(&incidents).Error
-	if err != nil {
-		return nil, 0, err
+	if filter.Expression != nil {
+		sql, args, err := incidentFilterSQL(filter.Expression, time.Now())
+		if err != nil {
+			query.AddError(err)
+		} else {
+			query = query.Where(sql, args...)
+		}
 	}
+	return query
+}
 
-	return incidents, total, nil
+// ListIDs returns the IDs of every incident matching a filter, newest first, up to max
+func (r *incidentRepository) ListIDs(ctx context.Context, filter *models.IncidentFilter, max int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	query := applyIncidentFilter(r.db.WithContext(ctx).Model(&models.Incident{}), filter)
+	err := query.Order("created_at DESC").Limit(max).Pluck("id", &ids).Error
+	return ids, err
 }
 
 func (r *incidentRepository) Update(ctx context.Context, incident *models.Incident) error {
@@ -331,6 +392,20 @@ func (r *incidentRepository) GenerateQueryNumber(ctx context.Context) (string, e
 	return fmt.Sprintf("QRY-%d-%06d", year, nextSeq), nil
 }
 
+// GenerateRecordNumber generates the next number for the given record type
+func (r *incidentRepository) GenerateRecordNumber(ctx context.Context, recordType string) (string, error) {
+	switch recordType {
+	case "request":
+		return r.GenerateRequestNumber(ctx)
+	case "complaint":
+		return r.GenerateComplaintNumber(ctx)
+	case "query":
+		return r.GenerateQueryNumber(ctx)
+	default:
+		return r.GenerateIncidentNumber(ctx)
+	}
+}
+
 // State transitions
 
 func (r *incidentRepository) UpdateState(ctx context.Context, incidentID, newStateID uuid.UUID) error {
@@ -357,6 +432,58 @@ func (r *incidentRepository) GetTransitionHistory(ctx context.Context, incidentI
 	return history, err
 }
 
+// SetTransitionActionResults overwrites the action results stored on a transition history record
+func (r *incidentRepository) SetTransitionActionResults(ctx context.Context, historyID uuid.UUID, results []models.TransitionActionResult) error {
+	data, err := json.Marshal(results)
+	if err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).
+		Model(&models.IncidentTransitionHistory{}).
+		Where("id = ?", historyID).
+		Update("action_results", string(data)).Error
+}
+
+// UpsertTransitionActionResult replaces the result entry for a single action, locking the
+// history row so concurrent async workers don't overwrite each other's results
+func (r *incidentRepository) UpsertTransitionActionResult(ctx context.Context, historyID uuid.UUID, result models.TransitionActionResult) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var history models.IncidentTransitionHistory
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "action_results").
+			First(&history, "id = ?", historyID).Error; err != nil {
+			return err
+		}
+
+		var results []models.TransitionActionResult
+		if history.ActionResults != "" {
+			if err := json.Unmarshal([]byte(history.ActionResults), &results); err != nil {
+				return fmt.Errorf("failed to parse action results: %w", err)
+			}
+		}
+
+		replaced := false
+		for i := range results {
+			if results[i].ActionID == result.ActionID {
+				results[i] = result
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			results = append(results, result)
+		}
+
+		data, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.IncidentTransitionHistory{}).
+			Where("id = ?", historyID).
+			Update("action_results", string(data)).Error
+	})
+}
+
 // Comments
 
 func (r *incidentRepository) CreateComment(ctx context.Context, comment *models.IncidentComment) error {
@@ -436,7 +563,10 @@ func (r *incidentRepository) AssignIncident(ctx context.Context, incidentID, ass
 	return r.db.WithContext(ctx).
 		Model(&models.Incident{}).
 		Where("id = ?", incidentID).
-		Update("assignee_id", assigneeID).Error
+		Updates(map[string]interface{}{
+			"previous_assignee_id": gorm.Expr("CASE WHEN assignee_id IS DISTINCT FROM ? THEN assignee_id ELSE previous_assignee_id END", assigneeID),
+			"assignee_id":          assigneeID,
+		}).Error
 }
 
 func (r *incidentRepository) SetAssignees(ctx context.Context, incidentID uuid.UUID, userIDs []uuid.UUID) error {
@@ -486,6 +616,194 @@ func (r *incidentRepository) SetLookupValues(ctx context.Context, incidentID uui
 	return r.db.WithContext(ctx).Model(&incident).Association("LookupValues").Replace(actualLookupValues)
 }
 
+// Linked records
+
+// CountOpenBlockingChildren counts linked records of a parent that block its closure and are still open
+func (r *incidentRepository) CountOpenBlockingChildren(ctx context.Context, parentID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Incident{}).
+		Where("source_incident_id = ? AND blocks_parent_close = ? AND closed_at IS NULL", parentID, true).
+		Count(&count).Error
+	return count, err
+}
+
+// stateEnteredSQL is the time an incident aliased i last entered its current state: the latest
+// history entry into the state, or its creation
+const stateEnteredSQL = `COALESCE(
+		(SELECT MAX(h.transitioned_at) FROM incident_transition_histories h
+		 WHERE h.incident_id = i.id AND h.to_state_id = i.current_state_id AND h.from_state_id <> h.to_state_id),
+		i.created_at
+	)`
+
+// CountOpenSubWorkflowChildren counts the open child records a parent's sub-workflow state
+// launched since the parent last entered the state
+func (r *incidentRepository) CountOpenSubWorkflowChildren(ctx context.Context, parentID, stateID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Table("incidents AS c").
+		Joins("JOIN incidents i ON i.id = c.source_incident_id").
+		Where("c.source_incident_id = ? AND c.sub_workflow_state_id = ? AND c.closed_at IS NULL AND c.deleted_at IS NULL", parentID, stateID).
+		Where("c.created_at >= " + stateEnteredSQL).
+		Count(&count).Error
+	return count, err
+}
+
+// Approvals
+
+// CastApproval records a vote, replacing the user's earlier open vote on the same transition,
+// and returns the open votes on that transition just before and just after it. The incident
+// row stays locked for the whole transaction, so concurrent votes are counted one after
+// another and exactly one of them completes a round.
+func (r *incidentRepository) CastApproval(ctx context.Context, approval *models.TransitionApproval) (before, after []models.TransitionApproval, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var incident models.Incident
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&incident, "id = ?", approval.IncidentID).Error; err != nil {
+			return err
+		}
+
+		openVotes := func(dest *[]models.TransitionApproval) error {
+			return tx.Where("incident_id = ? AND transition_id = ? AND resolved_at IS NULL", approval.IncidentID, approval.TransitionID).
+				Order("created_at ASC").
+				Find(dest).Error
+		}
+		if err := openVotes(&before); err != nil {
+			return err
+		}
+
+		err := tx.Where("incident_id = ? AND transition_id = ? AND user_id = ? AND resolved_at IS NULL",
+			approval.IncidentID, approval.TransitionID, approval.UserID).
+			Delete(&models.TransitionApproval{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(approval).Error; err != nil {
+			return err
+		}
+		return openVotes(&after)
+	})
+	return before, after, err
+}
+
+// SetApprovalHistory links a vote to the transition history entry it produced
+func (r *incidentRepository) SetApprovalHistory(ctx context.Context, approvalID, historyID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.TransitionApproval{}).
+		Where("id = ?", approvalID).
+		Update("history_id", historyID).Error
+}
+
+func (r *incidentRepository) DeleteApproval(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.TransitionApproval{}, "id = ?", id).Error
+}
+
+// ListOpenApprovals returns the votes of the current approval round, optionally for a single transition
+func (r *incidentRepository) ListOpenApprovals(ctx context.Context, incidentID uuid.UUID, transitionID *uuid.UUID) ([]models.TransitionApproval, error) {
+	var approvals []models.TransitionApproval
+	query := r.db.WithContext(ctx).
+		Preload("User").
+		Where("incident_id = ? AND resolved_at IS NULL", incidentID)
+	if transitionID != nil {
+		query = query.Where("transition_id = ?", *transitionID)
+	}
+	err := query.Order("created_at ASC").Find(&approvals).Error
+	return approvals, err
+}
+
+// ResolveOpenApprovals closes every open vote on an incident, ending its approval rounds
+func (r *incidentRepository) ResolveOpenApprovals(ctx context.Context, incidentID uuid.UUID) error {
+	return r.db.WithContext(ctx).
+		Model(&models.TransitionApproval{}).
+		Where("incident_id = ? AND resolved_at IS NULL", incidentID).
+		Update("resolved_at", time.Now()).Error
+}
+
+// ListPendingApprovals finds open records sitting in a state with an approval transition the
+// user may vote on and hasn't voted on yet. Listed approvers take precedence over allowed roles.
+func (r *incidentRepository) ListPendingApprovals(ctx context.Context, userID uuid.UUID, recordType string) ([]models.PendingApprovalKey, error) {
+	var keys []models.PendingApprovalKey
+	query := r.db.WithContext(ctx).
+		Table("incidents AS i").
+		Select("i.id AS incident_id, t.id AS transition_id").
+		Joins("JOIN workflow_transitions t ON t.from_state_id = i.current_state_id AND t.is_active = ? AND t.approval_mode <> ''", true).
+		Where("i.closed_at IS NULL AND i.deleted_at IS NULL").
+		Where(`(
+			EXISTS (SELECT 1 FROM transition_approvers ta WHERE ta.workflow_transition_id = t.id AND ta.user_id = ?)
+			OR (
+				NOT EXISTS (SELECT 1 FROM transition_approvers ta WHERE ta.workflow_transition_id = t.id)
+				AND (
+					NOT EXISTS (SELECT 1 FROM transition_allowed_roles tr WHERE tr.workflow_transition_id = t.id)
+					OR EXISTS (
+						SELECT 1 FROM transition_allowed_roles tr
+						JOIN user_roles ur ON ur.role_id = tr.role_id
+						WHERE tr.workflow_transition_id = t.id AND ur.user_id = ?
+					)
+				)
+			)
+		)`, userID, userID).
+		Where(`NOT EXISTS (
+			SELECT 1 FROM transition_approvals v
+			WHERE v.incident_id = i.id AND v.transition_id = t.id AND v.user_id = ? AND v.resolved_at IS NULL
+		)`, userID)
+	if recordType != "" {
+		query = query.Where("i.record_type = ?", recordType)
+	}
+	err := query.Order("i.created_at DESC").Scan(&keys).Error
+	return keys, err
+}
+
+// Automatic transitions
+
+// autoTransitionsSQL lists the automatic transitions leaving an incident's current state. Records
+// pinned to a published version read them from the version's snapshot, the rest from the live
+// workflow definition.
+const autoTransitionsSQL = `JOIN LATERAL (
+		SELECT lt.id, lt.auto_trigger, lt.auto_after_hours::int, lt.child_state_code, lt.sort_order::int
+		FROM workflow_transitions lt
+		WHERE i.workflow_version = 0 AND lt.from_state_id = i.current_state_id
+			AND lt.is_active AND lt.deleted_at IS NULL AND lt.auto_trigger <> ''
+		UNION ALL
+		SELECT (st->>'id')::uuid, st->>'auto_trigger', COALESCE((st->>'auto_after_hours')::int, 0),
+			COALESCE(st->>'child_state_code', ''), COALESCE((st->>'sort_order')::int, 0)
+		FROM workflow_versions v
+		CROSS JOIN LATERAL jsonb_array_elements(COALESCE(v.snapshot::jsonb->'transitions', '[]'::jsonb)) st
+		WHERE i.workflow_version > 0 AND v.workflow_id = i.workflow_id AND v.version = i.workflow_version
+			AND (st->>'from_state_id')::uuid = i.current_state_id
+			AND COALESCE((st->>'is_active')::boolean, false) AND COALESCE(st->>'auto_trigger', '') <> ''
+	) t ON true`
+
+// ListDueAutoTransitions finds open records whose current state has an automatic transition that
+// has fired. Time in state counts from the last history entry into the state, or from creation.
+// Only sub-workflow children launched since the parent entered the state count towards
+// child_completed. Results are grouped by record, with the transition that should be tried first
+// leading each group.
+func (r *incidentRepository) ListDueAutoTransitions(ctx context.Context, now time.Time) ([]models.DueAutoTransition, error) {
+	var due []models.DueAutoTransition
+	err := r.db.WithContext(ctx).
+		Table("incidents AS i").
+		Select("i.id AS incident_id, t.id AS transition_id").
+		Joins(autoTransitionsSQL).
+		Where("i.closed_at IS NULL AND i.deleted_at IS NULL").
+		Where(`(
+			(t.auto_trigger = ? AND `+stateEnteredSQL+` + make_interval(hours => t.auto_after_hours) <= ?)
+			OR (t.auto_trigger = ? AND i.due_date IS NOT NULL AND i.due_date + make_interval(hours => t.auto_after_hours) <= ?)
+			OR (t.auto_trigger = ?
+				AND NOT EXISTS (SELECT 1 FROM incidents c
+					WHERE c.source_incident_id = i.id AND c.sub_workflow_state_id = i.current_state_id
+					AND c.closed_at IS NULL AND c.deleted_at IS NULL
+					AND c.created_at >= `+stateEnteredSQL+`)
+				AND EXISTS (SELECT 1 FROM incidents c JOIN workflow_states cs ON cs.id = c.current_state_id
+					WHERE c.source_incident_id = i.id AND c.sub_workflow_state_id = i.current_state_id
+					AND c.deleted_at IS NULL AND cs.state_type = 'terminal'
+					AND c.created_at >= `+stateEnteredSQL+`
+					AND (t.child_state_code = '' OR t.child_state_code = cs.code)))
+		)`, models.AutoTriggerTimeInState, now, models.AutoTriggerDueDatePassed, now, models.AutoTriggerChildCompleted).
+		// Sub-workflow outcomes naming the child's end state win over the catch-all
+		Order("i.id, t.child_state_code = '', t.sort_order").
+		Scan(&due).Error
+	return due, err
+}
+
 // Stats
 
 func (r *incidentRepository) GetStats(ctx context.Context, filter *models.IncidentFilter) (*models.IncidentStatsResponse, error) {
@@ -648,8 +966,8 @@ func (r *incidentRepository) GetStats(ctx context.Context, filter *models.Incide
 		switch stc.StateType {
 		case "initial":
 			stats.Open = stc.Count
-		case "normal":
-			stats.InProgress = stc.Count
+		case "normal", models.StateTypeSubWorkflow:
+			stats.InProgress += stc.Count
 		case "terminal":
 			stats.Closed = stc.Count
 		}
@@ -676,18 +994,36 @@ func (r *incidentRepository) UpdateSLABreached(ctx context.Context, incidentID u
 		Update("sla_breached", breached).Error
 }
 
-func (r *incidentRepository) MarkSLABreached(ctx context.Context) (int64, error) {
+// MarkSLABreached flags incidents past their SLA deadline and returns the newly breached
+// incidents (with assignees) so callers can notify the people responsible
+func (r *incidentRepository) MarkSLABreached(ctx context.Context) ([]models.Incident, error) {
 	// Find and update all incidents that have passed their SLA deadline
 	// but aren't marked as breached yet, and are not in a terminal state
-	result := r.db.WithContext(ctx).
-		Model(&models.Incident{}).
+	var marked []models.Incident
+	err := r.db.WithContext(ctx).
+		Model(&marked).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
 		Where("sla_deadline IS NOT NULL").
 		Where("sla_deadline < ?", time.Now()).
 		Where("sla_breached = ?", false).
 		Where("current_state_id NOT IN (SELECT id FROM workflow_states WHERE state_type = 'terminal')").
-		Update("sla_breached", true)
+		Update("sla_breached", true).Error
+	if err != nil || len(marked) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(marked))
+	for i, incident := range marked {
+		ids[i] = incident.ID
+	}
 
-	return result.RowsAffected, result.Error
+	var incidents []models.Incident
+	err = r.db.WithContext(ctx).
+		Preload("Assignees").
+		Preload("Department").
+		Where("id IN ?", ids).
+		Find(&incidents).Error
+	return incidents, err
 }
 
 // User-specific queries
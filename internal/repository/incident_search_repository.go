@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/automax/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// IncidentSearchRepository runs full-text searches over incident_search_documents
+type IncidentSearchRepository interface {
+	Search(ctx context.Context, filter *models.IncidentSearchFilter) ([]models.IncidentSearchResult, int64, error)
+}
+
+type incidentSearchRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentSearchRepository(db *gorm.DB) IncidentSearchRepository {
+	return &incidentSearchRepository{db: db}
+}
+
+// ts_headline marks matches with private-use sentinels rather than HTML, because it doesn't
+// escape the text around them. Results are escaped and the sentinels turned into <mark> tags.
+const (
+	searchMarkStart = "\uE000"
+	searchMarkStop  = "\uE001"
+
+	searchTitleHeadlineOptions = "StartSel=" + searchMarkStart + ", StopSel=" + searchMarkStop + ", HighlightAll=true"
+	searchHeadlineOptions      = "StartSel=" + searchMarkStart + ", StopSel=" + searchMarkStop + ", MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \""
+)
+
+var searchMarkReplacer = strings.NewReplacer(searchMarkStart, "<mark>", searchMarkStop, "</mark>")
+
+// highlightHTML escapes a ts_headline result and turns its sentinels into <mark> tags
+func highlightHTML(headline string) string {
+	return searchMarkReplacer.Replace(html.EscapeString(headline))
+}
+
+func (r *incidentSearchRepository) Search(ctx context.Context, filter *models.IncidentSearchFilter) ([]models.IncidentSearchResult, int64, error) {
+	tsquery, queryArgs := buildSearchTSQuery(filter.Query)
+	if tsquery == "" {
+		return []models.IncidentSearchResult{}, 0, nil
+	}
+
+	cte := `WITH q AS (SELECT ` + tsquery + ` AS query)`
+	body := `FROM incident_search_documents d
+		CROSS JOIN q
+		JOIN incidents i ON i.id = d.incident_id
+		JOIN workflow_states ws ON ws.id = i.current_state_id
+		WHERE d.document @@ q.query AND i.deleted_at IS NULL AND i.record_type IN ?`
+	args := []interface{}{filter.RecordTypes}
+	if filter.RecordType != "" {
+		body += " AND i.record_type = ?"
+		args = append(args, filter.RecordType)
+	}
+	// Empty viewable_roles = visible to all
+	if !filter.AllStates {
+		body += ` AND (
+			NOT EXISTS (SELECT 1 FROM state_viewable_roles WHERE workflow_state_id = i.current_state_id)
+			OR EXISTS (SELECT 1 FROM state_viewable_roles WHERE workflow_state_id = i.current_state_id AND role_id IN ?)
+		)`
+		args = append(args, filter.UserRoleIDs)
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Raw(cte+" SELECT COUNT(*) "+body, append(queryArgs, args...)...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Highlight with the stemmer of the query's script so inflected matches are marked
+	config := "english"
+	if containsArabic(filter.Query) {
+		config = "arabic"
+	}
+
+	offset := (filter.Page - 1) * filter.Limit
+	query := cte + `
+		SELECT i.id, i.incident_number, i.record_type, i.title,
+			ts_headline('` + config + `', i.title, q.query, ?) AS title_highlight,
+			ts_headline('` + config + `', d.content, q.query, ?) AS snippet,
+			ts_rank_cd(d.document, q.query) AS rank,
+			i.current_state_id, ws.name AS current_state_name,
+			i.created_at, i.updated_at
+		` + body + `
+		ORDER BY rank DESC, i.updated_at DESC
+		LIMIT ? OFFSET ?`
+
+	// Placeholders run through the query, the headline options, then the filters
+	selectArgs := append([]interface{}{}, queryArgs...)
+	selectArgs = append(selectArgs, searchTitleHeadlineOptions, searchHeadlineOptions)
+	selectArgs = append(selectArgs, args...)
+	selectArgs = append(selectArgs, filter.Limit, offset)
+
+	var results []models.IncidentSearchResult
+	err := r.db.WithContext(ctx).Raw(query, selectArgs...).Scan(&results).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range results {
+		results[i].TitleHighlight = highlightHTML(results[i].TitleHighlight)
+		results[i].Snippet = highlightHTML(results[i].Snippet)
+	}
+	return results, total, nil
+}
+
+// buildSearchTSQuery turns a search string into a tsquery expression. Terms are ANDed;
+// each term matches under the english, arabic and simple configurations. Supported syntax:
+// "quoted phrase", prefix*, and -excluded.
+func buildSearchTSQuery(input string) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+
+	add := func(lexemes []string, prefix, negate bool) {
+		if len(lexemes) == 0 {
+			return
+		}
+		quoted := make([]string, len(lexemes))
+		for i, l := range lexemes {
+			quoted[i] = "'" + l + "'"
+		}
+		text := strings.Join(quoted, " <-> ")
+		if prefix {
+			text += ":*"
+		}
+		expr := "(to_tsquery('english', ?) || to_tsquery('arabic', ?) || to_tsquery('simple', ?))"
+		if negate {
+			expr = "!!" + expr
+		}
+		parts = append(parts, expr)
+		args = append(args, text, text, text)
+	}
+
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+		negate := false
+		if runes[i] == '-' {
+			negate = true
+			i++
+		}
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			add(searchLexemes(string(runes[i+1:end])), false, negate)
+			i = end + 1
+			continue
+		}
+		end := i
+		for end < len(runes) && !unicode.IsSpace(runes[end]) {
+			end++
+		}
+		word := string(runes[i:end])
+		i = end
+		prefix := strings.HasSuffix(word, "*")
+		lexemes := searchLexemes(strings.TrimRight(word, "*"))
+		// A prefix only applies to a single word
+		add(lexemes, prefix && len(lexemes) == 1, negate)
+	}
+
+	return strings.Join(parts, " && "), args
+}
+
+// searchLexemes splits text into words, dropping characters with a meaning in tsquery syntax.
+// Hyphens, dots, underscores and @ stay so numbers like INC-2026-000001 and emails match whole.
+func searchLexemes(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !(unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) ||
+			r == '-' || r == '.' || r == '_' || r == '@')
+	})
+}
+
+func containsArabic(text string) bool {
+	for _, r := range text {
+		if unicode.Is(unicode.Arabic, r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IncidentViewRepository interface {
+	Create(ctx context.Context, view *models.IncidentView) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.IncidentView, error)
+	Update(ctx context.Context, view *models.IncidentView) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	ReplaceSharedRoles(ctx context.Context, view *models.IncidentView, roleIDs []uuid.UUID) error
+	ListVisible(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) ([]models.IncidentView, error)
+	IsVisible(ctx context.Context, viewID, userID uuid.UUID, roleIDs []uuid.UUID) (bool, error)
+
+	// Pins
+	ListPins(ctx context.Context, userID uuid.UUID) ([]models.IncidentViewPin, error)
+	Pin(ctx context.Context, pin *models.IncidentViewPin) error
+	Unpin(ctx context.Context, userID, viewID uuid.UUID) error
+
+	// CountIncidents counts the records matching a view's filter among the given record types
+	CountIncidents(ctx context.Context, filter *models.IncidentFilter, recordTypes []string) (int64, error)
+}
+
+type incidentViewRepository struct {
+	db *gorm.DB
+}
+
+func NewIncidentViewRepository(db *gorm.DB) IncidentViewRepository {
+	return &incidentViewRepository{db: db}
+}
+
+func (r *incidentViewRepository) Create(ctx context.Context, view *models.IncidentView) error {
+	return r.db.WithContext(ctx).Create(view).Error
+}
+
+func (r *incidentViewRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.IncidentView, error) {
+	var view models.IncidentView
+	err := r.db.WithContext(ctx).
+		Preload("Owner").
+		Preload("SharedRoles").
+		First(&view, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+func (r *incidentViewRepository) Update(ctx context.Context, view *models.IncidentView) error {
+	return r.db.WithContext(ctx).Omit("Owner", "SharedRoles").Save(view).Error
+}
+
+// Delete removes a view together with everyone's pins of it
+func (r *incidentViewRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&models.IncidentViewPin{}, "view_id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM incident_view_roles WHERE incident_view_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.IncidentView{}, "id = ?", id).Error
+	})
+}
+
+func (r *incidentViewRepository) ReplaceSharedRoles(ctx context.Context, view *models.IncidentView, roleIDs []uuid.UUID) error {
+	var roles []models.Role
+	if len(roleIDs) > 0 {
+		if err := r.db.WithContext(ctx).Where("id IN ?", roleIDs).Find(&roles).Error; err != nil {
+			return err
+		}
+		if len(roles) != len(roleIDs) {
+			return errors.New("one or more roles not found")
+		}
+	}
+	return r.db.WithContext(ctx).Model(view).Association("SharedRoles").Replace(roles)
+}
+
+// visibleViewsClause matches views the user owns or that are shared with one of their roles
+func visibleViewsClause(query *gorm.DB, userID uuid.UUID, roleIDs []uuid.UUID) *gorm.DB {
+	if len(roleIDs) == 0 {
+		return query.Where("incident_views.owner_id = ?", userID)
+	}
+	return query.Where(
+		"(incident_views.owner_id = ? OR incident_views.id IN (SELECT incident_view_id FROM incident_view_roles WHERE role_id IN ?))",
+		userID, roleIDs)
+}
+
+func (r *incidentViewRepository) ListVisible(ctx context.Context, userID uuid.UUID, roleIDs []uuid.UUID) ([]models.IncidentView, error) {
+	var views []models.IncidentView
+	query := visibleViewsClause(r.db.WithContext(ctx).Model(&models.IncidentView{}), userID, roleIDs)
+	err := query.
+		Preload("Owner").
+		Preload("SharedRoles").
+		Order("name ASC").
+		Find(&views).Error
+	return views, err
+}
+
+func (r *incidentViewRepository) IsVisible(ctx context.Context, viewID, userID uuid.UUID, roleIDs []uuid.UUID) (bool, error) {
+	var count int64
+	query := visibleViewsClause(r.db.WithContext(ctx).Model(&models.IncidentView{}).Where("incident_views.id = ?", viewID), userID, roleIDs)
+	err := query.Count(&count).Error
+	return count > 0, err
+}
+
+func (r *incidentViewRepository) ListPins(ctx context.Context, userID uuid.UUID) ([]models.IncidentViewPin, error) {
+	var pins []models.IncidentViewPin
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("sort_order ASC, created_at ASC").
+		Find(&pins).Error
+	return pins, err
+}
+
+// Pin adds a view to the user's pinned list, or moves it when already pinned
+func (r *incidentViewRepository) Pin(ctx context.Context, pin *models.IncidentViewPin) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "view_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"sort_order"}),
+		}).
+		Create(pin).Error
+}
+
+func (r *incidentViewRepository) Unpin(ctx context.Context, userID, viewID uuid.UUID) error {
+	return r.db.WithContext(ctx).Delete(&models.IncidentViewPin{}, "user_id = ? AND view_id = ?", userID, viewID).Error
+}
+
+func (r *incidentViewRepository) CountIncidents(ctx context.Context, filter *models.IncidentFilter, recordTypes []string) (int64, error) {
+	if len(recordTypes) == 0 {
+		return 0, nil
+	}
+	var total int64
+	query := r.db.WithContext(ctx).Model(&models.Incident{}).Where("record_type IN ?", recordTypes)
+	err := applyIncidentFilter(query, filter).Count(&total).Error
+	return total, err
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository interface {
+	CreateBatch(ctx context.Context, notifications []models.Notification) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Notification, error)
+	List(ctx context.Context, filter *models.NotificationFilter) ([]models.Notification, int64, error)
+	CountUnread(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkRead(ctx context.Context, id, userID uuid.UUID) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	Delete(ctx context.Context, id, userID uuid.UUID) error
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) CreateBatch(ctx context.Context, notifications []models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&notifications).Error
+}
+
+func (r *notificationRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Notification, error) {
+	var notification models.Notification
+	err := r.db.WithContext(ctx).
+		Preload("Incident").
+		First(&notification, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &notification, nil
+}
+
+func (r *notificationRepository) List(ctx context.Context, filter *models.NotificationFilter) ([]models.Notification, int64, error) {
+	var notifications []models.Notification
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ?", filter.UserID)
+
+	if filter.UnreadOnly {
+		query = query.Where("is_read = ?", false)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	offset := (filter.Page - 1) * filter.Limit
+
+	err := query.
+		Preload("Incident").
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+func (r *notificationRepository) CountUnread(ctx context.Context, userID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *notificationRepository) MarkRead(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *notificationRepository) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result := r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{
+			"is_read": true,
+			"read_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *notificationRepository) Delete(ctx context.Context, id, userID uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&models.Notification{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -17,6 +17,7 @@ type RoleRepository interface {
 	List(ctx context.Context) ([]models.Role, error)
 	AssignPermissions(ctx context.Context, roleID uuid.UUID, permissionIDs []uuid.UUID) error
 	GetPermissions(ctx context.Context, roleID uuid.UUID) ([]models.Permission, error)
+	FindRoleIDsWithActiveUsers(ctx context.Context, roleIDs []uuid.UUID) ([]uuid.UUID, error)
 }
 
 type roleRepository struct {
@@ -160,3 +161,18 @@ func (r *permissionRepository) GetModules(ctx context.Context) ([]string, error)
 	err := r.db.WithContext(ctx).Model(&models.Permission{}).Distinct("module").Pluck("module", &modules).Error
 	return modules, err
 }
+
+// FindRoleIDsWithActiveUsers returns the subset of roleIDs held by at least one active user
+func (r *roleRepository) FindRoleIDsWithActiveUsers(ctx context.Context, roleIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if len(roleIDs) == 0 {
+		return ids, nil
+	}
+	err := r.db.WithContext(ctx).
+		Table("user_roles").
+		Joins("JOIN users ON users.id = user_roles.user_id").
+		Where("user_roles.role_id IN ? AND users.is_active = ? AND users.deleted_at IS NULL", roleIDs, true).
+		Distinct().
+		Pluck("user_roles.role_id", &ids).Error
+	return ids, err
+}
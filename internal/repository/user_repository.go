@@ -29,6 +29,9 @@ type UserRepository interface {
 	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]models.Role, error)
 	GetUserPermissions(ctx context.Context, userID uuid.UUID) ([]string, error)
 	FindMatching(ctx context.Context, roleID, classificationID, locationID, departmentID, excludeUserID *uuid.UUID) ([]models.User, error)
+	FindActiveByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error)
+	FindActiveByRoleCode(ctx context.Context, roleCode string) ([]models.User, error)
+	FindActiveByDepartment(ctx context.Context, departmentID uuid.UUID) ([]models.User, error)
 
 	FindByExtension(ctx context.Context, extension string) (*models.User, error)
 }
@@ -325,6 +328,43 @@ func (r *userRepository) FindMatching(ctx context.Context, roleID, classificatio
 	return users, err
 }
 
+// FindActiveByIDs returns the active users among the given IDs
+func (r *userRepository) FindActiveByIDs(ctx context.Context, ids []uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("id IN ? AND is_active = ?", ids, true).
+		Find(&users).Error
+	return users, err
+}
+
+// FindActiveByRoleCode returns active users holding the active role with the given code
+func (r *userRepository) FindActiveByRoleCode(ctx context.Context, roleCode string) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Joins("JOIN user_roles ur ON ur.user_id = users.id").
+		Joins("JOIN roles ON roles.id = ur.role_id").
+		Where("roles.code = ? AND roles.is_active = ? AND roles.deleted_at IS NULL", roleCode, true).
+		Where("users.is_active = ?", true).
+		Distinct().
+		Find(&users).Error
+	return users, err
+}
+
+// FindActiveByDepartment returns active users whose primary or assigned departments include the department
+func (r *userRepository) FindActiveByDepartment(ctx context.Context, departmentID uuid.UUID) ([]models.User, error) {
+	var users []models.User
+	err := r.db.WithContext(ctx).
+		Joins("LEFT JOIN user_departments ud ON ud.user_id = users.id").
+		Where("users.department_id = ? OR ud.department_id = ?", departmentID, departmentID).
+		Where("users.is_active = ?", true).
+		Distinct().
+		Find(&users).Error
+	return users, err
+}
+
 func (r *userRepository) FindByExtension(ctx context.Context, extension string) (*models.User, error) {
 	var user models.User
 	err := r.db.WithContext(ctx).Where("extension = ?", extension).First(&user).Error
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	FindByID(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error)
+	List(ctx context.Context, filter *models.WebhookDeliveryFilter) ([]models.WebhookDelivery, int64, error)
+	GetLastAttempt(ctx context.Context, idempotencyKey string) (int, error)
+}
+
+type webhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{db: db}
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookDeliveryRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.WithContext(ctx).First(&delivery, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, filter *models.WebhookDeliveryFilter) ([]models.WebhookDelivery, int64, error) {
+	var deliveries []models.WebhookDelivery
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.WebhookDelivery{})
+
+	// Apply filters
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.IdempotencyKey != "" {
+		query = query.Where("idempotency_key = ?", filter.IdempotencyKey)
+	}
+	if filter.IncidentID != nil {
+		query = query.Where("incident_id = ?", *filter.IncidentID)
+	}
+	if filter.TransitionActionID != nil {
+		query = query.Where("transition_action_id = ?", *filter.TransitionActionID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", *filter.EndDate)
+	}
+	if filter.Search != "" {
+		searchPattern := "%" + filter.Search + "%"
+		query = query.Where("url ILIKE ? OR error ILIKE ?", searchPattern, searchPattern)
+	}
+
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Apply pagination
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+	offset := (filter.Page - 1) * filter.Limit
+	err := query.
+		Order("created_at DESC").
+		Offset(offset).
+		Limit(filter.Limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return deliveries, total, nil
+}
+
+// GetLastAttempt returns the highest attempt number recorded for an idempotency key
+func (r *webhookDeliveryRepository) GetLastAttempt(ctx context.Context, idempotencyKey string) (int, error) {
+	var attempt int
+	err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).
+		Where("idempotency_key = ?", idempotencyKey).
+		Select("COALESCE(MAX(attempt), 0)").
+		Scan(&attempt).Error
+	return attempt, err
+}
@@ -0,0 +1,211 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/automax/backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// WorkflowAnalyticsRepository aggregates the transition history of a workflow's records
+type WorkflowAnalyticsRepository interface {
+	GetDwellTimes(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.StateDwellTime, error)
+	GetTransitionFrequencies(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.TransitionFrequency, error)
+	GetReworkStates(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.ReworkState, error)
+	GetReworkTransitions(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.ReworkTransition, error)
+	GetUserThroughput(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.UserThroughput, error)
+}
+
+type workflowAnalyticsRepository struct {
+	db *gorm.DB
+}
+
+func NewWorkflowAnalyticsRepository(db *gorm.DB) WorkflowAnalyticsRepository {
+	return &workflowAnalyticsRepository{db: db}
+}
+
+// stateEntriesCTE builds the "entries" CTE: one row per time a record entered a state,
+// including the state it was created in. Approval votes stay on the same state and are skipped.
+func stateEntriesCTE(filter *models.WorkflowAnalyticsFilter) (string, []interface{}) {
+	incidentFilter := "i.workflow_id = ? AND i.deleted_at IS NULL"
+	args := []interface{}{filter.WorkflowID}
+	if filter.ClassificationID != nil {
+		incidentFilter += " AND i.classification_id = ?"
+		args = append(args, *filter.ClassificationID)
+	}
+	if filter.DepartmentID != nil {
+		incidentFilter += " AND i.department_id = ?"
+		args = append(args, *filter.DepartmentID)
+	}
+
+	cte := `entries AS (
+		SELECT h.incident_id, h.to_state_id AS state_id, h.transition_id, h.performed_by_id, h.transitioned_at AS entered_at
+		FROM incident_transition_histories h
+		JOIN incidents i ON i.id = h.incident_id
+		WHERE h.from_state_id <> h.to_state_id AND ` + incidentFilter + `
+		UNION ALL
+		SELECT i.id, COALESCE(
+			(SELECT h.from_state_id FROM incident_transition_histories h
+			 WHERE h.incident_id = i.id AND h.from_state_id <> h.to_state_id
+			 ORDER BY h.transitioned_at LIMIT 1),
+			i.current_state_id
+		), NULL::uuid, NULL::uuid, i.created_at
+		FROM incidents i
+		WHERE ` + incidentFilter + `
+	)`
+	return cte, append(args, args...)
+}
+
+// periodClause restricts a column to the filter's date range
+func periodClause(column string, filter *models.WorkflowAnalyticsFilter) (string, []interface{}) {
+	clause := ""
+	var args []interface{}
+	if filter.StartDate != nil {
+		clause += " AND " + column + " >= ?"
+		args = append(args, *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		clause += " AND " + column + " <= ?"
+		args = append(args, *filter.EndDate)
+	}
+	return clause, args
+}
+
+func (r *workflowAnalyticsRepository) GetDwellTimes(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.StateDwellTime, error) {
+	cte, args := stateEntriesCTE(filter)
+	period, periodArgs := periodClause("s.entered_at", filter)
+
+	// A stay ends when the record enters its next state
+	query := `WITH ` + cte + `, stays AS (
+		SELECT state_id, entered_at,
+			EXTRACT(EPOCH FROM LEAD(entered_at) OVER (PARTITION BY incident_id ORDER BY entered_at) - entered_at) / 3600 AS hours
+		FROM entries
+	)
+	SELECT ws.id AS state_id, ws.name AS state_name, ws.code AS state_code, ws.state_type,
+		COUNT(*) AS visits,
+		COUNT(s.hours) AS completed,
+		COUNT(*) - COUNT(s.hours) AS in_state,
+		AVG(s.hours) AS avg_hours,
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY s.hours) AS median_hours,
+		percentile_cont(0.9) WITHIN GROUP (ORDER BY s.hours) AS p90_hours
+	FROM stays s
+	JOIN workflow_states ws ON ws.id = s.state_id
+	WHERE ws.state_type <> 'terminal'` + period + `
+	GROUP BY ws.id, ws.name, ws.code, ws.state_type, ws.sort_order
+	ORDER BY ws.sort_order, ws.name`
+
+	var rows []models.StateDwellTime
+	err := r.db.WithContext(ctx).Raw(query, append(args, periodArgs...)...).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *workflowAnalyticsRepository) GetTransitionFrequencies(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.TransitionFrequency, error) {
+	query := r.db.WithContext(ctx).
+		Table("incident_transition_histories AS h").
+		Select(`h.transition_id, COALESCE(t.name, '') AS transition_name, COALESCE(t.code, '') AS transition_code,
+			h.from_state_id, COALESCE(fs.name, '') AS from_state_name, h.to_state_id, COALESCE(ts.name, '') AS to_state_name,
+			COUNT(*) AS count, COUNT(DISTINCT h.incident_id) AS incidents`).
+		Joins("JOIN incidents i ON i.id = h.incident_id").
+		Joins("LEFT JOIN workflow_transitions t ON t.id = h.transition_id").
+		Joins("LEFT JOIN workflow_states fs ON fs.id = h.from_state_id").
+		Joins("LEFT JOIN workflow_states ts ON ts.id = h.to_state_id").
+		Where("i.workflow_id = ? AND i.deleted_at IS NULL AND h.from_state_id <> h.to_state_id", filter.WorkflowID)
+	query = applyAnalyticsFilter(query, filter, "h.transitioned_at")
+
+	var rows []models.TransitionFrequency
+	err := query.
+		Group("h.transition_id, t.name, t.code, h.from_state_id, fs.name, h.to_state_id, ts.name").
+		Order("count DESC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// reworkEntries numbers each record's visits to a state; visits after the first are rework
+func reworkEntries(filter *models.WorkflowAnalyticsFilter) (string, []interface{}) {
+	cte, args := stateEntriesCTE(filter)
+	return `WITH ` + cte + `, visits AS (
+		SELECT incident_id, state_id, transition_id, entered_at,
+			ROW_NUMBER() OVER (PARTITION BY incident_id, state_id ORDER BY entered_at) AS visit
+		FROM entries
+	)`, args
+}
+
+func (r *workflowAnalyticsRepository) GetReworkStates(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.ReworkState, error) {
+	cte, args := reworkEntries(filter)
+	period, periodArgs := periodClause("v.entered_at", filter)
+
+	query := cte + `
+	SELECT ws.id AS state_id, ws.name AS state_name, ws.code AS state_code,
+		COUNT(*) AS reentries, COUNT(DISTINCT v.incident_id) AS incidents
+	FROM visits v
+	JOIN workflow_states ws ON ws.id = v.state_id
+	WHERE v.visit > 1` + period + `
+	GROUP BY ws.id, ws.name, ws.code
+	ORDER BY reentries DESC`
+
+	var rows []models.ReworkState
+	err := r.db.WithContext(ctx).Raw(query, append(args, periodArgs...)...).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *workflowAnalyticsRepository) GetReworkTransitions(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.ReworkTransition, error) {
+	cte, args := reworkEntries(filter)
+	period, periodArgs := periodClause("v.entered_at", filter)
+
+	query := cte + `
+	SELECT v.transition_id, COALESCE(t.name, '') AS transition_name, COALESCE(t.code, '') AS transition_code,
+		COUNT(*) AS reentries, COUNT(DISTINCT v.incident_id) AS incidents
+	FROM visits v
+	LEFT JOIN workflow_transitions t ON t.id = v.transition_id
+	WHERE v.visit > 1 AND v.transition_id IS NOT NULL` + period + `
+	GROUP BY v.transition_id, t.name, t.code
+	ORDER BY reentries DESC`
+
+	var rows []models.ReworkTransition
+	err := r.db.WithContext(ctx).Raw(query, append(args, periodArgs...)...).Scan(&rows).Error
+	return rows, err
+}
+
+func (r *workflowAnalyticsRepository) GetUserThroughput(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.UserThroughput, error) {
+	cte, args := stateEntriesCTE(filter)
+	period, periodArgs := periodClause("s.entered_at", filter)
+
+	// Handling time is how long the record sat in the state the user moved it out of
+	query := `WITH ` + cte + `, steps AS (
+		SELECT incident_id, state_id, performed_by_id, entered_at,
+			EXTRACT(EPOCH FROM entered_at - LAG(entered_at) OVER (PARTITION BY incident_id ORDER BY entered_at)) / 3600 AS handle_hours
+		FROM entries
+	)
+	SELECT u.id AS user_id, u.username, u.first_name, u.last_name,
+		COUNT(*) AS transitions,
+		COUNT(DISTINCT s.incident_id) AS incidents,
+		COUNT(*) FILTER (WHERE ws.state_type = 'terminal') AS closed,
+		AVG(s.handle_hours) AS avg_handle_hours
+	FROM steps s
+	JOIN users u ON u.id = s.performed_by_id
+	JOIN workflow_states ws ON ws.id = s.state_id
+	WHERE s.performed_by_id IS NOT NULL` + period + `
+	GROUP BY u.id, u.username, u.first_name, u.last_name
+	ORDER BY transitions DESC`
+
+	var rows []models.UserThroughput
+	err := r.db.WithContext(ctx).Raw(query, append(args, periodArgs...)...).Scan(&rows).Error
+	return rows, err
+}
+
+// applyAnalyticsFilter adds the classification, department and period filters to a history query
+func applyAnalyticsFilter(query *gorm.DB, filter *models.WorkflowAnalyticsFilter, dateColumn string) *gorm.DB {
+	if filter.ClassificationID != nil {
+		query = query.Where("i.classification_id = ?", *filter.ClassificationID)
+	}
+	if filter.DepartmentID != nil {
+		query = query.Where("i.department_id = ?", *filter.DepartmentID)
+	}
+	if filter.StartDate != nil {
+		query = query.Where(dateColumn+" >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where(dateColumn+" <= ?", *filter.EndDate)
+	}
+	return query
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/automax/backend/internal/models"
 	"github.com/google/uuid"
@@ -49,6 +50,7 @@ type WorkflowRepository interface {
 
 	// Transition role assignments
 	AssignTransitionRoles(ctx context.Context, transitionID uuid.UUID, roleIDs []uuid.UUID) error
+	SetTransitionApproval(ctx context.Context, transition *models.WorkflowTransition, approverIDs []uuid.UUID) error
 
 	// State viewable role assignments
 	AssignStateViewableRoles(ctx context.Context, stateID uuid.UUID, roleIDs []uuid.UUID) error
@@ -60,6 +62,15 @@ type WorkflowRepository interface {
 	// TransitionAction CRUD
 	SetTransitionActions(ctx context.Context, transitionID uuid.UUID, actions []models.TransitionAction) error
 	GetTransitionActions(ctx context.Context, transitionID uuid.UUID) ([]models.TransitionAction, error)
+
+	// Published versions
+	CreateVersion(ctx context.Context, version *models.WorkflowVersion) error
+	FindVersion(ctx context.Context, workflowID uuid.UUID, version int) (*models.WorkflowVersion, error)
+	GetLatestVersion(ctx context.Context, workflowID uuid.UUID) (*models.WorkflowVersion, error)
+	ListVersions(ctx context.Context, workflowID uuid.UUID) ([]models.WorkflowVersion, error)
+	ListOpenIncidentStates(ctx context.Context, workflowID uuid.UUID, version int, incidentIDs []uuid.UUID) ([]uuid.UUID, error)
+	ListOpenPinnedVersions(ctx context.Context, workflowID uuid.UUID) ([]int, error)
+	MigrateIncidents(ctx context.Context, workflowID uuid.UUID, fromVersion, toVersion int, stateMapping map[uuid.UUID]uuid.UUID, incidentIDs []uuid.UUID) (int64, error)
 }
 
 type workflowRepository struct {
@@ -99,6 +110,7 @@ func (r *workflowRepository) FindByIDWithRelations(ctx context.Context, id uuid.
 		Preload("Transitions.FromState").
 		Preload("Transitions.ToState").
 		Preload("Transitions.AllowedRoles").
+		Preload("Transitions.Approvers").
 		Preload("Transitions.Requirements").
 		Preload("Transitions.Actions", func(db *gorm.DB) *gorm.DB {
 			return db.Order("execution_order")
@@ -390,6 +402,7 @@ func (r *workflowRepository) FindTransitionByIDWithRelations(ctx context.Context
 		Preload("FromState").
 		Preload("ToState").
 		Preload("AllowedRoles").
+		Preload("Approvers").
 		Preload("AssignDepartment").
 		Preload("AssignUser").
 		Preload("AssignmentRole").
@@ -410,6 +423,7 @@ func (r *workflowRepository) ListTransitionsByWorkflowID(ctx context.Context, wo
 		Preload("FromState").
 		Preload("ToState").
 		Preload("AllowedRoles").
+		Preload("Approvers").
 		Preload("AssignDepartment").
 		Preload("AssignUser").
 		Preload("AssignmentRole").
@@ -426,6 +440,7 @@ func (r *workflowRepository) ListTransitionsFromState(ctx context.Context, state
 		Preload("FromState").
 		Preload("ToState").
 		Preload("AllowedRoles").
+		Preload("Approvers").
 		Preload("AssignDepartment").
 		Preload("AssignUser").
 		Preload("AssignmentRole").
@@ -449,6 +464,28 @@ func (r *workflowRepository) DeleteTransition(ctx context.Context, id uuid.UUID)
 
 // Transition role assignments
 
+// SetTransitionApproval saves the approval settings of a transition and replaces its approvers
+func (r *workflowRepository) SetTransitionApproval(ctx context.Context, transition *models.WorkflowTransition, approverIDs []uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(transition).Updates(map[string]interface{}{
+			"approval_mode":   transition.ApprovalMode,
+			"approval_quorum": transition.ApprovalQuorum,
+			"reject_state_id": transition.RejectStateID,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		var approvers []models.User
+		if len(approverIDs) > 0 {
+			if err := tx.Where("id IN ?", approverIDs).Find(&approvers).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(transition).Association("Approvers").Replace(approvers)
+	})
+}
+
 func (r *workflowRepository) AssignTransitionRoles(ctx context.Context, transitionID uuid.UUID, roleIDs []uuid.UUID) error {
 	var transition models.WorkflowTransition
 	if err := r.db.WithContext(ctx).First(&transition, "id = ?", transitionID).Error; err != nil {
@@ -552,3 +589,104 @@ func (r *workflowRepository) AssignConvertToRequestRoles(ctx context.Context, wo
 
 	return r.db.WithContext(ctx).Model(&workflow).Association("ConvertToRequestRoles").Replace(roles)
 }
+
+// Published versions
+
+// CreateVersion stores a version snapshot and makes it the workflow's current version
+func (r *workflowRepository) CreateVersion(ctx context.Context, version *models.WorkflowVersion) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Workflow{}).
+			Where("id = ?", version.WorkflowID).
+			Update("version", version.Version).Error
+	})
+}
+
+func (r *workflowRepository) FindVersion(ctx context.Context, workflowID uuid.UUID, version int) (*models.WorkflowVersion, error) {
+	var v models.WorkflowVersion
+	err := r.db.WithContext(ctx).
+		Preload("PublishedBy").
+		First(&v, "workflow_id = ? AND version = ?", workflowID, version).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func (r *workflowRepository) GetLatestVersion(ctx context.Context, workflowID uuid.UUID) (*models.WorkflowVersion, error) {
+	var v models.WorkflowVersion
+	err := r.db.WithContext(ctx).
+		Where("workflow_id = ?", workflowID).
+		Order("version DESC").
+		First(&v).Error
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// ListVersions returns the versions of a workflow, newest first, without snapshots
+func (r *workflowRepository) ListVersions(ctx context.Context, workflowID uuid.UUID) ([]models.WorkflowVersion, error) {
+	var versions []models.WorkflowVersion
+	err := r.db.WithContext(ctx).
+		Omit("snapshot").
+		Preload("PublishedBy").
+		Where("workflow_id = ?", workflowID).
+		Order("version DESC").
+		Find(&versions).Error
+	return versions, err
+}
+
+// openVersionIncidents scopes a query to open incidents of a workflow pinned to version
+func openVersionIncidents(db *gorm.DB, workflowID uuid.UUID, version int, incidentIDs []uuid.UUID) *gorm.DB {
+	db = db.Model(&models.Incident{}).
+		Where("workflow_id = ? AND workflow_version = ? AND closed_at IS NULL", workflowID, version)
+	if len(incidentIDs) > 0 {
+		db = db.Where("id IN ?", incidentIDs)
+	}
+	return db
+}
+
+// ListOpenIncidentStates returns the distinct current states of open incidents pinned to a version
+func (r *workflowRepository) ListOpenIncidentStates(ctx context.Context, workflowID uuid.UUID, version int, incidentIDs []uuid.UUID) ([]uuid.UUID, error) {
+	var stateIDs []uuid.UUID
+	err := openVersionIncidents(r.db.WithContext(ctx), workflowID, version, incidentIDs).
+		Distinct().
+		Pluck("current_state_id", &stateIDs).Error
+	return stateIDs, err
+}
+
+// ListOpenPinnedVersions returns the published versions that open incidents of a workflow are pinned to
+func (r *workflowRepository) ListOpenPinnedVersions(ctx context.Context, workflowID uuid.UUID) ([]int, error) {
+	var versions []int
+	err := r.db.WithContext(ctx).Model(&models.Incident{}).
+		Where("workflow_id = ? AND workflow_version > 0 AND closed_at IS NULL", workflowID).
+		Distinct().
+		Order("workflow_version").
+		Pluck("workflow_version", &versions).Error
+	return versions, err
+}
+
+// MigrateIncidents moves open incidents to another version, remapping their current state
+func (r *workflowRepository) MigrateIncidents(ctx context.Context, workflowID uuid.UUID, fromVersion, toVersion int, stateMapping map[uuid.UUID]uuid.UUID, incidentIDs []uuid.UUID) (int64, error) {
+	var migrated int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for fromState, toState := range stateMapping {
+			result := openVersionIncidents(tx, workflowID, fromVersion, incidentIDs).
+				Where("current_state_id = ?", fromState).
+				Updates(map[string]interface{}{
+					"workflow_version": toVersion,
+					"current_state_id": toState,
+					"updated_at":       time.Now(),
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			migrated += result.RowsAffected
+		}
+		return nil
+	})
+	return migrated, err
+}
@@ -0,0 +1,710 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/automax/backend/internal/models"
+)
+
+// Action conditions are small boolean expressions evaluated against the incident
+// before an action runs. Examples:
+//
+//	PRIORITY == CRITICAL
+//	classification under 'Hardware'
+//	channel == 'phone' and (SEVERITY in [HIGH, CRITICAL] or custom_field.vip == true)
+//
+// The left side of a comparison is a field, the right side a literal. Bare words on
+// the right side are treated as strings. Uppercase fields that are not built in are
+// lookup category codes and match either the value code or name. String comparisons
+// are case-insensitive.
+
+// conditionFields lists the built-in fields available to action conditions
+var conditionFields = map[string]bool{
+	"record_type":       true,
+	"channel":           true,
+	"state":             true,
+	"classification":    true,
+	"classification_id": true,
+	"department":        true,
+	"department_id":     true,
+	"location":          true,
+	"location_id":       true,
+	"assignee":          true,
+	"assignee_id":       true,
+	"reporter_email":    true,
+	"title":             true,
+	"description":       true,
+	"due_date":          true,
+	"sla_breached":      true,
+	"from_state":        true,
+	"to_state":          true,
+}
+
+// ActionCondition is a parsed action condition expression
+type ActionCondition struct {
+	root conditionNode
+}
+
+// ParseActionCondition parses a condition expression. An empty expression parses to nil.
+func ParseActionCondition(expr string) (*ActionCondition, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &conditionParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+	return &ActionCondition{root: node}, nil
+}
+
+// LookupCategories returns the lookup category codes the condition reads, so they can be
+// checked against the configured categories when the action is saved
+func (c *ActionCondition) LookupCategories() []string {
+	if c == nil {
+		return nil
+	}
+	var codes []string
+	seen := make(map[string]bool)
+	var walk func(node conditionNode)
+	walk = func(node conditionNode) {
+		switch n := node.(type) {
+		case *logicalNode:
+			walk(n.left)
+			walk(n.right)
+		case *notNode:
+			walk(n.inner)
+		case *comparisonNode:
+			if n.field.lookup != "" && !seen[n.field.lookup] {
+				seen[n.field.lookup] = true
+				codes = append(codes, n.field.lookup)
+			}
+		}
+	}
+	walk(c.root)
+	return codes
+}
+
+// Evaluate reports whether the condition holds for the template context. A nil condition always holds.
+func (c *ActionCondition) Evaluate(data *ActionTemplateContext) bool {
+	if c == nil {
+		return true
+	}
+	return c.root.eval(data)
+}
+
+// Tokenizer
+
+type conditionTokenKind int
+
+const (
+	tokIdent conditionTokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokComma
+)
+
+type conditionToken struct {
+	kind conditionTokenKind
+	text string
+	pos  int
+}
+
+func tokenizeCondition(expr string) ([]conditionToken, error) {
+	var tokens []conditionToken
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, conditionToken{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, conditionToken{tokRParen, ")", i})
+			i++
+		case r == '[':
+			tokens = append(tokens, conditionToken{tokLBracket, "[", i})
+			i++
+		case r == ']':
+			tokens = append(tokens, conditionToken{tokRBracket, "]", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, conditionToken{tokComma, ",", i})
+			i++
+		case r == '\'' || r == '"':
+			start := i
+			i++
+			var sb strings.Builder
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, conditionToken{tokString, sb.String(), start})
+		case strings.ContainsRune("=!<>&|", r):
+			start := i
+			op := string(r)
+			if i+1 < len(runes) {
+				two := string(runes[i : i+2])
+				switch two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = two
+				}
+			}
+			if op == "=" || op == "&" || op == "|" {
+				return nil, fmt.Errorf("unknown operator %q at position %d", op, start)
+			}
+			i += len(op)
+			tokens = append(tokens, conditionToken{tokOp, op, start})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, conditionToken{tokNumber, string(runes[start:i]), start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || strings.ContainsRune("_.-", runes[i])) {
+				i++
+			}
+			tokens = append(tokens, conditionToken{tokIdent, string(runes[start:i]), start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parser
+
+type conditionParser struct {
+	tokens []conditionToken
+	pos    int
+}
+
+func (p *conditionParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *conditionParser) peek() conditionToken {
+	if p.done() {
+		return conditionToken{pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() (conditionToken, error) {
+	if p.done() {
+		return conditionToken{}, fmt.Errorf("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+	return t, nil
+}
+
+// isKeyword reports whether the next token is the given operator or keyword
+func (p *conditionParser) isKeyword(words ...string) bool {
+	if p.done() {
+		return false
+	}
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return false
+	}
+	for _, w := range words {
+		if strings.EqualFold(t.text, w) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("||", "or") {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("&&", "and") {
+		p.pos++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseNot() (conditionNode, error) {
+	if p.isKeyword("!", "not") {
+		p.pos++
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *conditionParser) parsePrimary() (conditionNode, error) {
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.kind == tokLParen {
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, err := p.next(); err != nil || closing.kind != tokRParen {
+			return nil, fmt.Errorf("missing ')' for '(' at position %d", t.pos)
+		}
+		return node, nil
+	}
+
+	if t.kind != tokIdent {
+		return nil, fmt.Errorf("expected field name at position %d, got %q", t.pos, t.text)
+	}
+	field, err := parseConditionField(t.text)
+	if err != nil {
+		return nil, err
+	}
+
+	// A bare field checks that it has a value
+	if p.done() || p.isKeyword("&&", "||", "and", "or") || p.peek().kind == tokRParen {
+		return &comparisonNode{field: field, op: "exists"}, nil
+	}
+
+	negate := false
+	if p.isKeyword("not") {
+		p.pos++
+		negate = true
+		if !p.isKeyword("in", "contains", "under") {
+			return nil, fmt.Errorf("expected in, contains or under after 'not' at position %d", p.peek().pos)
+		}
+	}
+
+	opTok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opTok.text)
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=":
+		if opTok.kind != tokOp {
+			return nil, fmt.Errorf("unknown operator %q at position %d", opTok.text, opTok.pos)
+		}
+	case "in", "contains", "under":
+		if opTok.kind != tokIdent {
+			return nil, fmt.Errorf("unknown operator %q at position %d", opTok.text, opTok.pos)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operator %q at position %d", opTok.text, opTok.pos)
+	}
+	// The classification tree is the only hierarchy conditions can walk
+	if op == "under" && field.name != "classification" && field.name != "classification_id" {
+		return nil, fmt.Errorf("'under' only applies to classification and classification_id, not %q at position %d", t.text, t.pos)
+	}
+
+	var values []string
+	if op == "in" {
+		values, err = p.parseList()
+	} else {
+		var v string
+		v, err = p.parseValue()
+		values = []string{v}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	node := conditionNode(&comparisonNode{field: field, op: op, values: values})
+	if negate {
+		node = &notNode{inner: node}
+	}
+	return node, nil
+}
+
+func (p *conditionParser) parseValue() (string, error) {
+	t, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	switch t.kind {
+	case tokString, tokNumber, tokIdent:
+		return t.text, nil
+	}
+	return "", fmt.Errorf("expected value at position %d, got %q", t.pos, t.text)
+}
+
+func (p *conditionParser) parseList() ([]string, error) {
+	open, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if open.kind != tokLBracket {
+		return nil, fmt.Errorf("expected '[' at position %d", open.pos)
+	}
+
+	var values []string
+	for {
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+
+		t, err := p.next()
+		if err != nil {
+			return nil, fmt.Errorf("missing ']' for '[' at position %d", open.pos)
+		}
+		if t.kind == tokRBracket {
+			return values, nil
+		}
+		if t.kind != tokComma {
+			return nil, fmt.Errorf("expected ',' or ']' at position %d", t.pos)
+		}
+	}
+}
+
+// conditionField identifies what a condition reads from the incident
+type conditionField struct {
+	name        string // built-in field name
+	lookup      string // lookup category code
+	customField string // custom field key
+}
+
+func parseConditionField(text string) (conditionField, error) {
+	lower := strings.ToLower(text)
+	for _, prefix := range []string{"custom_field.", "custom_fields."} {
+		if strings.HasPrefix(lower, prefix) {
+			key := text[len(prefix):]
+			if key == "" {
+				return conditionField{}, fmt.Errorf("custom field key is required")
+			}
+			return conditionField{customField: key}, nil
+		}
+	}
+	if strings.HasPrefix(lower, "lookup.") {
+		return conditionField{lookup: strings.ToUpper(text[len("lookup."):])}, nil
+	}
+	if lower == "priority" || lower == "severity" {
+		return conditionField{lookup: strings.ToUpper(text)}, nil
+	}
+	if conditionFields[lower] {
+		return conditionField{name: lower}, nil
+	}
+	if text == strings.ToUpper(text) {
+		return conditionField{lookup: text}, nil
+	}
+	return conditionField{}, fmt.Errorf("unknown field %q", text)
+}
+
+// values returns every string the field matches against. Lookups and classifications
+// match on several representations (code, name, id).
+func (f conditionField) values(data *ActionTemplateContext) []string {
+	incident := data.incident
+
+	if f.lookup != "" {
+		var out []string
+		for _, lv := range incident.LookupValues {
+			if lv.Category != nil && strings.EqualFold(lv.Category.Code, f.lookup) {
+				out = append(out, lv.Code, lv.Name)
+			}
+		}
+		return out
+	}
+
+	if f.customField != "" {
+		v, ok := data.CustomFields[f.customField]
+		if !ok || v == nil {
+			return nil
+		}
+		if list, ok := v.([]interface{}); ok {
+			out := make([]string, 0, len(list))
+			for _, item := range list {
+				out = append(out, fmt.Sprint(item))
+			}
+			return out
+		}
+		return []string{fmt.Sprint(v)}
+	}
+
+	nonEmpty := func(values ...string) []string {
+		var out []string
+		for _, v := range values {
+			if v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+
+	switch f.name {
+	case "record_type":
+		return nonEmpty(incident.RecordType)
+	case "channel":
+		return nonEmpty(incident.Channel)
+	case "state":
+		if incident.CurrentState != nil {
+			return nonEmpty(incident.CurrentState.Code, incident.CurrentState.Name)
+		}
+	case "from_state":
+		if data.transition != nil && data.transition.FromState != nil {
+			return nonEmpty(data.transition.FromState.Code, data.transition.FromState.Name)
+		}
+	case "to_state":
+		if data.transition != nil && data.transition.ToState != nil {
+			return nonEmpty(data.transition.ToState.Code, data.transition.ToState.Name)
+		}
+	case "classification":
+		if incident.Classification != nil {
+			return nonEmpty(incident.Classification.Name, incident.Classification.ID.String())
+		}
+	case "classification_id":
+		if incident.ClassificationID != nil {
+			return []string{incident.ClassificationID.String()}
+		}
+	case "department":
+		if incident.Department != nil {
+			return nonEmpty(incident.Department.Code, incident.Department.Name, incident.Department.ID.String())
+		}
+	case "department_id":
+		if incident.DepartmentID != nil {
+			return []string{incident.DepartmentID.String()}
+		}
+	case "location":
+		if incident.Location != nil {
+			return nonEmpty(incident.Location.Code, incident.Location.Name, incident.Location.ID.String())
+		}
+	case "location_id":
+		if incident.LocationID != nil {
+			return []string{incident.LocationID.String()}
+		}
+	case "assignee":
+		if incident.Assignee != nil {
+			return nonEmpty(incident.Assignee.Username, incident.Assignee.Email, incident.Assignee.ID.String())
+		}
+	case "assignee_id":
+		if incident.AssigneeID != nil {
+			return []string{incident.AssigneeID.String()}
+		}
+	case "reporter_email":
+		return nonEmpty(incident.ReporterEmail)
+	case "title":
+		return nonEmpty(incident.Title)
+	case "description":
+		return nonEmpty(incident.Description)
+	case "due_date":
+		if incident.DueDate != nil {
+			return []string{incident.DueDate.Format(time.RFC3339)}
+		}
+	case "sla_breached":
+		return []string{strconv.FormatBool(incident.SLABreached)}
+	}
+	return nil
+}
+
+// Expression nodes
+
+type conditionNode interface {
+	eval(data *ActionTemplateContext) bool
+}
+
+type logicalNode struct {
+	or          bool
+	left, right conditionNode
+}
+
+func (n *logicalNode) eval(data *ActionTemplateContext) bool {
+	if n.or {
+		return n.left.eval(data) || n.right.eval(data)
+	}
+	return n.left.eval(data) && n.right.eval(data)
+}
+
+type notNode struct {
+	inner conditionNode
+}
+
+func (n *notNode) eval(data *ActionTemplateContext) bool {
+	return !n.inner.eval(data)
+}
+
+type comparisonNode struct {
+	field  conditionField
+	op     string
+	values []string
+}
+
+func (n *comparisonNode) eval(data *ActionTemplateContext) bool {
+	actual := n.field.values(data)
+
+	switch n.op {
+	case "exists":
+		for _, a := range actual {
+			if a != "" && a != "false" && a != "0" {
+				return true
+			}
+		}
+		return false
+	case "==", "in":
+		return anyEqualFold(actual, n.values)
+	case "!=":
+		return !anyEqualFold(actual, n.values)
+	case "contains":
+		for _, a := range actual {
+			if strings.Contains(strings.ToLower(a), strings.ToLower(n.values[0])) {
+				return true
+			}
+		}
+		return false
+	case "under":
+		return classificationUnder(data, n.values[0])
+	case "<", "<=", ">", ">=":
+		for _, a := range actual {
+			if cmp, ok := compareConditionValues(a, n.values[0]); ok {
+				switch n.op {
+				case "<":
+					return cmp < 0
+				case "<=":
+					return cmp <= 0
+				case ">":
+					return cmp > 0
+				case ">=":
+					return cmp >= 0
+				}
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func anyEqualFold(actual, expected []string) bool {
+	for _, a := range actual {
+		for _, e := range expected {
+			if strings.EqualFold(a, e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classificationUnder reports whether the incident's classification is the named
+// classification or one of its descendants. Matches by name or id.
+func classificationUnder(data *ActionTemplateContext, target string) bool {
+	incident := data.incident
+	if incident.Classification == nil {
+		return false
+	}
+
+	if data.ClassificationPath != "" {
+		for _, name := range strings.Split(data.ClassificationPath, " > ") {
+			if strings.EqualFold(name, target) {
+				return true
+			}
+		}
+	}
+	if strings.EqualFold(incident.Classification.Name, target) {
+		return true
+	}
+	for _, id := range strings.Split(incident.Classification.Path, "/") {
+		if id != "" && strings.EqualFold(id, target) {
+			return true
+		}
+	}
+	return strings.EqualFold(incident.Classification.ID.String(), target)
+}
+
+// compareConditionValues compares numerically, then as RFC3339 dates
+func compareConditionValues(a, b string) (int, bool) {
+	if af, err := strconv.ParseFloat(a, 64); err == nil {
+		if bf, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case af < bf:
+				return -1, true
+			case af > bf:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	if at, err := parseFieldTime(a); err == nil {
+		if bt, err := parseFieldTime(b); err == nil {
+			return at.Compare(bt), true
+		}
+	}
+	return 0, false
+}
+
+// newConditionContext builds a context for evaluating conditions without the
+// template renderer. Classification ancestors are matched by id only.
+func newConditionContext(incident *models.Incident, transition *models.WorkflowTransition) *ActionTemplateContext {
+	data := newTemplateContext(incident, transition, nil)
+	if incident.CustomFields != "" {
+		if err := json.Unmarshal([]byte(incident.CustomFields), &data.CustomFields); err != nil {
+			data.CustomFields = make(map[string]interface{})
+		}
+	}
+	return data
+}
+
+// evaluateActionCondition parses and evaluates an action's condition. Actions with
+// no condition always run.
+func evaluateActionCondition(action *models.TransitionAction, data *ActionTemplateContext) (bool, error) {
+	cond, err := ParseActionCondition(action.Condition)
+	if err != nil {
+		return false, err
+	}
+	return cond.Evaluate(data), nil
+}
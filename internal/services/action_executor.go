@@ -1,12 +1,12 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net/http"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,82 +15,326 @@ import (
 	"github.com/google/uuid"
 )
 
+// JobTypeTransitionAction is the job queue type for asynchronous transition actions
+const JobTypeTransitionAction = "transition_action"
+
 // ActionExecutor handles the execution of transition actions
 type ActionExecutor interface {
-	ExecuteActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error
-	ExecuteAction(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error
+	ExecuteActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User, historyID uuid.UUID) ([]models.TransitionActionResult, error)
+	ExecuteAction(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User, historyID uuid.UUID) error
+	PreviewActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) []models.TransitionActionPreview
 }
 
 type actionExecutor struct {
 	incidentRepo repository.IncidentRepository
 	userRepo     repository.UserRepository
-	httpClient   *http.Client
+	workflowRepo repository.WorkflowRepository
+	lookupRepo   repository.LookupRepository
+	jobQueue     JobQueue
+	mailService  MailService
+	notifier     NotificationService
+	webhooks     WebhookService
+	templates    ActionTemplateRenderer
+}
+
+// transitionActionJob is the payload of a queued asynchronous action
+type transitionActionJob struct {
+	ActionID      uuid.UUID `json:"action_id"`
+	IncidentID    uuid.UUID `json:"incident_id"`
+	TransitionID  uuid.UUID `json:"transition_id"`
+	PerformedByID uuid.UUID `json:"performed_by_id"`
+	HistoryID     uuid.UUID `json:"history_id"`
 }
 
-// NewActionExecutor creates a new action executor
-func NewActionExecutor(incidentRepo repository.IncidentRepository, userRepo repository.UserRepository) ActionExecutor {
-	return &actionExecutor{
+// NewActionExecutor creates a new action executor. Async actions are queued on jobQueue;
+// if jobQueue is nil they are executed inline like synchronous ones.
+func NewActionExecutor(incidentRepo repository.IncidentRepository, userRepo repository.UserRepository, workflowRepo repository.WorkflowRepository, lookupRepo repository.LookupRepository, jobQueue JobQueue, mailService MailService, notifier NotificationService, webhooks WebhookService, templates ActionTemplateRenderer) ActionExecutor {
+	e := &actionExecutor{
 		incidentRepo: incidentRepo,
 		userRepo:     userRepo,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		workflowRepo: workflowRepo,
+		lookupRepo:   lookupRepo,
+		jobQueue:     jobQueue,
+		mailService:  mailService,
+		notifier:     notifier,
+		webhooks:     webhooks,
+		templates:    templates,
 	}
+
+	if jobQueue != nil {
+		jobQueue.RegisterHandler(JobTypeTransitionAction, e.handleActionJob)
+	}
+
+	return e
 }
 
-// ExecuteActions executes all actions for a transition
-func (e *actionExecutor) ExecuteActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error {
-	if transition.Actions == nil || len(transition.Actions) == 0 {
-		return nil
+// ExecuteActions executes all active actions for a transition in execution order.
+// Synchronous actions run immediately, async actions are queued. The per-action
+// results are stored on the transition history record and returned.
+func (e *actionExecutor) ExecuteActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User, historyID uuid.UUID) ([]models.TransitionActionResult, error) {
+	if len(transition.Actions) == 0 {
+		return nil, nil
 	}
 
 	// Sort actions by execution order
 	actions := make([]models.TransitionAction, len(transition.Actions))
 	copy(actions, transition.Actions)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].ExecutionOrder < actions[j].ExecutionOrder
+	})
+
+	results := make([]models.TransitionActionResult, 0, len(actions))
+	var queued []models.TransitionAction
+	var retries []actionRetry
+
+	// Conditions are evaluated against the incident as it is at transition time,
+	// including for async actions. The context is only built when needed.
+	var conditionData *ActionTemplateContext
+
+	for i := range actions {
+		action := &actions[i]
+		if !action.IsActive {
+			continue
+		}
 
-	for i := 0; i < len(actions)-1; i++ {
-		for j := i + 1; j < len(actions); j++ {
-			if actions[i].ExecutionOrder > actions[j].ExecutionOrder {
-				actions[i], actions[j] = actions[j], actions[i]
+		result := models.TransitionActionResult{
+			ActionID:   action.ID,
+			ActionType: action.ActionType,
+			Name:       action.Name,
+			IsAsync:    action.IsAsync,
+		}
+
+		if action.Condition != "" {
+			if conditionData == nil {
+				conditionData = e.templates.BuildContext(ctx, incident, transition, performedBy)
+			}
+			ok, err := evaluateActionCondition(action, conditionData)
+			if err != nil {
+				log.Printf("Invalid condition on action %s: %v", action.Name, err)
+				result.Status = models.ActionResultFailed
+				result.Error = fmt.Sprintf("invalid condition: %v", err)
+				results = append(results, result)
+				continue
+			}
+			if !ok {
+				result.Status = models.ActionResultSkipped
+				results = append(results, result)
+				continue
 			}
 		}
-	}
 
-	for _, action := range actions {
-		if !action.IsActive {
+		if action.IsAsync && e.jobQueue != nil {
+			result.Status = models.ActionResultQueued
+			results = append(results, result)
+			queued = append(queued, *action)
 			continue
 		}
 
-		if action.IsAsync {
-			// Execute asynchronously
-			go func(act models.TransitionAction) {
-				if err := e.ExecuteAction(context.Background(), &act, incident, transition, performedBy); err != nil {
-					log.Printf("Async action execution failed: %v", err)
+		result.Attempts = 1
+		now := time.Now()
+		result.ExecutedAt = &now
+		if err := e.ExecuteAction(ctx, action, incident, transition, performedBy, historyID); err != nil {
+			// Continue with other actions even if one fails
+			log.Printf("Action execution failed: %v", err)
+			result.Status = models.ActionResultFailed
+			result.Error = err.Error()
+
+			// Webhooks that failed on a 5xx or network error are retried by the queue with backoff
+			if action.ActionType == "webhook" && !errors.Is(err, ErrWebhookRejected) && e.jobQueue != nil && actionMaxAttempts(action) > 1 {
+				result.Status = models.ActionResultRetrying
+				retries = append(retries, actionRetry{action: *action, err: err})
+			}
+		} else {
+			result.Status = models.ActionResultSuccess
+		}
+		results = append(results, result)
+	}
+
+	// Persist before queueing so async workers always find their entry
+	if historyID != uuid.Nil {
+		if err := e.incidentRepo.SetTransitionActionResults(ctx, historyID, results); err != nil {
+			log.Printf("Failed to store action results: %v", err)
+		}
+	}
+
+	payload := transitionActionJob{
+		IncidentID:   incident.ID,
+		TransitionID: transition.ID,
+		HistoryID:    historyID,
+	}
+	if performedBy != nil {
+		payload.PerformedByID = performedBy.ID
+	}
+	queueFailed := func(action *models.TransitionAction, err error) {
+		log.Printf("Failed to queue action %s: %v", action.Name, err)
+		for i := range results {
+			if results[i].ActionID == action.ID {
+				results[i].Status = models.ActionResultFailed
+				results[i].Error = fmt.Sprintf("failed to queue action: %v", err)
+				if historyID != uuid.Nil {
+					_ = e.incidentRepo.UpsertTransitionActionResult(ctx, historyID, results[i])
 				}
-			}(action)
+			}
+		}
+	}
+
+	for i := range queued {
+		payload.ActionID = queued[i].ID
+		if err := e.jobQueue.Enqueue(ctx, JobTypeTransitionAction, payload, actionMaxAttempts(&queued[i])); err != nil {
+			queueFailed(&queued[i], err)
+		}
+	}
+
+	// The inline execution counts as the first attempt
+	for i := range retries {
+		action := &retries[i].action
+		payload.ActionID = action.ID
+		if err := e.jobQueue.EnqueueRetry(ctx, JobTypeTransitionAction, payload, actionMaxAttempts(action), 1, retries[i].err); err != nil {
+			queueFailed(action, err)
+		}
+	}
+
+	return results, nil
+}
+
+// actionRetry is a synchronous action handed to the job queue after a retryable failure
+type actionRetry struct {
+	action models.TransitionAction
+	err    error
+}
+
+// handleActionJob executes a queued action and records its outcome. Besides async actions
+// these are retries of synchronous webhooks.
+func (e *actionExecutor) handleActionJob(ctx context.Context, job *Job) error {
+	var payload transitionActionJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		// Malformed payloads can never succeed, so don't retry them
+		log.Printf("Discarding invalid transition action job %s: %v", job.ID, err)
+		return nil
+	}
+
+	incident, err := e.incidentRepo.FindByIDWithRelations(ctx, payload.IncidentID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+
+	// Pinned incidents run the actions of their workflow version
+	transition, err := loadIncidentTransition(ctx, e.workflowRepo, incident, payload.TransitionID)
+	if err != nil {
+		return fmt.Errorf("failed to load transition: %w", err)
+	}
+
+	var action *models.TransitionAction
+	for i := range transition.Actions {
+		if transition.Actions[i].ID == payload.ActionID {
+			action = &transition.Actions[i]
+			break
+		}
+	}
+	if action == nil {
+		log.Printf("Transition action %s no longer exists, skipping job %s", payload.ActionID, job.ID)
+		return nil
+	}
+
+	var performedBy *models.User
+	if payload.PerformedByID != uuid.Nil {
+		performedBy, _ = e.userRepo.FindByID(ctx, payload.PerformedByID)
+	}
+
+	now := time.Now()
+	result := models.TransitionActionResult{
+		ActionID:   action.ID,
+		ActionType: action.ActionType,
+		Name:       action.Name,
+		IsAsync:    action.IsAsync,
+		Status:     models.ActionResultSuccess,
+		Attempts:   job.Attempt + 1,
+		ExecutedAt: &now,
+	}
+
+	execErr := e.ExecuteAction(ctx, action, incident, transition, performedBy, payload.HistoryID)
+	if execErr != nil {
+		result.Error = execErr.Error()
+		if job.IsLastAttempt() || errors.Is(execErr, ErrWebhookRejected) {
+			result.Status = models.ActionResultFailed
 		} else {
-			// Execute synchronously
-			if err := e.ExecuteAction(ctx, &action, incident, transition, performedBy); err != nil {
-				log.Printf("Action execution failed: %v", err)
-				// Continue with other actions even if one fails
+			result.Status = models.ActionResultRetrying
+		}
+	}
+
+	if payload.HistoryID != uuid.Nil {
+		if err := e.incidentRepo.UpsertTransitionActionResult(ctx, payload.HistoryID, result); err != nil {
+			log.Printf("Failed to store async action result: %v", err)
+		}
+	}
+
+	if errors.Is(execErr, ErrWebhookRejected) {
+		// Retrying would get the same answer
+		return nil
+	}
+	return execErr
+}
+
+// PreviewActions reports which actions of a transition would run, evaluating their
+// conditions the same way ExecuteActions does but without executing anything
+func (e *actionExecutor) PreviewActions(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) []models.TransitionActionPreview {
+	actions := make([]models.TransitionAction, len(transition.Actions))
+	copy(actions, transition.Actions)
+	sort.SliceStable(actions, func(i, j int) bool {
+		return actions[i].ExecutionOrder < actions[j].ExecutionOrder
+	})
+
+	previews := make([]models.TransitionActionPreview, 0, len(actions))
+	var conditionData *ActionTemplateContext
+
+	for i := range actions {
+		action := &actions[i]
+		if !action.IsActive {
+			continue
+		}
+
+		preview := models.TransitionActionPreview{
+			ActionID:   action.ID,
+			ActionType: action.ActionType,
+			Name:       action.Name,
+			IsAsync:    action.IsAsync,
+			WillRun:    true,
+		}
+
+		if action.Condition != "" {
+			if conditionData == nil {
+				conditionData = e.templates.BuildContext(ctx, incident, transition, performedBy)
+			}
+			ok, err := evaluateActionCondition(action, conditionData)
+			if err != nil {
+				preview.WillRun = false
+				preview.Reason = fmt.Sprintf("invalid condition: %v", err)
+			} else if !ok {
+				preview.WillRun = false
+				preview.Reason = "condition not met"
 			}
 		}
+
+		previews = append(previews, preview)
 	}
 
-	return nil
+	return previews
 }
 
-// ExecuteAction executes a single action
-func (e *actionExecutor) ExecuteAction(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error {
+// ExecuteAction executes a single action. historyID identifies the transition execution
+// and keeps webhook idempotency keys stable across retries.
+func (e *actionExecutor) ExecuteAction(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User, historyID uuid.UUID) error {
 	switch action.ActionType {
 	case "notification":
 		return e.executeNotification(ctx, action, incident, transition, performedBy)
 	case "email":
 		return e.executeEmail(ctx, action, incident, transition, performedBy)
 	case "webhook":
-		return e.executeWebhook(ctx, action, incident, transition, performedBy)
+		return e.executeWebhook(ctx, action, incident, transition, performedBy, historyID)
 	case "field_update":
-		return e.executeFieldUpdate(ctx, action, incident)
+		return e.executeFieldUpdate(ctx, action, incident, performedBy)
+	case "create_linked_record":
+		return e.executeCreateLinkedRecord(ctx, action, incident, transition, performedBy)
 	default:
 		return fmt.Errorf("unknown action type: %s", action.ActionType)
 	}
@@ -98,7 +342,7 @@ func (e *actionExecutor) ExecuteAction(ctx context.Context, action *models.Trans
 
 // NotificationConfig represents the configuration for a notification action
 type NotificationConfig struct {
-	Recipients []string `json:"recipients"` // "assignee", "reporter", "role:admin", "user:uuid"
+	Recipients []string `json:"recipients"` // "assignee", "assignees", "previous_assignee", "reporter", "department_manager", "role:code", "department:uuid", "user:uuid"
 	Title      string   `json:"title"`
 	Message    string   `json:"message"`
 }
@@ -113,24 +357,47 @@ func (e *actionExecutor) executeNotification(ctx context.Context, action *models
 	// Resolve recipients
 	recipientIDs := e.resolveRecipients(ctx, config.Recipients, incident)
 
-	// Replace placeholders in title and message
-	title := e.replacePlaceholders(config.Title, incident, transition, performedBy)
-	message := e.replacePlaceholders(config.Message, incident, transition, performedBy)
+	// Render title and message
+	data := e.templates.BuildContext(ctx, incident, transition, performedBy)
+	title, err := e.templates.Render("title", config.Title, false, data)
+	if err != nil {
+		return err
+	}
+	message, err := e.templates.Render("message", config.Message, false, data)
+	if err != nil {
+		return err
+	}
 
-	// Log the notification (in a real system, this would create notification records)
-	log.Printf("Notification: To=%v, Title=%s, Message=%s", recipientIDs, title, message)
+	if len(recipientIDs) == 0 {
+		log.Printf("Notification action %s skipped: no recipients resolved", action.Name)
+		return nil
+	}
+
+	if e.notifier == nil {
+		log.Printf("Notification: To=%v, Title=%s, Message=%s (notification service not configured)", recipientIDs, title, message)
+		return nil
+	}
 
-	// TODO: Create actual notification records in database
-	// For now, just log it
+	_, err = e.notifier.Notify(ctx, &CreateNotificationParams{
+		UserIDs:    recipientIDs,
+		Type:       models.NotificationTypeTransitionAction,
+		Title:      title,
+		Message:    message,
+		IncidentID: &incident.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create notifications: %w", err)
+	}
 	return nil
 }
 
 // EmailConfig represents the configuration for an email action
 type EmailConfig struct {
-	Recipients []string `json:"recipients"` // Same as notification
+	Recipients []string `json:"recipients"` // Same as notification, plus "email:address"
 	Subject    string   `json:"subject"`
 	Body       string   `json:"body"`
 	IsHTML     bool     `json:"is_html"`
+	TextBody   string   `json:"text_body"` // Optional plain-text alternative when is_html is set
 }
 
 // executeEmail sends email notifications
@@ -142,29 +409,82 @@ func (e *actionExecutor) executeEmail(ctx context.Context, action *models.Transi
 
 	// Resolve recipient emails
 	recipientEmails := e.resolveRecipientEmails(ctx, config.Recipients, incident)
+	if len(recipientEmails) == 0 {
+		log.Printf("Email action %s skipped: no recipients resolved", action.Name)
+		return nil
+	}
+
+	// Render subject and body; HTML bodies escape values by context
+	data := e.templates.BuildContext(ctx, incident, transition, performedBy)
+	subject, err := e.templates.Render("subject", config.Subject, false, data)
+	if err != nil {
+		return err
+	}
+	body, err := e.templates.Render("body", config.Body, config.IsHTML, data)
+	if err != nil {
+		return err
+	}
 
-	// Replace placeholders
-	subject := e.replacePlaceholders(config.Subject, incident, transition, performedBy)
-	body := e.replacePlaceholders(config.Body, incident, transition, performedBy)
+	params := &SendEmailParams{
+		To:                 recipientEmails,
+		Subject:            subject,
+		IncidentID:         &incident.ID,
+		TransitionActionID: &action.ID,
+	}
+	if config.IsHTML {
+		params.HTMLBody = body
+		if config.TextBody != "" {
+			params.TextBody, err = e.templates.Render("text_body", config.TextBody, false, data)
+			if err != nil {
+				return err
+			}
+		}
+	} else {
+		params.TextBody = body
+	}
 
-	// Log the email (in a real system, this would send actual emails)
-	log.Printf("Email: To=%v, Subject=%s, Body=%s", recipientEmails, subject, body)
+	if e.mailService == nil {
+		log.Printf("Email: To=%v, Subject=%s (mail service not configured)", recipientEmails, subject)
+		return nil
+	}
 
-	// TODO: Integrate with email service (SMTP, SendGrid, etc.)
-	// For now, just log it
-	return nil
+	_, err = e.mailService.Send(ctx, params)
+	return err
 }
 
 // WebhookConfig represents the configuration for a webhook action
 type WebhookConfig struct {
-	URL     string            `json:"url"`
-	Method  string            `json:"method"` // GET, POST, PUT
-	Headers map[string]string `json:"headers"`
-	Body    string            `json:"body"` // JSON template
+	URL        string            `json:"url"`
+	Method     string            `json:"method"` // GET, POST, PUT
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`        // JSON template
+	Secret     string            `json:"secret"`      // HMAC-SHA256 signing secret (optional)
+	MaxRetries int               `json:"max_retries"` // Queue retries on 5xx/network errors for async actions (default 3, -1 disables)
+}
+
+const defaultWebhookMaxRetries = 3
+
+// actionMaxAttempts returns how often the job queue runs an async action. Webhooks take it
+// from their max_retries; other actions use the queue default.
+func actionMaxAttempts(action *models.TransitionAction) int {
+	if action.ActionType != "webhook" {
+		return 0
+	}
+	var config WebhookConfig
+	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+		return 0
+	}
+	switch {
+	case config.MaxRetries < 0:
+		return 1
+	case config.MaxRetries == 0:
+		return defaultWebhookMaxRetries + 1
+	}
+	return config.MaxRetries + 1
 }
 
 // executeWebhook calls an external webhook
-func (e *actionExecutor) executeWebhook(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error {
+func (e *actionExecutor) executeWebhook(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User, historyID uuid.UUID) error {
 	var config WebhookConfig
 	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
 		return fmt.Errorf("invalid webhook config: %w", err)
@@ -174,218 +494,157 @@ func (e *actionExecutor) executeWebhook(ctx context.Context, action *models.Tran
 		config.Method = "POST"
 	}
 
-	// Replace placeholders in body
-	body := e.replacePlaceholders(config.Body, incident, transition, performedBy)
-
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, config.Method, config.URL, bytes.NewBufferString(body))
+	// Render body
+	data := e.templates.BuildContext(ctx, incident, transition, performedBy)
+	body, err := e.templates.Render("body", config.Body, false, data)
 	if err != nil {
-		return fmt.Errorf("failed to create webhook request: %w", err)
+		return err
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	for key, value := range config.Headers {
-		req.Header.Set(key, value)
+	// The same transition execution always produces the same key, so receivers
+	// can discard duplicates caused by retries
+	idempotencyKey := uuid.New().String()
+	if historyID != uuid.Nil {
+		idempotencyKey = uuid.NewSHA1(historyID, action.ID[:]).String()
 	}
 
-	// Execute request
-	resp, err := e.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("webhook request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode >= 400 {
-		return fmt.Errorf("webhook returned error status: %d", resp.StatusCode)
-	}
-
-	log.Printf("Webhook executed: URL=%s, Status=%d", config.URL, resp.StatusCode)
-	return nil
+	incidentID := incident.ID
+	actionID := action.ID
+	_, err = e.webhooks.Send(ctx, &SendWebhookParams{
+		URL:                config.URL,
+		Method:             config.Method,
+		Headers:            config.Headers,
+		Body:               body,
+		Secret:             config.Secret,
+		IdempotencyKey:     idempotencyKey,
+		IncidentID:         &incidentID,
+		TransitionActionID: &actionID,
+	})
+	return err
 }
 
-// FieldUpdateConfig represents the configuration for a field update action
-type FieldUpdateConfig struct {
-	Field string      `json:"field"` // priority, severity, assignee_id, etc.
-	Value interface{} `json:"value"`
-}
-
-// executeFieldUpdate updates incident fields
-func (e *actionExecutor) executeFieldUpdate(ctx context.Context, action *models.TransitionAction, incident *models.Incident) error {
-	var config FieldUpdateConfig
-	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
-		return fmt.Errorf("invalid field update config: %w", err)
-	}
-
-	// Update the field based on config
-	updates := make(map[string]interface{})
-
-	switch config.Field {
-	case "assignee_id":
-		if v, ok := config.Value.(string); ok {
-			if v == "" || v == "null" {
-				updates["assignee_id"] = nil
-			} else {
-				if uid, err := uuid.Parse(v); err == nil {
-					updates["assignee_id"] = uid
-				}
-			}
-		}
-	case "department_id":
-		if v, ok := config.Value.(string); ok {
-			if v == "" || v == "null" {
-				updates["department_id"] = nil
-			} else {
-				if uid, err := uuid.Parse(v); err == nil {
-					updates["department_id"] = uid
-				}
-			}
-		}
-	default:
-		return fmt.Errorf("unsupported field for update: %s", config.Field)
-	}
-
-	if len(updates) > 0 {
-		if err := e.incidentRepo.UpdateFields(ctx, incident.ID, updates); err != nil {
-			return fmt.Errorf("failed to update field: %w", err)
-		}
-		log.Printf("Field updated: Incident=%s, Field=%s, Value=%v", incident.IncidentNumber, config.Field, config.Value)
-	}
-
-	return nil
-}
-
-// resolveRecipients resolves recipient identifiers to user IDs
-func (e *actionExecutor) resolveRecipients(ctx context.Context, recipients []string, incident *models.Incident) []uuid.UUID {
-	var userIDs []uuid.UUID
-	seen := make(map[uuid.UUID]bool)
+// resolveRecipientUsers resolves recipient identifiers to active users. Supported forms:
+// "assignee", "assignees", "previous_assignee", "reporter", "department_manager",
+// "user:<id>", "role:<code>" and "department:<id>".
+func (e *actionExecutor) resolveRecipientUsers(ctx context.Context, recipients []string, incident *models.Incident) []models.User {
+	var ids []uuid.UUID
+	var users []models.User
 
 	for _, recipient := range recipients {
 		switch {
 		case recipient == "assignee":
-			if incident.AssigneeID != nil && !seen[*incident.AssigneeID] {
-				userIDs = append(userIDs, *incident.AssigneeID)
-				seen[*incident.AssigneeID] = true
+			if incident.AssigneeID != nil {
+				ids = append(ids, *incident.AssigneeID)
 			}
-		case recipient == "reporter":
-			if incident.ReporterID != nil && !seen[*incident.ReporterID] {
-				userIDs = append(userIDs, *incident.ReporterID)
-				seen[*incident.ReporterID] = true
+		case recipient == "assignees":
+			for _, assignee := range incident.Assignees {
+				ids = append(ids, assignee.ID)
 			}
-		case strings.HasPrefix(recipient, "user:"):
-			if uid, err := uuid.Parse(strings.TrimPrefix(recipient, "user:")); err == nil && !seen[uid] {
-				userIDs = append(userIDs, uid)
-				seen[uid] = true
+			if len(incident.Assignees) == 0 && incident.AssigneeID != nil {
+				ids = append(ids, *incident.AssigneeID)
 			}
-		case strings.HasPrefix(recipient, "role:"):
-			// TODO: Fetch users with specific role
-			roleName := strings.TrimPrefix(recipient, "role:")
-			log.Printf("Would notify users with role: %s", roleName)
-		}
-	}
-
-	return userIDs
-}
-
-// resolveRecipientEmails resolves recipient identifiers to email addresses
-func (e *actionExecutor) resolveRecipientEmails(ctx context.Context, recipients []string, incident *models.Incident) []string {
-	var emails []string
-	seen := make(map[string]bool)
-
-	for _, recipient := range recipients {
-		switch {
-		case recipient == "assignee":
-			if incident.Assignee != nil && incident.Assignee.Email != "" && !seen[incident.Assignee.Email] {
-				emails = append(emails, incident.Assignee.Email)
-				seen[incident.Assignee.Email] = true
+		case recipient == "previous_assignee":
+			if incident.PreviousAssigneeID != nil {
+				ids = append(ids, *incident.PreviousAssigneeID)
 			}
 		case recipient == "reporter":
-			if incident.Reporter != nil && incident.Reporter.Email != "" && !seen[incident.Reporter.Email] {
-				emails = append(emails, incident.Reporter.Email)
-				seen[incident.Reporter.Email] = true
-			} else if incident.ReporterEmail != "" && !seen[incident.ReporterEmail] {
-				emails = append(emails, incident.ReporterEmail)
-				seen[incident.ReporterEmail] = true
+			if incident.ReporterID != nil {
+				ids = append(ids, *incident.ReporterID)
 			}
-		case strings.HasPrefix(recipient, "email:"):
-			email := strings.TrimPrefix(recipient, "email:")
-			if !seen[email] {
-				emails = append(emails, email)
-				seen[email] = true
+		case recipient == "department_manager":
+			if incident.Department != nil && incident.Department.ManagerID != nil {
+				ids = append(ids, *incident.Department.ManagerID)
 			}
 		case strings.HasPrefix(recipient, "user:"):
 			if uid, err := uuid.Parse(strings.TrimPrefix(recipient, "user:")); err == nil {
-				user, err := e.userRepo.FindByID(ctx, uid)
-				if err == nil && user.Email != "" && !seen[user.Email] {
-					emails = append(emails, user.Email)
-					seen[user.Email] = true
-				}
+				ids = append(ids, uid)
+			}
+		case strings.HasPrefix(recipient, "role:"):
+			roleCode := strings.TrimPrefix(recipient, "role:")
+			roleUsers, err := e.userRepo.FindActiveByRoleCode(ctx, roleCode)
+			if err != nil {
+				log.Printf("Failed to resolve recipients for role %s: %v", roleCode, err)
+				continue
 			}
+			users = append(users, roleUsers...)
+		case strings.HasPrefix(recipient, "department:"):
+			deptID, err := uuid.Parse(strings.TrimPrefix(recipient, "department:"))
+			if err != nil {
+				log.Printf("Invalid department recipient: %s", recipient)
+				continue
+			}
+			deptUsers, err := e.userRepo.FindActiveByDepartment(ctx, deptID)
+			if err != nil {
+				log.Printf("Failed to resolve recipients for department %s: %v", deptID, err)
+				continue
+			}
+			users = append(users, deptUsers...)
 		}
 	}
 
-	return emails
-}
-
-// replacePlaceholders replaces template placeholders with actual values
-func (e *actionExecutor) replacePlaceholders(template string, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) string {
-	replacements := map[string]string{
-		"{{incident_number}}": incident.IncidentNumber,
-		"{{incident_title}}":  incident.Title,
-		"{{incident_id}}":     incident.ID.String(),
+	if len(ids) > 0 {
+		directUsers, err := e.userRepo.FindActiveByIDs(ctx, ids)
+		if err != nil {
+			log.Printf("Failed to resolve recipient users: %v", err)
+		} else {
+			users = append(users, directUsers...)
+		}
 	}
 
-	// Dynamic placeholders for lookup values
-	priority := "N/A"
-	severity := "N/A"
-	for _, lv := range incident.LookupValues {
-		if lv.Category == nil {
+	// Deduplicate while keeping the first occurrence
+	seen := make(map[uuid.UUID]bool)
+	unique := make([]models.User, 0, len(users))
+	for _, user := range users {
+		if seen[user.ID] {
 			continue
 		}
-		if lv.Category.Code == "PRIORITY" {
-			priority = lv.Name
-		}
-		if lv.Category.Code == "SEVERITY" {
-			severity = lv.Name
-		}
+		seen[user.ID] = true
+		unique = append(unique, user)
 	}
-	replacements["{{priority}}"] = priority
-	replacements["{{severity}}"] = severity
 
-	if transition != nil {
-		replacements["{{transition_name}}"] = transition.Name
-		if transition.FromState != nil {
-			replacements["{{from_state}}"] = transition.FromState.Name
-		}
-		if transition.ToState != nil {
-			replacements["{{to_state}}"] = transition.ToState.Name
-		}
-	}
+	return unique
+}
 
-	if performedBy != nil {
-		replacements["{{performed_by}}"] = performedBy.Username
-		if performedBy.FirstName != "" {
-			replacements["{{performed_by}}"] = performedBy.FirstName + " " + performedBy.LastName
-		}
+// resolveRecipients resolves recipient identifiers to user IDs
+func (e *actionExecutor) resolveRecipients(ctx context.Context, recipients []string, incident *models.Incident) []uuid.UUID {
+	users := e.resolveRecipientUsers(ctx, recipients, incident)
+
+	userIDs := make([]uuid.UUID, 0, len(users))
+	for _, user := range users {
+		userIDs = append(userIDs, user.ID)
 	}
 
-	if incident.Assignee != nil {
-		replacements["{{assignee}}"] = incident.Assignee.Username
-		if incident.Assignee.FirstName != "" {
-			replacements["{{assignee}}"] = incident.Assignee.FirstName + " " + incident.Assignee.LastName
+	return userIDs
+}
+
+// resolveRecipientEmails resolves recipient identifiers to email addresses. In addition to
+// the user recipients it accepts "email:<address>" and falls back to the reporter email
+// for reporters without an account.
+func (e *actionExecutor) resolveRecipientEmails(ctx context.Context, recipients []string, incident *models.Incident) []string {
+	var emails []string
+	seen := make(map[string]bool)
+
+	addEmail := func(email string) {
+		if email != "" && !seen[email] {
+			emails = append(emails, email)
+			seen[email] = true
 		}
-	} else {
-		replacements["{{assignee}}"] = "Unassigned"
 	}
 
-	if incident.CurrentState != nil {
-		replacements["{{current_state}}"] = incident.CurrentState.Name
+	for _, user := range e.resolveRecipientUsers(ctx, recipients, incident) {
+		addEmail(user.Email)
 	}
 
-	result := template
-	for placeholder, value := range replacements {
-		result = strings.ReplaceAll(result, placeholder, value)
+	for _, recipient := range recipients {
+		switch {
+		case recipient == "reporter":
+			if incident.ReporterID == nil {
+				addEmail(incident.ReporterEmail)
+			}
+		case strings.HasPrefix(recipient, "email:"):
+			addEmail(strings.TrimPrefix(recipient, "email:"))
+		}
 	}
 
-	return result
+	return emails
 }
@@ -0,0 +1,422 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// FieldUpdateConfig represents the configuration for a field update action.
+//
+// Supported fields:
+//   - assignee_id, department_id, location_id, classification_id: UUID, or "" to clear
+//   - due_date: RFC3339 / YYYY-MM-DD date, or a computed value such as "now + 2 days"
+//   - title_prefix, description_prefix: text prepended once to the title/description
+//   - lookup:<CATEGORY_CODE> (or priority/severity): lookup value code, e.g. "HIGH"
+//   - custom_field:<key>: any JSON value, null removes the key; computed dates are allowed
+type FieldUpdateConfig struct {
+	Field string      `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// computedTimePattern matches computed date values like "now", "now + 2 days", "now - 3 hours"
+var computedTimePattern = regexp.MustCompile(`(?i)^\s*now\s*(?:([+-])\s*(\d+)\s*(minute|hour|day|week|month|year)s?)?\s*$`)
+
+// isComputedTime reports whether the value is a computed date expression
+func isComputedTime(value string) bool {
+	return computedTimePattern.MatchString(value)
+}
+
+// resolveComputedTime evaluates a computed date expression relative to now
+func resolveComputedTime(value string, now time.Time) (time.Time, error) {
+	m := computedTimePattern.FindStringSubmatch(value)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("invalid computed date: %s", value)
+	}
+	if m[1] == "" {
+		return now, nil
+	}
+
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return time.Time{}, err
+	}
+	if m[1] == "-" {
+		n = -n
+	}
+
+	switch strings.ToLower(m[3]) {
+	case "minute":
+		return now.Add(time.Duration(n) * time.Minute), nil
+	case "hour":
+		return now.Add(time.Duration(n) * time.Hour), nil
+	case "day":
+		return now.AddDate(0, 0, n), nil
+	case "week":
+		return now.AddDate(0, 0, 7*n), nil
+	case "month":
+		return now.AddDate(0, n, 0), nil
+	default:
+		return now.AddDate(n, 0, 0), nil
+	}
+}
+
+// parseFieldTime parses a date value: computed expression, RFC3339 or YYYY-MM-DD
+func parseFieldTime(value string) (time.Time, error) {
+	if isComputedTime(value) {
+		return resolveComputedTime(value, time.Now())
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date value: %s", value)
+}
+
+// fieldUpdateTarget normalizes the configured field name. Legacy priority/severity map to lookups.
+func fieldUpdateTarget(field string) string {
+	switch field {
+	case "priority", "severity":
+		return "lookup:" + strings.ToUpper(field)
+	}
+	return field
+}
+
+// validateFieldUpdateConfig checks a field update configuration without touching the database
+func validateFieldUpdateConfig(config *FieldUpdateConfig) error {
+	field := fieldUpdateTarget(config.Field)
+
+	switch {
+	case field == "assignee_id", field == "department_id", field == "location_id", field == "classification_id":
+		v, ok := config.Value.(string)
+		if !ok && config.Value != nil {
+			return fmt.Errorf("%s must be a UUID string", field)
+		}
+		if v != "" && v != "null" {
+			if _, err := uuid.Parse(v); err != nil {
+				return fmt.Errorf("%s must be a UUID: %w", field, err)
+			}
+		}
+	case field == "due_date":
+		v, ok := config.Value.(string)
+		if !ok && config.Value != nil {
+			return fmt.Errorf("due_date must be a string")
+		}
+		if v != "" && v != "null" {
+			if _, err := parseFieldTime(v); err != nil {
+				return err
+			}
+		}
+	case field == "title_prefix", field == "description_prefix":
+		if v, ok := config.Value.(string); !ok || v == "" {
+			return fmt.Errorf("%s must be a non-empty string", field)
+		}
+	case strings.HasPrefix(field, "lookup:"):
+		if strings.TrimPrefix(field, "lookup:") == "" {
+			return fmt.Errorf("lookup category code is required")
+		}
+		if v, ok := config.Value.(string); !ok || v == "" {
+			return fmt.Errorf("%s value must be a lookup value code", field)
+		}
+	case strings.HasPrefix(field, "custom_field:"):
+		if strings.TrimPrefix(field, "custom_field:") == "" {
+			return fmt.Errorf("custom field key is required")
+		}
+	default:
+		return fmt.Errorf("unsupported field for update: %s", config.Field)
+	}
+
+	return nil
+}
+
+// executeFieldUpdate updates an incident field and records the change as a revision
+func (e *actionExecutor) executeFieldUpdate(ctx context.Context, action *models.TransitionAction, incident *models.Incident, performedBy *models.User) error {
+	var config FieldUpdateConfig
+	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+		return fmt.Errorf("invalid field update config: %w", err)
+	}
+	if err := validateFieldUpdateConfig(&config); err != nil {
+		return err
+	}
+
+	// Work on fresh data so earlier actions in the same transition are taken into account
+	incident, err := e.incidentRepo.FindByIDWithRelations(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to load incident: %w", err)
+	}
+
+	field := fieldUpdateTarget(config.Field)
+	strValue, _ := config.Value.(string)
+	clearValue := config.Value == nil || strValue == "" || strValue == "null"
+
+	// Update the field based on config
+	updates := make(map[string]interface{})
+
+	switch {
+	case field == "assignee_id", field == "department_id", field == "location_id", field == "classification_id":
+		if clearValue {
+			updates[field] = nil
+		} else {
+			id, _ := uuid.Parse(strValue)
+			updates[field] = id
+		}
+		if field == "assignee_id" && incident.AssigneeID != nil {
+			updates["previous_assignee_id"] = *incident.AssigneeID
+		}
+	case field == "due_date":
+		if clearValue {
+			updates["due_date"] = nil
+		} else {
+			dueDate, err := parseFieldTime(strValue)
+			if err != nil {
+				return err
+			}
+			updates["due_date"] = dueDate
+		}
+	case field == "title_prefix":
+		if !strings.HasPrefix(incident.Title, strValue) {
+			updates["title"] = strValue + incident.Title
+		}
+	case field == "description_prefix":
+		if !strings.HasPrefix(incident.Description, strValue) {
+			updates["description"] = strValue + incident.Description
+		}
+	case strings.HasPrefix(field, "lookup:"):
+		if err := e.updateLookupValue(ctx, incident, strings.TrimPrefix(field, "lookup:"), strValue); err != nil {
+			return err
+		}
+	case strings.HasPrefix(field, "custom_field:"):
+		customFields, err := e.updatedCustomFields(incident, strings.TrimPrefix(field, "custom_field:"), config.Value)
+		if err != nil {
+			return err
+		}
+		updates["custom_fields"] = customFields
+	}
+
+	if len(updates) > 0 {
+		if err := e.incidentRepo.UpdateFields(ctx, incident.ID, updates); err != nil {
+			return fmt.Errorf("failed to update field: %w", err)
+		}
+	}
+
+	// Compare against the reloaded incident so the revision shows display values
+	updated, err := e.incidentRepo.FindByIDWithRelations(ctx, incident.ID)
+	if err != nil {
+		return fmt.Errorf("failed to reload incident: %w", err)
+	}
+
+	changeField, label := fieldUpdateRevisionField(field)
+	oldVal := fieldDisplayValue(incident, changeField)
+	newVal := fieldDisplayValue(updated, changeField)
+	if oldVal == newVal {
+		return nil
+	}
+
+	log.Printf("Field updated: Incident=%s, Field=%s, Value=%v", incident.IncidentNumber, config.Field, config.Value)
+	e.recordFieldChange(ctx, incident.ID, action, changeField, label, oldVal, newVal, performedBy)
+	return nil
+}
+
+// updateLookupValue replaces the incident's value for one lookup category
+func (e *actionExecutor) updateLookupValue(ctx context.Context, incident *models.Incident, categoryCode, valueCode string) error {
+	values, err := e.lookupRepo.ListValuesByCategoryCode(ctx, categoryCode)
+	if err != nil {
+		return fmt.Errorf("failed to load lookup values for %s: %w", categoryCode, err)
+	}
+
+	var selected *models.LookupValue
+	for i := range values {
+		if strings.EqualFold(values[i].Code, valueCode) || strings.EqualFold(values[i].Name, valueCode) {
+			selected = &values[i]
+			break
+		}
+	}
+	if selected == nil {
+		return fmt.Errorf("lookup value %s not found in category %s", valueCode, categoryCode)
+	}
+
+	newValues := []models.LookupValue{*selected}
+	for _, lv := range incident.LookupValues {
+		if lv.Category != nil && strings.EqualFold(lv.Category.Code, categoryCode) {
+			continue
+		}
+		newValues = append(newValues, lv)
+	}
+
+	return e.incidentRepo.SetLookupValues(ctx, incident.ID, newValues)
+}
+
+// updatedCustomFields returns the incident's custom fields JSON with one key set or removed
+func (e *actionExecutor) updatedCustomFields(incident *models.Incident, key string, value interface{}) (string, error) {
+	fields := make(map[string]interface{})
+	if incident.CustomFields != "" {
+		if err := json.Unmarshal([]byte(incident.CustomFields), &fields); err != nil {
+			return "", fmt.Errorf("invalid custom fields on incident: %w", err)
+		}
+	}
+
+	if value == nil {
+		delete(fields, key)
+	} else {
+		if s, ok := value.(string); ok && isComputedTime(s) {
+			t, err := resolveComputedTime(s, time.Now())
+			if err != nil {
+				return "", err
+			}
+			value = t.Format(time.RFC3339)
+		}
+		fields[key] = value
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// recordFieldChange stores an automated field change as an incident revision. Changes
+// without a performing user (e.g. from background jobs) are attributed to the system user.
+func (e *actionExecutor) recordFieldChange(ctx context.Context, incidentID uuid.UUID, action *models.TransitionAction, field, label, oldVal, newVal string, performedBy *models.User) {
+	if performedBy == nil {
+		systemUser, err := e.userRepo.FindByEmail(ctx, models.SystemUserEmail)
+		if err != nil {
+			log.Printf("Skipping revision for field update %s: system user not found: %v", action.Name, err)
+			return
+		}
+		performedBy = systemUser
+	}
+
+	changes := []models.IncidentFieldChange{{
+		FieldName:  field,
+		FieldLabel: label,
+		OldValue:   &oldVal,
+		NewValue:   &newVal,
+	}}
+	description := fmt.Sprintf("%s changed from %s to %s by action %s", label, displayOrNone(oldVal), displayOrNone(newVal), action.Name)
+	e.createRevision(ctx, incidentID, models.RevisionActionFieldChange, description, changes, performedBy.ID)
+}
+
+// createRevision stores an incident revision for a change made by an action
+func (e *actionExecutor) createRevision(ctx context.Context, incidentID uuid.UUID, actionType models.IncidentRevisionActionType, description string, changes []models.IncidentFieldChange, userID uuid.UUID) {
+	var changesJSON string
+	if len(changes) > 0 {
+		b, err := json.Marshal(changes)
+		if err != nil {
+			return
+		}
+		changesJSON = string(b)
+	}
+
+	revNum, err := e.incidentRepo.GetNextRevisionNumber(ctx, incidentID)
+	if err != nil {
+		log.Printf("Failed to get revision number: %v", err)
+		return
+	}
+
+	revision := &models.IncidentRevision{
+		IncidentID:        incidentID,
+		RevisionNumber:    revNum,
+		ActionType:        actionType,
+		ActionDescription: description,
+		Changes:           changesJSON,
+		PerformedByID:     userID,
+		CreatedAt:         time.Now(),
+	}
+	if err := e.incidentRepo.CreateRevision(ctx, revision); err != nil {
+		log.Printf("Failed to create revision: %v", err)
+	}
+}
+
+// fieldUpdateRevisionField maps an update target to the revision field name and label
+func fieldUpdateRevisionField(field string) (string, string) {
+	switch {
+	case field == "assignee_id":
+		return field, "Assigned To"
+	case field == "department_id":
+		return field, "Department"
+	case field == "location_id":
+		return field, "Location"
+	case field == "classification_id":
+		return field, "Classification"
+	case field == "due_date":
+		return field, "Due Date"
+	case field == "title_prefix":
+		return "title", "Title"
+	case field == "description_prefix":
+		return "description", "Description"
+	case strings.HasPrefix(field, "lookup:"):
+		return field, strings.TrimPrefix(field, "lookup:")
+	case strings.HasPrefix(field, "custom_field:"):
+		return field, strings.TrimPrefix(field, "custom_field:")
+	}
+	return field, field
+}
+
+// fieldDisplayValue returns a human readable value of an incident field
+func fieldDisplayValue(incident *models.Incident, field string) string {
+	switch {
+	case field == "assignee_id":
+		if incident.Assignee != nil {
+			return userDisplayName(incident.Assignee)
+		}
+	case field == "department_id":
+		if incident.Department != nil {
+			return incident.Department.Name
+		}
+	case field == "location_id":
+		if incident.Location != nil {
+			return incident.Location.Name
+		}
+	case field == "classification_id":
+		if incident.Classification != nil {
+			return incident.Classification.Name
+		}
+	case field == "due_date":
+		if incident.DueDate != nil {
+			return incident.DueDate.Format(time.RFC3339)
+		}
+	case field == "title":
+		return incident.Title
+	case field == "description":
+		return incident.Description
+	case strings.HasPrefix(field, "lookup:"):
+		code := strings.TrimPrefix(field, "lookup:")
+		var names []string
+		for _, lv := range incident.LookupValues {
+			if lv.Category != nil && strings.EqualFold(lv.Category.Code, code) {
+				names = append(names, lv.Name)
+			}
+		}
+		return strings.Join(names, ", ")
+	case strings.HasPrefix(field, "custom_field:"):
+		fields := make(map[string]interface{})
+		if incident.CustomFields != "" && json.Unmarshal([]byte(incident.CustomFields), &fields) == nil {
+			if v, ok := fields[strings.TrimPrefix(field, "custom_field:")]; ok {
+				if s, ok := v.(string); ok {
+					return s
+				}
+				b, _ := json.Marshal(v)
+				return string(b)
+			}
+		}
+	}
+	return ""
+}
+
+func displayOrNone(value string) string {
+	if value == "" {
+		return "None"
+	}
+	return value
+}
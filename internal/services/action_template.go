@@ -0,0 +1,521 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// ActionTemplateContext is the data available to action templates (notification
+// titles and messages, email subjects and bodies, webhook bodies).
+//
+// Besides response views of the records (.Incident, .Transition, .PerformedBy) it
+// exposes flattened, nil-safe values for the most common fields, e.g.
+//
+//	{{.Incident.IncidentNumber}} {{.Lookups.PRIORITY}} {{index .CustomFields "plate_no"}}
+//	{{if .SLADeadline}}Due {{formatDate "02 Jan 2006 15:04" .SLADeadline}}{{end}}
+//	{{range .Assignees}}{{.}}, {{end}}
+//
+// The views are the API response types, so templates never see password hashes,
+// webhook secrets or other fields the API hides.
+type ActionTemplateContext struct {
+	Incident    *models.IncidentResponse
+	Transition  *models.WorkflowTransitionResponse
+	PerformedBy *models.UserResponse
+
+	// Models behind the views, for condition evaluation only
+	incident   *models.Incident
+	transition *models.WorkflowTransition
+
+	URL                string
+	State              string
+	Priority           string
+	Severity           string
+	Lookups            map[string]string   // Category code -> value name
+	LookupValues       map[string][]string // Category code -> all value names
+	CustomFields       map[string]interface{}
+	Location           string
+	Department         string
+	Classification     string
+	ClassificationPath string // e.g. "Infrastructure > Roads > Potholes"
+	SLADeadline        *time.Time
+	DueDate            *time.Time
+	AssigneeName       string
+	Assignees          []string
+	ReporterName       string
+	TransitionName     string
+	FromState          string
+	ToState            string
+	PerformedByName    string
+	Now                time.Time
+}
+
+// ActionValidationError reports an invalid transition action configuration
+type ActionValidationError struct {
+	Action string
+	Field  string
+	Err    error
+}
+
+func (e *ActionValidationError) Error() string {
+	return fmt.Sprintf("action %q: invalid %s: %v", e.Action, e.Field, e.Err)
+}
+
+func (e *ActionValidationError) Unwrap() error {
+	return e.Err
+}
+
+// legacyPlaceholderPattern matches the original fixed {{token}} placeholders
+var legacyPlaceholderPattern = regexp.MustCompile(`\{\{\s*(incident_number|incident_title|incident_id|priority|severity|transition_name|from_state|to_state|performed_by|assignee|current_state)\s*\}\}`)
+
+var legacyPlaceholders = map[string]string{
+	"incident_number": "{{.Incident.IncidentNumber}}",
+	"incident_title":  "{{.Incident.Title}}",
+	"incident_id":     "{{.Incident.ID}}",
+	"priority":        `{{default "N/A" .Priority}}`,
+	"severity":        `{{default "N/A" .Severity}}`,
+	"transition_name": "{{.TransitionName}}",
+	"from_state":      "{{.FromState}}",
+	"to_state":        "{{.ToState}}",
+	"performed_by":    "{{.PerformedByName}}",
+	"assignee":        "{{.AssigneeName}}",
+	"current_state":   "{{.State}}",
+}
+
+// templateFuncs are the helpers available in action templates
+var templateFuncs = map[string]interface{}{
+	"formatDate": formatTemplateDate,
+	"now":        time.Now,
+	"addDays": func(days int, t interface{}) time.Time {
+		return templateTime(t).AddDate(0, 0, days)
+	},
+	"addHours": func(hours int, t interface{}) time.Time {
+		return templateTime(t).Add(time.Duration(hours) * time.Hour)
+	},
+	"until": func(t interface{}) string {
+		return templateTime(t).Sub(time.Now()).Round(time.Minute).String()
+	},
+	"since": func(t interface{}) string {
+		return time.Since(templateTime(t)).Round(time.Minute).String()
+	},
+	"default": func(def string, value interface{}) interface{} {
+		if value == nil || fmt.Sprint(value) == "" {
+			return def
+		}
+		return value
+	},
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"join":     strings.Join,
+	"contains": strings.Contains,
+	"truncate": func(length int, s string) string {
+		return truncateString(s, length)
+	},
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// templateTime accepts time.Time and *time.Time values; nil yields the zero time
+func templateTime(v interface{}) time.Time {
+	switch t := v.(type) {
+	case time.Time:
+		return t
+	case *time.Time:
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Time{}
+}
+
+// formatTemplateDate formats a date with a Go layout, returning "" for missing dates
+func formatTemplateDate(layout string, v interface{}) string {
+	t := templateTime(v)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
+
+// convertLegacyPlaceholders rewrites the original {{token}} placeholders as template actions
+func convertLegacyPlaceholders(text string) string {
+	return legacyPlaceholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := legacyPlaceholderPattern.FindStringSubmatch(match)[1]
+		return legacyPlaceholders[name]
+	})
+}
+
+// actionTemplate is a parsed text or HTML template
+type actionTemplate interface {
+	Execute(wr *bytes.Buffer, data interface{}) error
+}
+
+type textActionTemplate struct{ t *template.Template }
+
+func (t textActionTemplate) Execute(wr *bytes.Buffer, data interface{}) error {
+	return t.t.Execute(wr, data)
+}
+
+type htmlActionTemplate struct{ t *htmltemplate.Template }
+
+func (t htmlActionTemplate) Execute(wr *bytes.Buffer, data interface{}) error {
+	return t.t.Execute(wr, data)
+}
+
+// parseActionTemplate parses an action template. HTML templates escape values
+// according to context.
+func parseActionTemplate(name, text string, html bool) (actionTemplate, error) {
+	text = convertLegacyPlaceholders(text)
+	if html {
+		t, err := htmltemplate.New(name).Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		return htmlActionTemplate{t}, nil
+	}
+
+	t, err := template.New(name).Funcs(template.FuncMap(templateFuncs)).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return textActionTemplate{t}, nil
+}
+
+// renderActionTemplate renders a template against the context
+func renderActionTemplate(name, text string, html bool, data *ActionTemplateContext) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := parseActionTemplate(name, text, html)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// sampleTemplateContext returns a fully populated context used to validate templates
+func sampleTemplateContext() *ActionTemplateContext {
+	now := time.Now()
+	state := &models.WorkflowState{Name: "Sample State"}
+	user := &models.User{Username: "sample", FirstName: "Sample", LastName: "User"}
+
+	incident := &models.Incident{
+		ID:             uuid.New(),
+		IncidentNumber: "INC-00000",
+		Title:          "Sample incident",
+		RecordType:     "incident",
+		Classification: &models.Classification{Name: "Sample"},
+		Workflow:       &models.Workflow{Name: "Sample"},
+		CurrentState:   state,
+		Assignee:       user,
+		Department:     &models.Department{Name: "Sample"},
+		Location:       &models.Location{Name: "Sample"},
+		Reporter:       user,
+		SLADeadline:    &now,
+		DueDate:        &now,
+	}
+
+	data := newTemplateContext(incident, &models.WorkflowTransition{Name: "Sample", FromState: state, ToState: state}, user)
+	data.URL = "http://localhost/incidents/" + incident.ID.String()
+	data.State = state.Name
+	data.ClassificationPath = "Sample"
+	data.SLADeadline = &now
+	data.DueDate = &now
+	data.Assignees = []string{"Sample User"}
+	return data
+}
+
+// newTemplateContext sets up a context with the response views of the records and empty
+// maps; the flattened values are left to the caller
+func newTemplateContext(incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) *ActionTemplateContext {
+	incidentView := models.ToIncidentResponse(incident)
+	data := &ActionTemplateContext{
+		Incident:     &incidentView,
+		Lookups:      make(map[string]string),
+		LookupValues: make(map[string][]string),
+		CustomFields: make(map[string]interface{}),
+		Now:          time.Now(),
+		incident:     incident,
+		transition:   transition,
+	}
+	if transition != nil {
+		transitionView := models.ToWorkflowTransitionResponse(transition)
+		data.Transition = &transitionView
+	}
+	if performedBy != nil {
+		userView := models.ToUserResponse(performedBy)
+		data.PerformedBy = &userView
+	}
+	return data
+}
+
+// validateActionTemplate checks that a template parses and renders against sample data
+func validateActionTemplate(actionName, field, text string, html bool) error {
+	if text == "" {
+		return nil
+	}
+	if _, err := renderActionTemplate(field, text, html, sampleTemplateContext()); err != nil {
+		return &ActionValidationError{Action: actionName, Field: field, Err: err}
+	}
+	return nil
+}
+
+// ValidateTransitionAction validates a transition action configuration, including its templates
+func ValidateTransitionAction(action *models.TransitionAction) error {
+	if _, err := ParseActionCondition(action.Condition); err != nil {
+		return &ActionValidationError{Action: action.Name, Field: "condition", Err: err}
+	}
+
+	if action.Config == "" {
+		return nil
+	}
+
+	switch action.ActionType {
+	case "notification":
+		var config NotificationConfig
+		if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		if err := validateActionTemplate(action.Name, "title", config.Title, false); err != nil {
+			return err
+		}
+		return validateActionTemplate(action.Name, "message", config.Message, false)
+	case "email":
+		var config EmailConfig
+		if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		if err := validateActionTemplate(action.Name, "subject", config.Subject, false); err != nil {
+			return err
+		}
+		if err := validateActionTemplate(action.Name, "body", config.Body, config.IsHTML); err != nil {
+			return err
+		}
+		return validateActionTemplate(action.Name, "text_body", config.TextBody, false)
+	case "webhook":
+		var config WebhookConfig
+		if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		return validateActionTemplate(action.Name, "body", config.Body, false)
+	case "field_update":
+		var config FieldUpdateConfig
+		if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		if err := validateFieldUpdateConfig(&config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "field", Err: err}
+		}
+	case "create_linked_record":
+		var config CreateLinkedRecordConfig
+		if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		if err := validateCreateLinkedRecordConfig(&config); err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		if err := validateActionTemplate(action.Name, "title", config.Title, false); err != nil {
+			return err
+		}
+		return validateActionTemplate(action.Name, "description", config.Description, false)
+	}
+
+	return nil
+}
+
+// restoreWebhookSecret puts back the stored secret of a webhook action whose config still
+// carries the redacted placeholder from a response. The stored action is matched by name.
+func restoreWebhookSecret(action *models.TransitionAction, existing []models.TransitionAction) error {
+	if action.ActionType != "webhook" || action.Config == "" {
+		return nil
+	}
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+		return nil // Reported by ValidateTransitionAction
+	}
+	if secret, _ := config["secret"].(string); secret != models.RedactedSecret {
+		return nil
+	}
+
+	for i := range existing {
+		if existing[i].ActionType != "webhook" || existing[i].Name != action.Name {
+			continue
+		}
+		var stored WebhookConfig
+		if err := json.Unmarshal([]byte(existing[i].Config), &stored); err != nil || stored.Secret == "" {
+			break
+		}
+		config["secret"] = stored.Secret
+		data, err := json.Marshal(config)
+		if err != nil {
+			return &ActionValidationError{Action: action.Name, Field: "config", Err: err}
+		}
+		action.Config = string(data)
+		return nil
+	}
+	return &ActionValidationError{Action: action.Name, Field: "secret", Err: errors.New("no stored secret to keep; enter the secret again")}
+}
+
+// ActionTemplateRenderer builds template contexts and renders action templates
+type ActionTemplateRenderer interface {
+	BuildContext(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) *ActionTemplateContext
+	Render(name, text string, html bool, data *ActionTemplateContext) (string, error)
+}
+
+type actionTemplateRenderer struct {
+	classificationRepo repository.ClassificationRepository
+	frontendURL        string
+}
+
+// NewActionTemplateRenderer creates a renderer. frontendURL is used to build incident links.
+func NewActionTemplateRenderer(classificationRepo repository.ClassificationRepository, frontendURL string) ActionTemplateRenderer {
+	return &actionTemplateRenderer{
+		classificationRepo: classificationRepo,
+		frontendURL:        strings.TrimRight(frontendURL, "/"),
+	}
+}
+
+func (r *actionTemplateRenderer) Render(name, text string, html bool, data *ActionTemplateContext) (string, error) {
+	out, err := renderActionTemplate(name, text, html, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return out, nil
+}
+
+func (r *actionTemplateRenderer) BuildContext(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) *ActionTemplateContext {
+	data := newTemplateContext(incident, transition, performedBy)
+	data.SLADeadline = incident.SLADeadline
+	data.DueDate = incident.DueDate
+	data.AssigneeName = "Unassigned"
+	data.ReporterName = incident.ReporterName
+
+	data.URL = fmt.Sprintf("%s/%s/%s", r.frontendURL, recordTypePath(incident.RecordType), incident.ID)
+
+	if incident.CurrentState != nil {
+		data.State = incident.CurrentState.Name
+	}
+
+	for _, lv := range incident.LookupValues {
+		if lv.Category == nil {
+			continue
+		}
+		code := lv.Category.Code
+		data.LookupValues[code] = append(data.LookupValues[code], lv.Name)
+		data.Lookups[code] = strings.Join(data.LookupValues[code], ", ")
+	}
+	data.Priority = data.Lookups["PRIORITY"]
+	data.Severity = data.Lookups["SEVERITY"]
+
+	if incident.CustomFields != "" {
+		if err := json.Unmarshal([]byte(incident.CustomFields), &data.CustomFields); err != nil {
+			data.CustomFields = make(map[string]interface{})
+		}
+	}
+
+	if incident.Location != nil {
+		data.Location = incident.Location.Name
+	}
+	if incident.Department != nil {
+		data.Department = incident.Department.Name
+	}
+	if incident.Classification != nil {
+		data.Classification = incident.Classification.Name
+		data.ClassificationPath = r.classificationPath(ctx, incident.Classification)
+	}
+
+	if incident.Assignee != nil {
+		data.AssigneeName = userDisplayName(incident.Assignee)
+	}
+	for i := range incident.Assignees {
+		data.Assignees = append(data.Assignees, userDisplayName(&incident.Assignees[i]))
+	}
+	if incident.Reporter != nil {
+		data.ReporterName = userDisplayName(incident.Reporter)
+	}
+
+	if transition != nil {
+		data.TransitionName = transition.Name
+		if transition.FromState != nil {
+			data.FromState = transition.FromState.Name
+		}
+		if transition.ToState != nil {
+			data.ToState = transition.ToState.Name
+		}
+	}
+
+	if performedBy != nil {
+		data.PerformedByName = userDisplayName(performedBy)
+	}
+
+	return data
+}
+
+// classificationPath resolves the names of a classification's ancestors from its materialized path
+func (r *actionTemplateRenderer) classificationPath(ctx context.Context, classification *models.Classification) string {
+	if classification.Path == "" || r.classificationRepo == nil {
+		return classification.Name
+	}
+
+	var names []string
+	for _, part := range strings.Split(classification.Path, "/") {
+		id, err := uuid.Parse(part)
+		if err != nil {
+			continue
+		}
+		if id == classification.ID {
+			names = append(names, classification.Name)
+			continue
+		}
+		if ancestor, err := r.classificationRepo.FindByID(ctx, id); err == nil {
+			names = append(names, ancestor.Name)
+		}
+	}
+
+	if len(names) == 0 {
+		return classification.Name
+	}
+	return strings.Join(names, " > ")
+}
+
+// recordTypePath maps a record type to its frontend route segment
+func recordTypePath(recordType string) string {
+	switch recordType {
+	case "request":
+		return "requests"
+	case "complaint":
+		return "complaints"
+	case "query":
+		return "queries"
+	default:
+		return "incidents"
+	}
+}
+
+// userDisplayName returns the user's full name, falling back to the username
+func userDisplayName(user *models.User) string {
+	if user.FirstName != "" {
+		return strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+	return user.Username
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// JobTypeBulkOperation is the job queue type for bulk operations too large to run inline
+const JobTypeBulkOperation = "bulk_operation"
+
+const (
+	bulkOperationMaxItems    = 1000 // Most incidents a single bulk operation may touch
+	bulkOperationInlineLimit = 20   // Larger operations run as a background job
+)
+
+// BulkOperationService applies transitions, assignments and updates to many incidents.
+// Every item goes through the same service methods as the single-record endpoints.
+type BulkOperationService interface {
+	BulkTransition(ctx context.Context, req *models.BulkTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error)
+	BulkAssign(ctx context.Context, req *models.BulkAssignRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error)
+	BulkUpdate(ctx context.Context, req *models.BulkUpdateRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error)
+	GetOperation(ctx context.Context, id, userID uuid.UUID) (*models.BulkOperationResponse, error)
+}
+
+type bulkOperationService struct {
+	repo            repository.BulkOperationRepository
+	incidentRepo    repository.IncidentRepository
+	incidentService IncidentService
+	jobQueue        JobQueue
+}
+
+// bulkOperationJob is the payload of a queued bulk operation
+type bulkOperationJob struct {
+	OperationID uuid.UUID `json:"operation_id"`
+}
+
+func NewBulkOperationService(repo repository.BulkOperationRepository, incidentRepo repository.IncidentRepository, incidentService IncidentService, jobQueue JobQueue) BulkOperationService {
+	s := &bulkOperationService{
+		repo:            repo,
+		incidentRepo:    incidentRepo,
+		incidentService: incidentService,
+		jobQueue:        jobQueue,
+	}
+
+	if jobQueue != nil {
+		jobQueue.RegisterHandler(JobTypeBulkOperation, s.handleJob)
+	}
+
+	return s
+}
+
+func (s *bulkOperationService) BulkTransition(ctx context.Context, req *models.BulkTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error) {
+	if _, err := uuid.Parse(req.Transition.TransitionID); err != nil {
+		return nil, errors.New("invalid transition_id")
+	}
+	return s.submit(ctx, models.BulkOperationTransition, req.Transition, &req.BulkTarget, userID, userRoleIDs)
+}
+
+func (s *bulkOperationService) BulkAssign(ctx context.Context, req *models.BulkAssignRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error) {
+	if _, err := uuid.Parse(req.AssigneeID); err != nil {
+		return nil, errors.New("invalid assignee_id")
+	}
+	return s.submit(ctx, models.BulkOperationAssign, req.AssigneeID, &req.BulkTarget, userID, userRoleIDs)
+}
+
+func (s *bulkOperationService) BulkUpdate(ctx context.Context, req *models.BulkUpdateRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error) {
+	return s.submit(ctx, models.BulkOperationUpdate, req.Update, &req.BulkTarget, userID, userRoleIDs)
+}
+
+func (s *bulkOperationService) GetOperation(ctx context.Context, id, userID uuid.UUID) (*models.BulkOperationResponse, error) {
+	operation, err := s.repo.FindByID(ctx, id)
+	if err != nil || operation.RequestedByID != userID {
+		return nil, errors.New("bulk operation not found")
+	}
+
+	resp := models.ToBulkOperationResponse(operation)
+	return &resp, nil
+}
+
+// submit resolves the target incidents and records the operation. Small operations run
+// straight away; larger ones are queued and their progress can be polled.
+func (s *bulkOperationService) submit(ctx context.Context, operationType string, params interface{}, target *models.BulkTarget, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.BulkOperationResponse, error) {
+	incidentIDs, err := s.resolveTargets(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	paramData, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	idData, err := json.Marshal(incidentIDs)
+	if err != nil {
+		return nil, err
+	}
+	roleData, err := json.Marshal(userRoleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := &models.BulkOperation{
+		Operation:     operationType,
+		Status:        models.BulkStatusPending,
+		Total:         len(incidentIDs),
+		Params:        string(paramData),
+		IncidentIDs:   string(idData),
+		RoleIDs:       string(roleData),
+		Results:       "[]",
+		RequestedByID: userID,
+	}
+	if err := s.repo.Create(ctx, operation); err != nil {
+		return nil, err
+	}
+
+	if len(incidentIDs) <= bulkOperationInlineLimit || s.jobQueue == nil {
+		if err := s.run(ctx, operation); err != nil {
+			return nil, err
+		}
+	} else if err := s.jobQueue.Enqueue(ctx, JobTypeBulkOperation, bulkOperationJob{OperationID: operation.ID}, 3); err != nil {
+		return nil, fmt.Errorf("failed to queue bulk operation: %w", err)
+	}
+
+	resp := models.ToBulkOperationResponse(operation)
+	return &resp, nil
+}
+
+// resolveTargets turns the explicit IDs or the filter of a request into a de-duplicated ID list
+func (s *bulkOperationService) resolveTargets(ctx context.Context, target *models.BulkTarget) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	switch {
+	case len(target.IncidentIDs) > 0:
+		for _, idStr := range target.IncidentIDs {
+			id, err := uuid.Parse(idStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid incident id: %s", idStr)
+			}
+			ids = append(ids, id)
+		}
+	case target.Filter != nil:
+		var err error
+		ids, err = s.incidentRepo.ListIDs(ctx, target.Filter, bulkOperationMaxItems+1)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("either incident_ids or filter is required")
+	}
+
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	if len(unique) == 0 {
+		return nil, errors.New("no incidents match the selection")
+	}
+	if len(unique) > bulkOperationMaxItems {
+		return nil, fmt.Errorf("bulk operations are limited to %d incidents", bulkOperationMaxItems)
+	}
+	return unique, nil
+}
+
+// run processes the remaining items of an operation, saving progress after each one.
+// A retried job resumes after the last saved item.
+func (s *bulkOperationService) run(ctx context.Context, operation *models.BulkOperation) error {
+	var incidentIDs, roleIDs []uuid.UUID
+	if err := json.Unmarshal([]byte(operation.IncidentIDs), &incidentIDs); err != nil {
+		return fmt.Errorf("invalid incident ids: %w", err)
+	}
+	if err := json.Unmarshal([]byte(operation.RoleIDs), &roleIDs); err != nil {
+		return fmt.Errorf("invalid role ids: %w", err)
+	}
+	var results []models.BulkItemResult
+	if operation.Results != "" {
+		if err := json.Unmarshal([]byte(operation.Results), &results); err != nil {
+			return fmt.Errorf("invalid results: %w", err)
+		}
+	}
+
+	apply, err := s.itemFunc(operation, roleIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	operation.Status = models.BulkStatusRunning
+	if operation.StartedAt == nil {
+		operation.StartedAt = &now
+	}
+
+	for i := operation.Processed; i < len(incidentIDs); i++ {
+		result := models.BulkItemResult{IncidentID: incidentIDs[i]}
+		incident, err := apply(ctx, incidentIDs[i])
+		if err != nil {
+			result.Error = err.Error()
+			var reqErr *TransitionRequirementsError
+			if errors.As(err, &reqErr) {
+				result.Details = reqErr.Failures
+			}
+			operation.Failed++
+		} else {
+			result.Success = true
+			result.IncidentNumber = incident.IncidentNumber
+			operation.Succeeded++
+		}
+		results = append(results, result)
+		operation.Processed++
+
+		data, err := json.Marshal(results)
+		if err != nil {
+			return err
+		}
+		operation.Results = string(data)
+		if err := s.repo.Update(ctx, operation); err != nil {
+			return err
+		}
+	}
+
+	completedAt := time.Now()
+	operation.Status = models.BulkStatusCompleted
+	operation.CompletedAt = &completedAt
+	return s.repo.Update(ctx, operation)
+}
+
+// itemFunc returns the single-record service call an operation applies to each incident
+func (s *bulkOperationService) itemFunc(operation *models.BulkOperation, roleIDs []uuid.UUID) (func(ctx context.Context, incidentID uuid.UUID) (*models.IncidentResponse, error), error) {
+	userID := operation.RequestedByID
+
+	switch operation.Operation {
+	case models.BulkOperationTransition:
+		var req models.IncidentTransitionRequest
+		if err := json.Unmarshal([]byte(operation.Params), &req); err != nil {
+			return nil, fmt.Errorf("invalid transition parameters: %w", err)
+		}
+		return func(ctx context.Context, incidentID uuid.UUID) (*models.IncidentResponse, error) {
+			itemReq := req
+			return s.incidentService.ExecuteTransition(ctx, incidentID, &itemReq, userID, roleIDs)
+		}, nil
+	case models.BulkOperationAssign:
+		var assigneeIDStr string
+		if err := json.Unmarshal([]byte(operation.Params), &assigneeIDStr); err != nil {
+			return nil, fmt.Errorf("invalid assign parameters: %w", err)
+		}
+		assigneeID, err := uuid.Parse(assigneeIDStr)
+		if err != nil {
+			return nil, errors.New("invalid assignee_id")
+		}
+		return func(ctx context.Context, incidentID uuid.UUID) (*models.IncidentResponse, error) {
+			return s.incidentService.AssignIncident(ctx, incidentID, assigneeID, userID)
+		}, nil
+	case models.BulkOperationUpdate:
+		var req models.IncidentUpdateRequest
+		if err := json.Unmarshal([]byte(operation.Params), &req); err != nil {
+			return nil, fmt.Errorf("invalid update parameters: %w", err)
+		}
+		return func(ctx context.Context, incidentID uuid.UUID) (*models.IncidentResponse, error) {
+			itemReq := req
+			return s.incidentService.UpdateIncident(ctx, incidentID, &itemReq, userID)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown bulk operation: %s", operation.Operation)
+}
+
+func (s *bulkOperationService) handleJob(ctx context.Context, job *Job) error {
+	var payload bulkOperationJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		// Malformed payloads can never succeed, so don't retry them
+		log.Printf("Discarding invalid bulk operation job %s: %v", job.ID, err)
+		return nil
+	}
+
+	operation, err := s.repo.FindByID(ctx, payload.OperationID)
+	if err != nil {
+		return fmt.Errorf("failed to load bulk operation: %w", err)
+	}
+	if operation.Status == models.BulkStatusCompleted || operation.Status == models.BulkStatusFailed {
+		return nil
+	}
+
+	if err := s.run(ctx, operation); err != nil {
+		if job.IsLastAttempt() {
+			operation.Status = models.BulkStatusFailed
+			operation.Error = err.Error()
+			if updateErr := s.repo.Update(ctx, operation); updateErr != nil {
+				log.Printf("Failed to mark bulk operation %s as failed: %v", operation.ID, updateErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
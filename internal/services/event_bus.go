@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	eventBusChannel       = "events"
+	eventSubscriberBuffer = 64
+)
+
+// eventEnvelope is what travels over Redis pub/sub between replicas
+type eventEnvelope struct {
+	Event    models.Event         `json:"event"`
+	Audience models.EventAudience `json:"audience"`
+}
+
+// EventSubscription is a local subscriber receiving events for one connected user
+type EventSubscription struct {
+	Subscriber *models.EventSubscriber
+	Events     chan models.Event
+}
+
+// EventBus publishes real-time events and fans them out to locally connected clients.
+// Publishing goes through Redis so every replica delivers events to its own clients.
+type EventBus interface {
+	Publish(ctx context.Context, eventType string, incidentID *uuid.UUID, recordType string, data interface{}, audience models.EventAudience) error
+	Subscribe(subscriber *models.EventSubscriber) (*EventSubscription, func())
+	Start(ctx context.Context)
+	Stop()
+}
+
+type eventBus struct {
+	client      *redis.Client
+	mu          sync.RWMutex
+	subscribers map[*EventSubscription]struct{}
+	stopChan    chan struct{}
+	wg          sync.WaitGroup
+	running     bool
+}
+
+// NewEventBus creates a new Redis pub/sub backed event bus
+func NewEventBus(client *redis.Client) EventBus {
+	return &eventBus{
+		client:      client,
+		subscribers: make(map[*EventSubscription]struct{}),
+		stopChan:    make(chan struct{}),
+	}
+}
+
+// Publish broadcasts an event to all replicas
+func (b *eventBus) Publish(ctx context.Context, eventType string, incidentID *uuid.UUID, recordType string, data interface{}, audience models.EventAudience) error {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	envelope := eventEnvelope{
+		Event: models.Event{
+			ID:         uuid.New(),
+			Type:       eventType,
+			IncidentID: incidentID,
+			RecordType: recordType,
+			Data:       raw,
+			OccurredAt: time.Now(),
+		},
+		Audience: audience,
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(ctx, eventBusChannel, payload).Err()
+}
+
+// Subscribe registers a local subscriber. The returned function must be called to unsubscribe.
+func (b *eventBus) Subscribe(subscriber *models.EventSubscriber) (*EventSubscription, func()) {
+	sub := &EventSubscription{
+		Subscriber: subscriber,
+		Events:     make(chan models.Event, eventSubscriberBuffer),
+	}
+
+	b.mu.Lock()
+	b.subscribers[sub] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return sub, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, sub)
+			b.mu.Unlock()
+		})
+	}
+}
+
+// Start listens on the Redis channel and dispatches events to local subscribers
+func (b *eventBus) Start(ctx context.Context) {
+	if b.running {
+		return
+	}
+	b.running = true
+
+	b.wg.Add(1)
+	go b.listen(ctx)
+
+	log.Println("Event bus started")
+}
+
+// Stop stops listening for events
+func (b *eventBus) Stop() {
+	if !b.running {
+		return
+	}
+	close(b.stopChan)
+	b.wg.Wait()
+	b.running = false
+	log.Println("Event bus stopped")
+}
+
+func (b *eventBus) listen(ctx context.Context) {
+	defer b.wg.Done()
+
+	for {
+		pubsub := b.client.Subscribe(ctx, eventBusChannel)
+		ch := pubsub.Channel()
+
+	receive:
+		for {
+			select {
+			case <-ctx.Done():
+				pubsub.Close()
+				return
+			case <-b.stopChan:
+				pubsub.Close()
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					break receive
+				}
+				b.dispatch(msg.Payload)
+			}
+		}
+
+		pubsub.Close()
+
+		// Channel closed unexpectedly; back off before resubscribing
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.stopChan:
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *eventBus) dispatch(payload string) {
+	var envelope eventEnvelope
+	if err := json.Unmarshal([]byte(payload), &envelope); err != nil {
+		log.Printf("Event bus: failed to decode event: %v", err)
+		return
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for sub := range b.subscribers {
+		if !envelope.Audience.Allows(sub.Subscriber) {
+			continue
+		}
+		select {
+		case sub.Events <- envelope.Event:
+		default:
+			// Slow client; drop rather than block other subscribers
+		}
+	}
+}
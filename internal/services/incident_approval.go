@@ -0,0 +1,328 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// approvalOutcome is the state of an approval round after a vote
+type approvalOutcome int
+
+const (
+	approvalPending approvalOutcome = iota
+	approvalReached
+	approvalRejected
+)
+
+// castApprovalVote saves the user's vote on an approval transition and works out whether it
+// completes the round. Votes are counted under a lock on the incident, and only the vote that
+// reaches the quorum completes the round. The caller links the vote to its history entry.
+func (s *incidentService) castApprovalVote(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.TransitionApproval, approvalOutcome, error) {
+	vote, err := buildApprovalVote(incident, transition, req, userID, userRoleIDs)
+	if err != nil {
+		return nil, approvalPending, err
+	}
+
+	before, after, err := s.incidentRepo.CastApproval(ctx, vote)
+	if err != nil {
+		return nil, approvalPending, fmt.Errorf("failed to record approval vote: %w", err)
+	}
+	return vote, approvalRoundOutcome(transition, vote, before, after), nil
+}
+
+// previewApprovalVote works out what castApprovalVote would decide without saving the vote
+func (s *incidentService) previewApprovalVote(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (approvalOutcome, error) {
+	vote, err := buildApprovalVote(incident, transition, req, userID, userRoleIDs)
+	if err != nil {
+		return approvalPending, err
+	}
+
+	before, err := s.incidentRepo.ListOpenApprovals(ctx, incident.ID, &transition.ID)
+	if err != nil {
+		return approvalPending, err
+	}
+
+	// The new vote replaces the user's earlier one
+	after := make([]models.TransitionApproval, 0, len(before)+1)
+	for _, v := range before {
+		if v.UserID != userID {
+			after = append(after, v)
+		}
+	}
+	after = append(after, *vote)
+	return approvalRoundOutcome(transition, vote, before, after), nil
+}
+
+// approvalRoundOutcome decides a vote given the open votes of its round before and after it.
+// A round that was already complete is being moved on by the vote that completed it.
+func approvalRoundOutcome(transition *models.WorkflowTransition, vote *models.TransitionApproval, before, after []models.TransitionApproval) approvalOutcome {
+	if vote.Decision == models.ApprovalDecisionReject {
+		return approvalRejected
+	}
+
+	approvedBefore, required := approvalProgress(transition, before)
+	approvedAfter, _ := approvalProgress(transition, after)
+	if approvedAfter >= required && approvedBefore < required {
+		return approvalReached
+	}
+	return approvalPending
+}
+
+// buildApprovalVote checks that the user may vote on the transition and builds the vote
+func buildApprovalVote(incident *models.Incident, transition *models.WorkflowTransition, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.TransitionApproval, error) {
+	if len(transition.Approvers) > 0 {
+		listed := false
+		for _, approver := range transition.Approvers {
+			if approver.ID == userID {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return nil, errors.New("you are not an approver for this transition")
+		}
+	}
+
+	decision := req.Decision
+	if decision == "" {
+		decision = models.ApprovalDecisionApprove
+	}
+
+	// Remember which of the allowed roles the voter holds, for one_per_role quorums
+	var voterRoles []uuid.UUID
+	for _, role := range transition.AllowedRoles {
+		for _, roleID := range userRoleIDs {
+			if role.ID == roleID {
+				voterRoles = append(voterRoles, role.ID)
+				break
+			}
+		}
+	}
+	roleData, err := json.Marshal(voterRoles)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TransitionApproval{
+		IncidentID:   incident.ID,
+		TransitionID: transition.ID,
+		FromStateID:  incident.CurrentStateID,
+		UserID:       userID,
+		Decision:     decision,
+		Comment:      req.Comment,
+		RoleIDs:      string(roleData),
+	}, nil
+}
+
+// approvalProgress counts the approvals of a round towards the transition's quorum
+func approvalProgress(transition *models.WorkflowTransition, votes []models.TransitionApproval) (approved, required int) {
+	approvers := make(map[uuid.UUID]bool)
+	for _, v := range votes {
+		if v.Decision == models.ApprovalDecisionApprove {
+			approvers[v.UserID] = true
+		}
+	}
+
+	switch transition.ApprovalMode {
+	case models.ApprovalModeAll:
+		if len(transition.Approvers) > 0 {
+			for _, user := range transition.Approvers {
+				if approvers[user.ID] {
+					approved++
+				}
+			}
+			return approved, len(transition.Approvers)
+		}
+	case models.ApprovalModeOnePerRole:
+		if len(transition.AllowedRoles) > 0 {
+			covered := make(map[uuid.UUID]bool)
+			for _, v := range votes {
+				if v.Decision != models.ApprovalDecisionApprove {
+					continue
+				}
+				var roleIDs []uuid.UUID
+				if err := json.Unmarshal([]byte(v.RoleIDs), &roleIDs); err != nil {
+					continue
+				}
+				for _, roleID := range roleIDs {
+					covered[roleID] = true
+				}
+			}
+			for _, role := range transition.AllowedRoles {
+				if covered[role.ID] {
+					approved++
+				}
+			}
+			return approved, len(transition.AllowedRoles)
+		}
+	}
+
+	required = transition.ApprovalQuorum
+	if required < 1 {
+		required = 1
+	}
+	return len(approvers), required
+}
+
+// recordApprovalVote stores a vote that doesn't change the incident's state. The history entry
+// stays on the current state and carries the decision.
+func (s *incidentService) recordApprovalVote(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, vote *models.TransitionApproval, outcome approvalOutcome) (*models.IncidentResponse, error) {
+	history := &models.IncidentTransitionHistory{
+		IncidentID:       incident.ID,
+		TransitionID:     &transition.ID,
+		FromStateID:      incident.CurrentStateID,
+		ToStateID:        incident.CurrentStateID,
+		PerformedByID:    vote.UserID,
+		Comment:          vote.Comment,
+		ApprovalDecision: vote.Decision,
+		TransitionedAt:   time.Now(),
+	}
+	if err := s.incidentRepo.CreateTransitionHistory(ctx, history); err != nil {
+		return nil, err
+	}
+
+	vote.HistoryID = &history.ID
+	if err := s.incidentRepo.SetApprovalHistory(ctx, vote.ID, history.ID); err != nil {
+		return nil, err
+	}
+
+	// A rejection without a reject state ends the round and leaves the incident where it is
+	if outcome == approvalRejected {
+		if err := s.incidentRepo.ResolveOpenApprovals(ctx, incident.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	if vote.Comment != "" {
+		comment := &models.IncidentComment{
+			IncidentID:          incident.ID,
+			AuthorID:            vote.UserID,
+			Content:             vote.Comment,
+			IsInternal:          true,
+			TransitionHistoryID: &history.ID,
+		}
+		s.incidentRepo.CreateComment(ctx, comment)
+	}
+
+	var description string
+	if vote.Decision == models.ApprovalDecisionReject {
+		description = fmt.Sprintf("Rejected %s", transition.Name)
+	} else {
+		description = fmt.Sprintf("Approved %s", transition.Name)
+	}
+	_ = s.CreateRevision(ctx, incident.ID, models.RevisionActionStatusChanged, description, nil, vote.UserID)
+
+	updated, err := s.incidentRepo.FindByIDWithRelations(ctx, incident.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ToIncidentResponse(updated)
+	s.publishIncidentEvent(ctx, models.EventIncidentApprovalVote, updated, resp)
+	return &resp, nil
+}
+
+// rejectionTransition turns an approval transition into the move to its reject state.
+// Actions and assignment settings belong to the approved path and are dropped.
+func (s *incidentService) rejectionTransition(ctx context.Context, transition *models.WorkflowTransition) (*models.WorkflowTransition, error) {
+	rejectState, err := s.workflowRepo.FindStateByID(ctx, *transition.RejectStateID)
+	if err != nil {
+		return nil, errors.New("reject state not found")
+	}
+
+	rejection := *transition
+	rejection.ToStateID = rejectState.ID
+	rejection.ToState = rejectState
+	rejection.Actions = nil
+	rejection.AssignDepartmentID = nil
+	rejection.AutoDetectDepartment = false
+	rejection.AssignUserID = nil
+	rejection.AutoMatchUser = false
+	rejection.ManualSelectUser = false
+	return &rejection, nil
+}
+
+func (s *incidentService) approvalStatus(ctx context.Context, incidentID uuid.UUID, transition *models.WorkflowTransition) (*models.ApprovalStatusResponse, error) {
+	votes, err := s.incidentRepo.ListOpenApprovals(ctx, incidentID, &transition.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	approved, required := approvalProgress(transition, votes)
+	status := &models.ApprovalStatusResponse{
+		TransitionID:   transition.ID,
+		TransitionName: transition.Name,
+		ApprovalMode:   transition.ApprovalMode,
+		Approved:       approved,
+		Required:       required,
+		Votes:          make([]models.TransitionApprovalResponse, len(votes)),
+	}
+	for i := range votes {
+		status.Votes[i] = models.ToTransitionApprovalResponse(&votes[i])
+	}
+	return status, nil
+}
+
+// GetIncidentApprovals returns the open approval rounds of the incident's current state
+func (s *incidentService) GetIncidentApprovals(ctx context.Context, incidentID uuid.UUID) ([]models.ApprovalStatusResponse, error) {
+	incident, err := s.incidentRepo.FindByID(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	transitions, err := loadIncidentTransitionsFromState(ctx, s.workflowRepo, incident)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []models.ApprovalStatusResponse{}
+	for i := range transitions {
+		if transitions[i].ApprovalMode == "" {
+			continue
+		}
+		status, err := s.approvalStatus(ctx, incidentID, &transitions[i])
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, *status)
+	}
+	return statuses, nil
+}
+
+// GetPendingApprovals lists the records waiting for the user's vote
+func (s *incidentService) GetPendingApprovals(ctx context.Context, userID uuid.UUID, recordType string) ([]models.PendingApprovalResponse, error) {
+	keys, err := s.incidentRepo.ListPendingApprovals(ctx, userID, recordType)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []models.PendingApprovalResponse{}
+	for _, key := range keys {
+		incident, err := s.incidentRepo.FindByIDWithRelations(ctx, key.IncidentID)
+		if err != nil {
+			continue
+		}
+
+		// Incidents pinned to a published version follow its transitions rather than the live ones
+		transition, err := loadIncidentTransition(ctx, s.workflowRepo, incident, key.TransitionID)
+		if err != nil || transition.ApprovalMode == "" || transition.FromStateID != incident.CurrentStateID {
+			continue
+		}
+
+		status, err := s.approvalStatus(ctx, incident.ID, transition)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, models.PendingApprovalResponse{
+			Incident: models.ToIncidentResponse(incident),
+			Approval: *status,
+		})
+	}
+	return pending, nil
+}
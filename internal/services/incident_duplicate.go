@@ -0,0 +1,275 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+const (
+	duplicateWindow        = 72 * time.Hour
+	duplicateRadiusMeters  = 500.0
+	duplicateThreshold     = 0.5
+	maxDuplicateCandidates = 5
+	maxDuplicateTitleTerms = 8
+	minDuplicateMobile     = 7 // Shorter numbers are too ambiguous to match on
+
+	// Signal weights; a candidate needs at least two signals, or a very similar title,
+	// to reach the threshold
+	duplicateWeightClassification = 0.25
+	duplicateWeightNearby         = 0.3
+	duplicateWeightTitle          = 0.35
+	duplicateWeightMobile         = 0.4
+	minDuplicateTitleSimilarity   = 0.3
+)
+
+// Common words that say nothing about the problem being reported
+var duplicateStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "from": true, "near": true,
+	"not": true, "has": true, "have": true, "are": true, "was": true, "this": true,
+	"that": true, "there": true, "please": true, "issue": true, "problem": true,
+}
+
+// FindDuplicates returns the open records that likely report the same problem as the given one
+func (s *incidentService) FindDuplicates(ctx context.Context, incidentID uuid.UUID) ([]models.DuplicateCandidate, error) {
+	incident, err := s.incidentRepo.FindByIDWithRelations(ctx, incidentID)
+	if err != nil {
+		return nil, errors.New("incident not found")
+	}
+	return s.detectDuplicates(ctx, incident)
+}
+
+// duplicateCandidates runs detection for a record that was just created. Detection never
+// fails the create; errors are only logged.
+func (s *incidentService) duplicateCandidates(ctx context.Context, incident *models.Incident) []models.DuplicateCandidate {
+	candidates, err := s.detectDuplicates(ctx, incident)
+	if err != nil {
+		log.Printf("Duplicate detection failed for %s: %v", incident.IncidentNumber, err)
+		return nil
+	}
+	return candidates
+}
+
+func (s *incidentService) detectDuplicates(ctx context.Context, incident *models.Incident) ([]models.DuplicateCandidate, error) {
+	terms := titleTerms(incident.Title)
+	mobile := recordMobile(incident)
+
+	query := &models.DuplicateCandidateQuery{
+		ExcludeID:        incident.ID,
+		RecordType:       incident.RecordType,
+		Since:            incident.CreatedAt.Add(-duplicateWindow),
+		ClassificationID: incident.ClassificationID,
+		Latitude:         incident.Latitude,
+		Longitude:        incident.Longitude,
+		RadiusMeters:     duplicateRadiusMeters,
+		Mobile:           mobile,
+		TitleTerms:       terms,
+	}
+	if len(query.TitleTerms) > maxDuplicateTitleTerms {
+		query.TitleTerms = query.TitleTerms[:maxDuplicateTitleTerms]
+	}
+
+	records, err := s.incidentRepo.FindDuplicateCandidates(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]models.DuplicateCandidate, 0, len(records))
+	for i := range records {
+		record := &records[i]
+		candidate := models.DuplicateCandidate{
+			ID:             record.ID,
+			IncidentNumber: record.IncidentNumber,
+			Title:          record.Title,
+			RecordType:     record.RecordType,
+			Reasons:        []string{},
+			CreatedAt:      record.CreatedAt,
+		}
+		if record.CurrentState != nil {
+			state := models.ToWorkflowStateResponse(record.CurrentState)
+			candidate.CurrentState = &state
+		}
+
+		if incident.ClassificationID != nil && record.ClassificationID != nil && *incident.ClassificationID == *record.ClassificationID {
+			candidate.Score += duplicateWeightClassification
+			candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonClassification)
+		}
+		if incident.Latitude != nil && incident.Longitude != nil && record.Latitude != nil && record.Longitude != nil {
+			distance := haversineMeters(*incident.Latitude, *incident.Longitude, *record.Latitude, *record.Longitude)
+			candidate.DistanceMeters = &distance
+			if distance <= duplicateRadiusMeters {
+				candidate.Score += duplicateWeightNearby
+				candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonNearby)
+			}
+		}
+		candidate.TitleSimilarity = termSimilarity(terms, titleTerms(record.Title))
+		if candidate.TitleSimilarity >= minDuplicateTitleSimilarity {
+			candidate.Score += duplicateWeightTitle * candidate.TitleSimilarity
+			candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonSimilarTitle)
+		}
+		if mobile != "" && recordMobile(record) == mobile {
+			candidate.Score += duplicateWeightMobile
+			candidate.Reasons = append(candidate.Reasons, models.DuplicateReasonSameMobile)
+		}
+
+		candidate.Score = math.Round(math.Min(candidate.Score, 1)*100) / 100
+		if candidate.Score >= duplicateThreshold {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].Score > candidates[b].Score
+	})
+	if len(candidates) > maxDuplicateCandidates {
+		candidates = candidates[:maxDuplicateCandidates]
+	}
+	return candidates, nil
+}
+
+// MergeDuplicate folds a duplicate into its master record. Comments and attachments move to
+// the master, and the duplicate keeps a link to it.
+func (s *incidentService) MergeDuplicate(ctx context.Context, duplicateID uuid.UUID, req *models.MergeDuplicateRequest, userID uuid.UUID) (*models.MergeDuplicateResponse, error) {
+	masterID, err := uuid.Parse(req.MasterID)
+	if err != nil {
+		return nil, errors.New("invalid master_id")
+	}
+	if masterID == duplicateID {
+		return nil, errors.New("a record cannot be merged into itself")
+	}
+
+	duplicate, err := s.incidentRepo.FindByID(ctx, duplicateID)
+	if err != nil {
+		return nil, errors.New("duplicate record not found")
+	}
+	master, err := s.incidentRepo.FindByID(ctx, masterID)
+	if err != nil {
+		return nil, errors.New("master record not found")
+	}
+	if duplicate.DuplicateOfID != nil {
+		return nil, fmt.Errorf("%s is already merged into another record", duplicate.IncidentNumber)
+	}
+	if master.DuplicateOfID != nil {
+		return nil, fmt.Errorf("%s is itself a duplicate and cannot be a master record", master.IncidentNumber)
+	}
+	if master.MergedIntoID != nil {
+		return nil, fmt.Errorf("%s was merged into another record and cannot be a master record", master.IncidentNumber)
+	}
+	if master.RecordType != duplicate.RecordType {
+		return nil, fmt.Errorf("cannot merge a %s into a %s", duplicate.RecordType, master.RecordType)
+	}
+
+	moved, err := s.incidentRepo.MergeDuplicate(ctx, duplicate.ID, master.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Duplicate merged: %s into %s (%d comments, %d attachments)",
+		duplicate.IncidentNumber, master.IncidentNumber, moved.Comments, moved.Attachments)
+
+	masterNumber := master.IncidentNumber
+	duplicateNumber := duplicate.IncidentNumber
+	_ = s.CreateRevision(ctx, duplicate.ID, models.RevisionActionMerged,
+		fmt.Sprintf("Merged as a duplicate into %s; moved %d comments and %d attachments", masterNumber, moved.Comments, moved.Attachments),
+		[]models.IncidentFieldChange{{FieldName: "duplicate_of", FieldLabel: "Duplicate of", NewValue: &masterNumber}},
+		userID)
+	_ = s.CreateRevision(ctx, master.ID, models.RevisionActionMerged,
+		fmt.Sprintf("Duplicate %s merged in; received %d comments and %d attachments", duplicateNumber, moved.Comments, moved.Attachments),
+		[]models.IncidentFieldChange{{FieldName: "merged_duplicate", FieldLabel: "Merged Duplicate", NewValue: &duplicateNumber}},
+		userID)
+
+	updatedMaster, err := s.incidentRepo.FindByIDWithRelations(ctx, master.ID)
+	if err != nil {
+		return nil, err
+	}
+	updatedDuplicate, err := s.incidentRepo.FindByIDWithRelations(ctx, duplicate.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.MergeDuplicateResponse{
+		Master:    models.ToIncidentResponse(updatedMaster),
+		Duplicate: models.ToIncidentResponse(updatedDuplicate),
+		Moved:     *moved,
+	}
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updatedMaster, resp.Master)
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updatedDuplicate, resp.Duplicate)
+	return resp, nil
+}
+
+// recordMobile returns the caller's mobile digits, falling back to the reporter's phone
+func recordMobile(incident *models.Incident) string {
+	mobile := digitsOnly(incident.CreatedByMobile)
+	if mobile == "" && incident.Reporter != nil {
+		mobile = digitsOnly(incident.Reporter.Phone)
+	}
+	if len(mobile) < minDuplicateMobile {
+		return ""
+	}
+	return mobile
+}
+
+func digitsOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// titleTerms splits a title into distinct lower-case words, dropping short and common ones
+func titleTerms(title string) []string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	seen := make(map[string]bool, len(words))
+	terms := make([]string, 0, len(words))
+	for _, w := range words {
+		if utf8.RuneCountInString(w) < 3 || duplicateStopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// termSimilarity is the Jaccard index of two term sets
+func termSimilarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(a))
+	for _, t := range a {
+		set[t] = true
+	}
+	shared := 0
+	for _, t := range b {
+		if set[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// haversineMeters is the great-circle distance between two points
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Asin(math.Sqrt(h))
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MergeIncident merges a source record into a target. The target takes over the source's
+// comments, attachments, feedback and assignees; the source is closed with a merged_into link.
+func (s *incidentService) MergeIncident(ctx context.Context, sourceID uuid.UUID, req *models.MergeIncidentRequest, userID uuid.UUID) (*models.MergeIncidentResponse, error) {
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		return nil, errors.New("invalid target_id")
+	}
+	if targetID == sourceID {
+		return nil, errors.New("a record cannot be merged into itself")
+	}
+
+	source, err := s.incidentRepo.FindByIDWithRelations(ctx, sourceID)
+	if err != nil {
+		return nil, errors.New("source record not found")
+	}
+	target, err := s.incidentRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, errors.New("target record not found")
+	}
+	if source.MergedIntoID != nil {
+		return nil, fmt.Errorf("%s is already merged into another record", source.IncidentNumber)
+	}
+	if source.ClosedAt != nil {
+		return nil, fmt.Errorf("%s is closed and cannot be merged", source.IncidentNumber)
+	}
+	if target.MergedIntoID != nil || target.ClosedAt != nil {
+		return nil, fmt.Errorf("cannot merge into %s because it is closed", target.IncidentNumber)
+	}
+	if source.RecordType != target.RecordType {
+		return nil, fmt.Errorf("cannot merge a %s into a %s", source.RecordType, target.RecordType)
+	}
+
+	// Same rule as closing through a transition
+	blocking, err := s.incidentRepo.CountOpenBlockingChildren(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	if blocking > 0 {
+		return nil, fmt.Errorf("cannot close %s: %d linked record(s) must be closed first", source.IncidentNumber, blocking)
+	}
+	if err := s.checkOpenBlockers(ctx, source.ID); err != nil {
+		return nil, err
+	}
+
+	closeState, err := s.mergeCloseState(ctx, source, req.CloseStateID)
+	if err != nil {
+		return nil, err
+	}
+
+	reason := ""
+	if r := strings.TrimSpace(req.Reason); r != "" {
+		reason = ": " + r
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"merged_into_id":   target.ID,
+		"current_state_id": closeState.ID,
+		"closed_at":        now,
+		"assignee_id":      nil,
+	}
+	if source.AssigneeID != nil {
+		updates["previous_assignee_id"] = *source.AssigneeID
+	}
+	// The close has no transition of its own, but shows in the history like one
+	history := &models.IncidentTransitionHistory{
+		IncidentID:     source.ID,
+		FromStateID:    source.CurrentStateID,
+		ToStateID:      closeState.ID,
+		PerformedByID:  userID,
+		Comment:        fmt.Sprintf("Merged into %s%s", target.IncidentNumber, reason),
+		TransitionedAt: now,
+	}
+	moved, err := s.incidentRepo.MergeIncident(ctx, source.ID, target.ID, updates, history)
+	if err != nil {
+		return nil, err
+	}
+	_ = s.incidentRepo.ResolveOpenApprovals(ctx, source.ID)
+
+	log.Printf("Record merged: %s into %s (%d comments, %d attachments, %d feedback, %d assignees)",
+		source.IncidentNumber, target.IncidentNumber, moved.Comments, moved.Attachments, moved.Feedback, moved.Assignees)
+
+	targetNumber := target.IncidentNumber
+	sourceNumber := source.IncidentNumber
+	oldStateName := ""
+	if source.CurrentState != nil {
+		oldStateName = source.CurrentState.Name
+	}
+	newStateName := closeState.Name
+	_ = s.CreateRevision(ctx, source.ID, models.RevisionActionMerged,
+		fmt.Sprintf("Merged into %s and closed; moved %d comments, %d attachments and %d feedback%s",
+			targetNumber, moved.Comments, moved.Attachments, moved.Feedback, reason),
+		[]models.IncidentFieldChange{
+			{FieldName: "merged_into", FieldLabel: "Merged into", NewValue: &targetNumber},
+			{FieldName: "current_state_id", FieldLabel: "Status", OldValue: &oldStateName, NewValue: &newStateName},
+		},
+		userID)
+	_ = s.CreateRevision(ctx, target.ID, models.RevisionActionMerged,
+		fmt.Sprintf("%s merged in; received %d comments, %d attachments, %d feedback and %d assignees%s",
+			sourceNumber, moved.Comments, moved.Attachments, moved.Feedback, moved.Assignees, reason),
+		[]models.IncidentFieldChange{{FieldName: "merged_record", FieldLabel: "Merged Record", NewValue: &sourceNumber}},
+		userID)
+
+	updatedSource, err := s.incidentRepo.FindByIDWithRelations(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	updatedTarget, err := s.incidentRepo.FindByIDWithRelations(ctx, target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.MergeIncidentResponse{
+		Source: models.ToIncidentResponse(updatedSource),
+		Target: models.ToIncidentResponse(updatedTarget),
+		Moved:  *moved,
+	}
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updatedSource, resp.Source)
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updatedTarget, resp.Target)
+
+	// A merged sub-workflow child counts as closed for its parent
+	if updatedSource.SubWorkflowStateID != nil {
+		if err := s.resumeSubWorkflowParent(ctx, updatedSource, closeState); err != nil {
+			log.Printf("Warning: failed to resume sub-workflow parent: %v", err)
+		}
+	}
+
+	return resp, nil
+}
+
+// mergeCloseState picks the terminal state a merged record is closed in: the requested one,
+// or the first active terminal state of its workflow version
+func (s *incidentService) mergeCloseState(ctx context.Context, incident *models.Incident, stateID *string) (*models.WorkflowState, error) {
+	states, err := loadIncidentStates(ctx, s.workflowRepo, incident)
+	if err != nil {
+		return nil, err
+	}
+
+	if stateID != nil && *stateID != "" {
+		id, err := uuid.Parse(*stateID)
+		if err != nil {
+			return nil, errors.New("invalid close_state_id")
+		}
+		for i := range states {
+			if states[i].ID == id {
+				if states[i].StateType != "terminal" {
+					return nil, fmt.Errorf("state %s is not a terminal state", states[i].Name)
+				}
+				return &states[i], nil
+			}
+		}
+		return nil, errors.New("close state not found in the record's workflow")
+	}
+
+	for i := range states {
+		if states[i].StateType == "terminal" && states[i].IsActive {
+			return &states[i], nil
+		}
+	}
+	return nil, errors.New("the record's workflow has no terminal state to close it in")
+}
+
+// SplitIncident creates new records from parts of an existing one. Each new record starts in
+// its workflow's initial state, is linked to the original, and takes over the comments and
+// attachments listed for it. The records are created in one transaction, so either every part
+// is split off or none is. The original stays open.
+func (s *incidentService) SplitIncident(ctx context.Context, sourceID uuid.UUID, req *models.SplitIncidentRequest, userID uuid.UUID) (*models.SplitIncidentResponse, error) {
+	source, err := s.incidentRepo.FindByIDWithRelations(ctx, sourceID)
+	if err != nil {
+		return nil, errors.New("source record not found")
+	}
+	if source.MergedIntoID != nil {
+		return nil, fmt.Errorf("%s was merged into another record and cannot be split", source.IncidentNumber)
+	}
+
+	comments := make(map[uuid.UUID]bool, len(source.Comments))
+	for _, c := range source.Comments {
+		comments[c.ID] = true
+	}
+	attachments := make(map[uuid.UUID]bool, len(source.Attachments))
+	for _, a := range source.Attachments {
+		attachments[a.ID] = true
+	}
+
+	// Validate every part before creating anything
+	taken := make(map[uuid.UUID]bool)
+	commentIDs := make([][]uuid.UUID, len(req.Records))
+	attachmentIDs := make([][]uuid.UUID, len(req.Records))
+	for i, part := range req.Records {
+		if len(part.CommentIDs) == 0 && len(part.AttachmentIDs) == 0 {
+			return nil, fmt.Errorf("record %d: select at least one comment or attachment", i+1)
+		}
+		if commentIDs[i], err = splitItemIDs(part.CommentIDs, comments, taken, "comment", source.IncidentNumber); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+		if attachmentIDs[i], err = splitItemIDs(part.AttachmentIDs, attachments, taken, "attachment", source.IncidentNumber); err != nil {
+			return nil, fmt.Errorf("record %d: %w", i+1, err)
+		}
+	}
+
+	split := make([]*models.Incident, len(req.Records))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		incidentRepo := repository.NewIncidentRepository(tx)
+		workflowRepo := repository.NewWorkflowRepository(tx)
+		for i, part := range req.Records {
+			record, err := createLinkedRecord(ctx, incidentRepo, workflowRepo, source, &linkedRecordParams{
+				RecordType:  source.RecordType,
+				WorkflowID:  source.WorkflowID,
+				Title:       part.Title,
+				Description: part.Description,
+			})
+			if err != nil {
+				return err
+			}
+			if err := incidentRepo.MoveRecordItems(ctx, source.ID, record.ID, commentIDs[i], attachmentIDs[i]); err != nil {
+				return fmt.Errorf("failed to move items to %s: %w", record.IncidentNumber, err)
+			}
+			split[i] = record
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sourceNumber := source.IncidentNumber
+	records := make([]models.IncidentResponse, 0, len(req.Records))
+	numbers := make([]string, 0, len(req.Records))
+	for i, record := range split {
+		_ = s.CreateRevision(ctx, record.ID, models.RevisionActionCreated,
+			fmt.Sprintf("%s split from %s with %d comments and %d attachments",
+				record.IncidentNumber, sourceNumber, len(commentIDs[i]), len(attachmentIDs[i])),
+			[]models.IncidentFieldChange{{FieldName: "source_incident", FieldLabel: "Split from", NewValue: &sourceNumber}},
+			userID)
+
+		created, err := s.incidentRepo.FindByIDWithRelations(ctx, record.ID)
+		if err != nil {
+			return nil, err
+		}
+		resp := models.ToIncidentResponse(created)
+		s.publishIncidentEvent(ctx, models.EventIncidentCreated, created, resp)
+		records = append(records, resp)
+		numbers = append(numbers, record.IncidentNumber)
+	}
+
+	log.Printf("Record split: %s into %s", sourceNumber, strings.Join(numbers, ", "))
+
+	splitInto := strings.Join(numbers, ", ")
+	_ = s.CreateRevision(ctx, source.ID, models.RevisionActionFieldChange,
+		fmt.Sprintf("Split into %s; moved %d comments and %d attachments", splitInto, countIDs(commentIDs), countIDs(attachmentIDs)),
+		[]models.IncidentFieldChange{{FieldName: "split_into", FieldLabel: "Split into", NewValue: &splitInto}},
+		userID)
+
+	updatedSource, err := s.incidentRepo.FindByIDWithRelations(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	sourceResp := models.ToIncidentResponse(updatedSource)
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updatedSource, sourceResp)
+
+	return &models.SplitIncidentResponse{
+		Source:  sourceResp,
+		Records: records,
+	}, nil
+}
+
+// splitItemIDs parses the comment or attachment IDs of one split part. Each item must belong
+// to the source and can only go to one new record.
+func splitItemIDs(values []string, owned, taken map[uuid.UUID]bool, kind, sourceNumber string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s id: %s", kind, v)
+		}
+		if !owned[id] {
+			return nil, fmt.Errorf("%s %s does not belong to %s", kind, v, sourceNumber)
+		}
+		if taken[id] {
+			return nil, fmt.Errorf("%s %s is selected more than once", kind, v)
+		}
+		taken[id] = true
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func countIDs(groups [][]uuid.UUID) int {
+	n := 0
+	for _, g := range groups {
+		n += len(g)
+	}
+	return n
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var ErrIncidentRelationNotFound = errors.New("relation not found")
+
+// ListRelations returns the record's relations as seen from the record, optionally only
+// those of one type (e.g. blocked_by)
+func (s *incidentService) ListRelations(ctx context.Context, incidentID uuid.UUID, relationType string) ([]models.IncidentRelationResponse, error) {
+	if _, err := s.incidentRepo.FindByID(ctx, incidentID); err != nil {
+		return nil, errors.New("record not found")
+	}
+	relations, err := s.incidentRepo.ListRelations(ctx, incidentID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.IncidentRelationResponse, 0, len(relations))
+	for i := range relations {
+		resp := models.ToIncidentRelationResponse(&relations[i], incidentID)
+		if relationType != "" && resp.RelationType != relationType {
+			continue
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+// CreateRelation relates the record to a target, read as "<record> <relation_type> <target>"
+func (s *incidentService) CreateRelation(ctx context.Context, incidentID uuid.UUID, req *models.IncidentRelationCreateRequest, userID uuid.UUID) (*models.IncidentRelationResponse, error) {
+	targetID, err := uuid.Parse(req.TargetID)
+	if err != nil {
+		return nil, errors.New("invalid target_id")
+	}
+	if targetID == incidentID {
+		return nil, errors.New("a record cannot be related to itself")
+	}
+	stored, swap, ok := models.NormalizeRelationType(req.RelationType)
+	if !ok {
+		return nil, fmt.Errorf("invalid relation_type %q", req.RelationType)
+	}
+	if req.Enforced && stored != models.RelationBlocks {
+		return nil, errors.New("only blocks and blocked_by relations can be enforced")
+	}
+
+	incident, err := s.incidentRepo.FindByID(ctx, incidentID)
+	if err != nil {
+		return nil, errors.New("record not found")
+	}
+	target, err := s.incidentRepo.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, errors.New("target record not found")
+	}
+
+	source, dest := incident, target
+	if swap {
+		source, dest = target, incident
+	}
+
+	exists, err := s.incidentRepo.RelationExists(ctx, source.ID, dest.ID, stored)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("%s and %s are already related as %s", incident.IncidentNumber, target.IncidentNumber, stored)
+	}
+
+	// Blocking and parent chains must not loop back
+	if stored == models.RelationBlocks || stored == models.RelationParentOf {
+		cycle, err := s.incidentRepo.RelationReachable(ctx, dest.ID, source.ID, stored)
+		if err != nil {
+			return nil, err
+		}
+		if cycle {
+			return nil, fmt.Errorf("relating %s to %s as %s would create a cycle", incident.IncidentNumber, target.IncidentNumber, req.RelationType)
+		}
+	}
+
+	relation := &models.IncidentRelation{
+		SourceID:     source.ID,
+		TargetID:     dest.ID,
+		RelationType: stored,
+		Enforced:     req.Enforced,
+		Note:         strings.TrimSpace(req.Note),
+		CreatedByID:  userID,
+	}
+	if err := s.incidentRepo.CreateRelation(ctx, relation); err != nil {
+		return nil, err
+	}
+
+	s.relationRevisions(ctx, relation, source, dest, "added", userID)
+
+	created, err := s.incidentRepo.FindRelationByID(ctx, relation.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := models.ToIncidentRelationResponse(created, incidentID)
+	return &resp, nil
+}
+
+// UpdateRelation changes a relation's note and enforcement
+func (s *incidentService) UpdateRelation(ctx context.Context, incidentID, relationID uuid.UUID, req *models.IncidentRelationUpdateRequest, userID uuid.UUID) (*models.IncidentRelationResponse, error) {
+	relation, err := s.recordRelation(ctx, incidentID, relationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Enforced != nil {
+		if *req.Enforced && relation.RelationType != models.RelationBlocks {
+			return nil, errors.New("only blocks and blocked_by relations can be enforced")
+		}
+		if relation.Enforced != *req.Enforced {
+			description := fmt.Sprintf("Blocker %s is no longer enforced", relationNumber(relation.Source))
+			if *req.Enforced {
+				description = fmt.Sprintf("Blocker %s is now enforced: cannot close while it is open", relationNumber(relation.Source))
+			}
+			_ = s.CreateRevision(ctx, relation.TargetID, models.RevisionActionFieldChange, description, nil, userID)
+		}
+		relation.Enforced = *req.Enforced
+	}
+	if req.Note != nil {
+		relation.Note = strings.TrimSpace(*req.Note)
+	}
+
+	if err := s.incidentRepo.UpdateRelation(ctx, relation); err != nil {
+		return nil, err
+	}
+	updated, err := s.incidentRepo.FindRelationByID(ctx, relation.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := models.ToIncidentRelationResponse(updated, incidentID)
+	return &resp, nil
+}
+
+func (s *incidentService) DeleteRelation(ctx context.Context, incidentID, relationID uuid.UUID, userID uuid.UUID) error {
+	relation, err := s.recordRelation(ctx, incidentID, relationID)
+	if err != nil {
+		return err
+	}
+	if err := s.incidentRepo.DeleteRelation(ctx, relation.ID); err != nil {
+		return err
+	}
+	s.relationRevisions(ctx, relation, relation.Source, relation.Target, "removed", userID)
+	return nil
+}
+
+// recordRelation loads a relation the record takes part in
+func (s *incidentService) recordRelation(ctx context.Context, incidentID, relationID uuid.UUID) (*models.IncidentRelation, error) {
+	relation, err := s.incidentRepo.FindRelationByID(ctx, relationID)
+	if err != nil {
+		return nil, ErrIncidentRelationNotFound
+	}
+	if relation.SourceID != incidentID && relation.TargetID != incidentID {
+		return nil, ErrIncidentRelationNotFound
+	}
+	return relation, nil
+}
+
+// relationRevisions writes a revision on both records, each describing the relation from its side
+func (s *incidentService) relationRevisions(ctx context.Context, relation *models.IncidentRelation, source, target *models.Incident, action string, userID uuid.UUID) {
+	sourceSide := fmt.Sprintf("%s %s", relation.RelationType, relationNumber(target))
+	targetSide := fmt.Sprintf("%s %s", models.InverseRelationType(relation.RelationType), relationNumber(source))
+
+	for _, side := range []struct {
+		id    uuid.UUID
+		label string
+	}{{relation.SourceID, sourceSide}, {relation.TargetID, targetSide}} {
+		label := side.label
+		change := models.IncidentFieldChange{FieldName: "relation", FieldLabel: "Relation"}
+		if action == "added" {
+			change.NewValue = &label
+		} else {
+			change.OldValue = &label
+		}
+		_ = s.CreateRevision(ctx, side.id, models.RevisionActionFieldChange,
+			fmt.Sprintf("Relation %s: %s", action, label),
+			[]models.IncidentFieldChange{change}, userID)
+	}
+}
+
+func relationNumber(incident *models.Incident) string {
+	if incident == nil {
+		return "a deleted record"
+	}
+	return incident.IncidentNumber
+}
+
+// checkOpenBlockers refuses to close a record while a record with an enforced blocks
+// relation on it is still open
+func (s *incidentService) checkOpenBlockers(ctx context.Context, incidentID uuid.UUID) error {
+	blockers, err := s.incidentRepo.ListOpenBlockers(ctx, incidentID)
+	if err != nil {
+		return err
+	}
+	if len(blockers) == 0 {
+		return nil
+	}
+	numbers := make([]string, len(blockers))
+	for i, b := range blockers {
+		numbers[i] = b.IncidentNumber
+	}
+	return fmt.Errorf("cannot close: blocked by open record(s) %s", strings.Join(numbers, ", "))
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// recordTypeViewPermissions maps each record type to the permission needed to see it
+var recordTypeViewPermissions = map[string]string{
+	"incident":  "incidents:view",
+	"request":   "requests:view",
+	"complaint": "complaints:view",
+	"query":     "queries:view",
+}
+
+var (
+	ErrSearchQueryRequired = errors.New("search query is required")
+	ErrSearchForbidden     = errors.New("not allowed to view this record type")
+)
+
+// IncidentSearchService runs full-text searches limited to what the caller may view
+type IncidentSearchService interface {
+	Search(ctx context.Context, userID uuid.UUID, filter *models.IncidentSearchFilter) ([]models.IncidentSearchResult, int64, error)
+}
+
+type incidentSearchService struct {
+	repo     repository.IncidentSearchRepository
+	userRepo repository.UserRepository
+}
+
+func NewIncidentSearchService(repo repository.IncidentSearchRepository, userRepo repository.UserRepository) IncidentSearchService {
+	return &incidentSearchService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+// viewableRecordTypes lists the record types the user holds the view permission for
+func viewableRecordTypes(user *models.User) []string {
+	var recordTypes []string
+	for recordType, permission := range recordTypeViewPermissions {
+		if user.HasPermission(permission) {
+			recordTypes = append(recordTypes, recordType)
+		}
+	}
+	sort.Strings(recordTypes)
+	return recordTypes
+}
+
+func (s *incidentSearchService) Search(ctx context.Context, userID uuid.UUID, filter *models.IncidentSearchFilter) ([]models.IncidentSearchResult, int64, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Query == "" {
+		return nil, 0, ErrSearchQueryRequired
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > 100 {
+		filter.Limit = 20
+	}
+
+	user, err := s.userRepo.FindByIDWithPermissions(ctx, userID)
+	if err != nil {
+		return nil, 0, errors.New("user not found")
+	}
+
+	// Record types follow the view permissions; states follow viewable_roles
+	filter.RecordTypes = viewableRecordTypes(user)
+	if len(filter.RecordTypes) == 0 {
+		return nil, 0, ErrSearchForbidden
+	}
+	if permission, ok := recordTypeViewPermissions[filter.RecordType]; ok && !user.HasPermission(permission) {
+		return nil, 0, ErrSearchForbidden
+	}
+
+	filter.AllStates = user.IsSuperAdmin
+	filter.UserRoleIDs = make([]uuid.UUID, 0, len(user.Roles))
+	for _, role := range user.Roles {
+		filter.UserRoleIDs = append(filter.UserRoleIDs, role.ID)
+	}
+
+	results, total, err := s.repo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	if results == nil {
+		results = []models.IncidentSearchResult{}
+	}
+	return results, total, nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	"github.com/automax/backend/internal/repository"
 	"github.com/automax/backend/internal/storage"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type IncidentService interface {
@@ -37,6 +39,10 @@ type IncidentService interface {
 	ExecuteTransition(ctx context.Context, incidentID uuid.UUID, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.IncidentResponse, error)
 	GetAvailableTransitions(ctx context.Context, incidentID uuid.UUID, userRoleIDs []uuid.UUID) ([]models.AvailableTransitionResponse, error)
 	GetTransitionHistory(ctx context.Context, incidentID uuid.UUID) ([]models.TransitionHistoryResponse, error)
+	PreviewTransition(ctx context.Context, incidentID uuid.UUID, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.TransitionPreviewResponse, error)
+	ExecuteAutoTransition(ctx context.Context, incidentID, transitionID, systemUserID uuid.UUID) (*models.IncidentResponse, error)
+	GetIncidentApprovals(ctx context.Context, incidentID uuid.UUID) ([]models.ApprovalStatusResponse, error)
+	GetPendingApprovals(ctx context.Context, userID uuid.UUID, recordType string) ([]models.PendingApprovalResponse, error)
 
 	// Comments
 	AddComment(ctx context.Context, incidentID uuid.UUID, req *models.IncidentCommentRequest, authorID uuid.UUID) (*models.IncidentCommentResponse, error)
@@ -65,21 +71,43 @@ type IncidentService interface {
 	// Revisions
 	ListRevisions(ctx context.Context, incidentID uuid.UUID, filter *models.IncidentRevisionFilter) ([]models.IncidentRevisionResponse, int64, error)
 	CreateRevision(ctx context.Context, incidentID uuid.UUID, actionType models.IncidentRevisionActionType, description string, changes []models.IncidentFieldChange, userID uuid.UUID) error
+
+	// Duplicates
+	FindDuplicates(ctx context.Context, incidentID uuid.UUID) ([]models.DuplicateCandidate, error)
+	MergeDuplicate(ctx context.Context, duplicateID uuid.UUID, req *models.MergeDuplicateRequest, userID uuid.UUID) (*models.MergeDuplicateResponse, error)
+
+	// Merge and split
+	MergeIncident(ctx context.Context, sourceID uuid.UUID, req *models.MergeIncidentRequest, userID uuid.UUID) (*models.MergeIncidentResponse, error)
+	SplitIncident(ctx context.Context, sourceID uuid.UUID, req *models.SplitIncidentRequest, userID uuid.UUID) (*models.SplitIncidentResponse, error)
+
+	// Relations
+	ListRelations(ctx context.Context, incidentID uuid.UUID, relationType string) ([]models.IncidentRelationResponse, error)
+	CreateRelation(ctx context.Context, incidentID uuid.UUID, req *models.IncidentRelationCreateRequest, userID uuid.UUID) (*models.IncidentRelationResponse, error)
+	UpdateRelation(ctx context.Context, incidentID, relationID uuid.UUID, req *models.IncidentRelationUpdateRequest, userID uuid.UUID) (*models.IncidentRelationResponse, error)
+	DeleteRelation(ctx context.Context, incidentID, relationID uuid.UUID, userID uuid.UUID) error
 }
 
 type incidentService struct {
-	incidentRepo repository.IncidentRepository
-	workflowRepo repository.WorkflowRepository
-	userRepo     repository.UserRepository
-	storage      *storage.MinIOStorage
+	incidentRepo   repository.IncidentRepository
+	workflowRepo   repository.WorkflowRepository
+	userRepo       repository.UserRepository
+	deptRepo       repository.DepartmentRepository
+	storage        *storage.MinIOStorage
+	actionExecutor ActionExecutor
+	eventBus       EventBus
+	db             *gorm.DB
 }
 
-func NewIncidentService(incidentRepo repository.IncidentRepository, workflowRepo repository.WorkflowRepository, userRepo repository.UserRepository, storage *storage.MinIOStorage) IncidentService {
+func NewIncidentService(incidentRepo repository.IncidentRepository, workflowRepo repository.WorkflowRepository, userRepo repository.UserRepository, deptRepo repository.DepartmentRepository, storage *storage.MinIOStorage, actionExecutor ActionExecutor, eventBus EventBus, db *gorm.DB) IncidentService {
 	return &incidentService{
-		incidentRepo: incidentRepo,
-		workflowRepo: workflowRepo,
-		userRepo:     userRepo,
-		storage:      storage,
+		incidentRepo:   incidentRepo,
+		workflowRepo:   workflowRepo,
+		userRepo:       userRepo,
+		deptRepo:       deptRepo,
+		storage:        storage,
+		actionExecutor: actionExecutor,
+		eventBus:       eventBus,
+		db:             db,
 	}
 }
 
@@ -92,8 +120,8 @@ func (s *incidentService) CreateIncident(ctx context.Context, req *models.Incide
 		return nil, errors.New("invalid workflow_id")
 	}
 
-	// Get the initial state of the workflow
-	initialState, err := s.workflowRepo.GetInitialState(ctx, workflowID)
+	// Get the initial state of the latest published version (or the live workflow)
+	initialState, workflowVersion, err := resolveWorkflowStart(ctx, s.workflowRepo, workflowID)
 	if err != nil {
 		return nil, errors.New("workflow has no initial state configured")
 	}
@@ -121,23 +149,24 @@ func (s *incidentService) CreateIncident(ctx context.Context, req *models.Incide
 	}
 
 	incident := &models.Incident{
-		IncidentNumber: incidentNumber,
-		Title:          req.Title,
-		Description:    req.Description,
-		WorkflowID:     workflowID,
-		CurrentStateID: initialState.ID,
-		ReporterID:     &reporterID,
-		ReporterEmail:  req.ReporterEmail,
-		ReporterName:   req.ReporterName,
-		CustomFields:   req.CustomFields,
-		Latitude:       req.Latitude,
-		Longitude:      req.Longitude,
-		Address:        req.Address,
-		City:           req.City,
-		State:          req.State,
-		Country:        req.Country,
-		PostalCode:     req.PostalCode,
-		RecordType:     recordType,
+		IncidentNumber:  incidentNumber,
+		Title:           req.Title,
+		Description:     req.Description,
+		WorkflowID:      workflowID,
+		WorkflowVersion: workflowVersion,
+		CurrentStateID:  initialState.ID,
+		ReporterID:      &reporterID,
+		ReporterEmail:   req.ReporterEmail,
+		ReporterName:    req.ReporterName,
+		CustomFields:    req.CustomFields,
+		Latitude:        req.Latitude,
+		Longitude:       req.Longitude,
+		Address:         req.Address,
+		City:            req.City,
+		State:           req.State,
+		Country:         req.Country,
+		PostalCode:      req.PostalCode,
+		RecordType:      recordType,
 	}
 
 	// Parse optional UUIDs
@@ -228,6 +257,8 @@ func (s *incidentService) CreateIncident(ctx context.Context, req *models.Incide
 	}
 
 	resp := models.ToIncidentResponse(created)
+	s.publishIncidentEvent(ctx, models.EventIncidentCreated, created, resp)
+	resp.DuplicateCandidates = s.duplicateCandidates(ctx, created)
 	return &resp, nil
 }
 
@@ -238,6 +269,15 @@ func (s *incidentService) GetIncident(ctx context.Context, id uuid.UUID) (*model
 	}
 
 	resp := models.ToIncidentDetailResponse(s.storage, incident)
+
+	relations, err := s.incidentRepo.ListRelations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	resp.Relations = make([]models.IncidentRelationResponse, len(relations))
+	for i := range relations {
+		resp.Relations[i] = models.ToIncidentRelationResponse(&relations[i], id)
+	}
 	return &resp, nil
 }
 
@@ -338,6 +378,7 @@ func (s *incidentService) UpdateIncident(ctx context.Context, id uuid.UUID, req
 					NewValue:   nil,
 				})
 				descriptions = append(descriptions, fmt.Sprintf("AssignedTo changed from %s to Unassigned", oldAssigneeName))
+				incident.PreviousAssigneeID = incident.AssigneeID
 			}
 			incident.AssigneeID = nil
 		} else {
@@ -352,6 +393,7 @@ func (s *incidentService) UpdateIncident(ctx context.Context, id uuid.UUID, req
 						NewValue:   &newVal,
 					})
 					descriptions = append(descriptions, fmt.Sprintf("AssignedTo changed from %s", oldAssigneeName))
+					incident.PreviousAssigneeID = incident.AssigneeID
 				}
 				incident.AssigneeID = &assigneeID
 			}
@@ -436,6 +478,7 @@ func (s *incidentService) UpdateIncident(ctx context.Context, id uuid.UUID, req
 	}
 
 	resp := models.ToIncidentResponse(updated)
+	s.publishIncidentEvent(ctx, models.EventIncidentUpdated, updated, resp)
 	return &resp, nil
 }
 
@@ -506,98 +549,54 @@ func (s *incidentService) ConvertToRequest(ctx context.Context, incidentID uuid.
 		return nil, errors.New("invalid workflow_id")
 	}
 
-	// Get the initial state of the request workflow
-	initialState, err := s.workflowRepo.GetInitialState(ctx, workflowID)
-	if err != nil {
-		return nil, errors.New("workflow has no initial state configured")
-	}
-
 	// Parse classification ID
 	classificationID, err := uuid.Parse(req.ClassificationID)
 	if err != nil {
 		return nil, errors.New("invalid classification_id")
 	}
 
-	// Generate request number
-	requestNumber, err := s.incidentRepo.GenerateRequestNumber(ctx)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate request number: %w", err)
+	params := &linkedRecordParams{
+		RecordType:       "request",
+		WorkflowID:       workflowID,
+		ClassificationID: &classificationID,
 	}
-
-	// Create the new request, copying relevant data from source incident
-	title := sourceIncident.Title
 	if req.Title != nil && *req.Title != "" {
-		title = *req.Title
+		params.Title = *req.Title
 	}
-
-	description := sourceIncident.Description
 	if req.Description != nil && *req.Description != "" {
-		description = *req.Description
-	}
-
-	newRequest := &models.Incident{
-		IncidentNumber:   requestNumber,
-		Title:            title,
-		Description:      description,
-		RecordType:       "request",
-		SourceIncidentID: &incidentID,
-		ClassificationID: &classificationID,
-		WorkflowID:       workflowID,
-		CurrentStateID:   initialState.ID,
-		ReporterID:       sourceIncident.ReporterID,
-		ReporterEmail:    sourceIncident.ReporterEmail,
-		ReporterName:     sourceIncident.ReporterName,
-		LocationID:       sourceIncident.LocationID,
-		Latitude:         sourceIncident.Latitude,
-		Longitude:        sourceIncident.Longitude,
-		CustomFields:     sourceIncident.CustomFields,
+		params.Description = *req.Description
 	}
 
 	// Handle optional assignee override
 	if req.AssigneeID != nil && *req.AssigneeID != "" {
 		assigneeID, err := uuid.Parse(*req.AssigneeID)
 		if err == nil {
-			newRequest.AssigneeID = &assigneeID
+			params.AssigneeID = &assigneeID
 		}
-	} else {
-		newRequest.AssigneeID = sourceIncident.AssigneeID
 	}
 
 	// Handle optional department override
 	if req.DepartmentID != nil && *req.DepartmentID != "" {
 		deptID, err := uuid.Parse(*req.DepartmentID)
 		if err == nil {
-			newRequest.DepartmentID = &deptID
+			params.DepartmentID = &deptID
 		}
-	} else {
-		newRequest.DepartmentID = sourceIncident.DepartmentID
 	}
 
 	// Handle due date
 	if req.DueDate != nil && *req.DueDate != "" {
 		dueDate, err := time.Parse(time.RFC3339, *req.DueDate)
 		if err == nil {
-			newRequest.DueDate = &dueDate
+			params.DueDate = &dueDate
 		}
 	}
 
-	// Calculate SLA deadline based on initial state
-	if initialState.SLAHours != nil && *initialState.SLAHours > 0 {
-		deadline := time.Now().Add(time.Duration(*initialState.SLAHours) * time.Hour)
-		newRequest.SLADeadline = &deadline
-	}
-
-	// Create the request
-	if err := s.incidentRepo.Create(ctx, newRequest); err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Copy lookup values from source incident
-	if len(sourceIncident.LookupValues) > 0 {
-		if err := s.incidentRepo.SetLookupValues(ctx, newRequest.ID, sourceIncident.LookupValues); err != nil {
-			fmt.Printf("Warning: failed to copy lookup values: %v\n", err)
-		}
+	// Create the request, copying relevant data from source incident
+	newRequest, err := createLinkedRecord(ctx, s.incidentRepo, s.workflowRepo, sourceIncident, params)
+	if err != nil {
+		return nil, err
 	}
+	requestNumber := newRequest.IncidentNumber
 
 	// Update source incident with reference to the converted request
 	if err := s.incidentRepo.UpdateFields(ctx, incidentID, map[string]interface{}{
@@ -622,7 +621,7 @@ func (s *incidentService) ConvertToRequest(ctx context.Context, incidentID uuid.
 			NewValue:   &requestNumber,
 		},
 	}
-	description = fmt.Sprintf("Incident converted to request %s", requestNumber)
+	description := fmt.Sprintf("Incident converted to request %s", requestNumber)
 	_ = s.CreateRevision(ctx, incidentID, models.RevisionActionFieldChange, description, changes, userID)
 
 	// Create revision for new request
@@ -640,6 +639,7 @@ func (s *incidentService) ConvertToRequest(ctx context.Context, incidentID uuid.
 	// Build response
 	originalResp := models.ToIncidentResponse(sourceIncident)
 	newResp := models.ToIncidentResponse(createdRequest)
+	s.publishIncidentEvent(ctx, models.EventIncidentCreated, createdRequest, newResp)
 
 	return &models.ConvertToRequestResponse{
 		OriginalIncident: &originalResp,
@@ -691,6 +691,19 @@ func (s *incidentService) CanConvertToRequest(ctx context.Context, incidentID uu
 // State transitions
 
 func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid.UUID, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.IncidentResponse, error) {
+	return s.executeTransition(ctx, incidentID, req, userID, userRoleIDs, false)
+}
+
+// ExecuteAutoTransition runs a transition on behalf of the scheduler. Role checks, requirements
+// and approvals apply to people and are skipped; the move is attributed to the system user.
+func (s *incidentService) ExecuteAutoTransition(ctx context.Context, incidentID, transitionID, systemUserID uuid.UUID) (*models.IncidentResponse, error) {
+	req := &models.IncidentTransitionRequest{
+		TransitionID: transitionID.String(),
+	}
+	return s.executeTransition(ctx, incidentID, req, systemUserID, nil, true)
+}
+
+func (s *incidentService) executeTransition(ctx context.Context, incidentID uuid.UUID, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID, automatic bool) (*models.IncidentResponse, error) {
 	// Get the incident
 	incident, err := s.incidentRepo.FindByID(ctx, incidentID)
 	if err != nil {
@@ -704,7 +717,7 @@ func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid
 	}
 
 	// Get the transition with relations
-	transition, err := s.workflowRepo.FindTransitionByIDWithRelations(ctx, transitionID)
+	transition, err := loadIncidentTransition(ctx, s.workflowRepo, incident, transitionID)
 	if err != nil {
 		return nil, errors.New("transition not found")
 	}
@@ -719,74 +732,114 @@ func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid
 		return nil, errors.New("transition cannot be executed from current state")
 	}
 
+	// Scheduled runs only execute transitions that are still configured to fire automatically
+	if automatic && transition.AutoTrigger == "" {
+		return nil, errors.New("transition is not automatic")
+	}
+
 	// Check role authorization
-	if len(transition.AllowedRoles) > 0 {
-		hasPermission := false
-		for _, allowedRole := range transition.AllowedRoles {
-			for _, userRoleID := range userRoleIDs {
-				if allowedRole.ID == userRoleID {
-					hasPermission = true
-					break
-				}
-			}
-			if hasPermission {
-				break
-			}
-		}
-		if !hasPermission {
-			return nil, errors.New("you do not have permission to execute this transition")
-		}
+	if !automatic && !hasTransitionRole(transition, userRoleIDs) {
+		return nil, errors.New("you do not have permission to execute this transition")
 	}
 
 	// Validate requirements
-	for _, requirement := range transition.Requirements {
-		if requirement.IsMandatory == nil || !*requirement.IsMandatory {
-			continue
+	if !automatic {
+		if err := s.checkTransitionRequirements(ctx, incident, transition, req); err != nil {
+			return nil, err
 		}
+	}
 
-		switch requirement.RequirementType {
-		case "comment":
-			if req.Comment == "" {
-				errMsg := requirement.ErrorMessage
-				if errMsg == "" {
-					errMsg = "Comment is required for this transition"
-				}
-				return nil, errors.New(errMsg)
+	// Approval transitions record a vote and only move on once the quorum is reached.
+	// A rejection moves the incident to the transition's reject state, if it has one.
+	var vote, pendingVote *models.TransitionApproval
+	defer func() {
+		if pendingVote != nil {
+			if err := s.incidentRepo.DeleteApproval(context.WithoutCancel(ctx), pendingVote.ID); err != nil {
+				log.Printf("Warning: failed to withdraw approval vote: %v", err)
 			}
-		case "attachment":
-			if len(req.Attachments) == 0 {
-				errMsg := requirement.ErrorMessage
-				if errMsg == "" {
-					errMsg = "Attachment is required for this transition"
-				}
-				return nil, errors.New(errMsg)
-			}
-		case "feedback":
-			if req.Feedback == nil || req.Feedback.Rating == 0 {
-				errMsg := requirement.ErrorMessage
-				if errMsg == "" {
-					errMsg = "Feedback is required for this transition"
-				}
-				return nil, errors.New(errMsg)
+		}
+	}()
+	if transition.ApprovalMode != "" && !automatic {
+		var outcome approvalOutcome
+		vote, outcome, err = s.castApprovalVote(ctx, incident, transition, req, userID, userRoleIDs)
+		if err != nil {
+			return nil, err
+		}
+		if outcome == approvalPending || (outcome == approvalRejected && transition.RejectStateID == nil) {
+			return s.recordApprovalVote(ctx, incident, transition, vote, outcome)
+		}
+
+		// The vote that decided the round is withdrawn if the incident doesn't move, so the
+		// next vote can decide it again
+		pendingVote = vote
+		if outcome == approvalRejected {
+			if transition, err = s.rejectionTransition(ctx, transition); err != nil {
+				return nil, err
 			}
 		}
 	}
 
+	// Linked records created with blocks_parent_close must be closed before the parent can be
+	if transition.ToState != nil && transition.ToState.StateType == "terminal" {
+		openChildren, err := s.incidentRepo.CountOpenBlockingChildren(ctx, incidentID)
+		if err != nil {
+			return nil, err
+		}
+		if openChildren > 0 {
+			return nil, fmt.Errorf("cannot close: %d linked record(s) are still open", openChildren)
+		}
+		if err := s.checkOpenBlockers(ctx, incidentID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Work out the new state, field updates and assignees
+	plan, err := s.planTransition(ctx, incident, transition, req)
+	if err != nil {
+		return nil, err
+	}
+	newState := plan.newState
+	updates := plan.updates
+	assigneeUserIDs := plan.assigneeUserIDs
+
+	// The parent enters the new state now; sub-workflow children count from this moment
+	transitionedAt := time.Now()
+
+	// Sub-workflow states launch their child record first, so a child that can't be created fails
+	// the transition before anything is written. The child copies the parent as it will be.
+	var child *models.Incident
+	if newState.StateType == models.StateTypeSubWorkflow {
+		child, err = s.launchSubWorkflow(ctx, plannedIncident(incident, plan, nil, nil), newState, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to launch sub-workflow: %w", err)
+		}
+	}
+
 	// Create transition history record
 	history := &models.IncidentTransitionHistory{
 		IncidentID:     incidentID,
-		TransitionID:   transitionID,
+		TransitionID:   &transitionID,
 		FromStateID:    incident.CurrentStateID,
 		ToStateID:      transition.ToStateID,
 		PerformedByID:  userID,
 		Comment:        req.Comment,
-		TransitionedAt: time.Now(),
+		TransitionedAt: transitionedAt,
+	}
+	if vote != nil {
+		history.ApprovalDecision = vote.Decision
 	}
 
 	if err := s.incidentRepo.CreateTransitionHistory(ctx, history); err != nil {
 		return nil, err
 	}
 
+	if vote != nil {
+		vote.HistoryID = &history.ID
+		if err := s.incidentRepo.SetApprovalHistory(ctx, vote.ID, history.ID); err != nil {
+			return nil, fmt.Errorf("failed to record approval vote: %w", err)
+		}
+	}
+
 	// Link attachments to this transition if provided
 	if len(req.Attachments) > 0 {
 		attachmentIDs := make([]uuid.UUID, 0, len(req.Attachments))
@@ -827,10 +880,98 @@ func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid
 		}
 	}
 
-	// Get new state for SLA calculation
-	newState, err := s.workflowRepo.FindStateByID(ctx, transition.ToStateID)
+	// Apply all updates in a single query
+	if err := s.incidentRepo.UpdateFields(ctx, incidentID, updates); err != nil {
+		if child != nil {
+			if delErr := s.incidentRepo.Delete(ctx, child.ID); delErr != nil {
+				fmt.Printf("Warning: failed to remove sub-workflow record: %v\n", delErr)
+			}
+		}
+		return nil, err
+	}
+	pendingVote = nil
+
+	// Leaving the state ends any approval rounds that were open on it
+	if err := s.incidentRepo.ResolveOpenApprovals(ctx, incidentID); err != nil {
+		fmt.Printf("Warning: failed to resolve approvals: %v\n", err)
+	}
+
+	// Set multiple assignees if applicable
+	if len(assigneeUserIDs) > 0 {
+		if err := s.incidentRepo.SetAssignees(ctx, incidentID, assigneeUserIDs); err != nil {
+			// Log error but don't fail the transition
+			log.Printf("Warning: failed to set assignees: %v", err)
+		}
+	}
+
+	// Fetch updated incident
+	updated, err := s.incidentRepo.FindByIDWithRelations(ctx, incidentID)
 	if err != nil {
-		return nil, errors.New("target state not found")
+		return nil, err
+	}
+
+	// Execute transition actions (email, webhook, field updates) against the updated incident.
+	// Action failures are recorded in the history's action results and never fail the transition.
+	if s.actionExecutor != nil && len(transition.Actions) > 0 {
+		performedBy, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			performedBy = nil
+		}
+		if _, err := s.actionExecutor.ExecuteActions(ctx, updated, transition, performedBy, history.ID); err != nil {
+			fmt.Printf("Warning: failed to execute transition actions: %v\n", err)
+		}
+
+		// Synchronous actions may have changed the incident
+		if refreshed, err := s.incidentRepo.FindByIDWithRelations(ctx, incidentID); err == nil {
+			updated = refreshed
+		}
+	}
+
+	// Create revision for state change
+	oldStateName := transition.FromState.Name
+	newStateName := newState.Name
+	changes := []models.IncidentFieldChange{
+		{
+			FieldName:  "current_state_id",
+			FieldLabel: "Status",
+			OldValue:   &oldStateName,
+			NewValue:   &newStateName,
+		},
+	}
+	description := fmt.Sprintf("Status changed from %s to %s", oldStateName, newStateName)
+	_ = s.CreateRevision(ctx, incidentID, models.RevisionActionStatusChanged, description, changes, userID)
+
+	resp := models.ToIncidentResponse(updated)
+	s.publishIncidentEvent(ctx, models.EventIncidentTransitioned, updated, resp)
+
+	// A closing sub-workflow child resumes its parent
+	if newState.StateType == "terminal" && updated.SubWorkflowStateID != nil {
+		if err := s.resumeSubWorkflowParent(ctx, updated, newState); err != nil {
+			fmt.Printf("Warning: failed to resume sub-workflow parent: %v\n", err)
+		}
+	}
+
+	return &resp, nil
+}
+
+// transitionPlan is the outcome of a transition worked out ahead of applying it
+type transitionPlan struct {
+	newState        *models.WorkflowState
+	updates         map[string]interface{}
+	assigneeUserIDs []uuid.UUID
+}
+
+// planTransition computes the state, department, assignee and SLA changes a transition makes
+// without writing anything, so previews and execution share the same rules
+func (s *incidentService) planTransition(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, req *models.IncidentTransitionRequest) (*transitionPlan, error) {
+	// Get new state for SLA calculation; pinned versions carry it in the snapshot
+	newState := transition.ToState
+	if newState == nil {
+		var err error
+		newState, err = s.workflowRepo.FindStateByID(ctx, transition.ToStateID)
+		if err != nil {
+			return nil, errors.New("target state not found")
+		}
 	}
 
 	// Prepare updates map for all fields that need to change
@@ -854,93 +995,45 @@ func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid
 	// Handle user assignment from transition settings
 	var assigneeUserIDs []uuid.UUID
 
-	fmt.Printf("[DEBUG] === USER ASSIGNMENT START ===\n")
-	fmt.Printf("[DEBUG] Transition: %s (ID: %s)\n", transition.Name, transition.ID)
-	fmt.Printf("[DEBUG] AssignUserID: %v\n", transition.AssignUserID)
-	fmt.Printf("[DEBUG] ManualSelectUser: %v\n", transition.ManualSelectUser)
-	fmt.Printf("[DEBUG] AutoMatchUser: %v\n", transition.AutoMatchUser)
-	fmt.Printf("[DEBUG] AssignmentRoleID: %v\n", transition.AssignmentRoleID)
-
 	if transition.AssignUserID != nil {
 		// Static user assignment - single user
-		fmt.Printf("[DEBUG] Using STATIC user assignment: %s\n", *transition.AssignUserID)
 		updates["assignee_id"] = *transition.AssignUserID
 		assigneeUserIDs = append(assigneeUserIDs, *transition.AssignUserID)
 	} else if transition.ManualSelectUser && transition.AssignmentRoleID != nil {
 		// Manual selection mode - user must select from dropdown
-		fmt.Printf("[DEBUG] Using MANUAL SELECT mode\n")
 		if req.UserID != nil && *req.UserID != "" {
-			fmt.Printf("[DEBUG] User selected: %s\n", *req.UserID)
 			userAssignID, err := uuid.Parse(*req.UserID)
 			if err == nil {
 				updates["assignee_id"] = userAssignID
 				assigneeUserIDs = append(assigneeUserIDs, userAssignID)
 			}
-		} else {
-			fmt.Printf("[DEBUG] No user selected in manual mode\n")
 		}
 		// If no user selected, keep current assignee (don't fail the transition)
 	} else if transition.AutoMatchUser && transition.AssignmentRoleID != nil {
 		// Auto-match mode - find ALL matching users and assign to all of them
-		fmt.Printf("[DEBUG] Using AUTO MATCH mode with role: %s\n", *transition.AssignmentRoleID)
-		var classificationID, locationID, departmentID, excludeUserID *uuid.UUID
-		if incident.ClassificationID != nil {
-			classificationID = incident.ClassificationID
-			fmt.Printf("[DEBUG] ClassificationID: %s\n", *classificationID)
-		}
-		if incident.LocationID != nil {
-			locationID = incident.LocationID
-			fmt.Printf("[DEBUG] LocationID: %s\n", *locationID)
-		}
-		if incident.DepartmentID != nil {
-			departmentID = incident.DepartmentID
-			fmt.Printf("[DEBUG] DepartmentID: %s\n", *departmentID)
-		}
-		if incident.AssigneeID != nil {
-			excludeUserID = incident.AssigneeID
-			fmt.Printf("[DEBUG] ExcludeUserID (current assignee): %s\n", *excludeUserID)
-		}
+		excludeUserID := incident.AssigneeID
 
 		// First try matching with all criteria
-		fmt.Printf("[DEBUG] Calling FindMatching with all criteria...\n")
-		matchedUsers, err := s.userRepo.FindMatching(ctx, transition.AssignmentRoleID, classificationID, locationID, departmentID, excludeUserID)
-		fmt.Printf("[DEBUG] FindMatching result: %d users, error: %v\n", len(matchedUsers), err)
-		if err == nil && len(matchedUsers) > 0 {
-			// Assign ALL matched users
-			fmt.Printf("[DEBUG] Found %d matching users with full criteria:\n", len(matchedUsers))
+		matchedUsers, err := s.userRepo.FindMatching(ctx, transition.AssignmentRoleID, incident.ClassificationID, incident.LocationID, incident.DepartmentID, excludeUserID)
+		if err == nil && len(matchedUsers) == 0 {
+			// No exact matches - try matching by role only (more permissive)
+			matchedUsers, err = s.userRepo.FindMatching(ctx, transition.AssignmentRoleID, nil, nil, nil, excludeUserID)
+		}
+		if err != nil {
+			log.Printf("Warning: failed to match assignees for transition %s: %v", transition.ID, err)
+		} else if len(matchedUsers) > 0 {
+			// Assign ALL matched users, with the first as primary assignee
 			for _, user := range matchedUsers {
-				fmt.Printf("[DEBUG]   - %s (%s)\n", user.Username, user.ID)
 				assigneeUserIDs = append(assigneeUserIDs, user.ID)
 			}
-			// Set primary assignee to first matched user
 			updates["assignee_id"] = matchedUsers[0].ID
-			fmt.Printf("[DEBUG] Primary assignee set to: %s\n", matchedUsers[0].Username)
-		} else if err == nil && len(matchedUsers) == 0 {
-			// No exact matches - try matching by role only (more permissive)
-			fmt.Printf("[DEBUG] No exact matches, trying role-only match...\n")
-			roleOnlyUsers, roleErr := s.userRepo.FindMatching(ctx, transition.AssignmentRoleID, nil, nil, nil, excludeUserID)
-			fmt.Printf("[DEBUG] Role-only match result: %d users, error: %v\n", len(roleOnlyUsers), roleErr)
-			if roleErr == nil && len(roleOnlyUsers) > 0 {
-				// Assign ALL users with that role
-				fmt.Printf("[DEBUG] Found %d users with role only:\n", len(roleOnlyUsers))
-				for _, user := range roleOnlyUsers {
-					fmt.Printf("[DEBUG]   - %s (%s)\n", user.Username, user.ID)
-					assigneeUserIDs = append(assigneeUserIDs, user.ID)
-				}
-				// Set primary assignee to first matched user
-				updates["assignee_id"] = roleOnlyUsers[0].ID
-				fmt.Printf("[DEBUG] Primary assignee set to: %s\n", roleOnlyUsers[0].Username)
-			} else {
-				fmt.Printf("[DEBUG] No users found even with role-only match\n")
-			}
-		} else if err != nil {
-			fmt.Printf("[DEBUG] Error in FindMatching: %v\n", err)
 		}
-	} else {
-		fmt.Printf("[DEBUG] No assignment mode matched - skipping user assignment\n")
 	}
-	fmt.Printf("[DEBUG] Final assigneeUserIDs: %v\n", assigneeUserIDs)
-	fmt.Printf("[DEBUG] === USER ASSIGNMENT END ===\n")
+
+	// Remember who was assigned before so actions can target the previous assignee
+	if newAssignee, ok := updates["assignee_id"].(uuid.UUID); ok && incident.AssigneeID != nil && *incident.AssigneeID != newAssignee {
+		updates["previous_assignee_id"] = *incident.AssigneeID
+	}
 
 	// Update SLA deadline based on new state
 	if newState.SLAHours != nil && *newState.SLAHours > 0 {
@@ -958,53 +1051,27 @@ func (s *incidentService) ExecuteTransition(ctx context.Context, incidentID uuid
 		updates["closed_at"] = now
 	}
 
-	// Apply all updates in a single query
-	fmt.Printf("[DEBUG] Applying updates: %+v\n", updates)
-	if err := s.incidentRepo.UpdateFields(ctx, incidentID, updates); err != nil {
-		fmt.Printf("[DEBUG] ERROR in UpdateFields: %v\n", err)
-		return nil, err
-	}
-	fmt.Printf("[DEBUG] UpdateFields successful\n")
-
-	// Set multiple assignees if applicable
-	fmt.Printf("[DEBUG] Setting multiple assignees, count: %d\n", len(assigneeUserIDs))
-	if len(assigneeUserIDs) > 0 {
-		fmt.Printf("[DEBUG] Calling SetAssignees with IDs: %v\n", assigneeUserIDs)
-		if err := s.incidentRepo.SetAssignees(ctx, incidentID, assigneeUserIDs); err != nil {
-			// Log error but don't fail the transition
-			fmt.Printf("[DEBUG] ERROR in SetAssignees: %v\n", err)
-		} else {
-			fmt.Printf("[DEBUG] SetAssignees successful\n")
-		}
-	} else {
-		fmt.Printf("[DEBUG] No assignees to set (assigneeUserIDs is empty)\n")
-	}
-
-	// TODO: Execute transition actions (email, webhook, field updates)
-	// This would be implemented in a separate action executor service
+	return &transitionPlan{
+		newState:        newState,
+		updates:         updates,
+		assigneeUserIDs: assigneeUserIDs,
+	}, nil
+}
 
-	// Fetch updated incident
-	updated, err := s.incidentRepo.FindByIDWithRelations(ctx, incidentID)
-	if err != nil {
-		return nil, err
+// hasTransitionRole reports whether the user holds one of the transition's allowed roles.
+// Transitions without allowed roles are open to everyone.
+func hasTransitionRole(transition *models.WorkflowTransition, userRoleIDs []uuid.UUID) bool {
+	if len(transition.AllowedRoles) == 0 {
+		return true
 	}
-
-	// Create revision for state change
-	oldStateName := transition.FromState.Name
-	newStateName := newState.Name
-	changes := []models.IncidentFieldChange{
-		{
-			FieldName:  "current_state_id",
-			FieldLabel: "Status",
-			OldValue:   &oldStateName,
-			NewValue:   &newStateName,
-		},
+	for _, allowedRole := range transition.AllowedRoles {
+		for _, userRoleID := range userRoleIDs {
+			if allowedRole.ID == userRoleID {
+				return true
+			}
+		}
 	}
-	description := fmt.Sprintf("Status changed from %s to %s", oldStateName, newStateName)
-	_ = s.CreateRevision(ctx, incidentID, models.RevisionActionStatusChanged, description, changes, userID)
-
-	resp := models.ToIncidentResponse(updated)
-	return &resp, nil
+	return false
 }
 
 func (s *incidentService) GetAvailableTransitions(ctx context.Context, incidentID uuid.UUID, userRoleIDs []uuid.UUID) ([]models.AvailableTransitionResponse, error) {
@@ -1014,8 +1081,8 @@ func (s *incidentService) GetAvailableTransitions(ctx context.Context, incidentI
 		return nil, err
 	}
 
-	// Get all transitions from current state
-	transitions, err := s.workflowRepo.ListTransitionsFromState(ctx, incident.CurrentStateID)
+	// Get all transitions from current state in the incident's workflow version
+	transitions, err := loadIncidentTransitionsFromState(ctx, s.workflowRepo, incident)
 	if err != nil {
 		return nil, err
 	}
@@ -1110,6 +1177,9 @@ func (s *incidentService) AddComment(ctx context.Context, incidentID uuid.UUID,
 	_ = s.CreateRevision(ctx, incidentID, models.RevisionActionCommentAdded, description, nil, authorID)
 
 	resp := models.ToIncidentCommentResponse(created)
+	if incident, err := s.incidentRepo.FindByID(ctx, incidentID); err == nil {
+		s.publishIncidentEvent(ctx, models.EventIncidentCommented, incident, resp)
+	}
 	return &resp, nil
 }
 
@@ -1309,6 +1379,7 @@ func (s *incidentService) AssignIncident(ctx context.Context, incidentID, assign
 	_ = s.CreateRevision(ctx, incidentID, models.RevisionActionAssigneeChanged, description, changes, userID)
 
 	resp := models.ToIncidentResponse(updated)
+	s.publishIncidentEvent(ctx, models.EventIncidentAssigned, updated, resp)
 	return &resp, nil
 }
 
@@ -1428,6 +1499,26 @@ func (s *incidentService) CreateRevision(ctx context.Context, incidentID uuid.UU
 	return s.incidentRepo.CreateRevision(ctx, revision)
 }
 
+// publishIncidentEvent pushes an incident event to users allowed to view the incident
+// in its current state. Failures never affect the calling operation.
+func (s *incidentService) publishIncidentEvent(ctx context.Context, eventType string, incident *models.Incident, data interface{}) {
+	if s.eventBus == nil || incident == nil {
+		return
+	}
+
+	audience := models.EventAudience{Permission: "incidents:view"}
+	if state, err := s.workflowRepo.FindStateByID(ctx, incident.CurrentStateID); err == nil {
+		for _, role := range state.ViewableRoles {
+			audience.ViewableRoleIDs = append(audience.ViewableRoleIDs, role.ID)
+		}
+	}
+
+	incidentID := incident.ID
+	if err := s.eventBus.Publish(ctx, eventType, &incidentID, incident.RecordType, data, audience); err != nil {
+		fmt.Printf("Warning: failed to publish %s event for incident %s: %v\n", eventType, incident.ID, err)
+	}
+}
+
 // Complaint operations
 
 func (s *incidentService) CreateComplaint(ctx context.Context, req *models.CreateComplaintRequest, creatorID uuid.UUID) (*models.IncidentResponse, error) {
@@ -1437,8 +1528,8 @@ func (s *incidentService) CreateComplaint(ctx context.Context, req *models.Creat
 		return nil, errors.New("invalid workflow_id")
 	}
 
-	// Get the initial state of the workflow
-	initialState, err := s.workflowRepo.GetInitialState(ctx, workflowID)
+	// Get the initial state of the latest published version (or the live workflow)
+	initialState, workflowVersion, err := resolveWorkflowStart(ctx, s.workflowRepo, workflowID)
 	if err != nil {
 		return nil, errors.New("workflow has no initial state configured")
 	}
@@ -1462,8 +1553,11 @@ func (s *incidentService) CreateComplaint(ctx context.Context, req *models.Creat
 		RecordType:       "complaint",
 		ClassificationID: &classificationID,
 		WorkflowID:       workflowID,
+		WorkflowVersion:  workflowVersion,
 		CurrentStateID:   initialState.ID,
 		Channel:          req.Channel,
+		CreatedByName:    req.CreatedByName,
+		CreatedByMobile:  req.CreatedByMobile,
 	}
 
 	// Set reporter - use provided reporter_id or fall back to creator
@@ -1546,6 +1640,8 @@ func (s *incidentService) CreateComplaint(ctx context.Context, req *models.Creat
 	_ = s.CreateRevision(ctx, complaint.ID, models.RevisionActionCreated, description, nil, creatorID)
 
 	resp := models.ToIncidentResponse(created)
+	s.publishIncidentEvent(ctx, models.EventIncidentCreated, created, resp)
+	resp.DuplicateCandidates = s.duplicateCandidates(ctx, created)
 	return &resp, nil
 }
 
@@ -1575,8 +1671,8 @@ func (s *incidentService) CreateQuery(ctx context.Context, req *models.CreateQue
 		return nil, errors.New("invalid workflow_id")
 	}
 
-	// Get the initial state of the workflow
-	initialState, err := s.workflowRepo.GetInitialState(ctx, workflowID)
+	// Get the initial state of the latest published version (or the live workflow)
+	initialState, workflowVersion, err := resolveWorkflowStart(ctx, s.workflowRepo, workflowID)
 	if err != nil {
 		return nil, errors.New("workflow has no initial state configured")
 	}
@@ -1600,6 +1696,7 @@ func (s *incidentService) CreateQuery(ctx context.Context, req *models.CreateQue
 		RecordType:       "query",
 		ClassificationID: &classificationID,
 		WorkflowID:       workflowID,
+		WorkflowVersion:  workflowVersion,
 		CurrentStateID:   initialState.ID,
 		Channel:          req.Channel,
 		ReporterID:       &creatorID,
@@ -1675,6 +1772,7 @@ func (s *incidentService) CreateQuery(ctx context.Context, req *models.CreateQue
 	_ = s.CreateRevision(ctx, query.ID, models.RevisionActionCreated, description, nil, creatorID)
 
 	resp := models.ToIncidentResponse(created)
+	s.publishIncidentEvent(ctx, models.EventIncidentCreated, created, resp)
 	return &resp, nil
 }
 
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// launchSubWorkflow creates the child record of a sub-workflow state. The child blocks the
+// parent from closing, and the parent stays in the state until the child closes.
+func (s *incidentService) launchSubWorkflow(ctx context.Context, parent *models.Incident, state *models.WorkflowState, userID uuid.UUID) (*models.Incident, error) {
+	if state.SubWorkflowID == nil {
+		return nil, errors.New("sub-workflow state has no workflow configured")
+	}
+
+	recordType := state.SubRecordType
+	if recordType == "" {
+		recordType = parent.RecordType
+	}
+
+	stateID := state.ID
+	child, err := createLinkedRecord(ctx, s.incidentRepo, s.workflowRepo, parent, &linkedRecordParams{
+		RecordType:         recordType,
+		WorkflowID:         *state.SubWorkflowID,
+		BlocksParentClose:  true,
+		SubWorkflowStateID: &stateID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Sub-workflow launched: Parent=%s, Child=%s (%s)", parent.IncidentNumber, child.IncidentNumber, child.RecordType)
+
+	parentNumber := parent.IncidentNumber
+	childNumber := child.IncidentNumber
+	_ = s.CreateRevision(ctx, parent.ID, models.RevisionActionFieldChange,
+		fmt.Sprintf("Sub-workflow %s %s started in %s", child.RecordType, childNumber, state.Name),
+		[]models.IncidentFieldChange{{FieldName: "linked_record", FieldLabel: "Linked Record", NewValue: &childNumber}},
+		userID)
+	_ = s.CreateRevision(ctx, child.ID, models.RevisionActionCreated,
+		fmt.Sprintf("%s created as a sub-workflow of %s %s", child.RecordType, parent.RecordType, parentNumber),
+		[]models.IncidentFieldChange{{FieldName: "source_incident", FieldLabel: "Created from", NewValue: &parentNumber}},
+		userID)
+
+	if created, err := s.incidentRepo.FindByIDWithRelations(ctx, child.ID); err == nil {
+		s.publishIncidentEvent(ctx, models.EventIncidentCreated, created, models.ToIncidentResponse(created))
+	}
+	return child, nil
+}
+
+// resumeSubWorkflowParent fires the parent's child_completed transition once the last open
+// child of its sub-workflow state has closed. A transition naming the child's end state wins
+// over one that accepts any end state. Parents without a matching transition keep waiting
+// for a manual transition.
+func (s *incidentService) resumeSubWorkflowParent(ctx context.Context, child *models.Incident, endState *models.WorkflowState) error {
+	if child.SourceIncidentID == nil || child.SubWorkflowStateID == nil {
+		return nil
+	}
+
+	parent, err := s.incidentRepo.FindByID(ctx, *child.SourceIncidentID)
+	if err != nil {
+		return fmt.Errorf("parent not found: %w", err)
+	}
+	// The parent moved on manually in the meantime
+	if parent.CurrentStateID != *child.SubWorkflowStateID {
+		return nil
+	}
+
+	open, err := s.incidentRepo.CountOpenSubWorkflowChildren(ctx, parent.ID, parent.CurrentStateID)
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return nil
+	}
+
+	transitions, err := loadIncidentTransitionsFromState(ctx, s.workflowRepo, parent)
+	if err != nil {
+		return err
+	}
+	var outcome *models.WorkflowTransition
+	for i := range transitions {
+		t := &transitions[i]
+		if t.AutoTrigger != models.AutoTriggerChildCompleted {
+			continue
+		}
+		if t.ChildStateCode == endState.Code {
+			outcome = t
+			break
+		}
+		if t.ChildStateCode == "" && outcome == nil {
+			outcome = t
+		}
+	}
+	if outcome == nil {
+		log.Printf("Sub-workflow %s ended in %s but %s has no matching transition", child.IncidentNumber, endState.Code, parent.IncidentNumber)
+		return nil
+	}
+
+	systemUser, err := s.userRepo.FindByEmail(ctx, models.SystemUserEmail)
+	if err != nil {
+		return fmt.Errorf("system user not found: %w", err)
+	}
+
+	req := &models.IncidentTransitionRequest{
+		TransitionID: outcome.ID.String(),
+		Comment:      fmt.Sprintf("Sub-workflow %s %s ended in %s", child.RecordType, child.IncidentNumber, endState.Name),
+	}
+	_, err = s.executeTransition(ctx, parent.ID, req, systemUser.ID, nil, true)
+	return err
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrIncidentViewNotFound = errors.New("view not found")
+	ErrIncidentViewNotOwner = errors.New("only the owner can change this view")
+)
+
+// IncidentViewService manages saved incident views and applies them to incident filters
+type IncidentViewService interface {
+	CreateView(ctx context.Context, req *models.IncidentViewCreateRequest, userID uuid.UUID) (*models.IncidentViewResponse, error)
+	GetView(ctx context.Context, id, userID uuid.UUID) (*models.IncidentViewResponse, error)
+	ListViews(ctx context.Context, userID uuid.UUID, withCounts bool) ([]models.IncidentViewResponse, error)
+	UpdateView(ctx context.Context, id uuid.UUID, req *models.IncidentViewUpdateRequest, userID uuid.UUID) (*models.IncidentViewResponse, error)
+	DeleteView(ctx context.Context, id, userID uuid.UUID) error
+	PinView(ctx context.Context, id, userID uuid.UUID, sortOrder int) error
+	UnpinView(ctx context.Context, id, userID uuid.UUID) error
+
+	// ApplyView narrows an incident filter to a view the user can see
+	ApplyView(ctx context.Context, id, userID uuid.UUID, filter *models.IncidentFilter) error
+}
+
+type incidentViewService struct {
+	repo     repository.IncidentViewRepository
+	userRepo repository.UserRepository
+}
+
+func NewIncidentViewService(repo repository.IncidentViewRepository, userRepo repository.UserRepository) IncidentViewService {
+	return &incidentViewService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+func (s *incidentViewService) CreateView(ctx context.Context, req *models.IncidentViewCreateRequest, userID uuid.UUID) (*models.IncidentViewResponse, error) {
+	if err := validateViewRecordType(req.RecordType); err != nil {
+		return nil, err
+	}
+	filterJSON, err := encodeViewFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	roleIDs, err := parseViewRoleIDs(req.RoleIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &models.IncidentView{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		RecordType:  req.RecordType,
+		Search:      req.Search,
+		Filter:      filterJSON,
+		OwnerID:     userID,
+	}
+	if err := s.repo.Create(ctx, view); err != nil {
+		return nil, err
+	}
+	if len(roleIDs) > 0 {
+		if err := s.repo.ReplaceSharedRoles(ctx, view, roleIDs); err != nil {
+			_ = s.repo.Delete(ctx, view.ID)
+			return nil, err
+		}
+	}
+
+	return s.GetView(ctx, view.ID, userID)
+}
+
+func (s *incidentViewService) GetView(ctx context.Context, id, userID uuid.UUID) (*models.IncidentViewResponse, error) {
+	view, err := s.visibleView(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ToIncidentViewResponse(view, userID)
+	pins, err := s.repo.ListPins(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pin := range pins {
+		if pin.ViewID == view.ID {
+			resp.Pinned = true
+			resp.PinOrder = pin.SortOrder
+		}
+	}
+	return &resp, nil
+}
+
+// ListViews returns the user's own and shared views, pinned views first in pin order
+func (s *incidentViewService) ListViews(ctx context.Context, userID uuid.UUID, withCounts bool) ([]models.IncidentViewResponse, error) {
+	roleIDs, err := s.userRoleIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.repo.ListVisible(ctx, userID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	pins, err := s.repo.ListPins(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make(map[uuid.UUID]int, len(pins))
+	for i, pin := range pins {
+		pinned[pin.ViewID] = i
+	}
+
+	// Counts only include the record types the user may view
+	var recordTypes []string
+	if withCounts {
+		user, err := s.userRepo.FindByIDWithPermissions(ctx, userID)
+		if err != nil {
+			return nil, errors.New("user not found")
+		}
+		recordTypes = viewableRecordTypes(user)
+	}
+
+	responses := make([]models.IncidentViewResponse, len(views))
+	for i := range views {
+		responses[i] = models.ToIncidentViewResponse(&views[i], userID)
+		if _, ok := pinned[views[i].ID]; ok {
+			responses[i].Pinned = true
+			responses[i].PinOrder = pins[pinned[views[i].ID]].SortOrder
+		}
+		if withCounts {
+			count, err := s.repo.CountIncidents(ctx, views[i].ToIncidentFilter(), recordTypes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count view %s: %w", views[i].Name, err)
+			}
+			responses[i].Count = &count
+		}
+	}
+
+	// Views come sorted by name; keep that order after the pinned ones
+	sort.SliceStable(responses, func(a, b int) bool {
+		pa, oka := pinned[responses[a].ID]
+		pb, okb := pinned[responses[b].ID]
+		if oka != okb {
+			return oka
+		}
+		return oka && pa < pb
+	})
+	return responses, nil
+}
+
+func (s *incidentViewService) UpdateView(ctx context.Context, id uuid.UUID, req *models.IncidentViewUpdateRequest, userID uuid.UUID) (*models.IncidentViewResponse, error) {
+	view, err := s.ownedView(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, errors.New("name cannot be empty")
+		}
+		view.Name = name
+	}
+	if req.Description != nil {
+		view.Description = *req.Description
+	}
+	if req.RecordType != nil {
+		if err := validateViewRecordType(*req.RecordType); err != nil {
+			return nil, err
+		}
+		view.RecordType = *req.RecordType
+	}
+	if req.Search != nil {
+		view.Search = *req.Search
+	}
+	if req.Filter != nil {
+		node := req.Filter
+		if !node.IsGroup() && node.Field == "" {
+			node = nil
+		}
+		if view.Filter, err = encodeViewFilter(node); err != nil {
+			return nil, err
+		}
+	}
+
+	// Parse the roles first so an invalid ID doesn't leave the rest of the update saved
+	var roleIDs []uuid.UUID
+	if req.RoleIDs != nil {
+		if roleIDs, err = parseViewRoleIDs(*req.RoleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, view); err != nil {
+		return nil, err
+	}
+	if req.RoleIDs != nil {
+		if err := s.repo.ReplaceSharedRoles(ctx, view, roleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetView(ctx, view.ID, userID)
+}
+
+func (s *incidentViewService) DeleteView(ctx context.Context, id, userID uuid.UUID) error {
+	if _, err := s.ownedView(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, id)
+}
+
+func (s *incidentViewService) PinView(ctx context.Context, id, userID uuid.UUID, sortOrder int) error {
+	if _, err := s.visibleView(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.repo.Pin(ctx, &models.IncidentViewPin{
+		UserID:    userID,
+		ViewID:    id,
+		SortOrder: sortOrder,
+	})
+}
+
+func (s *incidentViewService) UnpinView(ctx context.Context, id, userID uuid.UUID) error {
+	return s.repo.Unpin(ctx, userID, id)
+}
+
+// ApplyView adds the view's conditions to the filter. Record type and search from the request
+// win over the view's; filter expressions are combined with AND.
+func (s *incidentViewService) ApplyView(ctx context.Context, id, userID uuid.UUID, filter *models.IncidentFilter) error {
+	view, err := s.visibleView(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	viewFilter := view.ToIncidentFilter()
+	if filter.RecordType == nil {
+		filter.RecordType = viewFilter.RecordType
+	}
+	if filter.Search == "" {
+		filter.Search = viewFilter.Search
+	}
+	switch {
+	case viewFilter.Expression == nil:
+	case filter.Expression == nil:
+		filter.Expression = viewFilter.Expression
+	default:
+		filter.Expression = &models.IncidentFilterNode{
+			Op:         "and",
+			Conditions: []models.IncidentFilterNode{*viewFilter.Expression, *filter.Expression},
+		}
+	}
+	return nil
+}
+
+func (s *incidentViewService) visibleView(ctx context.Context, id, userID uuid.UUID) (*models.IncidentView, error) {
+	view, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrIncidentViewNotFound
+	}
+	if view.OwnerID == userID {
+		return view, nil
+	}
+	roleIDs, err := s.userRoleIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	visible, err := s.repo.IsVisible(ctx, id, userID, roleIDs)
+	if err != nil {
+		return nil, err
+	}
+	if !visible {
+		return nil, ErrIncidentViewNotFound
+	}
+	return view, nil
+}
+
+func (s *incidentViewService) ownedView(ctx context.Context, id, userID uuid.UUID) (*models.IncidentView, error) {
+	view, err := s.visibleView(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if view.OwnerID != userID {
+		return nil, ErrIncidentViewNotOwner
+	}
+	return view, nil
+}
+
+func (s *incidentViewService) userRoleIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	roles, err := s.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(roles))
+	for i, role := range roles {
+		ids[i] = role.ID
+	}
+	return ids, nil
+}
+
+// validateViewRecordType accepts a known record type, or empty for all of them
+func validateViewRecordType(recordType string) error {
+	if _, ok := recordTypeViewPermissions[recordType]; !ok && recordType != "" {
+		return fmt.Errorf("invalid record_type %q", recordType)
+	}
+	return nil
+}
+
+// encodeViewFilter validates a filter expression and serializes it for storage
+func encodeViewFilter(node *models.IncidentFilterNode) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+	if err := node.Validate(); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(node)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize filter: %w", err)
+	}
+	return string(data), nil
+}
+
+func parseViewRoleIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(values))
+	for _, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid role id: %s", v)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
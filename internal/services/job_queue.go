@@ -0,0 +1,403 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	jobQueueReadyKey      = "jobs:ready"
+	jobQueueProcessingKey = "jobs:processing:" // + worker ID, one in-flight list per replica
+	jobQueueWorkersKey    = "jobs:workers"     // Set of worker IDs with a processing list
+	jobQueueHeartbeatKey  = "jobs:heartbeat:"  // + worker ID, expires when the replica dies
+	jobQueueDelayedKey    = "jobs:delayed"
+	jobQueueDeadKey       = "jobs:dead"
+
+	jobHeartbeatInterval = 10 * time.Second
+	jobHeartbeatTTL      = 3 * jobHeartbeatInterval
+
+	defaultJobMaxAttempts = 5
+	jobBaseBackoff        = 5 * time.Second
+	jobMaxBackoff         = 30 * time.Minute
+)
+
+// Job is a unit of background work persisted in Redis
+type Job struct {
+	ID          string          `json:"id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempt     int             `json:"attempt"` // Number of attempts already made
+	MaxAttempts int             `json:"max_attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+	EnqueuedAt  time.Time       `json:"enqueued_at"`
+}
+
+// IsLastAttempt reports whether the current execution is the final one
+func (j *Job) IsLastAttempt() bool {
+	return j.Attempt+1 >= j.MaxAttempts
+}
+
+// JobHandler processes a job. Returning an error schedules a retry with backoff.
+type JobHandler func(ctx context.Context, job *Job) error
+
+// JobQueue is a durable background job queue backed by Redis
+type JobQueue interface {
+	RegisterHandler(jobType string, handler JobHandler)
+	Enqueue(ctx context.Context, jobType string, payload interface{}, maxAttempts int) error
+	EnqueueRetry(ctx context.Context, jobType string, payload interface{}, maxAttempts, attempts int, lastErr error) error
+	Start(ctx context.Context)
+	Stop()
+}
+
+type jobQueue struct {
+	client   *redis.Client
+	workerID string
+	workers  int
+	handlers map[string]JobHandler
+	mu       sync.RWMutex
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+	running  bool
+}
+
+// NewJobQueue creates a new Redis-backed job queue
+func NewJobQueue(client *redis.Client, workers int) JobQueue {
+	if workers <= 0 {
+		workers = 4 // Default to 4 workers
+	}
+
+	return &jobQueue{
+		client:   client,
+		workerID: uuid.New().String(),
+		workers:  workers,
+		handlers: make(map[string]JobHandler),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// RegisterHandler registers the handler for a job type
+func (q *jobQueue) RegisterHandler(jobType string, handler JobHandler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[jobType] = handler
+}
+
+// Enqueue adds a job to the ready list
+func (q *jobQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, maxAttempts int) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+
+	job := &Job{
+		ID:          uuid.New().String(),
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: maxAttempts,
+		EnqueuedAt:  time.Now(),
+	}
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	return q.client.LPush(ctx, jobQueueReadyKey, raw).Err()
+}
+
+// EnqueueRetry queues a job whose first attempts were made outside the queue. It runs after
+// the backoff for the attempts already made, as if the queue had retried it itself.
+func (q *jobQueue) EnqueueRetry(ctx context.Context, jobType string, payload interface{}, maxAttempts, attempts int, lastErr error) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job payload: %w", err)
+	}
+
+	if maxAttempts <= 0 {
+		maxAttempts = defaultJobMaxAttempts
+	}
+
+	job := &Job{
+		ID:          uuid.New().String(),
+		Type:        jobType,
+		Payload:     data,
+		Attempt:     attempts,
+		MaxAttempts: maxAttempts,
+		EnqueuedAt:  time.Now(),
+	}
+	if lastErr != nil {
+		job.LastError = lastErr.Error()
+	}
+
+	raw, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	retryAt := time.Now().Add(jobBackoff(attempts))
+	return q.client.ZAdd(ctx, jobQueueDelayedKey, redis.Z{
+		Score:  float64(retryAt.Unix()),
+		Member: raw,
+	}).Err()
+}
+
+// Start launches the worker pool and the delayed job scheduler
+func (q *jobQueue) Start(ctx context.Context) {
+	if q.running {
+		return
+	}
+
+	q.running = true
+
+	// Announce this replica before taking jobs so no other replica reclaims them
+	q.heartbeat(ctx)
+	q.client.SAdd(ctx, jobQueueWorkersKey, q.workerID)
+
+	// Jobs left by replicas that stopped heartbeating were interrupted by a shutdown or crash
+	q.recoverProcessing(ctx)
+
+	log.Printf("Job queue started with %d workers (worker %s)", q.workers, q.workerID)
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work(ctx)
+	}
+
+	q.wg.Add(1)
+	go q.promoteDelayed(ctx)
+
+	q.wg.Add(1)
+	go q.keepAlive(ctx)
+}
+
+// Stop signals workers to exit and waits for in-flight jobs to finish
+func (q *jobQueue) Stop() {
+	if !q.running {
+		return
+	}
+
+	q.running = false
+	close(q.stopChan)
+	q.wg.Wait()
+
+	// In-flight jobs have finished, so the processing list is empty
+	ctx := context.Background()
+	q.client.SRem(ctx, jobQueueWorkersKey, q.workerID)
+	q.client.Del(ctx, q.processingKey(), jobQueueHeartbeatKey+q.workerID)
+	log.Println("Job queue stopped")
+}
+
+func (q *jobQueue) processingKey() string {
+	return jobQueueProcessingKey + q.workerID
+}
+
+func (q *jobQueue) heartbeat(ctx context.Context) {
+	if err := q.client.Set(ctx, jobQueueHeartbeatKey+q.workerID, time.Now().Unix(), jobHeartbeatTTL).Err(); err != nil {
+		log.Printf("Job queue heartbeat failed: %v", err)
+	}
+}
+
+// keepAlive refreshes the heartbeat and periodically reclaims jobs from dead replicas
+func (q *jobQueue) keepAlive(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			q.heartbeat(ctx)
+			q.recoverProcessing(ctx)
+		case <-q.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// recoverProcessing moves the in-flight jobs of replicas whose heartbeat has expired back to
+// the ready list. Jobs of live replicas are left alone.
+func (q *jobQueue) recoverProcessing(ctx context.Context) {
+	workers, err := q.client.SMembers(ctx, jobQueueWorkersKey).Result()
+	if err != nil {
+		log.Printf("Failed to list job queue workers: %v", err)
+		return
+	}
+
+	for _, worker := range workers {
+		if worker == q.workerID {
+			continue
+		}
+		alive, err := q.client.Exists(ctx, jobQueueHeartbeatKey+worker).Result()
+		if err != nil || alive > 0 {
+			continue
+		}
+
+		recovered := 0
+		for {
+			err := q.client.LMove(ctx, jobQueueProcessingKey+worker, jobQueueReadyKey, "RIGHT", "LEFT").Err()
+			if err == redis.Nil {
+				break
+			}
+			if err != nil {
+				log.Printf("Failed to recover in-flight jobs of worker %s: %v", worker, err)
+				return
+			}
+			recovered++
+		}
+		q.client.SRem(ctx, jobQueueWorkersKey, worker)
+		if recovered > 0 {
+			log.Printf("Recovered %d in-flight job(s) from stopped worker %s", recovered, worker)
+		}
+	}
+}
+
+func (q *jobQueue) work(ctx context.Context) {
+	defer q.wg.Done()
+
+	for {
+		select {
+		case <-q.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		raw, err := q.client.BLMove(ctx, jobQueueReadyKey, q.processingKey(), "RIGHT", "LEFT", time.Second).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Job queue fetch failed: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		q.process(ctx, raw)
+	}
+}
+
+func (q *jobQueue) process(ctx context.Context, raw string) {
+	defer q.client.LRem(ctx, q.processingKey(), 1, raw)
+
+	var job Job
+	if err := json.Unmarshal([]byte(raw), &job); err != nil {
+		log.Printf("Discarding malformed job: %v", err)
+		return
+	}
+
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Type]
+	q.mu.RUnlock()
+
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for job type: %s", job.Type)
+	} else {
+		err = q.run(ctx, handler, &job)
+	}
+
+	if err == nil {
+		return
+	}
+
+	job.Attempt++
+	job.LastError = err.Error()
+
+	data, marshalErr := json.Marshal(&job)
+	if marshalErr != nil {
+		log.Printf("Failed to marshal job %s for retry: %v", job.ID, marshalErr)
+		return
+	}
+
+	if job.Attempt >= job.MaxAttempts {
+		log.Printf("Job %s (%s) failed permanently after %d attempts: %v", job.ID, job.Type, job.Attempt, err)
+		q.client.LPush(ctx, jobQueueDeadKey, data)
+		return
+	}
+
+	retryAt := time.Now().Add(jobBackoff(job.Attempt))
+	log.Printf("Job %s (%s) failed on attempt %d, retrying at %s: %v", job.ID, job.Type, job.Attempt, retryAt.Format(time.RFC3339), err)
+	q.client.ZAdd(ctx, jobQueueDelayedKey, redis.Z{
+		Score:  float64(retryAt.Unix()),
+		Member: data,
+	})
+}
+
+// run executes the handler, converting panics into errors so a bad job can't take a worker down
+func (q *jobQueue) run(ctx context.Context, handler JobHandler, job *Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// promoteDelayed moves jobs whose backoff has elapsed back onto the ready list
+func (q *jobQueue) promoteDelayed(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			due, err := q.client.ZRangeByScore(ctx, jobQueueDelayedKey, &redis.ZRangeBy{
+				Min: "-inf",
+				Max: strconv.FormatInt(time.Now().Unix(), 10),
+			}).Result()
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Printf("Failed to read delayed jobs: %v", err)
+				}
+				continue
+			}
+
+			for _, raw := range due {
+				// Only the replica that removes the member re-queues it
+				removed, err := q.client.ZRem(ctx, jobQueueDelayedKey, raw).Result()
+				if err != nil || removed == 0 {
+					continue
+				}
+				q.client.LPush(ctx, jobQueueReadyKey, raw)
+			}
+		case <-q.stopChan:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// jobBackoff returns the exponential delay before the given retry attempt
+func jobBackoff(attempt int) time.Duration {
+	delay := jobBaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= jobMaxBackoff {
+			return jobMaxBackoff
+		}
+	}
+	return delay
+}
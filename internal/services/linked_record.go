@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// Fields that can be copied from a source record to a linked record
+const (
+	LinkedCopyClassification = "classification_id"
+	LinkedCopyAssignee       = "assignee_id"
+	LinkedCopyDepartment     = "department_id"
+	LinkedCopyLocation       = "location_id"
+	LinkedCopyGeolocation    = "geolocation"
+	LinkedCopyReporter       = "reporter"
+	LinkedCopyCustomFields   = "custom_fields"
+	LinkedCopyLookupValues   = "lookup_values"
+	LinkedCopyDueDate        = "due_date"
+)
+
+// defaultLinkedCopyFields matches what converting an incident to a request has always copied
+var defaultLinkedCopyFields = []string{
+	LinkedCopyClassification,
+	LinkedCopyAssignee,
+	LinkedCopyDepartment,
+	LinkedCopyLocation,
+	LinkedCopyGeolocation,
+	LinkedCopyReporter,
+	LinkedCopyCustomFields,
+	LinkedCopyLookupValues,
+}
+
+var validLinkedRecordTypes = map[string]bool{
+	"incident":  true,
+	"request":   true,
+	"complaint": true,
+	"query":     true,
+}
+
+var validLinkedCopyFields = map[string]bool{
+	LinkedCopyClassification: true,
+	LinkedCopyAssignee:       true,
+	LinkedCopyDepartment:     true,
+	LinkedCopyLocation:       true,
+	LinkedCopyGeolocation:    true,
+	LinkedCopyReporter:       true,
+	LinkedCopyCustomFields:   true,
+	LinkedCopyLookupValues:   true,
+	LinkedCopyDueDate:        true,
+}
+
+// linkedRecordParams describes a record created from a source record. Explicit values
+// take precedence over copied ones.
+type linkedRecordParams struct {
+	RecordType        string
+	WorkflowID        uuid.UUID
+	Title             string // Defaults to the source title
+	Description       string // Defaults to the source description
+	ClassificationID  *uuid.UUID
+	AssigneeID        *uuid.UUID
+	DepartmentID      *uuid.UUID
+	DueDate           *time.Time
+	CopyFields        []string // nil uses defaultLinkedCopyFields
+	BlocksParentClose bool
+
+	SubWorkflowStateID *uuid.UUID // Source state waiting on the record as a sub-workflow
+}
+
+// createLinkedRecord creates a new record of any type in the initial state of the given
+// workflow, linked to the source through SourceIncidentID
+func createLinkedRecord(ctx context.Context, incidentRepo repository.IncidentRepository, workflowRepo repository.WorkflowRepository, source *models.Incident, params *linkedRecordParams) (*models.Incident, error) {
+	if !validLinkedRecordTypes[params.RecordType] {
+		return nil, fmt.Errorf("invalid record type: %s", params.RecordType)
+	}
+
+	// Get the initial state of the target workflow
+	initialState, workflowVersion, err := resolveWorkflowStart(ctx, workflowRepo, params.WorkflowID)
+	if err != nil {
+		return nil, errors.New("workflow has no initial state configured")
+	}
+
+	number, err := incidentRepo.GenerateRecordNumber(ctx, params.RecordType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate %s number: %w", params.RecordType, err)
+	}
+
+	copyFields := params.CopyFields
+	if copyFields == nil {
+		copyFields = defaultLinkedCopyFields
+	}
+	copyField := make(map[string]bool, len(copyFields))
+	for _, f := range copyFields {
+		copyField[f] = true
+	}
+
+	sourceID := source.ID
+	record := &models.Incident{
+		IncidentNumber:     number,
+		Title:              source.Title,
+		Description:        source.Description,
+		RecordType:         params.RecordType,
+		SourceIncidentID:   &sourceID,
+		BlocksParentClose:  params.BlocksParentClose,
+		SubWorkflowStateID: params.SubWorkflowStateID,
+		WorkflowID:         params.WorkflowID,
+		WorkflowVersion:    workflowVersion,
+		CurrentStateID:     initialState.ID,
+	}
+	if params.Title != "" {
+		record.Title = params.Title
+	}
+	if params.Description != "" {
+		record.Description = params.Description
+	}
+
+	if copyField[LinkedCopyClassification] {
+		record.ClassificationID = source.ClassificationID
+	}
+	if copyField[LinkedCopyAssignee] {
+		record.AssigneeID = source.AssigneeID
+	}
+	if copyField[LinkedCopyDepartment] {
+		record.DepartmentID = source.DepartmentID
+	}
+	if copyField[LinkedCopyLocation] {
+		record.LocationID = source.LocationID
+	}
+	if copyField[LinkedCopyGeolocation] {
+		record.Latitude = source.Latitude
+		record.Longitude = source.Longitude
+	}
+	if copyField[LinkedCopyReporter] {
+		record.ReporterID = source.ReporterID
+		record.ReporterEmail = source.ReporterEmail
+		record.ReporterName = source.ReporterName
+	}
+	if copyField[LinkedCopyCustomFields] {
+		record.CustomFields = source.CustomFields
+	}
+	if copyField[LinkedCopyDueDate] {
+		record.DueDate = source.DueDate
+	}
+
+	// Explicit overrides
+	if params.ClassificationID != nil {
+		record.ClassificationID = params.ClassificationID
+	}
+	if params.AssigneeID != nil {
+		record.AssigneeID = params.AssigneeID
+	}
+	if params.DepartmentID != nil {
+		record.DepartmentID = params.DepartmentID
+	}
+	if params.DueDate != nil {
+		record.DueDate = params.DueDate
+	}
+
+	// Calculate SLA deadline based on initial state
+	if initialState.SLAHours != nil && *initialState.SLAHours > 0 {
+		deadline := time.Now().Add(time.Duration(*initialState.SLAHours) * time.Hour)
+		record.SLADeadline = &deadline
+	}
+
+	if err := incidentRepo.Create(ctx, record); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", params.RecordType, err)
+	}
+
+	// Copy lookup values from source
+	if copyField[LinkedCopyLookupValues] && len(source.LookupValues) > 0 {
+		if err := incidentRepo.SetLookupValues(ctx, record.ID, source.LookupValues); err != nil {
+			fmt.Printf("Warning: failed to copy lookup values: %v\n", err)
+		}
+	}
+
+	return record, nil
+}
+
+// CreateLinkedRecordConfig represents the configuration for a create_linked_record action
+type CreateLinkedRecordConfig struct {
+	RecordType        string   `json:"record_type"` // incident, request, complaint, query
+	WorkflowID        string   `json:"workflow_id"`
+	ClassificationID  string   `json:"classification_id"`   // Optional override
+	AssigneeID        string   `json:"assignee_id"`         // Optional override
+	DepartmentID      string   `json:"department_id"`       // Optional override
+	Title             string   `json:"title"`               // Template, defaults to the source title
+	Description       string   `json:"description"`         // Template, defaults to the source description
+	CopyFields        []string `json:"copy_fields"`         // Fields copied from the source; omitted uses the defaults
+	BlocksParentClose bool     `json:"blocks_parent_close"` // Parent cannot reach a terminal state until this record is closed
+}
+
+// validateCreateLinkedRecordConfig checks a create_linked_record configuration without touching the database
+func validateCreateLinkedRecordConfig(config *CreateLinkedRecordConfig) error {
+	if !validLinkedRecordTypes[config.RecordType] {
+		return fmt.Errorf("record_type must be one of incident, request, complaint, query")
+	}
+	if _, err := uuid.Parse(config.WorkflowID); err != nil {
+		return fmt.Errorf("workflow_id must be a UUID")
+	}
+	for name, value := range map[string]string{
+		"classification_id": config.ClassificationID,
+		"assignee_id":       config.AssigneeID,
+		"department_id":     config.DepartmentID,
+	} {
+		if value == "" {
+			continue
+		}
+		if _, err := uuid.Parse(value); err != nil {
+			return fmt.Errorf("%s must be a UUID", name)
+		}
+	}
+	for _, f := range config.CopyFields {
+		if !validLinkedCopyFields[f] {
+			return fmt.Errorf("unsupported copy field: %s", f)
+		}
+	}
+	return nil
+}
+
+// parseOptionalUUID parses a UUID string, returning nil for empty or invalid values
+func parseOptionalUUID(value string) *uuid.UUID {
+	if value == "" {
+		return nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// executeCreateLinkedRecord creates a child record linked to the incident
+func (e *actionExecutor) executeCreateLinkedRecord(ctx context.Context, action *models.TransitionAction, incident *models.Incident, transition *models.WorkflowTransition, performedBy *models.User) error {
+	var config CreateLinkedRecordConfig
+	if err := json.Unmarshal([]byte(action.Config), &config); err != nil {
+		return fmt.Errorf("invalid create linked record config: %w", err)
+	}
+	if err := validateCreateLinkedRecordConfig(&config); err != nil {
+		return err
+	}
+
+	data := e.templates.BuildContext(ctx, incident, transition, performedBy)
+	title, err := e.templates.Render("title", config.Title, false, data)
+	if err != nil {
+		return err
+	}
+	description, err := e.templates.Render("description", config.Description, false, data)
+	if err != nil {
+		return err
+	}
+
+	workflowID, _ := uuid.Parse(config.WorkflowID)
+	params := &linkedRecordParams{
+		RecordType:        config.RecordType,
+		WorkflowID:        workflowID,
+		Title:             title,
+		Description:       description,
+		ClassificationID:  parseOptionalUUID(config.ClassificationID),
+		AssigneeID:        parseOptionalUUID(config.AssigneeID),
+		DepartmentID:      parseOptionalUUID(config.DepartmentID),
+		CopyFields:        config.CopyFields,
+		BlocksParentClose: config.BlocksParentClose,
+	}
+
+	record, err := createLinkedRecord(ctx, e.incidentRepo, e.workflowRepo, incident, params)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Linked record created: Parent=%s, Child=%s (%s)", incident.IncidentNumber, record.IncidentNumber, record.RecordType)
+
+	if performedBy != nil {
+		parentNumber := incident.IncidentNumber
+		childNumber := record.IncidentNumber
+		e.createRevision(ctx, incident.ID, models.RevisionActionFieldChange,
+			fmt.Sprintf("Linked %s %s created by action %s", record.RecordType, childNumber, action.Name),
+			[]models.IncidentFieldChange{{FieldName: "linked_record", FieldLabel: "Linked Record", NewValue: &childNumber}},
+			performedBy.ID)
+		e.createRevision(ctx, record.ID, models.RevisionActionCreated,
+			fmt.Sprintf("%s created from %s %s", record.RecordType, incident.RecordType, parentNumber),
+			[]models.IncidentFieldChange{{FieldName: "source_incident", FieldLabel: "Created from", NewValue: &parentNumber}},
+			performedBy.ID)
+	}
+
+	return nil
+}
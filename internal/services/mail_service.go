@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/mail"
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+// JobTypeEmailDelivery is the job queue type for sending one email delivery
+const JobTypeEmailDelivery = "email_delivery"
+
+var (
+	ErrEmailDeliveryNotFound = errors.New("email delivery not found")
+	ErrEmailDeliveryPending  = errors.New("email delivery is still pending")
+)
+
+// SendEmailParams describes an email to send. Each recipient gets its own
+// message and delivery record.
+type SendEmailParams struct {
+	To                 []string
+	Subject            string
+	TextBody           string
+	HTMLBody           string
+	IncidentID         *uuid.UUID
+	TransitionActionID *uuid.UUID
+}
+
+type MailService interface {
+	Send(ctx context.Context, params *SendEmailParams) ([]models.EmailDeliveryResponse, error)
+	Resend(ctx context.Context, id uuid.UUID) (*models.EmailDeliveryResponse, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.EmailDeliveryResponse, error)
+	ListDeliveries(ctx context.Context, filter *models.EmailDeliveryFilter) ([]models.EmailDeliveryResponse, int64, error)
+}
+
+type mailService struct {
+	sender     mail.Sender
+	repo       repository.EmailDeliveryRepository
+	jobQueue   JobQueue
+	maxRetries int
+}
+
+type emailDeliveryJob struct {
+	DeliveryID uuid.UUID `json:"delivery_id"`
+}
+
+// NewMailService creates a new mail service. Deliveries are sent by jobQueue, which retries
+// transient failures with backoff; if jobQueue is nil each delivery gets a single inline attempt.
+func NewMailService(sender mail.Sender, repo repository.EmailDeliveryRepository, jobQueue JobQueue, maxRetries int) MailService {
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	s := &mailService{
+		sender:     sender,
+		repo:       repo,
+		jobQueue:   jobQueue,
+		maxRetries: maxRetries,
+	}
+
+	if jobQueue != nil {
+		jobQueue.RegisterHandler(JobTypeEmailDelivery, s.handleDeliveryJob)
+	}
+
+	return s
+}
+
+// Send creates a delivery record per recipient and queues it for sending. It only returns
+// an error when no recipient could be queued or reached; individual failures are kept in
+// the delivery records.
+func (s *mailService) Send(ctx context.Context, params *SendEmailParams) ([]models.EmailDeliveryResponse, error) {
+	if len(params.To) == 0 {
+		return nil, errors.New("no recipients")
+	}
+
+	responses := make([]models.EmailDeliveryResponse, 0, len(params.To))
+	var failures []string
+
+	for _, to := range params.To {
+		to = strings.TrimSpace(to)
+		if to == "" {
+			continue
+		}
+
+		delivery := &models.EmailDelivery{
+			FromAddr:           s.sender.From(),
+			ToAddr:             to,
+			Subject:            params.Subject,
+			TextBody:           params.TextBody,
+			HTMLBody:           params.HTMLBody,
+			Status:             models.EmailStatusPending,
+			IncidentID:         params.IncidentID,
+			TransitionActionID: params.TransitionActionID,
+		}
+		if err := s.repo.Create(ctx, delivery); err != nil {
+			log.Printf("Failed to create email delivery record: %v", err)
+		}
+
+		s.dispatch(ctx, delivery)
+		if delivery.Status == models.EmailStatusFailed {
+			failures = append(failures, fmt.Sprintf("%s: %s", to, delivery.LastError))
+		}
+		responses = append(responses, models.ToEmailDeliveryResponse(delivery))
+	}
+
+	if len(responses) > 0 && len(failures) == len(responses) {
+		return responses, fmt.Errorf("email delivery failed: %s", strings.Join(failures, "; "))
+	}
+
+	return responses, nil
+}
+
+// Resend queues a sent or failed email record for delivery again. Attempts keep counting on
+// the same record. Pending deliveries are already queued and can't be resent.
+func (s *mailService) Resend(ctx context.Context, id uuid.UUID) (*models.EmailDeliveryResponse, error) {
+	delivery, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, ErrEmailDeliveryNotFound
+	}
+	if delivery.Status == models.EmailStatusPending {
+		return nil, ErrEmailDeliveryPending
+	}
+
+	delivery.Status = models.EmailStatusPending
+	delivery.LastError = ""
+	if err := s.repo.Update(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to update email delivery: %w", err)
+	}
+	s.dispatch(ctx, delivery)
+
+	resp := models.ToEmailDeliveryResponse(delivery)
+	return &resp, nil
+}
+
+func (s *mailService) GetDelivery(ctx context.Context, id uuid.UUID) (*models.EmailDeliveryResponse, error) {
+	delivery, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ToEmailDeliveryResponse(delivery)
+	return &resp, nil
+}
+
+func (s *mailService) ListDeliveries(ctx context.Context, filter *models.EmailDeliveryFilter) ([]models.EmailDeliveryResponse, int64, error) {
+	deliveries, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.EmailDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = models.ToEmailDeliveryResponse(&deliveries[i])
+	}
+
+	return responses, total, nil
+}
+
+// dispatch queues a delivery, falling back to a single inline attempt when there is no
+// queue or the record could not be stored
+func (s *mailService) dispatch(ctx context.Context, delivery *models.EmailDelivery) {
+	if s.jobQueue == nil || delivery.ID == uuid.Nil {
+		s.attempt(ctx, delivery, true)
+		return
+	}
+
+	err := s.jobQueue.Enqueue(ctx, JobTypeEmailDelivery, emailDeliveryJob{DeliveryID: delivery.ID}, s.maxRetries+1)
+	if err != nil {
+		delivery.Status = models.EmailStatusFailed
+		delivery.LastError = fmt.Sprintf("failed to queue delivery: %v", err)
+		s.save(ctx, delivery)
+	}
+}
+
+// handleDeliveryJob makes one delivery attempt. Transient failures are returned so the
+// queue retries them with backoff; the same record is updated on every attempt.
+func (s *mailService) handleDeliveryJob(ctx context.Context, job *Job) error {
+	var payload emailDeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		log.Printf("Discarding invalid email delivery job %s: %v", job.ID, err)
+		return nil
+	}
+
+	delivery, err := s.repo.FindByID(ctx, payload.DeliveryID)
+	if err != nil {
+		return fmt.Errorf("failed to load email delivery: %w", err)
+	}
+	if delivery.Status != models.EmailStatusPending {
+		// Already sent, or given up on, by an earlier execution
+		return nil
+	}
+
+	return s.attempt(ctx, delivery, job.IsLastAttempt())
+}
+
+// attempt sends a delivery once and stores the outcome on the record. The delivery only
+// becomes failed on a permanent error or when last is set; otherwise it stays pending and
+// the error is returned for a retry.
+func (s *mailService) attempt(ctx context.Context, delivery *models.EmailDelivery, last bool) error {
+	msg := &mail.Message{
+		To:       []string{delivery.ToAddr},
+		Subject:  delivery.Subject,
+		TextBody: delivery.TextBody,
+		HTMLBody: delivery.HTMLBody,
+	}
+
+	delivery.Attempts++
+	messageID, err := s.sender.Send(ctx, msg)
+	if err == nil {
+		now := time.Now()
+		delivery.MessageID = messageID
+		delivery.Status = models.EmailStatusSent
+		delivery.LastError = ""
+		delivery.SentAt = &now
+		s.save(ctx, delivery)
+		log.Printf("Email sent: To=%s, Subject=%s", delivery.ToAddr, delivery.Subject)
+		return nil
+	}
+
+	delivery.LastError = err.Error()
+	log.Printf("Email delivery attempt %d to %s failed: %v", delivery.Attempts, delivery.ToAddr, err)
+	if last || mail.IsPermanent(err) {
+		delivery.Status = models.EmailStatusFailed
+		s.save(ctx, delivery)
+		return nil
+	}
+
+	s.save(ctx, delivery)
+	return err
+}
+
+func (s *mailService) save(ctx context.Context, delivery *models.EmailDelivery) {
+	if delivery.ID == uuid.Nil {
+		return
+	}
+	if err := s.repo.Update(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("Failed to update email delivery record: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/automax/backend/internal/config"
+	"github.com/automax/backend/internal/mail"
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// smtpStandIn is a minimal SMTP server. Each RCPT TO is answered with the next reply in
+// rcptReplies, then with 250 once they run out.
+type smtpStandIn struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	rcptReplies []string
+	messages    []string
+}
+
+func newSMTPStandIn(t *testing.T, rcptReplies ...string) *smtpStandIn {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &smtpStandIn{listener: listener, rcptReplies: rcptReplies}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *smtpStandIn) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM"):
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO"):
+			reply(s.nextRcptReply())
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK queued")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpStandIn) nextRcptReply() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.rcptReplies) == 0 {
+		return "250 OK"
+	}
+	next := s.rcptReplies[0]
+	s.rcptReplies = s.rcptReplies[1:]
+	return next
+}
+
+func (s *smtpStandIn) sent() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+func (s *smtpStandIn) sender(t *testing.T) mail.Sender {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatalf("port: %v", err)
+	}
+	return mail.NewSMTPSender(&config.MailConfig{
+		Host:           host,
+		Port:           p,
+		FromAddress:    "no-reply@example.com",
+		Encryption:     "none",
+		TimeoutSeconds: 5,
+	})
+}
+
+// memoryDeliveryRepo keeps delivery records in memory and counts inserts
+type memoryDeliveryRepo struct {
+	mu      sync.Mutex
+	rows    map[uuid.UUID]models.EmailDelivery
+	creates int
+}
+
+func newMemoryDeliveryRepo() *memoryDeliveryRepo {
+	return &memoryDeliveryRepo{rows: make(map[uuid.UUID]models.EmailDelivery)}
+}
+
+func (r *memoryDeliveryRepo) Create(ctx context.Context, delivery *models.EmailDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if delivery.ID == uuid.Nil {
+		delivery.ID = uuid.New()
+	}
+	r.creates++
+	r.rows[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryDeliveryRepo) Update(ctx context.Context, delivery *models.EmailDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rows[delivery.ID] = *delivery
+	return nil
+}
+
+func (r *memoryDeliveryRepo) FindByID(ctx context.Context, id uuid.UUID) (*models.EmailDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delivery, ok := r.rows[id]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return &delivery, nil
+}
+
+func (r *memoryDeliveryRepo) List(ctx context.Context, filter *models.EmailDeliveryFilter) ([]models.EmailDelivery, int64, error) {
+	return nil, 0, nil
+}
+
+// manualQueue collects jobs and runs them on demand the way jobQueue does: a failed job is
+// run again with the next attempt number until it succeeds or runs out of attempts.
+type manualQueue struct {
+	handlers map[string]JobHandler
+	jobs     []*Job
+}
+
+func newManualQueue() *manualQueue {
+	return &manualQueue{handlers: make(map[string]JobHandler)}
+}
+
+func (q *manualQueue) RegisterHandler(jobType string, handler JobHandler) {
+	q.handlers[jobType] = handler
+}
+
+func (q *manualQueue) Enqueue(ctx context.Context, jobType string, payload interface{}, maxAttempts int) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	q.jobs = append(q.jobs, &Job{ID: uuid.New().String(), Type: jobType, Payload: data, MaxAttempts: maxAttempts})
+	return nil
+}
+
+func (q *manualQueue) EnqueueRetry(ctx context.Context, jobType string, payload interface{}, maxAttempts, attempts int, lastErr error) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	q.jobs = append(q.jobs, &Job{ID: uuid.New().String(), Type: jobType, Payload: data, Attempt: attempts, MaxAttempts: maxAttempts})
+	return nil
+}
+
+func (q *manualQueue) Start(ctx context.Context) {}
+func (q *manualQueue) Stop()                     {}
+
+// drain runs every queued job to completion and returns the number of executions
+func (q *manualQueue) drain(ctx context.Context) int {
+	runs := 0
+	for len(q.jobs) > 0 {
+		job := q.jobs[0]
+		q.jobs = q.jobs[1:]
+		for {
+			runs++
+			err := q.handlers[job.Type](ctx, job)
+			job.Attempt++
+			if err == nil || job.Attempt >= job.MaxAttempts {
+				break
+			}
+		}
+	}
+	return runs
+}
+
+func TestMailServiceSendQueuesWithoutAttempting(t *testing.T) {
+	server := newSMTPStandIn(t)
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 3)
+
+	responses, err := svc.Send(context.Background(), &SendEmailParams{
+		To:       []string{"a@example.com", "b@example.com"},
+		Subject:  "Hello",
+		TextBody: "Body",
+	})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if len(responses) != 2 || len(queue.jobs) != 2 {
+		t.Fatalf("got %d responses and %d jobs, want 2 and 2", len(responses), len(queue.jobs))
+	}
+	for _, resp := range responses {
+		if resp.Status != models.EmailStatusPending || resp.Attempts != 0 {
+			t.Errorf("delivery %s: status %s with %d attempts, want pending with 0", resp.To, resp.Status, resp.Attempts)
+		}
+	}
+	if server.sent() != 0 {
+		t.Errorf("Send contacted the SMTP server; delivery must happen in the queue")
+	}
+
+	queue.drain(context.Background())
+	if server.sent() != 2 {
+		t.Errorf("SMTP server received %d messages, want 2", server.sent())
+	}
+}
+
+func TestMailServiceRetriesReuseDeliveryRecord(t *testing.T) {
+	server := newSMTPStandIn(t, "451 Try again later", "451 Try again later")
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 3)
+
+	responses, err := svc.Send(context.Background(), &SendEmailParams{To: []string{"a@example.com"}, Subject: "Retry"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if runs := queue.drain(context.Background()); runs != 3 {
+		t.Errorf("job ran %d times, want 3", runs)
+	}
+	if repo.creates != 1 {
+		t.Errorf("created %d delivery records, want 1", repo.creates)
+	}
+
+	delivery, _ := repo.FindByID(context.Background(), responses[0].ID)
+	if delivery.Status != models.EmailStatusSent || delivery.Attempts != 3 {
+		t.Errorf("delivery: status %s with %d attempts, want sent with 3", delivery.Status, delivery.Attempts)
+	}
+	if delivery.MessageID == "" || delivery.LastError != "" {
+		t.Errorf("delivery: message id %q, last error %q", delivery.MessageID, delivery.LastError)
+	}
+}
+
+func TestMailServicePermanentFailureStopsRetrying(t *testing.T) {
+	server := newSMTPStandIn(t, "550 No such user")
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 3)
+
+	responses, err := svc.Send(context.Background(), &SendEmailParams{To: []string{"nobody@example.com"}, Subject: "Bounce"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if runs := queue.drain(context.Background()); runs != 1 {
+		t.Errorf("job ran %d times, want 1", runs)
+	}
+	delivery, _ := repo.FindByID(context.Background(), responses[0].ID)
+	if delivery.Status != models.EmailStatusFailed || delivery.Attempts != 1 {
+		t.Errorf("delivery: status %s with %d attempts, want failed with 1", delivery.Status, delivery.Attempts)
+	}
+	if !strings.Contains(delivery.LastError, "550") {
+		t.Errorf("last error %q does not carry the SMTP reply", delivery.LastError)
+	}
+}
+
+func TestMailServiceExhaustedRetriesMarkFailed(t *testing.T) {
+	server := newSMTPStandIn(t, "451 Busy", "451 Busy", "451 Busy")
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 2)
+
+	responses, err := svc.Send(context.Background(), &SendEmailParams{To: []string{"a@example.com"}, Subject: "Busy"})
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	queue.drain(context.Background())
+	delivery, _ := repo.FindByID(context.Background(), responses[0].ID)
+	if delivery.Status != models.EmailStatusFailed || delivery.Attempts != 3 {
+		t.Errorf("delivery: status %s with %d attempts, want failed with 3", delivery.Status, delivery.Attempts)
+	}
+	if server.sent() != 0 {
+		t.Errorf("SMTP server received %d messages, want 0", server.sent())
+	}
+}
+
+func TestMailServiceResendReusesRecord(t *testing.T) {
+	server := newSMTPStandIn(t, "550 No such user")
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 0)
+
+	responses, _ := svc.Send(context.Background(), &SendEmailParams{To: []string{"a@example.com"}, Subject: "Again"})
+	queue.drain(context.Background())
+
+	resp, err := svc.Resend(context.Background(), responses[0].ID)
+	if err != nil {
+		t.Fatalf("Resend: %v", err)
+	}
+	if resp.Status != models.EmailStatusPending {
+		t.Errorf("resent delivery status %s, want pending", resp.Status)
+	}
+	queue.drain(context.Background())
+
+	delivery, _ := repo.FindByID(context.Background(), responses[0].ID)
+	if repo.creates != 1 || delivery.Status != models.EmailStatusSent || delivery.Attempts != 2 {
+		t.Errorf("after resend: %d records, status %s with %d attempts, want 1 record sent with 2", repo.creates, delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestMailServiceResendRejectsPendingDelivery(t *testing.T) {
+	server := newSMTPStandIn(t)
+	repo := newMemoryDeliveryRepo()
+	queue := newManualQueue()
+	svc := NewMailService(server.sender(t), repo, queue, 0)
+
+	responses, _ := svc.Send(context.Background(), &SendEmailParams{To: []string{"a@example.com"}, Subject: "Queued"})
+
+	if _, err := svc.Resend(context.Background(), responses[0].ID); !errors.Is(err, ErrEmailDeliveryPending) {
+		t.Fatalf("Resend of a queued delivery returned %v, want ErrEmailDeliveryPending", err)
+	}
+	queue.drain(context.Background())
+
+	if server.sent() != 1 {
+		t.Errorf("server received %d messages, want 1", server.sent())
+	}
+}
+
+func TestMailServiceWithoutQueueAttemptsOnce(t *testing.T) {
+	server := newSMTPStandIn(t, "451 Busy")
+	repo := newMemoryDeliveryRepo()
+	svc := NewMailService(server.sender(t), repo, nil, 3)
+
+	responses, err := svc.Send(context.Background(), &SendEmailParams{To: []string{"a@example.com"}, Subject: "Inline"})
+	if err == nil {
+		t.Fatalf("Send succeeded, want an error for the only recipient failing")
+	}
+	if len(responses) != 1 || responses[0].Status != models.EmailStatusFailed || responses[0].Attempts != 1 {
+		t.Errorf("responses %+v, want one failed delivery with 1 attempt", responses)
+	}
+}
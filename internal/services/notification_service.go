@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateNotificationParams describes a notification sent to one or more users
+type CreateNotificationParams struct {
+	UserIDs    []uuid.UUID
+	Type       string
+	Title      string
+	Message    string
+	IncidentID *uuid.UUID
+}
+
+type NotificationService interface {
+	Notify(ctx context.Context, params *CreateNotificationParams) ([]models.Notification, error)
+	ListNotifications(ctx context.Context, filter *models.NotificationFilter) ([]models.NotificationResponse, int64, error)
+	GetUnreadCount(ctx context.Context, userID uuid.UUID) (int64, error)
+	MarkRead(ctx context.Context, id, userID uuid.UUID) error
+	MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteNotification(ctx context.Context, id, userID uuid.UUID) error
+}
+
+type notificationService struct {
+	repo     repository.NotificationRepository
+	eventBus EventBus
+}
+
+func NewNotificationService(repo repository.NotificationRepository, eventBus EventBus) NotificationService {
+	return &notificationService{repo: repo, eventBus: eventBus}
+}
+
+// Notify creates one notification record per distinct recipient
+func (s *notificationService) Notify(ctx context.Context, params *CreateNotificationParams) ([]models.Notification, error) {
+	seen := make(map[uuid.UUID]bool)
+	notifications := make([]models.Notification, 0, len(params.UserIDs))
+
+	for _, userID := range params.UserIDs {
+		if userID == uuid.Nil || seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		notifications = append(notifications, models.Notification{
+			UserID:     userID,
+			Type:       params.Type,
+			Title:      params.Title,
+			Message:    params.Message,
+			IncidentID: params.IncidentID,
+		})
+	}
+
+	if err := s.repo.CreateBatch(ctx, notifications); err != nil {
+		return nil, err
+	}
+
+	// Push each notification to its recipient's open event streams
+	if s.eventBus != nil {
+		for i := range notifications {
+			n := &notifications[i]
+			audience := models.EventAudience{UserIDs: []uuid.UUID{n.UserID}}
+			_ = s.eventBus.Publish(ctx, models.EventNotificationCreated, n.IncidentID, "", models.ToNotificationResponse(n), audience)
+		}
+	}
+
+	return notifications, nil
+}
+
+func (s *notificationService) ListNotifications(ctx context.Context, filter *models.NotificationFilter) ([]models.NotificationResponse, int64, error) {
+	notifications, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.NotificationResponse, len(notifications))
+	for i := range notifications {
+		responses[i] = models.ToNotificationResponse(&notifications[i])
+	}
+
+	return responses, total, nil
+}
+
+func (s *notificationService) GetUnreadCount(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.repo.CountUnread(ctx, userID)
+}
+
+func (s *notificationService) MarkRead(ctx context.Context, id, userID uuid.UUID) error {
+	if err := s.repo.MarkRead(ctx, id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("notification not found")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *notificationService) MarkAllRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.repo.MarkAllRead(ctx, userID)
+}
+
+func (s *notificationService) DeleteNotification(ctx context.Context, id, userID uuid.UUID) error {
+	if err := s.repo.Delete(ctx, id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("notification not found")
+		}
+		return err
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/automax/backend/internal/models"
 	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
 )
 
 // SLAMonitor handles background SLA breach detection
@@ -17,19 +20,21 @@ type SLAMonitor interface {
 
 type slaMonitor struct {
 	incidentRepo repository.IncidentRepository
+	notifier     NotificationService
 	interval     time.Duration
 	stopChan     chan struct{}
 	running      bool
 }
 
 // NewSLAMonitor creates a new SLA monitor
-func NewSLAMonitor(incidentRepo repository.IncidentRepository, checkInterval time.Duration) SLAMonitor {
+func NewSLAMonitor(incidentRepo repository.IncidentRepository, notifier NotificationService, checkInterval time.Duration) SLAMonitor {
 	if checkInterval == 0 {
 		checkInterval = 5 * time.Minute // Default to 5 minutes
 	}
 
 	return &slaMonitor{
 		incidentRepo: incidentRepo,
+		notifier:     notifier,
 		interval:     checkInterval,
 		stopChan:     make(chan struct{}),
 	}
@@ -85,13 +90,16 @@ func (m *slaMonitor) CheckSLABreaches(ctx context.Context) error {
 	log.Println("Running SLA breach check...")
 
 	// Find incidents that have passed their SLA deadline but aren't marked as breached
-	breachedCount, err := m.incidentRepo.MarkSLABreached(ctx)
+	breached, err := m.incidentRepo.MarkSLABreached(ctx)
 	if err != nil {
 		return err
 	}
 
-	if breachedCount > 0 {
-		log.Printf("Marked %d incidents as SLA breached", breachedCount)
+	if len(breached) > 0 {
+		log.Printf("Marked %d incidents as SLA breached", len(breached))
+		for i := range breached {
+			m.notifyBreach(ctx, &breached[i])
+		}
 	}
 
 	// Get statistics for logging
@@ -105,3 +113,35 @@ func (m *slaMonitor) CheckSLABreaches(ctx context.Context) error {
 
 	return nil
 }
+
+// notifyBreach notifies the assignees and the department manager of a breached incident
+func (m *slaMonitor) notifyBreach(ctx context.Context, incident *models.Incident) {
+	if m.notifier == nil {
+		return
+	}
+
+	var recipients []uuid.UUID
+	if incident.AssigneeID != nil {
+		recipients = append(recipients, *incident.AssigneeID)
+	}
+	for _, assignee := range incident.Assignees {
+		recipients = append(recipients, assignee.ID)
+	}
+	if incident.Department != nil && incident.Department.ManagerID != nil {
+		recipients = append(recipients, *incident.Department.ManagerID)
+	}
+	if len(recipients) == 0 {
+		return
+	}
+
+	_, err := m.notifier.Notify(ctx, &CreateNotificationParams{
+		UserIDs:    recipients,
+		Type:       models.NotificationTypeSLABreach,
+		Title:      fmt.Sprintf("SLA breached: %s", incident.IncidentNumber),
+		Message:    fmt.Sprintf("%s has passed its SLA deadline", incident.Title),
+		IncidentID: &incident.ID,
+	})
+	if err != nil {
+		log.Printf("Failed to create SLA breach notifications for %s: %v", incident.IncidentNumber, err)
+	}
+}
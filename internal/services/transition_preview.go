@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// PreviewTransition reports what ExecuteTransition would do with the same request: unmet
+// requirements, the department and assignees it would set, the new SLA deadline and the actions
+// that would fire. Nothing is written.
+func (s *incidentService) PreviewTransition(ctx context.Context, incidentID uuid.UUID, req *models.IncidentTransitionRequest, userID uuid.UUID, userRoleIDs []uuid.UUID) (*models.TransitionPreviewResponse, error) {
+	incident, err := s.incidentRepo.FindByIDWithRelations(ctx, incidentID)
+	if err != nil {
+		return nil, errors.New("incident not found")
+	}
+
+	transitionID, err := uuid.Parse(req.TransitionID)
+	if err != nil {
+		return nil, errors.New("invalid transition_id")
+	}
+
+	transition, err := loadIncidentTransition(ctx, s.workflowRepo, incident, transitionID)
+	if err != nil {
+		return nil, errors.New("transition not found")
+	}
+	if transition.WorkflowID != incident.WorkflowID {
+		return nil, errors.New("transition does not belong to this workflow")
+	}
+	if transition.FromStateID != incident.CurrentStateID {
+		return nil, errors.New("transition cannot be executed from current state")
+	}
+
+	preview := &models.TransitionPreviewResponse{
+		Transition:        models.ToWorkflowTransitionResponse(transition),
+		CanExecute:        true,
+		UnmetRequirements: []models.TransitionRequirementFailure{},
+		Assignees:         []models.UserResponse{},
+		Actions:           []models.TransitionActionPreview{},
+	}
+	if transition.FromState != nil {
+		fromState := models.ToWorkflowStateResponse(transition.FromState)
+		preview.FromState = &fromState
+	}
+	block := func(reason string) {
+		if preview.CanExecute {
+			preview.CanExecute = false
+			preview.Reason = reason
+		}
+	}
+
+	if !hasTransitionRole(transition, userRoleIDs) {
+		block("you do not have permission to execute this transition")
+	}
+
+	if err := s.checkTransitionRequirements(ctx, incident, transition, req); err != nil {
+		var reqErr *TransitionRequirementsError
+		if !errors.As(err, &reqErr) {
+			return nil, err
+		}
+		preview.UnmetRequirements = reqErr.Failures
+		block("transition requirements are not met")
+	}
+
+	// Approval transitions may only record a vote, or move to the reject state instead
+	if transition.ApprovalMode != "" {
+		status, err := s.approvalStatus(ctx, incidentID, transition)
+		if err != nil {
+			return nil, err
+		}
+		preview.Approval = status
+
+		outcome, err := s.previewApprovalVote(ctx, incident, transition, req, userID, userRoleIDs)
+		if err != nil {
+			block(err.Error())
+		} else {
+			switch outcome {
+			case approvalPending:
+				preview.ApprovalOutcome = "vote_recorded"
+			case approvalReached:
+				preview.ApprovalOutcome = "quorum_reached"
+			case approvalRejected:
+				preview.ApprovalOutcome = "rejected"
+			}
+		}
+
+		if outcome == approvalPending || (outcome == approvalRejected && transition.RejectStateID == nil) {
+			// The incident stays where it is
+			preview.ToState = preview.FromState
+			return preview, nil
+		}
+		if outcome == approvalRejected {
+			if transition, err = s.rejectionTransition(ctx, transition); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	plan, err := s.planTransition(ctx, incident, transition, req)
+	if err != nil {
+		return nil, err
+	}
+
+	toState := models.ToWorkflowStateResponse(plan.newState)
+	preview.ToState = &toState
+	_, preview.ClosesRecord = plan.updates["closed_at"]
+	if deadline, ok := plan.updates["sla_deadline"].(time.Time); ok {
+		preview.SLADeadline = &deadline
+	}
+
+	if preview.ClosesRecord {
+		openChildren, err := s.incidentRepo.CountOpenBlockingChildren(ctx, incidentID)
+		if err != nil {
+			return nil, err
+		}
+		if openChildren > 0 {
+			block(fmt.Sprintf("cannot close: %d linked record(s) are still open", openChildren))
+		}
+		if err := s.checkOpenBlockers(ctx, incidentID); err != nil {
+			block(err.Error())
+		}
+	}
+
+	// Department: the one that would be set, plus the matches to pick from when auto-detecting
+	var department *models.Department
+	if deptID, ok := plan.updates["department_id"].(uuid.UUID); ok {
+		if dept, err := s.deptRepo.FindByID(ctx, deptID); err == nil {
+			department = dept
+			deptResp := models.ToDepartmentResponse(dept)
+			preview.Department = &deptResp
+		}
+	}
+	if transition.AssignDepartmentID == nil && transition.AutoDetectDepartment {
+		candidates, err := s.deptRepo.FindMatching(ctx, incident.ClassificationID, incident.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		preview.DepartmentCandidates = make([]models.DepartmentResponse, len(candidates))
+		for i := range candidates {
+			preview.DepartmentCandidates[i] = models.ToDepartmentResponse(&candidates[i])
+		}
+	}
+
+	var assignees []models.User
+	for _, assigneeID := range plan.assigneeUserIDs {
+		if user, err := s.userRepo.FindByID(ctx, assigneeID); err == nil {
+			assignees = append(assignees, *user)
+			preview.Assignees = append(preview.Assignees, models.ToUserResponse(user))
+		}
+	}
+
+	if s.actionExecutor != nil && len(transition.Actions) > 0 {
+		performedBy, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			performedBy = nil
+		}
+		// Actions run against the record after the transition, so their conditions do too
+		planned := plannedIncident(incident, plan, department, assignees)
+		preview.Actions = s.actionExecutor.PreviewActions(ctx, planned, transition, performedBy)
+	}
+
+	return preview, nil
+}
+
+// plannedIncident returns a copy of the incident with a transition plan applied, as
+// ExecuteTransition would leave it. The incident itself is not modified.
+func plannedIncident(incident *models.Incident, plan *transitionPlan, department *models.Department, assignees []models.User) *models.Incident {
+	planned := *incident
+	planned.CurrentStateID = plan.newState.ID
+	planned.CurrentState = plan.newState
+
+	if deptID, ok := plan.updates["department_id"].(uuid.UUID); ok {
+		planned.DepartmentID = &deptID
+		planned.Department = department
+	}
+	if assigneeID, ok := plan.updates["assignee_id"].(uuid.UUID); ok {
+		planned.AssigneeID = &assigneeID
+		planned.Assignee = nil
+		for i := range assignees {
+			if assignees[i].ID == assigneeID {
+				planned.Assignee = &assignees[i]
+				break
+			}
+		}
+	}
+	if previousID, ok := plan.updates["previous_assignee_id"].(uuid.UUID); ok {
+		planned.PreviousAssigneeID = &previousID
+	}
+	if len(assignees) > 0 {
+		planned.Assignees = assignees
+	}
+	if deadline, ok := plan.updates["sla_deadline"].(time.Time); ok {
+		planned.SLADeadline = &deadline
+		planned.SLABreached = false
+	}
+	if resolvedAt, ok := plan.updates["resolved_at"].(time.Time); ok {
+		planned.ResolvedAt = &resolvedAt
+	}
+	if closedAt, ok := plan.updates["closed_at"].(time.Time); ok {
+		planned.ClosedAt = &closedAt
+	}
+	return &planned
+}
@@ -0,0 +1,534 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+)
+
+// TransitionRequirementsError lists every unmet requirement of a transition
+type TransitionRequirementsError struct {
+	Failures []models.TransitionRequirementFailure
+}
+
+func (e *TransitionRequirementsError) Error() string {
+	messages := make([]string, len(e.Failures))
+	for i, f := range e.Failures {
+		messages[i] = f.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RequirementConfigError reports an invalid transition requirement definition
+type RequirementConfigError struct {
+	RequirementType string
+	FieldName       string
+	Err             error
+}
+
+func (e *RequirementConfigError) Error() string {
+	if e.FieldName != "" {
+		return fmt.Sprintf("%s requirement on %q: %v", e.RequirementType, e.FieldName, e.Err)
+	}
+	return fmt.Sprintf("%s requirement: %v", e.RequirementType, e.Err)
+}
+
+func (e *RequirementConfigError) Unwrap() error {
+	return e.Err
+}
+
+// requirementFields lists the incident fields field_value requirements can check,
+// besides lookup:<CATEGORY_CODE> and custom_field:<key>
+var requirementFields = map[string]string{
+	"title":             "Title",
+	"description":       "Description",
+	"channel":           "Channel",
+	"reporter_email":    "Reporter email",
+	"reporter_name":     "Reporter name",
+	"assignee_id":       "Assignee",
+	"department_id":     "Department",
+	"location_id":       "Location",
+	"classification_id": "Classification",
+	"due_date":          "Due date",
+	"latitude":          "Latitude",
+	"longitude":         "Longitude",
+	"address":           "Address",
+}
+
+// requirementOperator returns the effective operator. Requirements created before
+// operators existed use equals when a value is set and required otherwise.
+func requirementOperator(r *models.TransitionRequirement) string {
+	if r.Operator != "" {
+		return r.Operator
+	}
+	if r.FieldValue != "" {
+		return models.RequirementOperatorEquals
+	}
+	return models.RequirementOperatorRequired
+}
+
+// ValidateTransitionRequirement checks a requirement definition before it is saved
+func ValidateTransitionRequirement(r *models.TransitionRequirement) error {
+	invalid := func(format string, args ...interface{}) error {
+		return &RequirementConfigError{RequirementType: r.RequirementType, FieldName: r.FieldName, Err: fmt.Errorf(format, args...)}
+	}
+
+	switch r.RequirementType {
+	case "comment", "feedback":
+		return nil
+	case "attachment":
+		if r.MinCount < 0 {
+			return invalid("min_count cannot be negative")
+		}
+		return nil
+	case "field_value":
+	default:
+		return invalid("unsupported requirement type")
+	}
+
+	op := requirementOperator(r)
+	if op == models.RequirementOperatorExpression {
+		if strings.TrimSpace(r.FieldValue) == "" {
+			return invalid("expression is required")
+		}
+		if _, err := ParseActionCondition(r.FieldValue); err != nil {
+			return invalid("invalid expression: %v", err)
+		}
+		return nil
+	}
+
+	if err := validateRequirementField(r.FieldName); err != nil {
+		return invalid("%v", err)
+	}
+
+	switch op {
+	case models.RequirementOperatorRequired, models.RequirementOperatorDateFuture, models.RequirementOperatorDatePast:
+	case models.RequirementOperatorEquals, models.RequirementOperatorNotEquals, models.RequirementOperatorIn:
+		if r.FieldValue == "" {
+			return invalid("field_value is required for %s", op)
+		}
+	case models.RequirementOperatorRegex:
+		if _, err := regexp.Compile(r.FieldValue); err != nil {
+			return invalid("invalid regex: %v", err)
+		}
+	case models.RequirementOperatorMin, models.RequirementOperatorMax:
+		if _, err := strconv.ParseFloat(r.FieldValue, 64); err != nil {
+			return invalid("field_value must be a number for %s", op)
+		}
+	case models.RequirementOperatorMinLength, models.RequirementOperatorMaxLength:
+		if n, err := strconv.Atoi(r.FieldValue); err != nil || n < 0 {
+			return invalid("field_value must be a non-negative integer for %s", op)
+		}
+	default:
+		return invalid("unsupported operator %q", op)
+	}
+	return nil
+}
+
+func validateRequirementField(field string) error {
+	switch {
+	case field == "":
+		return fmt.Errorf("field_name is required")
+	case strings.HasPrefix(field, "lookup:"):
+		if strings.TrimPrefix(field, "lookup:") == "" {
+			return fmt.Errorf("lookup category code is required")
+		}
+	case strings.HasPrefix(field, "custom_field:"):
+		if strings.TrimPrefix(field, "custom_field:") == "" {
+			return fmt.Errorf("custom field key is required")
+		}
+	case field == "priority" || field == "severity":
+	default:
+		if _, ok := requirementFields[field]; !ok {
+			return fmt.Errorf("unsupported field")
+		}
+	}
+	return nil
+}
+
+// checkTransitionRequirements evaluates every requirement of a transition and
+// returns all failures together
+func (s *incidentService) checkTransitionRequirements(ctx context.Context, incident *models.Incident, transition *models.WorkflowTransition, req *models.IncidentTransitionRequest) error {
+	var failures []models.TransitionRequirementFailure
+	fail := func(r *models.TransitionRequirement, field, operator, defaultMsg string) {
+		msg := r.ErrorMessage
+		if msg == "" {
+			msg = defaultMsg
+		}
+		failures = append(failures, models.TransitionRequirementFailure{
+			RequirementID:   r.ID,
+			RequirementType: r.RequirementType,
+			Field:           field,
+			Operator:        operator,
+			Message:         msg,
+		})
+	}
+
+	// field_value checks need lookups, location and classification loaded
+	var full *models.Incident
+	var attachments []*models.IncidentAttachment
+	attachmentsLoaded := false
+
+	for i := range transition.Requirements {
+		requirement := &transition.Requirements[i]
+		mandatory := requirement.IsMandatory != nil && *requirement.IsMandatory
+
+		switch requirement.RequirementType {
+		case "comment":
+			if mandatory && strings.TrimSpace(req.Comment) == "" {
+				fail(requirement, "comment", "", "Comment is required for this transition")
+			}
+		case "attachment":
+			if !attachmentsLoaded {
+				attachments = s.transitionAttachments(ctx, incident.ID, req.Attachments)
+				attachmentsLoaded = true
+			}
+			minCount := requirement.MinCount
+			if minCount == 0 && mandatory {
+				minCount = 1
+			}
+			if len(attachments) < minCount {
+				if minCount == 1 {
+					fail(requirement, "attachments", "", "Attachment is required for this transition")
+				} else {
+					fail(requirement, "attachments", "", fmt.Sprintf("At least %d attachments are required for this transition", minCount))
+				}
+				continue
+			}
+			if requirement.FieldValue != "" {
+				allowed := splitRequirementList(requirement.FieldValue)
+				for _, a := range attachments {
+					if !mimeTypeAllowed(a.MimeType, allowed) {
+						fail(requirement, "attachments", "", fmt.Sprintf("Attachment %s must be one of: %s", a.FileName, strings.Join(allowed, ", ")))
+					}
+				}
+			}
+		case "feedback":
+			if mandatory && (req.Feedback == nil || req.Feedback.Rating == 0) {
+				fail(requirement, "feedback", "", "Feedback is required for this transition")
+			}
+		case "field_value":
+			if full == nil {
+				loaded, err := s.incidentRepo.FindByIDWithRelations(ctx, incident.ID)
+				if err != nil {
+					return err
+				}
+				full = loaded
+			}
+			if msg := evaluateFieldRequirement(requirement, full, transition, mandatory); msg != "" {
+				fail(requirement, requirement.FieldName, requirementOperator(requirement), msg)
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		return &TransitionRequirementsError{Failures: failures}
+	}
+	return nil
+}
+
+// transitionAttachments loads the attachments submitted with a transition that belong to the incident
+func (s *incidentService) transitionAttachments(ctx context.Context, incidentID uuid.UUID, ids []string) []*models.IncidentAttachment {
+	var attachments []*models.IncidentAttachment
+	for _, idStr := range ids {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			continue
+		}
+		attachment, err := s.incidentRepo.FindAttachmentByID(ctx, id)
+		if err != nil || attachment.IncidentID != incidentID {
+			continue
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments
+}
+
+// mimeTypeAllowed matches a MIME type against patterns such as "application/pdf" or "image/*"
+func mimeTypeAllowed(mimeType string, allowed []string) bool {
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	for _, pattern := range allowed {
+		if ok, _ := path.Match(strings.ToLower(pattern), mimeType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func splitRequirementList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// evaluateFieldRequirement checks a field_value requirement and returns the failure
+// message, or "" when it passes. Optional requirements only check values that are set.
+func evaluateFieldRequirement(r *models.TransitionRequirement, incident *models.Incident, transition *models.WorkflowTransition, mandatory bool) string {
+	op := requirementOperator(r)
+
+	if op == models.RequirementOperatorExpression {
+		cond, err := ParseActionCondition(r.FieldValue)
+		if err != nil {
+			return fmt.Sprintf("Invalid requirement expression: %v", err)
+		}
+		if !cond.Evaluate(newConditionContext(incident, transition)) {
+			return fmt.Sprintf("Condition not met: %s", r.FieldValue)
+		}
+		return ""
+	}
+
+	label := requirementFieldLabel(r.FieldName)
+	values := requirementFieldValues(r.FieldName, incident)
+	forms := flattenRequirementValues(values)
+	if len(values) == 0 {
+		if mandatory || op == models.RequirementOperatorRequired {
+			return fmt.Sprintf("%s is required", label)
+		}
+		return ""
+	}
+
+	switch op {
+	case models.RequirementOperatorRequired:
+		return ""
+	case models.RequirementOperatorEquals:
+		if !anyEqualFold(forms, []string{r.FieldValue}) {
+			return fmt.Sprintf("%s must be %s", label, r.FieldValue)
+		}
+	case models.RequirementOperatorNotEquals:
+		if anyEqualFold(forms, []string{r.FieldValue}) {
+			return fmt.Sprintf("%s must not be %s", label, r.FieldValue)
+		}
+	case models.RequirementOperatorIn:
+		allowed := splitRequirementList(r.FieldValue)
+		if !anyEqualFold(forms, allowed) {
+			return fmt.Sprintf("%s must be one of: %s", label, strings.Join(allowed, ", "))
+		}
+	case models.RequirementOperatorRegex:
+		re, err := regexp.Compile(r.FieldValue)
+		if err != nil {
+			return fmt.Sprintf("Invalid requirement pattern for %s", label)
+		}
+		for _, v := range values {
+			if msg := checkRequirementValue(v, func(form string) string {
+				if !re.MatchString(form) {
+					return fmt.Sprintf("%s has an invalid format", label)
+				}
+				return ""
+			}); msg != "" {
+				return msg
+			}
+		}
+	case models.RequirementOperatorMin, models.RequirementOperatorMax:
+		limit, _ := strconv.ParseFloat(r.FieldValue, 64)
+		for _, v := range values {
+			if msg := checkRequirementValue(v, func(form string) string {
+				n, err := strconv.ParseFloat(form, 64)
+				if err != nil {
+					return fmt.Sprintf("%s must be a number", label)
+				}
+				if op == models.RequirementOperatorMin && n < limit {
+					return fmt.Sprintf("%s must be at least %s", label, r.FieldValue)
+				}
+				if op == models.RequirementOperatorMax && n > limit {
+					return fmt.Sprintf("%s must be at most %s", label, r.FieldValue)
+				}
+				return ""
+			}); msg != "" {
+				return msg
+			}
+		}
+	case models.RequirementOperatorMinLength, models.RequirementOperatorMaxLength:
+		limit, _ := strconv.Atoi(r.FieldValue)
+		for _, v := range values {
+			if msg := checkRequirementValue(v, func(form string) string {
+				length := len([]rune(form))
+				if op == models.RequirementOperatorMinLength && length < limit {
+					return fmt.Sprintf("%s must be at least %d characters", label, limit)
+				}
+				if op == models.RequirementOperatorMaxLength && length > limit {
+					return fmt.Sprintf("%s must be at most %d characters", label, limit)
+				}
+				return ""
+			}); msg != "" {
+				return msg
+			}
+		}
+	case models.RequirementOperatorDateFuture, models.RequirementOperatorDatePast:
+		now := time.Now()
+		for _, v := range values {
+			if msg := checkRequirementValue(v, func(form string) string {
+				t, err := parseFieldTime(form)
+				if err != nil {
+					return fmt.Sprintf("%s must be a date", label)
+				}
+				if op == models.RequirementOperatorDateFuture && !t.After(now) {
+					return fmt.Sprintf("%s must be in the future", label)
+				}
+				if op == models.RequirementOperatorDatePast && !t.Before(now) {
+					return fmt.Sprintf("%s must be in the past", label)
+				}
+				return ""
+			}); msg != "" {
+				return msg
+			}
+		}
+	default:
+		return fmt.Sprintf("Unsupported requirement operator %q", op)
+	}
+	return ""
+}
+
+func requirementFieldLabel(field string) string {
+	switch {
+	case strings.HasPrefix(field, "lookup:"):
+		return strings.TrimPrefix(field, "lookup:")
+	case strings.HasPrefix(field, "custom_field:"):
+		return strings.TrimPrefix(field, "custom_field:")
+	case field == "priority":
+		return "Priority"
+	case field == "severity":
+		return "Severity"
+	}
+	if label, ok := requirementFields[field]; ok {
+		return label
+	}
+	return field
+}
+
+// checkRequirementValue applies a check to every form of a value. The value passes when
+// any form does; otherwise the first form's message is returned.
+func checkRequirementValue(value requirementValue, check func(form string) string) string {
+	first := ""
+	for _, form := range value {
+		msg := check(form)
+		if msg == "" {
+			return ""
+		}
+		if first == "" {
+			first = msg
+		}
+	}
+	return first
+}
+
+func flattenRequirementValues(values []requirementValue) []string {
+	var forms []string
+	for _, v := range values {
+		forms = append(forms, v...)
+	}
+	return forms
+}
+
+// requirementValue is one value of a field in every form a rule may target, e.g. the code
+// and the name of a lookup value
+type requirementValue []string
+
+// requirementFieldValues returns the non-empty values of a field
+func requirementFieldValues(field string, incident *models.Incident) []requirementValue {
+	nonEmpty := func(values ...string) []requirementValue {
+		var out []requirementValue
+		for _, v := range values {
+			if strings.TrimSpace(v) != "" {
+				out = append(out, requirementValue{v})
+			}
+		}
+		return out
+	}
+	optionalID := func(id *uuid.UUID) []requirementValue {
+		if id == nil {
+			return nil
+		}
+		return []requirementValue{{id.String()}}
+	}
+	optionalFloat := func(f *float64) []requirementValue {
+		if f == nil {
+			return nil
+		}
+		return []requirementValue{{strconv.FormatFloat(*f, 'f', -1, 64)}}
+	}
+
+	if field == "priority" || field == "severity" {
+		field = "lookup:" + strings.ToUpper(field)
+	}
+
+	switch {
+	case strings.HasPrefix(field, "lookup:"):
+		code := strings.TrimPrefix(field, "lookup:")
+		var out []requirementValue
+		for _, lv := range incident.LookupValues {
+			if lv.Category != nil && strings.EqualFold(lv.Category.Code, code) {
+				if value := flattenRequirementValues(nonEmpty(lv.Code, lv.Name)); len(value) > 0 {
+					out = append(out, value)
+				}
+			}
+		}
+		return out
+	case strings.HasPrefix(field, "custom_field:"):
+		key := strings.TrimPrefix(field, "custom_field:")
+		if incident.CustomFields == "" {
+			return nil
+		}
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(incident.CustomFields), &fields); err != nil {
+			return nil
+		}
+		switch v := fields[key].(type) {
+		case nil:
+			return nil
+		case []interface{}:
+			out := make([]string, 0, len(v))
+			for _, item := range v {
+				out = append(out, fmt.Sprint(item))
+			}
+			return nonEmpty(out...)
+		case float64:
+			return []requirementValue{{strconv.FormatFloat(v, 'f', -1, 64)}}
+		default:
+			return nonEmpty(fmt.Sprint(v))
+		}
+	}
+
+	switch field {
+	case "title":
+		return nonEmpty(incident.Title)
+	case "description":
+		return nonEmpty(incident.Description)
+	case "channel":
+		return nonEmpty(incident.Channel)
+	case "reporter_email":
+		return nonEmpty(incident.ReporterEmail)
+	case "reporter_name":
+		return nonEmpty(incident.ReporterName)
+	case "assignee_id":
+		return optionalID(incident.AssigneeID)
+	case "department_id":
+		return optionalID(incident.DepartmentID)
+	case "location_id":
+		return optionalID(incident.LocationID)
+	case "classification_id":
+		return optionalID(incident.ClassificationID)
+	case "due_date":
+		if incident.DueDate != nil {
+			return []requirementValue{{incident.DueDate.Format(time.RFC3339)}}
+		}
+	case "latitude":
+		return optionalFloat(incident.Latitude)
+	case "longitude":
+		return optionalFloat(incident.Longitude)
+	case "address":
+		return nonEmpty(incident.Address)
+	}
+	return nil
+}
@@ -0,0 +1,264 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	transitionSchedulerLeaseKey = "scheduler:auto_transitions:lease"
+	autoTransitionMaxBackoff    = 24 * time.Hour
+)
+
+// Extends the lease only if this replica still holds it
+var renewLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Releases the lease only if this replica still holds it
+var releaseLeaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// TransitionScheduler executes timer-based automatic transitions in the background.
+// A Redis lease makes sure only one replica runs them.
+type TransitionScheduler interface {
+	Start(ctx context.Context)
+	Stop()
+	RunDueTransitions(ctx context.Context) error
+}
+
+type transitionScheduler struct {
+	incidentRepo    repository.IncidentRepository
+	userRepo        repository.UserRepository
+	incidentService IncidentService
+	client          *redis.Client
+	interval        time.Duration
+	token           string
+	systemUserID    uuid.UUID
+	stopChan        chan struct{}
+	running         bool
+
+	mu       sync.Mutex
+	failures map[models.DueAutoTransition]autoTransitionFailure
+}
+
+// autoTransitionFailure tracks an automatic transition that keeps failing, e.g. because a
+// blocking link or an unmet requirement stops it, so it isn't retried on every tick
+type autoTransitionFailure struct {
+	attempts int
+	retryAt  time.Time
+}
+
+// NewTransitionScheduler creates a new automatic transition scheduler
+func NewTransitionScheduler(incidentRepo repository.IncidentRepository, userRepo repository.UserRepository, incidentService IncidentService, client *redis.Client, checkInterval time.Duration) TransitionScheduler {
+	if checkInterval == 0 {
+		checkInterval = time.Minute // Default to 1 minute
+	}
+
+	return &transitionScheduler{
+		incidentRepo:    incidentRepo,
+		userRepo:        userRepo,
+		incidentService: incidentService,
+		client:          client,
+		interval:        checkInterval,
+		token:           uuid.New().String(),
+		stopChan:        make(chan struct{}),
+		failures:        make(map[models.DueAutoTransition]autoTransitionFailure),
+	}
+}
+
+// Start begins the background scheduler
+func (m *transitionScheduler) Start(ctx context.Context) {
+	if m.running {
+		return
+	}
+
+	m.running = true
+	log.Printf("Transition scheduler started with interval: %v", m.interval)
+
+	go func() {
+		m.tick(ctx)
+
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.tick(ctx)
+			case <-m.stopChan:
+				log.Println("Transition scheduler stopped")
+				return
+			case <-ctx.Done():
+				log.Println("Transition scheduler context cancelled")
+				return
+			}
+		}
+	}()
+}
+
+// Stop halts the scheduler and gives up the lease so another replica can take over
+func (m *transitionScheduler) Stop() {
+	if !m.running {
+		return
+	}
+
+	m.running = false
+	close(m.stopChan)
+
+	if m.client != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		releaseLeaseScript.Run(ctx, m.client, []string{transitionSchedulerLeaseKey}, m.token)
+	}
+}
+
+func (m *transitionScheduler) tick(ctx context.Context) {
+	if !m.acquireLease(ctx) {
+		return
+	}
+	if err := m.RunDueTransitions(ctx); err != nil {
+		log.Printf("Automatic transition run failed: %v", err)
+	}
+}
+
+// acquireLease takes or renews the scheduler lease. The lease outlives one interval so the
+// holder keeps it between ticks; if the holder dies another replica takes over once it expires.
+func (m *transitionScheduler) acquireLease(ctx context.Context) bool {
+	if m.client == nil {
+		return true
+	}
+
+	acquired, err := m.client.SetNX(ctx, transitionSchedulerLeaseKey, m.token, m.leaseTTL()).Result()
+	if err != nil {
+		log.Printf("Failed to acquire transition scheduler lease: %v", err)
+		return false
+	}
+	if acquired {
+		return true
+	}
+	return m.renewLease(ctx)
+}
+
+// renewLease extends the lease this replica holds. Runs renew it before every transition, so a
+// run that takes longer than the lease doesn't overlap with another replica's.
+func (m *transitionScheduler) renewLease(ctx context.Context) bool {
+	if m.client == nil {
+		return true
+	}
+
+	renewed, err := renewLeaseScript.Run(ctx, m.client, []string{transitionSchedulerLeaseKey}, m.token, m.leaseTTL().Milliseconds()).Int()
+	if err != nil {
+		log.Printf("Failed to renew transition scheduler lease: %v", err)
+		return false
+	}
+	return renewed == 1
+}
+
+func (m *transitionScheduler) leaseTTL() time.Duration {
+	return 2 * m.interval
+}
+
+// RunDueTransitions executes the automatic transitions whose trigger has fired, at most one per
+// record. When a record's first transition fails the next one due is tried; failed transitions
+// are retried with an exponential backoff until they succeed or stop being due. It stops early
+// if this replica loses the scheduler lease.
+func (m *transitionScheduler) RunDueTransitions(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	systemUserID, err := m.resolveSystemUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	due, err := m.incidentRepo.ListDueAutoTransitions(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Forget failures of transitions that are no longer due, e.g. because the record moved on
+	stillDue := make(map[models.DueAutoTransition]bool, len(due))
+	for _, item := range due {
+		stillDue[item] = true
+	}
+	for item := range m.failures {
+		if !stillDue[item] {
+			delete(m.failures, item)
+		}
+	}
+
+	executed := 0
+	var transitioned uuid.UUID
+	for _, item := range due {
+		// Due transitions are grouped by record; once one succeeds the rest left the old state
+		if item.IncidentID == transitioned {
+			continue
+		}
+		if failure, ok := m.failures[item]; ok && now.Before(failure.retryAt) {
+			continue
+		}
+		if !m.renewLease(ctx) {
+			return errors.New("transition scheduler lease lost during run")
+		}
+		if _, err := m.incidentService.ExecuteAutoTransition(ctx, item.IncidentID, item.TransitionID, systemUserID); err != nil {
+			failure := m.failures[item]
+			failure.attempts++
+			failure.retryAt = now.Add(m.failureBackoff(failure.attempts))
+			m.failures[item] = failure
+			log.Printf("Automatic transition %s on incident %s failed (attempt %d, retrying at %s): %v",
+				item.TransitionID, item.IncidentID, failure.attempts, failure.retryAt.Format(time.RFC3339), err)
+			continue
+		}
+		delete(m.failures, item)
+		transitioned = item.IncidentID
+		executed++
+	}
+
+	if executed > 0 {
+		log.Printf("Executed %d automatic transitions", executed)
+	}
+	return nil
+}
+
+// failureBackoff returns the delay before retrying a transition that failed the given number of
+// times, doubling from one scheduler interval
+func (m *transitionScheduler) failureBackoff(attempts int) time.Duration {
+	delay := m.interval
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= autoTransitionMaxBackoff {
+			return autoTransitionMaxBackoff
+		}
+	}
+	return delay
+}
+
+func (m *transitionScheduler) resolveSystemUser(ctx context.Context) (uuid.UUID, error) {
+	if m.systemUserID != uuid.Nil {
+		return m.systemUserID, nil
+	}
+
+	user, err := m.userRepo.FindByEmail(ctx, models.SystemUserEmail)
+	if err != nil {
+		return uuid.Nil, errors.New("system user not found")
+	}
+	m.systemUserID = user.ID
+	return m.systemUserID, nil
+}
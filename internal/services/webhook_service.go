@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	WebhookSignatureHeader   = "X-Webhook-Signature-256"
+	WebhookIdempotencyHeader = "Idempotency-Key"
+
+	webhookResponseSnippet = 2048
+)
+
+// ErrWebhookRejected marks a failed delivery that retrying will not fix, such as a 4xx response
+var ErrWebhookRejected = errors.New("webhook rejected")
+
+// SendWebhookParams describes an outbound webhook call
+type SendWebhookParams struct {
+	URL                string
+	Method             string
+	Headers            map[string]string
+	Body               string
+	Secret             string // Signs the body with HMAC-SHA256 when set
+	IdempotencyKey     string // Stable across retries; generated when empty
+	IncidentID         *uuid.UUID
+	TransitionActionID *uuid.UUID
+}
+
+type WebhookService interface {
+	Send(ctx context.Context, params *SendWebhookParams) (*models.WebhookDeliveryResponse, error)
+	Redeliver(ctx context.Context, id uuid.UUID) (*models.WebhookDeliveryResponse, error)
+	GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDeliveryResponse, error)
+	ListDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) ([]models.WebhookDeliveryResponse, int64, error)
+}
+
+type webhookService struct {
+	repo       repository.WebhookDeliveryRepository
+	httpClient *http.Client
+}
+
+func NewWebhookService(repo repository.WebhookDeliveryRepository) WebhookService {
+	return &webhookService{
+		repo: repo,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// SignWebhookPayload returns the signature header value for a body
+func SignWebhookPayload(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send performs a single webhook attempt and records it as a delivery. Retries are left to
+// the caller (the job queue for async actions); attempts sharing an idempotency key are
+// numbered consecutively.
+func (s *webhookService) Send(ctx context.Context, params *SendWebhookParams) (*models.WebhookDeliveryResponse, error) {
+	if params.URL == "" {
+		return nil, errors.New("webhook URL is required")
+	}
+
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	key := params.IdempotencyKey
+	if key == "" {
+		key = uuid.New().String()
+	}
+
+	headers := map[string]string{"Content-Type": "application/json"}
+	for k, v := range params.Headers {
+		headers[k] = v
+	}
+	headers[WebhookIdempotencyHeader] = key
+	if params.Secret != "" {
+		headers[WebhookSignatureHeader] = SignWebhookPayload(params.Secret, params.Body)
+	}
+
+	// Continue attempt numbering when the same event is retried by the job queue
+	lastAttempt, err := s.repo.GetLastAttempt(ctx, key)
+	if err != nil {
+		log.Printf("Failed to read webhook attempts for %s: %v", key, err)
+	}
+
+	delivery := &models.WebhookDelivery{
+		IdempotencyKey:     key,
+		Attempt:            lastAttempt + 1,
+		URL:                params.URL,
+		Method:             method,
+		RequestBody:        params.Body,
+		IncidentID:         params.IncidentID,
+		TransitionActionID: params.TransitionActionID,
+	}
+	retryable := s.deliver(ctx, delivery, headers)
+
+	resp := models.ToWebhookDeliveryResponse(delivery)
+	if delivery.Status != models.WebhookStatusSuccess {
+		if !retryable {
+			return &resp, fmt.Errorf("%w: %s", ErrWebhookRejected, delivery.Error)
+		}
+		return &resp, fmt.Errorf("webhook delivery failed: %s", delivery.Error)
+	}
+	return &resp, nil
+}
+
+// Redeliver replays a recorded delivery once with its original headers and idempotency key
+func (s *webhookService) Redeliver(ctx context.Context, id uuid.UUID) (*models.WebhookDeliveryResponse, error) {
+	original, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, errors.New("webhook delivery not found")
+	}
+
+	headers := make(map[string]string)
+	if original.RequestHeaders != "" {
+		if err := json.Unmarshal([]byte(original.RequestHeaders), &headers); err != nil {
+			return nil, fmt.Errorf("invalid stored request headers: %w", err)
+		}
+	}
+
+	lastAttempt, err := s.repo.GetLastAttempt(ctx, original.IdempotencyKey)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		IdempotencyKey:     original.IdempotencyKey,
+		Attempt:            lastAttempt + 1,
+		IsRedelivery:       true,
+		URL:                original.URL,
+		Method:             original.Method,
+		RequestBody:        original.RequestBody,
+		IncidentID:         original.IncidentID,
+		TransitionActionID: original.TransitionActionID,
+	}
+	s.deliver(ctx, delivery, headers)
+
+	resp := models.ToWebhookDeliveryResponse(delivery)
+	return &resp, nil
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, id uuid.UUID) (*models.WebhookDeliveryResponse, error) {
+	delivery, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ToWebhookDeliveryResponse(delivery)
+	return &resp, nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, filter *models.WebhookDeliveryFilter) ([]models.WebhookDeliveryResponse, int64, error) {
+	deliveries, total, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.WebhookDeliveryResponse, len(deliveries))
+	for i := range deliveries {
+		responses[i] = models.ToWebhookDeliveryResponse(&deliveries[i])
+	}
+
+	return responses, total, nil
+}
+
+// deliver performs a single HTTP attempt and records it. It reports whether a
+// failed attempt may be retried (network errors and 5xx responses).
+func (s *webhookService) deliver(ctx context.Context, delivery *models.WebhookDelivery, headers map[string]string) bool {
+	if headersJSON, err := json.Marshal(headers); err == nil {
+		delivery.RequestHeaders = string(headersJSON)
+	}
+
+	defer func() {
+		if err := s.repo.Create(context.WithoutCancel(ctx), delivery); err != nil {
+			log.Printf("Failed to record webhook delivery: %v", err)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, delivery.Method, delivery.URL, bytes.NewBufferString(delivery.RequestBody))
+	if err != nil {
+		delivery.Status = models.WebhookStatusFailed
+		delivery.Error = fmt.Sprintf("failed to create webhook request: %v", err)
+		return false
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	start := time.Now()
+	resp, err := s.httpClient.Do(req)
+	delivery.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Status = models.WebhookStatusFailed
+		delivery.Error = fmt.Sprintf("webhook request failed: %v", err)
+		log.Printf("Webhook attempt %d to %s failed: %v", delivery.Attempt, delivery.URL, err)
+		return true
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseSnippet))
+	delivery.ResponseStatus = resp.StatusCode
+	delivery.ResponseBody = string(snippet)
+
+	if resp.StatusCode >= 400 {
+		delivery.Status = models.WebhookStatusFailed
+		delivery.Error = fmt.Sprintf("webhook returned error status: %d", resp.StatusCode)
+		log.Printf("Webhook attempt %d to %s returned %d", delivery.Attempt, delivery.URL, resp.StatusCode)
+		return resp.StatusCode >= 500
+	}
+
+	delivery.Status = models.WebhookStatusSuccess
+	log.Printf("Webhook executed: URL=%s, Status=%d, Attempt=%d", delivery.URL, resp.StatusCode, delivery.Attempt)
+	return false
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+)
+
+var (
+	ErrAnalyticsWorkflowNotFound = errors.New("workflow not found")
+	ErrAnalyticsInvalidPeriod    = errors.New("end_date must not be before start_date")
+)
+
+// WorkflowAnalyticsService reports process performance figures from a workflow's transition history
+type WorkflowAnalyticsService interface {
+	GetDwellTimes(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.StateDwellTime, error)
+	GetTransitionFrequencies(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.TransitionFrequency, error)
+	GetRework(ctx context.Context, filter *models.WorkflowAnalyticsFilter) (*models.WorkflowReworkResponse, error)
+	GetUserThroughput(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.UserThroughput, error)
+}
+
+type workflowAnalyticsService struct {
+	repo         repository.WorkflowAnalyticsRepository
+	workflowRepo repository.WorkflowRepository
+}
+
+func NewWorkflowAnalyticsService(repo repository.WorkflowAnalyticsRepository, workflowRepo repository.WorkflowRepository) WorkflowAnalyticsService {
+	return &workflowAnalyticsService{
+		repo:         repo,
+		workflowRepo: workflowRepo,
+	}
+}
+
+func (s *workflowAnalyticsService) GetDwellTimes(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.StateDwellTime, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetDwellTimes(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []models.StateDwellTime{}
+	}
+	return rows, nil
+}
+
+func (s *workflowAnalyticsService) GetTransitionFrequencies(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.TransitionFrequency, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetTransitionFrequencies(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []models.TransitionFrequency{}
+	}
+	return rows, nil
+}
+
+func (s *workflowAnalyticsService) GetRework(ctx context.Context, filter *models.WorkflowAnalyticsFilter) (*models.WorkflowReworkResponse, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	states, err := s.repo.GetReworkStates(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	transitions, err := s.repo.GetReworkTransitions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.WorkflowReworkResponse{
+		States:      states,
+		Transitions: transitions,
+	}
+	if resp.States == nil {
+		resp.States = []models.ReworkState{}
+	}
+	if resp.Transitions == nil {
+		resp.Transitions = []models.ReworkTransition{}
+	}
+	return resp, nil
+}
+
+func (s *workflowAnalyticsService) GetUserThroughput(ctx context.Context, filter *models.WorkflowAnalyticsFilter) ([]models.UserThroughput, error) {
+	if err := s.checkFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+	rows, err := s.repo.GetUserThroughput(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if rows == nil {
+		rows = []models.UserThroughput{}
+	}
+	return rows, nil
+}
+
+// checkFilter makes sure the workflow exists and the date range is in order
+func (s *workflowAnalyticsService) checkFilter(ctx context.Context, filter *models.WorkflowAnalyticsFilter) error {
+	if _, err := s.workflowRepo.FindByID(ctx, filter.WorkflowID); err != nil {
+		return ErrAnalyticsWorkflowNotFound
+	}
+	if filter.StartDate != nil && filter.EndDate != nil && filter.EndDate.Before(*filter.StartDate) {
+		return ErrAnalyticsInvalidPeriod
+	}
+	return nil
+}
@@ -0,0 +1,426 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkflowExportVersion is the bundle format written by ExportWorkflow. Import also
+// accepts 1.0 bundles, which have no manifest and keep raw IDs in action configs.
+const WorkflowExportVersion = "2.0"
+
+// classificationPathSeparator joins classification names into a portable path
+const classificationPathSeparator = " / "
+
+// fieldUpdateRefs maps the field_update targets that hold record IDs to their dependency kind
+var fieldUpdateRefs = map[string]string{
+	"assignee_id":       models.BundleDependencyUser,
+	"department_id":     models.BundleDependencyDepartment,
+	"location_id":       models.BundleDependencyLocation,
+	"classification_id": models.BundleDependencyClassification,
+}
+
+// linkedRecordRefs maps the create_linked_record keys that hold record IDs to their dependency kind
+var linkedRecordRefs = map[string]string{
+	"workflow_id":       models.BundleDependencyWorkflow,
+	"classification_id": models.BundleDependencyClassification,
+	"assignee_id":       models.BundleDependencyUser,
+	"department_id":     models.BundleDependencyDepartment,
+}
+
+// recipientRefs are the notification/email recipient prefixes followed by a record ID
+var recipientRefs = []string{models.BundleDependencyUser, models.BundleDependencyDepartment}
+
+// rewriteActionConfigRefs passes every record reference in an action config through rewrite
+// and returns the updated config. Keys it doesn't know about are kept as they are.
+func rewriteActionConfigRefs(actionType, config string, rewrite func(kind, value string) (string, error)) (string, error) {
+	if config == "" {
+		return config, nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(config), &data); err != nil {
+		// Left untouched; the action's own validation reports broken configs
+		return config, nil
+	}
+
+	changed := false
+	replace := func(key, kind string) error {
+		value, ok := data[key].(string)
+		if !ok || value == "" {
+			return nil
+		}
+		rewritten, err := rewrite(kind, value)
+		if err != nil {
+			return err
+		}
+		if rewritten != value {
+			data[key] = rewritten
+			changed = true
+		}
+		return nil
+	}
+
+	switch actionType {
+	case "notification", "email":
+		recipients, _ := data["recipients"].([]interface{})
+		for i, r := range recipients {
+			recipient, ok := r.(string)
+			if !ok {
+				continue
+			}
+			for _, kind := range recipientRefs {
+				if !strings.HasPrefix(recipient, kind+":") {
+					continue
+				}
+				value := strings.TrimPrefix(recipient, kind+":")
+				rewritten, err := rewrite(kind, value)
+				if err != nil {
+					return "", err
+				}
+				if rewritten != value {
+					recipients[i] = kind + ":" + rewritten
+					changed = true
+				}
+			}
+		}
+	case "field_update":
+		field, _ := data["field"].(string)
+		if kind, ok := fieldUpdateRefs[field]; ok {
+			if err := replace("value", kind); err != nil {
+				return "", err
+			}
+		}
+	case "create_linked_record":
+		for key, kind := range linkedRecordRefs {
+			if err := replace(key, kind); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if !changed {
+		return config, nil
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// bundleDependencies collects the distinct records a bundle refers to, in order of first use
+type bundleDependencies struct {
+	seen map[string]bool
+	list []models.WorkflowBundleDependency
+}
+
+func (d *bundleDependencies) add(kind, code, name string) {
+	if code == "" {
+		return
+	}
+	key := kind + ":" + code
+	if d.seen[key] {
+		return
+	}
+	d.seen[key] = true
+	d.list = append(d.list, models.WorkflowBundleDependency{Kind: kind, Code: code, Name: name})
+}
+
+func (d *bundleDependencies) addPair(kind string, pair *models.CodeNamePair) {
+	if pair != nil {
+		d.add(kind, pair.Code, pair.Name)
+	}
+}
+
+// collectBundleDependencies lists the records a bundle needs in the target environment.
+// References to the bundle's own workflow and raw IDs left in 1.0 bundles are not dependencies.
+func collectBundleDependencies(content *models.WorkflowExportContent) []models.WorkflowBundleDependency {
+	deps := &bundleDependencies{seen: make(map[string]bool)}
+
+	for i := range content.Classifications {
+		deps.addPair(models.BundleDependencyClassification, &content.Classifications[i])
+	}
+	for i := range content.ConvertToRequestRoles {
+		deps.addPair(models.BundleDependencyRole, &content.ConvertToRequestRoles[i])
+	}
+	for _, state := range content.States {
+		for i := range state.ViewableRoles {
+			deps.addPair(models.BundleDependencyRole, &state.ViewableRoles[i])
+		}
+		if state.SubWorkflow != nil && state.SubWorkflow.Code != content.Code {
+			deps.addPair(models.BundleDependencyWorkflow, state.SubWorkflow)
+		}
+	}
+	for _, trans := range content.Transitions {
+		for i := range trans.AllowedRoles {
+			deps.addPair(models.BundleDependencyRole, &trans.AllowedRoles[i])
+		}
+		deps.addPair(models.BundleDependencyDepartment, trans.AssignDepartment)
+		deps.addPair(models.BundleDependencyUser, trans.AssignUser)
+		deps.addPair(models.BundleDependencyRole, trans.AssignmentRole)
+		for i := range trans.Approvers {
+			deps.addPair(models.BundleDependencyUser, &trans.Approvers[i])
+		}
+		for _, action := range trans.Actions {
+			rewriteActionConfigRefs(action.ActionType, action.Config, func(kind, value string) (string, error) {
+				if _, err := uuid.Parse(value); err == nil {
+					return value, nil
+				}
+				if kind == models.BundleDependencyWorkflow && value == content.Code {
+					return value, nil
+				}
+				deps.add(kind, value, "")
+				return value, nil
+			})
+		}
+	}
+
+	return deps.list
+}
+
+// classificationNamePaths builds the name path of every classification, e.g. "IT / Network"
+func (s *workflowService) classificationNamePaths(ctx context.Context) (map[uuid.UUID]string, error) {
+	classifications, err := s.classRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]*models.Classification, len(classifications))
+	for i := range classifications {
+		byID[classifications[i].ID] = &classifications[i]
+	}
+
+	paths := make(map[uuid.UUID]string, len(classifications))
+	var pathOf func(c *models.Classification, depth int) string
+	pathOf = func(c *models.Classification, depth int) string {
+		if path, ok := paths[c.ID]; ok {
+			return path
+		}
+		path := c.Name
+		// The depth guard stops a corrupted parent cycle from recursing forever
+		if c.ParentID != nil && depth < len(classifications) {
+			if parent, ok := byID[*c.ParentID]; ok {
+				path = pathOf(parent, depth+1) + classificationPathSeparator + c.Name
+			}
+		}
+		paths[c.ID] = path
+		return path
+	}
+	for i := range classifications {
+		pathOf(&classifications[i], 0)
+	}
+	return paths, nil
+}
+
+// bundleExporter turns the record IDs in action configs into portable codes
+type bundleExporter struct {
+	s         *workflowService
+	ctx       context.Context
+	classPath map[uuid.UUID]string
+}
+
+// ref returns the portable code of a record, or the ID unchanged when the record
+// has no code or no longer exists
+func (e *bundleExporter) ref(kind, value string) (string, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return value, nil
+	}
+
+	db := e.s.db.WithContext(e.ctx)
+	var code string
+	switch kind {
+	case models.BundleDependencyUser:
+		var user models.User
+		if err = db.Select("email").First(&user, "id = ?", id).Error; err == nil {
+			code = user.Email
+		}
+	case models.BundleDependencyDepartment:
+		var dept models.Department
+		if err = db.Select("code").First(&dept, "id = ?", id).Error; err == nil {
+			code = dept.Code
+		}
+	case models.BundleDependencyLocation:
+		var location models.Location
+		if err = db.Select("code").First(&location, "id = ?", id).Error; err == nil {
+			code = location.Code
+		}
+	case models.BundleDependencyWorkflow:
+		var workflow models.Workflow
+		if err = db.Select("code").First(&workflow, "id = ?", id).Error; err == nil {
+			code = workflow.Code
+		}
+	case models.BundleDependencyClassification:
+		if e.classPath == nil {
+			if e.classPath, err = e.s.classificationNamePaths(e.ctx); err != nil {
+				return "", err
+			}
+		}
+		code = e.classPath[id]
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if code == "" {
+		return value, nil
+	}
+	return code, nil
+}
+
+// bundleResolver finds the records a bundle refers to by code. Lookups are cached, so each
+// dependency is queried once however often the bundle uses it.
+type bundleResolver struct {
+	s     *workflowService
+	ctx   context.Context
+	cache map[string]*uuid.UUID
+
+	classByPath map[string]uuid.UUID
+	classByName map[string][]uuid.UUID
+
+	// selfCode is the bundle's own workflow code; references to it resolve to selfID,
+	// the workflow being imported
+	selfCode string
+	selfID   uuid.UUID
+}
+
+func newBundleResolver(ctx context.Context, s *workflowService, selfCode string) *bundleResolver {
+	return &bundleResolver{
+		s:        s,
+		ctx:      ctx,
+		cache:    make(map[string]*uuid.UUID),
+		selfCode: selfCode,
+	}
+}
+
+// resolve returns the ID of the record with the given code
+func (r *bundleResolver) resolve(kind, code string) (uuid.UUID, bool, error) {
+	if kind == models.BundleDependencyWorkflow && code == r.selfCode {
+		return r.selfID, true, nil
+	}
+
+	key := kind + ":" + code
+	if id, ok := r.cache[key]; ok {
+		if id == nil {
+			return uuid.Nil, false, nil
+		}
+		return *id, true, nil
+	}
+
+	id, err := r.lookup(kind, code)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, false, err
+		}
+		r.cache[key] = nil
+		return uuid.Nil, false, nil
+	}
+	r.cache[key] = &id
+	return id, true, nil
+}
+
+func (r *bundleResolver) lookup(kind, code string) (uuid.UUID, error) {
+	db := r.s.db.WithContext(r.ctx)
+	switch kind {
+	case models.BundleDependencyRole:
+		role, err := r.s.roleRepo.FindByCode(r.ctx, code)
+		if err != nil {
+			return uuid.Nil, gorm.ErrRecordNotFound
+		}
+		return role.ID, nil
+	case models.BundleDependencyDepartment:
+		dept, err := r.s.deptRepo.FindByCode(r.ctx, code)
+		if err != nil {
+			return uuid.Nil, gorm.ErrRecordNotFound
+		}
+		return dept.ID, nil
+	case models.BundleDependencyUser:
+		var user models.User
+		err := db.Select("id").Where("email = ?", code).First(&user).Error
+		return user.ID, err
+	case models.BundleDependencyLocation:
+		var location models.Location
+		err := db.Select("id").Where("code = ?", code).First(&location).Error
+		return location.ID, err
+	case models.BundleDependencyWorkflow:
+		workflow, err := r.s.repo.FindByCode(r.ctx, code)
+		if err != nil {
+			return uuid.Nil, gorm.ErrRecordNotFound
+		}
+		return workflow.ID, nil
+	case models.BundleDependencyClassification:
+		return r.lookupClassification(code)
+	}
+	return uuid.Nil, gorm.ErrRecordNotFound
+}
+
+// lookupClassification matches a name path. A bare name, as written by 1.0 bundles,
+// also matches a nested classification as long as the name is unique.
+func (r *bundleResolver) lookupClassification(path string) (uuid.UUID, error) {
+	if r.classByPath == nil {
+		paths, err := r.s.classificationNamePaths(r.ctx)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		r.classByPath = make(map[string]uuid.UUID, len(paths))
+		r.classByName = make(map[string][]uuid.UUID)
+		for id, p := range paths {
+			r.classByPath[p] = id
+			name := p
+			if i := strings.LastIndex(p, classificationPathSeparator); i >= 0 {
+				name = p[i+len(classificationPathSeparator):]
+			}
+			r.classByName[name] = append(r.classByName[name], id)
+		}
+	}
+
+	if id, ok := r.classByPath[path]; ok {
+		return id, nil
+	}
+	if ids := r.classByName[path]; len(ids) == 1 {
+		return ids[0], nil
+	}
+	return uuid.Nil, gorm.ErrRecordNotFound
+}
+
+// missing returns the dependencies that can't be found in this environment
+func (r *bundleResolver) missing(deps []models.WorkflowBundleDependency) ([]models.WorkflowBundleDependency, error) {
+	missing := []models.WorkflowBundleDependency{}
+	for _, dep := range deps {
+		_, found, err := r.resolve(dep.Kind, dep.Code)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			missing = append(missing, dep)
+		}
+	}
+	return missing, nil
+}
+
+// resolveActionConfig replaces the codes in an action config with record IDs. It returns
+// the references that couldn't be resolved; raw IDs are kept as they are.
+func (r *bundleResolver) resolveActionConfig(actionType, config string) (string, []string, error) {
+	var unresolved []string
+	resolved, err := rewriteActionConfigRefs(actionType, config, func(kind, value string) (string, error) {
+		if _, err := uuid.Parse(value); err == nil {
+			return value, nil
+		}
+		id, found, err := r.resolve(kind, value)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			unresolved = append(unresolved, kind+" '"+value+"'")
+			return value, nil
+		}
+		return id.String(), nil
+	})
+	return resolved, unresolved, err
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/automax/backend/internal/models"
@@ -46,6 +47,7 @@ type WorkflowService interface {
 	SetTransitionRoles(ctx context.Context, transitionID uuid.UUID, roleIDs []uuid.UUID) error
 	SetTransitionRequirements(ctx context.Context, transitionID uuid.UUID, requirements []models.TransitionRequirementRequest) error
 	SetTransitionActions(ctx context.Context, transitionID uuid.UUID, actions []models.TransitionActionRequest) error
+	SetTransitionApproval(ctx context.Context, transitionID uuid.UUID, req *models.TransitionApprovalRequest) (*models.WorkflowTransitionResponse, error)
 
 	// Get transitions from a state (for incident transition UI)
 	GetTransitionsFromState(ctx context.Context, stateID uuid.UUID) ([]models.WorkflowTransitionResponse, error)
@@ -56,7 +58,17 @@ type WorkflowService interface {
 
 	// Import/Export
 	ExportWorkflow(ctx context.Context, id uuid.UUID) ([]byte, string, error)
-	ImportWorkflow(ctx context.Context, data *models.WorkflowImportData, createdByID uuid.UUID) (*models.WorkflowResponse, []string, error)
+	ImportWorkflow(ctx context.Context, data *models.WorkflowImportData, createdByID uuid.UUID, opts models.WorkflowImportOptions) (*models.WorkflowImportResponse, error)
+
+	// Graph validation
+	ValidateWorkflow(ctx context.Context, id uuid.UUID) (*models.WorkflowValidationResult, error)
+
+	// Versioning
+	PublishWorkflow(ctx context.Context, id uuid.UUID, req *models.WorkflowPublishRequest, publishedByID uuid.UUID) (*models.WorkflowVersionResponse, error)
+	ListVersions(ctx context.Context, id uuid.UUID) ([]models.WorkflowVersionResponse, error)
+	GetVersion(ctx context.Context, id uuid.UUID, version int) (*models.WorkflowVersionDetailResponse, error)
+	DiffVersions(ctx context.Context, id uuid.UUID, fromVersion, toVersion int) (*models.WorkflowVersionDiff, error)
+	MigrateIncidents(ctx context.Context, id uuid.UUID, req *models.WorkflowMigrateRequest) (*models.WorkflowMigrateResponse, error)
 }
 
 type workflowService struct {
@@ -64,16 +76,18 @@ type workflowService struct {
 	roleRepo   repository.RoleRepository
 	deptRepo   repository.DepartmentRepository
 	classRepo  repository.ClassificationRepository
+	lookupRepo repository.LookupRepository
 	db         *gorm.DB
 }
 
-func NewWorkflowService(repo repository.WorkflowRepository, roleRepo repository.RoleRepository, deptRepo repository.DepartmentRepository, classRepo repository.ClassificationRepository, db *gorm.DB) WorkflowService {
+func NewWorkflowService(repo repository.WorkflowRepository, roleRepo repository.RoleRepository, deptRepo repository.DepartmentRepository, classRepo repository.ClassificationRepository, lookupRepo repository.LookupRepository, db *gorm.DB) WorkflowService {
 	return &workflowService{
-		repo:      repo,
-		roleRepo:  roleRepo,
-		deptRepo:  deptRepo,
-		classRepo: classRepo,
-		db:        db,
+		repo:       repo,
+		roleRepo:   roleRepo,
+		deptRepo:   deptRepo,
+		classRepo:  classRepo,
+		lookupRepo: lookupRepo,
+		db:         db,
 	}
 }
 
@@ -101,7 +115,7 @@ func (s *workflowService) CreateWorkflow(ctx context.Context, req *models.Workfl
 		RecordType:     recordType,
 		RequiredFields: requiredFieldsJSON,
 		CreatedByID:    &createdByID,
-		IsActive:       true,
+		IsActive:       false, // Activated through UpdateWorkflow once the graph validates
 		Version:        1,
 	}
 
@@ -188,6 +202,16 @@ func (s *workflowService) UpdateWorkflow(ctx context.Context, id uuid.UUID, req
 		workflow.Description = req.Description
 	}
 	if req.IsActive != nil {
+		// Activating a workflow requires a valid graph
+		if *req.IsActive && !workflow.IsActive {
+			result, err := s.ValidateWorkflow(ctx, workflow.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !result.Valid {
+				return nil, &WorkflowValidationError{Result: result}
+			}
+		}
 		workflow.IsActive = *req.IsActive
 	}
 	if req.IsDefault != nil {
@@ -315,6 +339,9 @@ func (s *workflowService) DuplicateWorkflow(ctx context.Context, id uuid.UUID, c
 			SLAHours:    state.SLAHours,
 			SortOrder:   state.SortOrder,
 			IsActive:    state.IsActive,
+
+			SubWorkflowID: state.SubWorkflowID,
+			SubRecordType: state.SubRecordType,
 		}
 		if err := s.repo.CreateState(ctx, newState); err != nil {
 			return nil, err
@@ -342,11 +369,31 @@ func (s *workflowService) DuplicateWorkflow(ctx context.Context, id uuid.UUID, c
 			ToStateID:   newToStateID,
 			SortOrder:   trans.SortOrder,
 			IsActive:    trans.IsActive,
+
+			AutoTrigger:    trans.AutoTrigger,
+			AutoAfterHours: trans.AutoAfterHours,
+			ChildStateCode: trans.ChildStateCode,
 		}
 		if err := s.repo.CreateTransition(ctx, newTrans); err != nil {
 			return nil, err
 		}
 
+		// Copy approval settings
+		if trans.ApprovalMode != "" {
+			newTrans.ApprovalMode = trans.ApprovalMode
+			newTrans.ApprovalQuorum = trans.ApprovalQuorum
+			if trans.RejectStateID != nil {
+				if rejectStateID, ok := stateIDMap[*trans.RejectStateID]; ok {
+					newTrans.RejectStateID = &rejectStateID
+				}
+			}
+			approverIDs := make([]uuid.UUID, len(trans.Approvers))
+			for i, approver := range trans.Approvers {
+				approverIDs[i] = approver.ID
+			}
+			s.repo.SetTransitionApproval(ctx, newTrans, approverIDs)
+		}
+
 		// Copy role assignments
 		if len(trans.AllowedRoles) > 0 {
 			roleIDs := make([]uuid.UUID, len(trans.AllowedRoles))
@@ -363,7 +410,9 @@ func (s *workflowService) DuplicateWorkflow(ctx context.Context, id uuid.UUID, c
 				newReqs[i] = models.TransitionRequirement{
 					RequirementType: req.RequirementType,
 					FieldName:       req.FieldName,
+					Operator:        req.Operator,
 					FieldValue:      req.FieldValue,
+					MinCount:        req.MinCount,
 					IsMandatory:     req.IsMandatory,
 					ErrorMessage:    req.ErrorMessage,
 				}
@@ -380,6 +429,7 @@ func (s *workflowService) DuplicateWorkflow(ctx context.Context, id uuid.UUID, c
 					Name:           action.Name,
 					Description:    action.Description,
 					Config:         action.Config,
+					Condition:      action.Condition,
 					ExecutionOrder: action.ExecutionOrder,
 					IsAsync:        action.IsAsync,
 					IsActive:       action.IsActive,
@@ -433,6 +483,11 @@ func (s *workflowService) CreateState(ctx context.Context, workflowID uuid.UUID,
 		SLAHours:    req.SLAHours,
 		SortOrder:   req.SortOrder,
 		IsActive:    true,
+
+		SubRecordType: req.SubRecordType,
+	}
+	if req.SubWorkflowID != nil {
+		state.SubWorkflowID = parseOptionalUUID(*req.SubWorkflowID)
 	}
 
 	if state.StateType == "" {
@@ -442,23 +497,29 @@ func (s *workflowService) CreateState(ctx context.Context, workflowID uuid.UUID,
 		state.Color = "#6366f1"
 	}
 
-	if err := s.repo.CreateState(ctx, state); err != nil {
-		return nil, err
-	}
+	err := s.editWorkflowGraph(ctx, workflowID, func(repo repository.WorkflowRepository) error {
+		if err := repo.CreateState(ctx, state); err != nil {
+			return err
+		}
 
-	// Assign viewable roles if provided
-	if len(req.ViewableRoleIDs) > 0 {
-		roleIDs := make([]uuid.UUID, 0, len(req.ViewableRoleIDs))
-		for _, idStr := range req.ViewableRoleIDs {
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				continue
+		// Assign viewable roles if provided
+		if len(req.ViewableRoleIDs) > 0 {
+			roleIDs := make([]uuid.UUID, 0, len(req.ViewableRoleIDs))
+			for _, idStr := range req.ViewableRoleIDs {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					continue
+				}
+				roleIDs = append(roleIDs, id)
+			}
+			if err := repo.AssignStateViewableRoles(ctx, state.ID, roleIDs); err != nil {
+				return err
 			}
-			roleIDs = append(roleIDs, id)
-		}
-		if err := s.repo.AssignStateViewableRoles(ctx, state.ID, roleIDs); err != nil {
-			return nil, err
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Fetch the state with relations
@@ -521,24 +582,36 @@ func (s *workflowService) UpdateState(ctx context.Context, stateID uuid.UUID, re
 	if req.IsActive != nil {
 		state.IsActive = *req.IsActive
 	}
-
-	if err := s.repo.UpdateState(ctx, state); err != nil {
-		return nil, err
+	if req.SubWorkflowID != nil {
+		state.SubWorkflowID = parseOptionalUUID(*req.SubWorkflowID)
+	}
+	if req.SubRecordType != nil {
+		state.SubRecordType = *req.SubRecordType
 	}
 
-	// Update viewable roles if provided
-	if req.ViewableRoleIDs != nil {
-		roleIDs := make([]uuid.UUID, 0, len(req.ViewableRoleIDs))
-		for _, idStr := range req.ViewableRoleIDs {
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				continue
-			}
-			roleIDs = append(roleIDs, id)
+	err = s.editWorkflowGraph(ctx, state.WorkflowID, func(repo repository.WorkflowRepository) error {
+		if err := repo.UpdateState(ctx, state); err != nil {
+			return err
 		}
-		if err := s.repo.AssignStateViewableRoles(ctx, stateID, roleIDs); err != nil {
-			return nil, err
+
+		// Update viewable roles if provided
+		if req.ViewableRoleIDs != nil {
+			roleIDs := make([]uuid.UUID, 0, len(req.ViewableRoleIDs))
+			for _, idStr := range req.ViewableRoleIDs {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					continue
+				}
+				roleIDs = append(roleIDs, id)
+			}
+			if err := repo.AssignStateViewableRoles(ctx, stateID, roleIDs); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Fetch the state with relations
@@ -552,7 +625,24 @@ func (s *workflowService) UpdateState(ctx context.Context, stateID uuid.UUID, re
 }
 
 func (s *workflowService) DeleteState(ctx context.Context, stateID uuid.UUID) error {
-	return s.repo.DeleteState(ctx, stateID)
+	state, err := s.repo.FindStateByID(ctx, stateID)
+	if err != nil {
+		return err
+	}
+	err = checkPinnedVersions(ctx, s.repo, state.WorkflowID, fmt.Sprintf("state '%s'", state.Name), func(snapshot *models.Workflow) bool {
+		for _, st := range snapshot.States {
+			if st.ID == stateID {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	return s.editWorkflowGraph(ctx, state.WorkflowID, func(repo repository.WorkflowRepository) error {
+		return repo.DeleteState(ctx, stateID)
+	})
 }
 
 // Transition management
@@ -580,6 +670,9 @@ func (s *workflowService) CreateTransition(ctx context.Context, workflowID uuid.
 		AutoDetectDepartment: req.AutoDetectDepartment,
 		AutoMatchUser:        req.AutoMatchUser,
 		ManualSelectUser:     req.ManualSelectUser,
+		AutoTrigger:          req.AutoTrigger,
+		AutoAfterHours:       req.AutoAfterHours,
+		ChildStateCode:       req.ChildStateCode,
 	}
 
 	// Department Assignment
@@ -605,23 +698,29 @@ func (s *workflowService) CreateTransition(ctx context.Context, workflowID uuid.
 		}
 	}
 
-	if err := s.repo.CreateTransition(ctx, transition); err != nil {
-		return nil, err
-	}
+	err = s.editWorkflowGraph(ctx, workflowID, func(repo repository.WorkflowRepository) error {
+		if err := repo.CreateTransition(ctx, transition); err != nil {
+			return err
+		}
 
-	// Assign roles if provided
-	if len(req.RoleIDs) > 0 {
-		roleIDs := make([]uuid.UUID, 0, len(req.RoleIDs))
-		for _, idStr := range req.RoleIDs {
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				continue
+		// Assign roles if provided
+		if len(req.RoleIDs) > 0 {
+			roleIDs := make([]uuid.UUID, 0, len(req.RoleIDs))
+			for _, idStr := range req.RoleIDs {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					continue
+				}
+				roleIDs = append(roleIDs, id)
+			}
+			if err := repo.AssignTransitionRoles(ctx, transition.ID, roleIDs); err != nil {
+				return err
 			}
-			roleIDs = append(roleIDs, id)
-		}
-		if err := s.repo.AssignTransitionRoles(ctx, transition.ID, roleIDs); err != nil {
-			return nil, err
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	created, err := s.repo.FindTransitionByIDWithRelations(ctx, transition.ID)
@@ -703,6 +802,15 @@ func (s *workflowService) UpdateTransition(ctx context.Context, transitionID uui
 	if req.ManualSelectUser != nil {
 		transition.ManualSelectUser = *req.ManualSelectUser
 	}
+	if req.AutoTrigger != nil {
+		transition.AutoTrigger = *req.AutoTrigger
+	}
+	if req.AutoAfterHours != nil {
+		transition.AutoAfterHours = *req.AutoAfterHours
+	}
+	if req.ChildStateCode != nil {
+		transition.ChildStateCode = *req.ChildStateCode
+	}
 	if req.AssignUserID != nil {
 		if *req.AssignUserID == "" {
 			transition.AssignUserID = nil
@@ -724,23 +832,29 @@ func (s *workflowService) UpdateTransition(ctx context.Context, transitionID uui
 		}
 	}
 
-	if err := s.repo.UpdateTransition(ctx, transition); err != nil {
-		return nil, err
-	}
+	err = s.editWorkflowGraph(ctx, transition.WorkflowID, func(repo repository.WorkflowRepository) error {
+		if err := repo.UpdateTransition(ctx, transition); err != nil {
+			return err
+		}
 
-	// Update roles if provided
-	if req.RoleIDs != nil {
-		roleIDs := make([]uuid.UUID, 0, len(req.RoleIDs))
-		for _, idStr := range req.RoleIDs {
-			id, err := uuid.Parse(idStr)
-			if err != nil {
-				continue
+		// Update roles if provided
+		if req.RoleIDs != nil {
+			roleIDs := make([]uuid.UUID, 0, len(req.RoleIDs))
+			for _, idStr := range req.RoleIDs {
+				id, err := uuid.Parse(idStr)
+				if err != nil {
+					continue
+				}
+				roleIDs = append(roleIDs, id)
+			}
+			if err := repo.AssignTransitionRoles(ctx, transitionID, roleIDs); err != nil {
+				return err
 			}
-			roleIDs = append(roleIDs, id)
-		}
-		if err := s.repo.AssignTransitionRoles(ctx, transitionID, roleIDs); err != nil {
-			return nil, err
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	updated, err := s.repo.FindTransitionByIDWithRelations(ctx, transitionID)
@@ -753,7 +867,24 @@ func (s *workflowService) UpdateTransition(ctx context.Context, transitionID uui
 }
 
 func (s *workflowService) DeleteTransition(ctx context.Context, transitionID uuid.UUID) error {
-	return s.repo.DeleteTransition(ctx, transitionID)
+	transition, err := s.repo.FindTransitionByID(ctx, transitionID)
+	if err != nil {
+		return err
+	}
+	err = checkPinnedVersions(ctx, s.repo, transition.WorkflowID, fmt.Sprintf("transition '%s'", transition.Name), func(snapshot *models.Workflow) bool {
+		for _, t := range snapshot.Transitions {
+			if t.ID == transitionID {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return err
+	}
+	return s.editWorkflowGraph(ctx, transition.WorkflowID, func(repo repository.WorkflowRepository) error {
+		return repo.DeleteTransition(ctx, transitionID)
+	})
 }
 
 // Transition configuration
@@ -768,15 +899,25 @@ func (s *workflowService) SetTransitionRequirements(ctx context.Context, transit
 		requirements[i] = models.TransitionRequirement{
 			RequirementType: req.RequirementType,
 			FieldName:       req.FieldName,
+			Operator:        req.Operator,
 			FieldValue:      req.FieldValue,
+			MinCount:        req.MinCount,
 			IsMandatory:     req.IsMandatory,
 			ErrorMessage:    req.ErrorMessage,
 		}
+		if err := ValidateTransitionRequirement(&requirements[i]); err != nil {
+			return err
+		}
 	}
 	return s.repo.SetTransitionRequirements(ctx, transitionID, requirements)
 }
 
 func (s *workflowService) SetTransitionActions(ctx context.Context, transitionID uuid.UUID, actionData []models.TransitionActionRequest) error {
+	existing, err := s.repo.GetTransitionActions(ctx, transitionID)
+	if err != nil {
+		return err
+	}
+
 	actions := make([]models.TransitionAction, len(actionData))
 	for i, action := range actionData {
 		actions[i] = models.TransitionAction{
@@ -784,14 +925,140 @@ func (s *workflowService) SetTransitionActions(ctx context.Context, transitionID
 			Name:           action.Name,
 			Description:    action.Description,
 			Config:         action.Config,
+			Condition:      action.Condition,
 			ExecutionOrder: action.ExecutionOrder,
 			IsAsync:        action.IsAsync,
 			IsActive:       action.IsActive,
 		}
+		if err := restoreWebhookSecret(&actions[i], existing); err != nil {
+			return err
+		}
+		if err := ValidateTransitionAction(&actions[i]); err != nil {
+			return err
+		}
+		unknown, err := s.unknownLookupCategories(ctx, actions[i].Condition)
+		if err != nil {
+			return err
+		}
+		if len(unknown) > 0 {
+			return &ActionValidationError{Action: actions[i].Name, Field: "condition", Err: fmt.Errorf("unknown lookup category %s", strings.Join(unknown, ", "))}
+		}
 	}
 	return s.repo.SetTransitionActions(ctx, transitionID, actions)
 }
 
+// unknownLookupCategories lists the lookup categories an action condition reads that don't
+// exist. A misspelled category would otherwise never match and silently skip the action.
+func (s *workflowService) unknownLookupCategories(ctx context.Context, condition string) ([]string, error) {
+	cond, err := ParseActionCondition(condition)
+	if err != nil || cond == nil {
+		return nil, nil
+	}
+	codes := cond.LookupCategories()
+	if len(codes) == 0 {
+		return nil, nil
+	}
+	categories, err := s.lookupRepo.ListCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Conditions match category codes case-insensitively
+	var unknown []string
+	for _, code := range codes {
+		found := false
+		for _, category := range categories {
+			if strings.EqualFold(category.Code, code) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, "'"+code+"'")
+		}
+	}
+	return unknown, nil
+}
+
+// ApprovalConfigError reports an invalid approval configuration on a transition
+type ApprovalConfigError struct {
+	Message string
+}
+
+func (e *ApprovalConfigError) Error() string {
+	return e.Message
+}
+
+// SetTransitionApproval configures multi-signoff on a transition. An empty mode turns it off.
+func (s *workflowService) SetTransitionApproval(ctx context.Context, transitionID uuid.UUID, req *models.TransitionApprovalRequest) (*models.WorkflowTransitionResponse, error) {
+	transition, err := s.repo.FindTransitionByIDWithRelations(ctx, transitionID)
+	if err != nil {
+		return nil, errors.New("transition not found")
+	}
+
+	approverIDs := make([]uuid.UUID, 0, len(req.ApproverIDs))
+	for _, idStr := range req.ApproverIDs {
+		id, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, &ApprovalConfigError{Message: fmt.Sprintf("invalid approver id: %s", idStr)}
+		}
+		approverIDs = append(approverIDs, id)
+	}
+
+	var rejectStateID *uuid.UUID
+	if req.RejectStateID != nil && *req.RejectStateID != "" {
+		id, err := uuid.Parse(*req.RejectStateID)
+		if err != nil {
+			return nil, &ApprovalConfigError{Message: "invalid reject_state_id"}
+		}
+		state, err := s.repo.FindStateByID(ctx, id)
+		if err != nil || state.WorkflowID != transition.WorkflowID {
+			return nil, &ApprovalConfigError{Message: "reject state must belong to the transition's workflow"}
+		}
+		rejectStateID = &id
+	}
+
+	switch req.ApprovalMode {
+	case "":
+		// Approvals disabled, clear the rest of the configuration
+		approverIDs = nil
+		rejectStateID = nil
+		req.ApprovalQuorum = 0
+	case models.ApprovalModeAnyN:
+		if req.ApprovalQuorum < 1 {
+			return nil, &ApprovalConfigError{Message: "approval_quorum must be at least 1"}
+		}
+		if len(approverIDs) > 0 && req.ApprovalQuorum > len(approverIDs) {
+			return nil, &ApprovalConfigError{Message: fmt.Sprintf("approval_quorum %d exceeds the %d listed approvers", req.ApprovalQuorum, len(approverIDs))}
+		}
+	case models.ApprovalModeAll:
+		if len(approverIDs) == 0 {
+			return nil, &ApprovalConfigError{Message: "approval mode 'all' requires approvers"}
+		}
+	case models.ApprovalModeOnePerRole:
+		if len(transition.AllowedRoles) == 0 {
+			return nil, &ApprovalConfigError{Message: "approval mode 'one_per_role' requires allowed roles on the transition"}
+		}
+	default:
+		return nil, &ApprovalConfigError{Message: fmt.Sprintf("unknown approval mode: %s", req.ApprovalMode)}
+	}
+
+	transition.ApprovalMode = req.ApprovalMode
+	transition.ApprovalQuorum = req.ApprovalQuorum
+	transition.RejectStateID = rejectStateID
+	if err := s.repo.SetTransitionApproval(ctx, transition, approverIDs); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.FindTransitionByIDWithRelations(ctx, transitionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := models.ToWorkflowTransitionResponse(updated)
+	return &resp, nil
+}
+
 // Get transitions from a state (for incident transition UI)
 
 func (s *workflowService) GetTransitionsFromState(ctx context.Context, stateID uuid.UUID) ([]models.WorkflowTransitionResponse, error) {
@@ -980,6 +1247,9 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 		requiredFields = []string{}
 	}
 
+	// Record IDs inside action configs are written as codes so the bundle is portable
+	exporter := &bundleExporter{s: s, ctx: ctx}
+
 	// Build state code to ID mapping for transitions
 	stateCodeMap := make(map[uuid.UUID]string)
 	exportStates := make([]models.WorkflowStateExport, len(workflow.States))
@@ -995,6 +1265,14 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 			}
 		}
 
+		// Sub-workflows are referenced by workflow code
+		var subWorkflow *models.CodeNamePair
+		if state.SubWorkflowID != nil {
+			if sub, err := s.repo.FindByID(ctx, *state.SubWorkflowID); err == nil {
+				subWorkflow = &models.CodeNamePair{Code: sub.Code, Name: sub.Name}
+			}
+		}
+
 		exportStates[i] = models.WorkflowStateExport{
 			Name:          state.Name,
 			Code:          state.Code,
@@ -1006,6 +1284,8 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 			SLAHours:      state.SLAHours,
 			SortOrder:     state.SortOrder,
 			ViewableRoles: viewableRoles,
+			SubWorkflow:   subWorkflow,
+			SubRecordType: state.SubRecordType,
 		}
 	}
 
@@ -1033,20 +1313,18 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 		// Convert assign user to code/name pair (use email as code)
 		var assignUser *models.CodeNamePair
 		if trans.AssignUser != nil {
-			fullName := trans.AssignUser.FirstName
-			if trans.AssignUser.LastName != "" {
-				if fullName != "" {
-					fullName += " "
-				}
-				fullName += trans.AssignUser.LastName
-			}
-			if fullName == "" {
-				fullName = trans.AssignUser.Username
-			}
-			assignUser = &models.CodeNamePair{
-				Code: trans.AssignUser.Email,
-				Name: fullName,
-			}
+			pair := userCodeNamePair(trans.AssignUser)
+			assignUser = &pair
+		}
+
+		// Convert approvers to code/name pairs
+		var approvers []models.CodeNamePair
+		for j := range trans.Approvers {
+			approvers = append(approvers, userCodeNamePair(&trans.Approvers[j]))
+		}
+		var rejectStateCode string
+		if trans.RejectStateID != nil {
+			rejectStateCode = stateCodeMap[*trans.RejectStateID]
 		}
 
 		// Convert assignment role to code/name pair
@@ -1064,7 +1342,9 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 			requirements[j] = models.TransitionRequirementExport{
 				RequirementType: req.RequirementType,
 				FieldName:       req.FieldName,
+				Operator:        req.Operator,
 				FieldValue:      req.FieldValue,
+				MinCount:        req.MinCount,
 				IsMandatory:     req.IsMandatory,
 				ErrorMessage:    req.ErrorMessage,
 			}
@@ -1073,11 +1353,16 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 		// Convert actions
 		actions := make([]models.TransitionActionExport, len(trans.Actions))
 		for j, action := range trans.Actions {
+			config, err := rewriteActionConfigRefs(action.ActionType, action.Config, exporter.ref)
+			if err != nil {
+				return nil, "", err
+			}
 			actions[j] = models.TransitionActionExport{
 				ActionType:     action.ActionType,
 				Name:           action.Name,
 				Description:    action.Description,
-				Config:         action.Config,
+				Config:         config,
+				Condition:      action.Condition,
 				ExecutionOrder: action.ExecutionOrder,
 				IsAsync:        action.IsAsync,
 				IsActive:       action.IsActive,
@@ -1097,17 +1382,32 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 			AssignmentRole:       assignmentRole,
 			AutoMatchUser:        trans.AutoMatchUser,
 			ManualSelectUser:     trans.ManualSelectUser,
+			ApprovalMode:         trans.ApprovalMode,
+			ApprovalQuorum:       trans.ApprovalQuorum,
+			Approvers:            approvers,
+			RejectStateCode:      rejectStateCode,
+			AutoTrigger:          trans.AutoTrigger,
+			AutoAfterHours:       trans.AutoAfterHours,
+			ChildStateCode:       trans.ChildStateCode,
 			Requirements:         requirements,
 			Actions:              actions,
 			SortOrder:            trans.SortOrder,
 		}
 	}
 
-	// Convert classifications to code/name pairs (use name as code since no code field)
+	// Convert classifications to code/name pairs (use the name path as code since no code field)
+	classPaths, err := s.classificationNamePaths(ctx)
+	if err != nil {
+		return nil, "", err
+	}
 	classifications := make([]models.CodeNamePair, len(workflow.Classifications))
 	for i, class := range workflow.Classifications {
+		path := classPaths[class.ID]
+		if path == "" {
+			path = class.Name
+		}
 		classifications[i] = models.CodeNamePair{
-			Code: class.Name,
+			Code: path,
 			Name: class.Name,
 		}
 	}
@@ -1123,7 +1423,7 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 
 	// Build export structure
 	exportData := models.WorkflowExportData{
-		ExportVersion: "1.0",
+		ExportVersion: WorkflowExportVersion,
 		ExportedAt:    time.Now().Format(time.RFC3339),
 		Workflow: models.WorkflowExportContent{
 			Name:                  workflow.Name,
@@ -1138,6 +1438,19 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 		},
 	}
 
+	// The manifest lists everything the target environment must already have
+	actionCount := 0
+	for _, trans := range exportTransitions {
+		actionCount += len(trans.Actions)
+	}
+	exportData.Manifest = &models.WorkflowBundleManifest{
+		Workflow:        models.CodeNamePair{Code: workflow.Code, Name: workflow.Name},
+		StateCount:      len(exportStates),
+		TransitionCount: len(exportTransitions),
+		ActionCount:     actionCount,
+		Dependencies:    collectBundleDependencies(&exportData.Workflow),
+	}
+
 	// Marshal to pretty-printed JSON
 	jsonBytes, err := json.MarshalIndent(exportData, "", "  ")
 	if err != nil {
@@ -1151,21 +1464,26 @@ func (s *workflowService) ExportWorkflow(ctx context.Context, id uuid.UUID) ([]b
 	return jsonBytes, filename, nil
 }
 
-// ImportWorkflow imports a workflow from JSON data
-func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.WorkflowImportData, createdByID uuid.UUID) (*models.WorkflowResponse, []string, error) {
+// ImportWorkflow imports a workflow bundle. Roles, departments, users and the other records
+// it depends on are resolved by code; missing ones are reported and left out of the workflow.
+// With UpdateExisting the workflow with the same code is updated in place, matching states and
+// transitions by code; dropping a state or transition that records pinned to a published
+// version still use fails with ErrWorkflowItemPinned. A dry run applies everything in a
+// transaction and rolls it back.
+func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.WorkflowImportData, createdByID uuid.UUID, opts models.WorkflowImportOptions) (*models.WorkflowImportResponse, error) {
 	warnings := []string{}
 
 	// Validate export version
-	if data.ExportVersion != "1.0" {
-		return nil, nil, fmt.Errorf("unsupported export version: %s", data.ExportVersion)
+	if data.ExportVersion != WorkflowExportVersion && data.ExportVersion != "1.0" {
+		return nil, fmt.Errorf("unsupported export version: %s", data.ExportVersion)
 	}
 
 	// Validate required fields
 	if data.Workflow.Name == "" || data.Workflow.Code == "" {
-		return nil, nil, errors.New("workflow name and code are required")
+		return nil, errors.New("workflow name and code are required")
 	}
 	if len(data.Workflow.States) == 0 {
-		return nil, nil, errors.New("workflow must have at least one state")
+		return nil, errors.New("workflow must have at least one state")
 	}
 
 	// Validate at least one initial state exists
@@ -1177,17 +1495,35 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 		}
 	}
 	if !hasInitialState {
-		return nil, nil, errors.New("workflow must have at least one initial state")
+		return nil, errors.New("workflow must have at least one initial state")
+	}
+
+	// Resolve every dependency up front so the caller sees exactly what is missing.
+	// The dependencies are cached, so the lookups below don't query again.
+	resolver := newBundleResolver(ctx, s, data.Workflow.Code)
+	missing, err := resolver.missing(collectBundleDependencies(&data.Workflow))
+	if err != nil {
+		return nil, err
+	}
+	lookup := func(kind, code string) (uuid.UUID, bool) {
+		id, found, _ := resolver.resolve(kind, code)
+		return id, found
 	}
 
 	// Check for duplicate workflow code
+	mode := models.WorkflowImportCreated
 	workflowCode := data.Workflow.Code
 	existing, _ := s.repo.FindByCode(ctx, workflowCode)
 	if existing != nil {
-		// Append timestamp to make it unique
-		timestamp := time.Now().Format("20060102_150405")
-		workflowCode = fmt.Sprintf("%s_imported_%s", workflowCode, timestamp)
-		warnings = append(warnings, fmt.Sprintf("Workflow code was modified to '%s' to avoid duplicate", workflowCode))
+		if opts.UpdateExisting {
+			mode = models.WorkflowImportUpdated
+		} else {
+			// Append timestamp to make it unique
+			timestamp := time.Now().Format("20060102_150405")
+			workflowCode = fmt.Sprintf("%s_imported_%s", workflowCode, timestamp)
+			warnings = append(warnings, fmt.Sprintf("Workflow code was modified to '%s' to avoid duplicate", workflowCode))
+			existing = nil
+		}
 	}
 
 	// Start transaction
@@ -1199,61 +1535,82 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 	}()
 
 	if tx.Error != nil {
-		return nil, nil, tx.Error
+		return nil, tx.Error
 	}
 
 	// Resolve classification codes to IDs
 	classificationIDs := []uuid.UUID{}
 	for _, class := range data.Workflow.Classifications {
-		var classification models.Classification
-		err := tx.Where("name = ?", class.Code).First(&classification).Error
-		if err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				warnings = append(warnings, fmt.Sprintf("Classification '%s' not found and will be skipped", class.Name))
-				continue
-			}
-			tx.Rollback()
-			return nil, nil, err
+		id, found := lookup(models.BundleDependencyClassification, class.Code)
+		if !found {
+			warnings = append(warnings, fmt.Sprintf("Classification '%s' not found and will be skipped", class.Code))
+			continue
 		}
-		classificationIDs = append(classificationIDs, classification.ID)
+		classificationIDs = append(classificationIDs, id)
 	}
 
 	// Resolve convert-to-request role codes to IDs
 	convertRoleIDs := []uuid.UUID{}
 	for _, role := range data.Workflow.ConvertToRequestRoles {
-		foundRole, err := s.roleRepo.FindByCode(ctx, role.Code)
-		if err != nil {
+		id, found := lookup(models.BundleDependencyRole, role.Code)
+		if !found {
 			warnings = append(warnings, fmt.Sprintf("Role '%s' not found for convert-to-request permission", role.Name))
 			continue
 		}
-		convertRoleIDs = append(convertRoleIDs, foundRole.ID)
+		convertRoleIDs = append(convertRoleIDs, id)
 	}
 
-	// Create workflow
+	// Create the workflow, or update the existing one
 	requiredFieldsJSON, _ := json.Marshal(data.Workflow.RequiredFields)
-	workflow := &models.Workflow{
-		ID:             uuid.New(),
-		Name:           data.Workflow.Name,
-		Code:           workflowCode,
-		Description:    data.Workflow.Description,
-		RecordType:     data.Workflow.RecordType,
-		RequiredFields: string(requiredFieldsJSON),
-		CreatedByID:    &createdByID,
-		IsActive:       false, // Start as inactive
-		Version:        1,
-	}
+	var workflow *models.Workflow
+	if existing != nil {
+		workflow = existing
+		if err := tx.Model(&models.Workflow{}).Where("id = ?", workflow.ID).Updates(map[string]interface{}{
+			"name":            data.Workflow.Name,
+			"description":     data.Workflow.Description,
+			"record_type":     data.Workflow.RecordType,
+			"required_fields": string(requiredFieldsJSON),
+		}).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 
-	if err := tx.Create(workflow).Error; err != nil {
-		tx.Rollback()
-		return nil, nil, err
+		// The bundle's classifications and convert-to-request roles replace the current ones
+		for _, stmt := range []string{
+			"DELETE FROM workflow_classifications WHERE workflow_id = ?",
+			"DELETE FROM workflow_convert_to_request_roles WHERE workflow_id = ?",
+		} {
+			if err := tx.Exec(stmt, workflow.ID).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		}
+	} else {
+		workflow = &models.Workflow{
+			ID:             uuid.New(),
+			Name:           data.Workflow.Name,
+			Code:           workflowCode,
+			Description:    data.Workflow.Description,
+			RecordType:     data.Workflow.RecordType,
+			RequiredFields: string(requiredFieldsJSON),
+			CreatedByID:    &createdByID,
+			IsActive:       false, // Start as inactive
+			Version:        1,
+		}
+
+		if err := tx.Create(workflow).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
 	}
+	resolver.selfID = workflow.ID
 
 	// Assign classifications
 	if len(classificationIDs) > 0 {
 		if err := tx.Exec("INSERT INTO workflow_classifications (workflow_id, classification_id) VALUES "+
 			buildBulkInsertValues(workflow.ID, classificationIDs)).Error; err != nil {
 			tx.Rollback()
-			return nil, nil, err
+			return nil, err
 		}
 	}
 
@@ -1262,9 +1619,32 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 		if err := tx.Exec("INSERT INTO workflow_convert_to_request_roles (workflow_id, role_id) VALUES "+
 			buildBulkInsertValues(workflow.ID, convertRoleIDs)).Error; err != nil {
 			tx.Rollback()
-			return nil, nil, err
+			return nil, err
+		}
+	}
+
+	// When updating, states and transitions are matched by code and keep their IDs,
+	// so incidents and history keep pointing at them
+	var currentStates []models.WorkflowState
+	var currentTransitions []models.WorkflowTransition
+	if existing != nil {
+		if err := tx.Where("workflow_id = ?", workflow.ID).Find(&currentStates).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Where("workflow_id = ?", workflow.ID).Find(&currentTransitions).Error; err != nil {
+			tx.Rollback()
+			return nil, err
 		}
 	}
+	currentStateByCode := make(map[string]*models.WorkflowState, len(currentStates))
+	for i := range currentStates {
+		currentStateByCode[currentStates[i].Code] = &currentStates[i]
+	}
+	currentTransitionByCode := make(map[string]*models.WorkflowTransition, len(currentTransitions))
+	for i := range currentTransitions {
+		currentTransitionByCode[currentTransitions[i].Code] = &currentTransitions[i]
+	}
 
 	// Create states and build code to ID mapping
 	stateCodeToID := make(map[string]uuid.UUID)
@@ -1282,11 +1662,31 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 			SLAHours:    stateData.SLAHours,
 			SortOrder:   stateData.SortOrder,
 			IsActive:    true,
+
+			SubRecordType: stateData.SubRecordType,
+		}
+		if stateData.SubWorkflow != nil {
+			if id, found := lookup(models.BundleDependencyWorkflow, stateData.SubWorkflow.Code); found {
+				state.SubWorkflowID = &id
+			} else {
+				warnings = append(warnings, fmt.Sprintf("Sub-workflow '%s' not found for state '%s'", stateData.SubWorkflow.Name, stateData.Name))
+			}
 		}
 
-		if err := tx.Create(state).Error; err != nil {
+		if current, ok := currentStateByCode[stateData.Code]; ok {
+			state.ID = current.ID
+			state.CreatedAt = current.CreatedAt
+			if err := tx.Save(state).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if err := tx.Exec("DELETE FROM state_viewable_roles WHERE workflow_state_id = ?", state.ID).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		} else if err := tx.Create(state).Error; err != nil {
 			tx.Rollback()
-			return nil, nil, err
+			return nil, err
 		}
 
 		stateCodeToID[stateData.Code] = state.ID
@@ -1295,36 +1695,74 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 		if len(stateData.ViewableRoles) > 0 {
 			roleIDs := []uuid.UUID{}
 			for _, roleRef := range stateData.ViewableRoles {
-				role, err := s.roleRepo.FindByCode(ctx, roleRef.Code)
-				if err != nil {
+				id, found := lookup(models.BundleDependencyRole, roleRef.Code)
+				if !found {
 					warnings = append(warnings, fmt.Sprintf("Role '%s' not found for state '%s'", roleRef.Name, stateData.Name))
 					continue
 				}
-				roleIDs = append(roleIDs, role.ID)
+				roleIDs = append(roleIDs, id)
 			}
 
 			if len(roleIDs) > 0 {
 				if err := tx.Exec("INSERT INTO state_viewable_roles (workflow_state_id, role_id) VALUES "+
 					buildBulkInsertValues(state.ID, roleIDs)).Error; err != nil {
 					tx.Rollback()
-					return nil, nil, err
+					return nil, err
 				}
 			}
 		}
 	}
 
+	// Records pinned to a published version still use the states and transitions in its
+	// snapshot, so dropping one of those fails the import; a dry run lists the conflicts
+	txRepo := repository.NewWorkflowRepository(tx)
+	var pinnedErrs []error
+	checkDropped := func(name string, uses func(snapshot *models.Workflow) bool) error {
+		err := checkPinnedVersions(ctx, txRepo, workflow.ID, name, uses)
+		if errors.Is(err, ErrWorkflowItemPinned) {
+			pinnedErrs = append(pinnedErrs, err)
+			return nil
+		}
+		return err
+	}
+
+	// States dropped from the bundle may still hold incidents, so they are deactivated rather than deleted
+	for _, state := range currentStates {
+		if _, kept := stateCodeToID[state.Code]; kept || !state.IsActive {
+			continue
+		}
+		err := checkDropped(fmt.Sprintf("state '%s'", state.Name), func(snapshot *models.Workflow) bool {
+			for _, st := range snapshot.States {
+				if st.ID == state.ID {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Model(&models.WorkflowState{}).Where("id = ?", state.ID).Update("is_active", false).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf("State '%s' is not in the bundle and was deactivated", state.Name))
+	}
+
 	// Create transitions
+	importedTransitions := make(map[string]bool)
 	for _, transData := range data.Workflow.Transitions {
 		fromStateID, ok := stateCodeToID[transData.FromStateCode]
 		if !ok {
 			tx.Rollback()
-			return nil, nil, fmt.Errorf("invalid from_state_code: %s", transData.FromStateCode)
+			return nil, fmt.Errorf("invalid from_state_code: %s", transData.FromStateCode)
 		}
 
 		toStateID, ok := stateCodeToID[transData.ToStateCode]
 		if !ok {
 			tx.Rollback()
-			return nil, nil, fmt.Errorf("invalid to_state_code: %s", transData.ToStateCode)
+			return nil, fmt.Errorf("invalid to_state_code: %s", transData.ToStateCode)
 		}
 
 		transition := &models.WorkflowTransition{
@@ -1338,26 +1776,36 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 			AutoDetectDepartment: transData.AutoDetectDepartment,
 			AutoMatchUser:        transData.AutoMatchUser,
 			ManualSelectUser:     transData.ManualSelectUser,
+			ApprovalMode:         transData.ApprovalMode,
+			ApprovalQuorum:       transData.ApprovalQuorum,
+			AutoTrigger:          transData.AutoTrigger,
+			AutoAfterHours:       transData.AutoAfterHours,
+			ChildStateCode:       transData.ChildStateCode,
 			SortOrder:            transData.SortOrder,
 			IsActive:             true,
 		}
 
+		if transData.RejectStateCode != "" {
+			if rejectStateID, ok := stateCodeToID[transData.RejectStateCode]; ok {
+				transition.RejectStateID = &rejectStateID
+			} else {
+				warnings = append(warnings, fmt.Sprintf("Reject state '%s' not found for transition '%s'", transData.RejectStateCode, transData.Name))
+			}
+		}
+
 		// Resolve department
 		if transData.AssignDepartment != nil {
-			dept, err := s.deptRepo.FindByCode(ctx, transData.AssignDepartment.Code)
-			if err != nil {
-				warnings = append(warnings, fmt.Sprintf("Department '%s' not found for transition '%s'", transData.AssignDepartment.Name, transData.Name))
+			if id, found := lookup(models.BundleDependencyDepartment, transData.AssignDepartment.Code); found {
+				transition.AssignDepartmentID = &id
 			} else {
-				transition.AssignDepartmentID = &dept.ID
+				warnings = append(warnings, fmt.Sprintf("Department '%s' not found for transition '%s'", transData.AssignDepartment.Name, transData.Name))
 			}
 		}
 
 		// Resolve user (skip if not found, as users are environment-specific)
 		if transData.AssignUser != nil {
-			var user models.User
-			err := tx.Where("email = ?", transData.AssignUser.Code).First(&user).Error
-			if err == nil {
-				transition.AssignUserID = &user.ID
+			if id, found := lookup(models.BundleDependencyUser, transData.AssignUser.Code); found {
+				transition.AssignUserID = &id
 			} else {
 				warnings = append(warnings, fmt.Sprintf("User '%s' not found for transition '%s'", transData.AssignUser.Name, transData.Name))
 			}
@@ -1365,36 +1813,82 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 
 		// Resolve assignment role
 		if transData.AssignmentRole != nil {
-			role, err := s.roleRepo.FindByCode(ctx, transData.AssignmentRole.Code)
-			if err != nil {
-				warnings = append(warnings, fmt.Sprintf("Assignment role '%s' not found for transition '%s'", transData.AssignmentRole.Name, transData.Name))
+			if id, found := lookup(models.BundleDependencyRole, transData.AssignmentRole.Code); found {
+				transition.AssignmentRoleID = &id
 			} else {
-				transition.AssignmentRoleID = &role.ID
+				warnings = append(warnings, fmt.Sprintf("Assignment role '%s' not found for transition '%s'", transData.AssignmentRole.Name, transData.Name))
 			}
 		}
 
-		if err := tx.Create(transition).Error; err != nil {
+		if current, ok := currentTransitionByCode[transData.Code]; ok {
+			// Roles, approvers, requirements and actions are replaced by the bundle's
+			transition.ID = current.ID
+			transition.CreatedAt = current.CreatedAt
+			if err := tx.Save(transition).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			for _, stmt := range []string{
+				"DELETE FROM transition_allowed_roles WHERE workflow_transition_id = ?",
+				"DELETE FROM transition_approvers WHERE workflow_transition_id = ?",
+			} {
+				if err := tx.Exec(stmt, transition.ID).Error; err != nil {
+					tx.Rollback()
+					return nil, err
+				}
+			}
+			if err := tx.Where("transition_id = ?", transition.ID).Delete(&models.TransitionRequirement{}).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			if err := tx.Where("transition_id = ?", transition.ID).Delete(&models.TransitionAction{}).Error; err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+		} else if err := tx.Create(transition).Error; err != nil {
 			tx.Rollback()
-			return nil, nil, err
+			return nil, err
 		}
+		importedTransitions[transData.Code] = true
 
 		// Assign allowed roles
 		if len(transData.AllowedRoles) > 0 {
 			roleIDs := []uuid.UUID{}
 			for _, roleRef := range transData.AllowedRoles {
-				role, err := s.roleRepo.FindByCode(ctx, roleRef.Code)
-				if err != nil {
+				id, found := lookup(models.BundleDependencyRole, roleRef.Code)
+				if !found {
 					warnings = append(warnings, fmt.Sprintf("Role '%s' not found for transition '%s'", roleRef.Name, transData.Name))
 					continue
 				}
-				roleIDs = append(roleIDs, role.ID)
+				roleIDs = append(roleIDs, id)
 			}
 
 			if len(roleIDs) > 0 {
 				if err := tx.Exec("INSERT INTO transition_allowed_roles (workflow_transition_id, role_id) VALUES "+
 					buildBulkInsertValues(transition.ID, roleIDs)).Error; err != nil {
 					tx.Rollback()
-					return nil, nil, err
+					return nil, err
+				}
+			}
+		}
+
+		// Resolve approvers (skip if not found, as users are environment-specific)
+		if len(transData.Approvers) > 0 {
+			approverIDs := []uuid.UUID{}
+			for _, userRef := range transData.Approvers {
+				id, found := lookup(models.BundleDependencyUser, userRef.Code)
+				if !found {
+					warnings = append(warnings, fmt.Sprintf("Approver '%s' not found for transition '%s'", userRef.Name, transData.Name))
+					continue
+				}
+				approverIDs = append(approverIDs, id)
+			}
+
+			if len(approverIDs) > 0 {
+				if err := tx.Exec("INSERT INTO transition_approvers (workflow_transition_id, user_id) VALUES "+
+					buildBulkInsertValues(transition.ID, approverIDs)).Error; err != nil {
+					tx.Rollback()
+					return nil, err
 				}
 			}
 		}
@@ -1406,26 +1900,43 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 				TransitionID:    transition.ID,
 				RequirementType: reqData.RequirementType,
 				FieldName:       reqData.FieldName,
+				Operator:        reqData.Operator,
 				FieldValue:      reqData.FieldValue,
+				MinCount:        reqData.MinCount,
 				IsMandatory:     reqData.IsMandatory,
 				ErrorMessage:    reqData.ErrorMessage,
 			}
 
 			if err := tx.Create(requirement).Error; err != nil {
 				tx.Rollback()
-				return nil, nil, err
+				return nil, err
 			}
 		}
 
 		// Create actions
 		for _, actionData := range transData.Actions {
+			config, unresolved, err := resolver.resolveActionConfig(actionData.ActionType, actionData.Config)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			unknownLookups, err := s.unknownLookupCategories(ctx, actionData.Condition)
+			if err != nil {
+				tx.Rollback()
+				return nil, err
+			}
+			for _, code := range unknownLookups {
+				unresolved = append(unresolved, "lookup category "+code)
+			}
+
 			action := &models.TransitionAction{
 				ID:             uuid.New(),
 				TransitionID:   transition.ID,
 				ActionType:     actionData.ActionType,
 				Name:           actionData.Name,
 				Description:    actionData.Description,
-				Config:         actionData.Config,
+				Config:         config,
+				Condition:      actionData.Condition,
 				ExecutionOrder: actionData.ExecutionOrder,
 				IsAsync:        actionData.IsAsync,
 				IsActive:       actionData.IsActive,
@@ -1433,24 +1944,123 @@ func (s *workflowService) ImportWorkflow(ctx context.Context, data *models.Workf
 
 			if err := tx.Create(action).Error; err != nil {
 				tx.Rollback()
-				return nil, nil, err
+				return nil, err
+			}
+
+			// An action pointing at a missing record would fail or touch the wrong one, so it
+			// is kept but disabled. is_active has a default, so false has to be written explicitly.
+			if len(unresolved) > 0 {
+				warnings = append(warnings, fmt.Sprintf("Action '%s' on transition '%s' was disabled: %s not found", actionData.Name, transData.Name, strings.Join(unresolved, ", ")))
+			}
+			if !actionData.IsActive || len(unresolved) > 0 {
+				if err := tx.Model(action).Update("is_active", false).Error; err != nil {
+					tx.Rollback()
+					return nil, err
+				}
 			}
 		}
 	}
 
+	// Transitions dropped from the bundle are removed
+	for _, transition := range currentTransitions {
+		if importedTransitions[transition.Code] {
+			continue
+		}
+		err := checkDropped(fmt.Sprintf("transition '%s'", transition.Name), func(snapshot *models.Workflow) bool {
+			for _, t := range snapshot.Transitions {
+				if t.ID == transition.ID {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Delete(&models.WorkflowTransition{}, "id = ?", transition.ID).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf("Transition '%s' is not in the bundle and was removed", transition.Name))
+	}
+	if len(pinnedErrs) > 0 {
+		if !opts.DryRun {
+			tx.Rollback()
+			return nil, errors.Join(pinnedErrs...)
+		}
+		for _, err := range pinnedErrs {
+			warnings = append(warnings, err.Error())
+		}
+	}
+
+	// Validate inside the transaction so dry runs report the same graph problems
+	var imported models.Workflow
+	if err := tx.Preload("States").Preload("Transitions.AllowedRoles").First(&imported, "id = ?", workflow.ID).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	result, err := s.validateLoadedWorkflow(ctx, &imported)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// An active workflow must stay valid; new imports start inactive and only report problems
+	if existing != nil && existing.IsActive && len(result.Errors) > 0 {
+		tx.Rollback()
+		return nil, &WorkflowValidationError{Result: result}
+	}
+	for _, issue := range append(result.Errors, result.Warnings...) {
+		warnings = append(warnings, fmt.Sprintf("[%s] %s", issue.Severity, issue.Message))
+	}
+
+	response := &models.WorkflowImportResponse{
+		Mode:     mode,
+		DryRun:   opts.DryRun,
+		Missing:  missing,
+		Warnings: warnings,
+	}
+
+	if opts.DryRun {
+		if err := tx.Rollback().Error; err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	// Fetch the created workflow with all relations
-	createdWorkflow, err := s.repo.FindByIDWithRelations(ctx, workflow.ID)
+	// Fetch the imported workflow with all relations
+	importedWorkflow, err := s.repo.FindByIDWithRelations(ctx, workflow.ID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	resp := models.ToWorkflowResponse(createdWorkflow)
-	return &resp, warnings, nil
+	resp := models.ToWorkflowResponse(importedWorkflow)
+	response.Workflow = &resp
+	return response, nil
+}
+
+// userCodeNamePair identifies a user by email in exports
+func userCodeNamePair(user *models.User) models.CodeNamePair {
+	fullName := user.FirstName
+	if user.LastName != "" {
+		if fullName != "" {
+			fullName += " "
+		}
+		fullName += user.LastName
+	}
+	if fullName == "" {
+		fullName = user.Username
+	}
+	return models.CodeNamePair{
+		Code: user.Email,
+		Name: fullName,
+	}
 }
 
 // Helper function to build bulk insert SQL values
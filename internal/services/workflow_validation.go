@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WorkflowValidationError is returned when a workflow with validation errors is activated,
+// or when an edit would leave an active workflow with validation errors
+type WorkflowValidationError struct {
+	Result *models.WorkflowValidationResult
+}
+
+func (e *WorkflowValidationError) Error() string {
+	messages := make([]string, len(e.Result.Errors))
+	for i, issue := range e.Result.Errors {
+		messages[i] = issue.Message
+	}
+	return "workflow is invalid: " + strings.Join(messages, "; ")
+}
+
+// ValidateWorkflow checks a workflow graph for structural problems
+func (s *workflowService) ValidateWorkflow(ctx context.Context, id uuid.UUID) (*models.WorkflowValidationResult, error) {
+	workflow, err := s.repo.FindByIDWithRelations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.validateLoadedWorkflow(ctx, workflow)
+}
+
+// editWorkflowGraph applies a change to a workflow's states or transitions. Changes to an
+// active workflow run in a transaction that is rolled back when the edit introduces
+// validation errors that weren't there before; errors about states the edit created (a new
+// state has no transitions yet) are left for activation. Inactive workflows are edited
+// directly and validated on activation.
+func (s *workflowService) editWorkflowGraph(ctx context.Context, workflowID uuid.UUID, edit func(repo repository.WorkflowRepository) error) error {
+	workflow, err := s.repo.FindByIDWithRelations(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	if !workflow.IsActive {
+		return edit(s.repo)
+	}
+	before, err := s.validateLoadedWorkflow(ctx, workflow)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		repo := repository.NewWorkflowRepository(tx)
+		if err := edit(repo); err != nil {
+			return err
+		}
+		edited, err := repo.FindByIDWithRelations(ctx, workflowID)
+		if err != nil {
+			return err
+		}
+		result, err := s.validateLoadedWorkflow(ctx, edited)
+		if err != nil {
+			return err
+		}
+		if introduced := introducedIssues(before, result, workflow, edited); len(introduced) > 0 {
+			result.Valid = false
+			result.Errors = introduced
+			return &WorkflowValidationError{Result: result}
+		}
+		return nil
+	})
+}
+
+// introducedIssues returns the errors in after that weren't reported in before, ignoring
+// errors about states that only exist in the edited workflow
+func introducedIssues(before, after *models.WorkflowValidationResult, original, edited *models.Workflow) []models.WorkflowValidationIssue {
+	type issueKey struct {
+		code         string
+		stateID      uuid.UUID
+		transitionID uuid.UUID
+	}
+	keyOf := func(issue models.WorkflowValidationIssue) issueKey {
+		key := issueKey{code: issue.Code}
+		if issue.StateID != nil {
+			key.stateID = *issue.StateID
+		}
+		if issue.TransitionID != nil {
+			key.transitionID = *issue.TransitionID
+		}
+		return key
+	}
+
+	existing := make(map[issueKey]bool, len(before.Errors))
+	for _, issue := range before.Errors {
+		existing[keyOf(issue)] = true
+	}
+	originalStates := make(map[uuid.UUID]bool, len(original.States))
+	for _, state := range original.States {
+		originalStates[state.ID] = true
+	}
+	newStates := make(map[uuid.UUID]bool)
+	for _, state := range edited.States {
+		if !originalStates[state.ID] {
+			newStates[state.ID] = true
+		}
+	}
+
+	var introduced []models.WorkflowValidationIssue
+	for _, issue := range after.Errors {
+		if existing[keyOf(issue)] {
+			continue
+		}
+		if issue.StateID != nil && newStates[*issue.StateID] {
+			continue
+		}
+		introduced = append(introduced, issue)
+	}
+	return introduced
+}
+
+// validateLoadedWorkflow validates a workflow whose states and transitions (with allowed
+// roles) are already loaded, e.g. inside an import transaction
+func (s *workflowService) validateLoadedWorkflow(ctx context.Context, workflow *models.Workflow) (*models.WorkflowValidationResult, error) {
+	// Collect the roles used by transitions to check they are held by someone
+	var roleIDs []uuid.UUID
+	seenRoles := make(map[uuid.UUID]bool)
+	for _, t := range workflow.Transitions {
+		for _, role := range t.AllowedRoles {
+			if !seenRoles[role.ID] {
+				seenRoles[role.ID] = true
+				roleIDs = append(roleIDs, role.ID)
+			}
+		}
+	}
+	heldRoles := make(map[uuid.UUID]bool)
+	if len(roleIDs) > 0 {
+		held, err := s.roleRepo.FindRoleIDsWithActiveUsers(ctx, roleIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, roleID := range held {
+			heldRoles[roleID] = true
+		}
+	}
+
+	return validateWorkflowGraph(workflow, heldRoles), nil
+}
+
+// validateWorkflowGraph runs the graph checks on a loaded workflow. heldRoles contains
+// the allowed role IDs that at least one active user holds.
+func validateWorkflowGraph(workflow *models.Workflow, heldRoles map[uuid.UUID]bool) *models.WorkflowValidationResult {
+	result := &models.WorkflowValidationResult{
+		WorkflowID: workflow.ID,
+		Errors:     []models.WorkflowValidationIssue{},
+		Warnings:   []models.WorkflowValidationIssue{},
+	}
+	add := func(severity, code, message string, stateID, transitionID *uuid.UUID) {
+		issue := models.WorkflowValidationIssue{
+			Severity:     severity,
+			Code:         code,
+			Message:      message,
+			StateID:      stateID,
+			TransitionID: transitionID,
+		}
+		if severity == models.WorkflowIssueError {
+			result.Errors = append(result.Errors, issue)
+		} else {
+			result.Warnings = append(result.Warnings, issue)
+		}
+	}
+
+	// Only active states and transitions take part in the graph
+	states := make(map[uuid.UUID]*models.WorkflowState)
+	var initialStates []*models.WorkflowState
+	hasTerminal := false
+	for i := range workflow.States {
+		state := &workflow.States[i]
+		if !state.IsActive {
+			continue
+		}
+		states[state.ID] = state
+		switch state.StateType {
+		case "initial":
+			initialStates = append(initialStates, state)
+		case "terminal":
+			hasTerminal = true
+		case models.StateTypeSubWorkflow:
+			if state.SubWorkflowID == nil {
+				add(models.WorkflowIssueError, "missing_sub_workflow",
+					fmt.Sprintf("Sub-workflow state '%s' has no workflow to launch", state.Name),
+					&state.ID, nil)
+			} else if *state.SubWorkflowID == workflow.ID {
+				add(models.WorkflowIssueError, "recursive_sub_workflow",
+					fmt.Sprintf("Sub-workflow state '%s' launches its own workflow", state.Name),
+					&state.ID, nil)
+			}
+		}
+	}
+
+	switch {
+	case len(initialStates) == 0:
+		add(models.WorkflowIssueError, "missing_initial_state", "Workflow has no initial state", nil, nil)
+	case len(initialStates) > 1:
+		for _, state := range initialStates {
+			add(models.WorkflowIssueError, "multiple_initial_states",
+				fmt.Sprintf("State '%s' is one of %d initial states; only one is allowed", state.Name, len(initialStates)),
+				&state.ID, nil)
+		}
+	}
+	if len(states) > 0 && !hasTerminal {
+		add(models.WorkflowIssueWarning, "missing_terminal_state", "Workflow has no terminal state, records can never be closed", nil, nil)
+	}
+
+	outgoing := make(map[uuid.UUID][]uuid.UUID)
+	childCompleted := make(map[uuid.UUID]bool)
+	codes := make(map[string][]*models.WorkflowTransition)
+	for i := range workflow.Transitions {
+		t := &workflow.Transitions[i]
+		if !t.IsActive {
+			continue
+		}
+
+		from, fromOK := states[t.FromStateID]
+		_, toOK := states[t.ToStateID]
+		if !fromOK || !toOK {
+			add(models.WorkflowIssueError, "invalid_transition_state",
+				fmt.Sprintf("Transition '%s' connects a state that is inactive or not part of this workflow", t.Name),
+				nil, &t.ID)
+			continue
+		}
+
+		outgoing[t.FromStateID] = append(outgoing[t.FromStateID], t.ToStateID)
+		codes[strings.ToLower(t.Code)] = append(codes[strings.ToLower(t.Code)], t)
+
+		if from.StateType == "terminal" {
+			add(models.WorkflowIssueWarning, "transition_from_terminal",
+				fmt.Sprintf("Transition '%s' leaves terminal state '%s'", t.Name, from.Name),
+				&from.ID, &t.ID)
+		}
+
+		if t.AutoTrigger == models.AutoTriggerChildCompleted {
+			if from.StateType != models.StateTypeSubWorkflow {
+				add(models.WorkflowIssueError, "child_completed_outside_sub_workflow",
+					fmt.Sprintf("Transition '%s' waits for a sub-workflow but '%s' is not a sub-workflow state", t.Name, from.Name),
+					&from.ID, &t.ID)
+			}
+			childCompleted[from.ID] = true
+		}
+
+		if len(t.AllowedRoles) > 0 {
+			held := false
+			for _, role := range t.AllowedRoles {
+				if heldRoles[role.ID] {
+					held = true
+					break
+				}
+			}
+			if !held {
+				add(models.WorkflowIssueWarning, "transition_roles_unassigned",
+					fmt.Sprintf("No active user holds any of the roles allowed to execute transition '%s'", t.Name),
+					nil, &t.ID)
+			}
+		}
+	}
+
+	for code, transitions := range codes {
+		if len(transitions) < 2 {
+			continue
+		}
+		for _, t := range transitions {
+			add(models.WorkflowIssueError, "duplicate_transition_code",
+				fmt.Sprintf("Transition code '%s' is used by %d transitions", code, len(transitions)),
+				nil, &t.ID)
+		}
+	}
+
+	// Reachability from the initial state(s)
+	reachable := make(map[uuid.UUID]bool)
+	queue := make([]uuid.UUID, 0, len(initialStates))
+	for _, state := range initialStates {
+		reachable[state.ID] = true
+		queue = append(queue, state.ID)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, next := range outgoing[current] {
+			if !reachable[next] {
+				reachable[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	for i := range workflow.States {
+		state := &workflow.States[i]
+		if _, ok := states[state.ID]; !ok {
+			continue
+		}
+		if len(initialStates) > 0 && !reachable[state.ID] {
+			add(models.WorkflowIssueWarning, "unreachable_state",
+				fmt.Sprintf("State '%s' cannot be reached from the initial state", state.Name),
+				&state.ID, nil)
+		}
+		if state.StateType != "terminal" && len(outgoing[state.ID]) == 0 {
+			add(models.WorkflowIssueError, "dead_end_state",
+				fmt.Sprintf("State '%s' is not terminal but has no outgoing transitions", state.Name),
+				&state.ID, nil)
+		}
+		if state.StateType == models.StateTypeSubWorkflow && !childCompleted[state.ID] {
+			add(models.WorkflowIssueWarning, "sub_workflow_not_resumed",
+				fmt.Sprintf("Sub-workflow state '%s' has no child_completed transition, records wait there until moved manually", state.Name),
+				&state.ID, nil)
+		}
+	}
+
+	result.Valid = len(result.Errors) == 0
+	return result
+}
@@ -0,0 +1,518 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/automax/backend/internal/models"
+	"github.com/automax/backend/internal/repository"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrWorkflowItemPinned is returned when deleting a state or transition that a published
+// version with open incidents still uses. Snapshots reference live state and transition IDs.
+var ErrWorkflowItemPinned = errors.New("used by a published version with open records")
+
+// checkPinnedVersions refuses a change when a version that open incidents are pinned to
+// contains the item, as reported by uses
+func checkPinnedVersions(ctx context.Context, workflowRepo repository.WorkflowRepository, workflowID uuid.UUID, name string, uses func(snapshot *models.Workflow) bool) error {
+	versions, err := workflowRepo.ListOpenPinnedVersions(ctx, workflowID)
+	if err != nil {
+		return err
+	}
+	var pinned []string
+	for _, version := range versions {
+		snapshot, err := loadWorkflowSnapshot(ctx, workflowRepo, workflowID, version)
+		if err != nil {
+			return err
+		}
+		if uses(snapshot) {
+			pinned = append(pinned, strconv.Itoa(version))
+		}
+	}
+	if len(pinned) > 0 {
+		return fmt.Errorf("%s is %w (version %s); migrate those records first", name, ErrWorkflowItemPinned, strings.Join(pinned, ", "))
+	}
+	return nil
+}
+
+// loadWorkflowSnapshot returns the workflow definition stored with a published version
+func loadWorkflowSnapshot(ctx context.Context, workflowRepo repository.WorkflowRepository, workflowID uuid.UUID, version int) (*models.Workflow, error) {
+	v, err := workflowRepo.FindVersion(ctx, workflowID, version)
+	if err != nil {
+		return nil, fmt.Errorf("workflow version %d not found", version)
+	}
+	return decodeWorkflowSnapshot(v)
+}
+
+func decodeWorkflowSnapshot(v *models.WorkflowVersion) (*models.Workflow, error) {
+	var workflow models.Workflow
+	if err := json.Unmarshal([]byte(v.Snapshot), &workflow); err != nil {
+		return nil, fmt.Errorf("invalid snapshot for workflow version %d: %w", v.Version, err)
+	}
+	return &workflow, nil
+}
+
+// resolveWorkflowStart returns the initial state and version new records of a workflow
+// start on. Workflows that were never published run on their live definition (version 0).
+func resolveWorkflowStart(ctx context.Context, workflowRepo repository.WorkflowRepository, workflowID uuid.UUID) (*models.WorkflowState, int, error) {
+	latest, err := workflowRepo.GetLatestVersion(ctx, workflowID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, err
+		}
+		state, err := workflowRepo.GetInitialState(ctx, workflowID)
+		if err != nil {
+			return nil, 0, err
+		}
+		return state, 0, nil
+	}
+
+	snapshot, err := decodeWorkflowSnapshot(latest)
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range snapshot.States {
+		state := &snapshot.States[i]
+		if state.StateType == "initial" && state.IsActive {
+			return state, latest.Version, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("workflow version %d has no initial state", latest.Version)
+}
+
+// loadIncidentTransition returns a transition as defined in the workflow version the
+// incident is pinned to, or the live transition for unpinned incidents
+func loadIncidentTransition(ctx context.Context, workflowRepo repository.WorkflowRepository, incident *models.Incident, transitionID uuid.UUID) (*models.WorkflowTransition, error) {
+	if incident.WorkflowVersion == 0 {
+		return workflowRepo.FindTransitionByIDWithRelations(ctx, transitionID)
+	}
+
+	snapshot, err := loadWorkflowSnapshot(ctx, workflowRepo, incident.WorkflowID, incident.WorkflowVersion)
+	if err != nil {
+		return nil, err
+	}
+	for i := range snapshot.Transitions {
+		if snapshot.Transitions[i].ID == transitionID {
+			return &snapshot.Transitions[i], nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// loadIncidentTransitionsFromState returns the active transitions out of the incident's
+// current state in the version it is pinned to
+func loadIncidentTransitionsFromState(ctx context.Context, workflowRepo repository.WorkflowRepository, incident *models.Incident) ([]models.WorkflowTransition, error) {
+	if incident.WorkflowVersion == 0 {
+		return workflowRepo.ListTransitionsFromState(ctx, incident.CurrentStateID)
+	}
+
+	snapshot, err := loadWorkflowSnapshot(ctx, workflowRepo, incident.WorkflowID, incident.WorkflowVersion)
+	if err != nil {
+		return nil, err
+	}
+	var transitions []models.WorkflowTransition
+	for _, t := range snapshot.Transitions {
+		if t.FromStateID == incident.CurrentStateID && t.IsActive {
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions, nil
+}
+
+// loadIncidentStates returns the states of the workflow version the incident is pinned to
+func loadIncidentStates(ctx context.Context, workflowRepo repository.WorkflowRepository, incident *models.Incident) ([]models.WorkflowState, error) {
+	if incident.WorkflowVersion == 0 {
+		return workflowRepo.ListStatesByWorkflowID(ctx, incident.WorkflowID)
+	}
+
+	snapshot, err := loadWorkflowSnapshot(ctx, workflowRepo, incident.WorkflowID, incident.WorkflowVersion)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.States, nil
+}
+
+// PublishWorkflow validates the workflow and stores its current definition as a new version
+func (s *workflowService) PublishWorkflow(ctx context.Context, id uuid.UUID, req *models.WorkflowPublishRequest, publishedByID uuid.UUID) (*models.WorkflowVersionResponse, error) {
+	result, err := s.ValidateWorkflow(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Valid {
+		return nil, &WorkflowValidationError{Result: result}
+	}
+
+	workflow, err := s.repo.FindByIDWithRelations(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	next := 1
+	if latest, err := s.repo.GetLatestVersion(ctx, id); err == nil {
+		next = latest.Version + 1
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	workflow.Version = next
+	snapshot, err := json.Marshal(workflow)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot workflow: %w", err)
+	}
+
+	version := &models.WorkflowVersion{
+		WorkflowID:    id,
+		Version:       next,
+		Notes:         req.Notes,
+		Snapshot:      string(snapshot),
+		PublishedByID: &publishedByID,
+	}
+	if err := s.repo.CreateVersion(ctx, version); err != nil {
+		return nil, err
+	}
+
+	created, err := s.repo.FindVersion(ctx, id, next)
+	if err != nil {
+		return nil, err
+	}
+	resp := models.ToWorkflowVersionResponse(created)
+	return &resp, nil
+}
+
+func (s *workflowService) ListVersions(ctx context.Context, id uuid.UUID) ([]models.WorkflowVersionResponse, error) {
+	versions, err := s.repo.ListVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	responses := make([]models.WorkflowVersionResponse, len(versions))
+	for i := range versions {
+		responses[i] = models.ToWorkflowVersionResponse(&versions[i])
+	}
+	return responses, nil
+}
+
+func (s *workflowService) GetVersion(ctx context.Context, id uuid.UUID, version int) (*models.WorkflowVersionDetailResponse, error) {
+	v, err := s.repo.FindVersion(ctx, id, version)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := decodeWorkflowSnapshot(v)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WorkflowVersionDetailResponse{
+		WorkflowVersionResponse: models.ToWorkflowVersionResponse(v),
+		Workflow:                models.ToWorkflowResponse(snapshot),
+	}, nil
+}
+
+// MigrateIncidents moves open incidents pinned to one version onto another, mapping
+// their current states. The whole migration is rejected if any state cannot be mapped.
+func (s *workflowService) MigrateIncidents(ctx context.Context, id uuid.UUID, req *models.WorkflowMigrateRequest) (*models.WorkflowMigrateResponse, error) {
+	toVersion := req.ToVersion
+	if toVersion == 0 {
+		latest, err := s.repo.GetLatestVersion(ctx, id)
+		if err != nil {
+			return nil, errors.New("workflow has no published version")
+		}
+		toVersion = latest.Version
+	}
+	if req.FromVersion == toVersion {
+		return nil, errors.New("from_version and to_version must differ")
+	}
+
+	target, err := loadWorkflowSnapshot(ctx, s.repo, id, toVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	// States of the source version, used to match by code
+	var sourceStates []models.WorkflowState
+	if req.FromVersion == 0 {
+		sourceStates, err = s.repo.ListStatesByWorkflowID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		source, err := loadWorkflowSnapshot(ctx, s.repo, id, req.FromVersion)
+		if err != nil {
+			return nil, err
+		}
+		sourceStates = source.States
+	}
+
+	targetByID := make(map[uuid.UUID]*models.WorkflowState)
+	targetByCode := make(map[string]*models.WorkflowState)
+	for i := range target.States {
+		state := &target.States[i]
+		targetByID[state.ID] = state
+		targetByCode[strings.ToLower(state.Code)] = state
+	}
+	sourceByID := make(map[uuid.UUID]*models.WorkflowState)
+	for i := range sourceStates {
+		sourceByID[sourceStates[i].ID] = &sourceStates[i]
+	}
+
+	explicit := make(map[uuid.UUID]uuid.UUID)
+	for from, to := range req.StateMapping {
+		fromID, err := uuid.Parse(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state id in state_mapping: %s", from)
+		}
+		toID, err := uuid.Parse(to)
+		if err != nil {
+			return nil, fmt.Errorf("invalid state id in state_mapping: %s", to)
+		}
+		if _, ok := targetByID[toID]; !ok {
+			return nil, fmt.Errorf("state %s is not part of version %d", to, toVersion)
+		}
+		explicit[fromID] = toID
+	}
+
+	var incidentIDs []uuid.UUID
+	for _, idStr := range req.IncidentIDs {
+		incidentID, err := uuid.Parse(idStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid incident id: %s", idStr)
+		}
+		incidentIDs = append(incidentIDs, incidentID)
+	}
+
+	inUse, err := s.repo.ListOpenIncidentStates(ctx, id, req.FromVersion, incidentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := make(map[uuid.UUID]uuid.UUID)
+	var unmapped []string
+	for _, stateID := range inUse {
+		if to, ok := explicit[stateID]; ok {
+			mapping[stateID] = to
+			continue
+		}
+		if _, ok := targetByID[stateID]; ok {
+			mapping[stateID] = stateID
+			continue
+		}
+		if source, ok := sourceByID[stateID]; ok {
+			if match, ok := targetByCode[strings.ToLower(source.Code)]; ok {
+				mapping[stateID] = match.ID
+				continue
+			}
+			unmapped = append(unmapped, fmt.Sprintf("%s (%s)", source.Name, stateID))
+			continue
+		}
+		unmapped = append(unmapped, stateID.String())
+	}
+	if len(unmapped) > 0 {
+		return nil, fmt.Errorf("no target state for: %s", strings.Join(unmapped, ", "))
+	}
+
+	migrated, err := s.repo.MigrateIncidents(ctx, id, req.FromVersion, toVersion, mapping, incidentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkflowMigrateResponse{
+		FromVersion: req.FromVersion,
+		ToVersion:   toVersion,
+		Migrated:    migrated,
+	}, nil
+}
+
+// DiffVersions compares two versions. A to version of 0 compares against the current draft.
+func (s *workflowService) DiffVersions(ctx context.Context, id uuid.UUID, fromVersion, toVersion int) (*models.WorkflowVersionDiff, error) {
+	from, err := loadWorkflowSnapshot(ctx, s.repo, id, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	var to *models.Workflow
+	if toVersion == 0 {
+		to, err = s.repo.FindByIDWithRelations(ctx, id)
+	} else {
+		to, err = loadWorkflowSnapshot(ctx, s.repo, id, toVersion)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.WorkflowVersionDiff{
+		FromVersion:        fromVersion,
+		ToVersion:          toVersion,
+		WorkflowChanges:    diffFields(workflowDiffFields(from), workflowDiffFields(to)),
+		StatesAdded:        []models.WorkflowDiffEntry{},
+		StatesRemoved:      []models.WorkflowDiffEntry{},
+		StatesChanged:      []models.WorkflowDiffEntry{},
+		TransitionsAdded:   []models.WorkflowDiffEntry{},
+		TransitionsRemoved: []models.WorkflowDiffEntry{},
+		TransitionsChanged: []models.WorkflowDiffEntry{},
+	}
+
+	fromStates := make(map[uuid.UUID]*models.WorkflowState)
+	for i := range from.States {
+		fromStates[from.States[i].ID] = &from.States[i]
+	}
+	toStates := make(map[uuid.UUID]bool)
+	for i := range to.States {
+		state := &to.States[i]
+		toStates[state.ID] = true
+		entry := models.WorkflowDiffEntry{ID: state.ID, Code: state.Code, Name: state.Name}
+		old, ok := fromStates[state.ID]
+		if !ok {
+			diff.StatesAdded = append(diff.StatesAdded, entry)
+			continue
+		}
+		if entry.Changes = diffFields(stateDiffFields(old), stateDiffFields(state)); len(entry.Changes) > 0 {
+			diff.StatesChanged = append(diff.StatesChanged, entry)
+		}
+	}
+	for _, state := range from.States {
+		if !toStates[state.ID] {
+			diff.StatesRemoved = append(diff.StatesRemoved, models.WorkflowDiffEntry{ID: state.ID, Code: state.Code, Name: state.Name})
+		}
+	}
+
+	fromCodes := stateCodes(from)
+	toCodes := stateCodes(to)
+	fromTransitions := make(map[uuid.UUID]*models.WorkflowTransition)
+	for i := range from.Transitions {
+		fromTransitions[from.Transitions[i].ID] = &from.Transitions[i]
+	}
+	toTransitions := make(map[uuid.UUID]bool)
+	for i := range to.Transitions {
+		t := &to.Transitions[i]
+		toTransitions[t.ID] = true
+		entry := models.WorkflowDiffEntry{ID: t.ID, Code: t.Code, Name: t.Name}
+		old, ok := fromTransitions[t.ID]
+		if !ok {
+			diff.TransitionsAdded = append(diff.TransitionsAdded, entry)
+			continue
+		}
+		if entry.Changes = diffFields(transitionDiffFields(old, fromCodes), transitionDiffFields(t, toCodes)); len(entry.Changes) > 0 {
+			diff.TransitionsChanged = append(diff.TransitionsChanged, entry)
+		}
+	}
+	for _, t := range from.Transitions {
+		if !toTransitions[t.ID] {
+			diff.TransitionsRemoved = append(diff.TransitionsRemoved, models.WorkflowDiffEntry{ID: t.ID, Code: t.Code, Name: t.Name})
+		}
+	}
+
+	return diff, nil
+}
+
+func stateCodes(workflow *models.Workflow) map[uuid.UUID]string {
+	codes := make(map[uuid.UUID]string, len(workflow.States))
+	for _, state := range workflow.States {
+		codes[state.ID] = state.Code
+	}
+	return codes
+}
+
+// diffFields returns the fields whose values differ, in field name order
+func diffFields(old, new map[string]string) []models.WorkflowFieldChange {
+	names := make([]string, 0, len(new))
+	for name := range new {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []models.WorkflowFieldChange
+	for _, name := range names {
+		if old[name] != new[name] {
+			changes = append(changes, models.WorkflowFieldChange{Field: name, OldValue: old[name], NewValue: new[name]})
+		}
+	}
+	return changes
+}
+
+func workflowDiffFields(w *models.Workflow) map[string]string {
+	return map[string]string{
+		"name":            w.Name,
+		"code":            w.Code,
+		"description":     w.Description,
+		"record_type":     w.RecordType,
+		"required_fields": w.RequiredFields,
+	}
+}
+
+func stateDiffFields(s *models.WorkflowState) map[string]string {
+	sla := ""
+	if s.SLAHours != nil {
+		sla = strconv.Itoa(*s.SLAHours)
+	}
+	roles := make([]string, len(s.ViewableRoles))
+	for i, role := range s.ViewableRoles {
+		roles[i] = role.Code
+	}
+	sort.Strings(roles)
+	subWorkflow := ""
+	if s.SubWorkflowID != nil {
+		subWorkflow = s.SubWorkflowID.String()
+	}
+	return map[string]string{
+		"name":            s.Name,
+		"code":            s.Code,
+		"description":     s.Description,
+		"state_type":      s.StateType,
+		"sla_hours":       sla,
+		"viewable_roles":  strings.Join(roles, ", "),
+		"sub_workflow_id": subWorkflow,
+		"sub_record_type": s.SubRecordType,
+		"is_active":       strconv.FormatBool(s.IsActive),
+	}
+}
+
+func transitionDiffFields(t *models.WorkflowTransition, codes map[uuid.UUID]string) map[string]string {
+	optionalID := func(id *uuid.UUID) string {
+		if id == nil {
+			return ""
+		}
+		return id.String()
+	}
+	roles := make([]string, len(t.AllowedRoles))
+	for i, role := range t.AllowedRoles {
+		roles[i] = role.Code
+	}
+	sort.Strings(roles)
+
+	requirements := make([]string, len(t.Requirements))
+	for i, r := range t.Requirements {
+		requirements[i] = strings.Join([]string{r.RequirementType, r.FieldName, r.Operator, r.FieldValue}, ":")
+	}
+	sort.Strings(requirements)
+
+	actions := make([]string, len(t.Actions))
+	for i, a := range t.Actions {
+		actions[i] = fmt.Sprintf("%d:%s:%s", a.ExecutionOrder, a.ActionType, a.Name)
+	}
+	sort.Strings(actions)
+
+	return map[string]string{
+		"name":                   t.Name,
+		"code":                   t.Code,
+		"description":            t.Description,
+		"from_state":             codes[t.FromStateID],
+		"to_state":               codes[t.ToStateID],
+		"allowed_roles":          strings.Join(roles, ", "),
+		"requirements":           strings.Join(requirements, ", "),
+		"actions":                strings.Join(actions, ", "),
+		"assign_department_id":   optionalID(t.AssignDepartmentID),
+		"assign_user_id":         optionalID(t.AssignUserID),
+		"assignment_role_id":     optionalID(t.AssignmentRoleID),
+		"auto_detect_department": strconv.FormatBool(t.AutoDetectDepartment),
+		"auto_match_user":        strconv.FormatBool(t.AutoMatchUser),
+		"manual_select_user":     strconv.FormatBool(t.ManualSelectUser),
+		"auto_trigger":           t.AutoTrigger,
+		"child_state_code":       t.ChildStateCode,
+		"is_active":              strconv.FormatBool(t.IsActive),
+	}
+}